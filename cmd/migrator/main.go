@@ -1,5 +1,73 @@
 package main
 
+import (
+	"database/sql"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+
+	_ "github.com/lib/pq"
+)
+
 func main() {
+	dir := flag.String("dir", "migrations", "directory with *.sql migrations")
+	flag.Parse()
+
+	dsn := os.Getenv("DATABASE_URL")
+	if dsn == "" {
+		log.Fatal("DATABASE_URL is not set")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (name TEXT PRIMARY KEY)`); err != nil {
+		log.Fatal(err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(*dir, "*.sql"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		name := filepath.Base(file)
+
+		var applied bool
+		if err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE name = $1)`, name).Scan(&applied); err != nil {
+			log.Fatal(err)
+		}
+		if applied {
+			continue
+		}
+
+		query, err := os.ReadFile(file)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if _, err := tx.Exec(string(query)); err != nil {
+			tx.Rollback()
+			log.Fatalf("migration %s: %v", name, err)
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (name) VALUES ($1)`, name); err != nil {
+			tx.Rollback()
+			log.Fatal(err)
+		}
+		if err := tx.Commit(); err != nil {
+			log.Fatal(err)
+		}
 
+		log.Printf("applied %s", name)
+	}
 }
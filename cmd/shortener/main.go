@@ -1,23 +1,332 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
 	"log"
+	"net"
 	"net/http"
+	"net/netip"
+	"os"
+	"os/signal"
+	"shorted/internal/auth"
+	"shorted/internal/contract"
+	"shorted/internal/domain/repositories"
+	"shorted/internal/repository/instrumented"
 	"shorted/internal/repository/memory"
+	"shorted/internal/repository/postgres"
+	"shorted/internal/service/moderation"
 	"shorted/internal/service/shortener"
+	grpcTransport "shorted/internal/transport/grpc"
 	initRouters "shorted/internal/transport/http"
+	"shorted/internal/transport/http/middleware"
+	"shorted/internal/transport/http/openapi"
+	"shorted/pkg/apierror"
+	"shorted/pkg/codec"
+	"shorted/pkg/geoip"
+	"shorted/pkg/health"
+	"shorted/pkg/metrics"
+	"shorted/pkg/pagemeta"
+	"shorted/pkg/qr"
+	"shorted/pkg/tracing"
+	"shorted/pkg/unshorten"
+	"shorted/pkg/urlpolicy"
+	"shorted/pkg/urlvalidate"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	_ "github.com/lib/pq"
+	"google.golang.org/grpc"
+)
+
+const (
+	// время, за которое оркестратор успевает увидеть not-ready и снять трафик
+	readinessDrainDelay = 5 * time.Second
+	shutdownTimeout     = 10 * time.Second
 )
 
 func main() {
-	linkRepo := memory.NewLinkRepo()
-	shortenerService := shortener.NewService(linkRepo)
-	router := initRouters.NewRouter(shortenerService)
+	checker := health.NewChecker()
+	registry := metrics.NewRegistry()
+	registry.RegisterGoCollector()
+
+	tracer, err := tracing.NewFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var (
+		linkRepo       repositories.LinkRepository       = memory.NewLinkRepo()
+		statsRepo      repositories.StatsRepository      = memory.NewStatsRepo()
+		reportRepo     repositories.ReportRepository     = memory.NewReportRepo()
+		conversionRepo repositories.ConversionRepository = memory.NewConversionRepo()
+		campaignRepo   repositories.CampaignRepository   = memory.NewCampaignRepo()
+	)
+	if dsn := os.Getenv("DATABASE_URL"); dsn != "" {
+		db, err := sql.Open("postgres", dsn)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer db.Close()
+		linkRepo = postgres.NewLinkRepo(db)
+		statsRepo = postgres.NewStatsRepo(db)
+		reportRepo = postgres.NewReportRepo(db)
+		conversionRepo = postgres.NewConversionRepo(db)
+		campaignRepo = postgres.NewCampaignRepo(db)
+	}
+
+	if pinger, ok := linkRepo.(repositories.Pinger); ok {
+		checker.Register("repository", 2*time.Second, pinger.Ping)
+	}
+
+	linkRepo = instrumented.NewLinkRepo(linkRepo, registry, tracer)
+
+	var authenticator auth.Authenticator
+	if keys := auth.ParseStaticKeys(os.Getenv("API_KEYS")); keys.Enabled() {
+		authenticator = keys
+	}
+
+	policy, err := urlpolicy.New(urlpolicy.Config{
+		Files:     splitList(os.Getenv("POLICY_FILES")),
+		AdminFile: os.Getenv("POLICY_ADMIN_FILE"),
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// ADMIN_OWNERS — владельцы ключей с доступом к /api/admin и к ссылкам без владельца
+	admins := splitList(os.Getenv("ADMIN_OWNERS"))
+	serviceOpts := []shortener.Option{
+		shortener.WithURLValidator(newURLValidator()),
+		shortener.WithChainPolicy(newChainPolicy()),
+		shortener.WithPolicy(policy),
+		shortener.WithConversions(conversionRepo),
+		shortener.WithCampaigns(campaignRepo),
+		shortener.WithAdmins(admins...),
+		shortener.WithMetrics(registry),
+	}
+	if os.Getenv("REUSE_EXISTING_LINKS") == "true" {
+		serviceOpts = append(serviceOpts, shortener.WithReuseExisting())
+	}
+	// METADATA_FETCH=false отключает загрузку заголовков страниц назначения
+	if os.Getenv("METADATA_FETCH") != "false" {
+		serviceOpts = append(serviceOpts, shortener.WithMetadataFetcher(pagemeta.New(pagemeta.Config{
+			AllowPrivate: os.Getenv("URL_ALLOW_PRIVATE") == "true",
+		})))
+	}
+	// GEOIP_DB — база MaxMind DB для географических правил ссылок
+	if path := os.Getenv("GEOIP_DB"); path != "" {
+		geo, err := geoip.Open(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer geo.Close()
+		serviceOpts = append(serviceOpts, shortener.WithGeoLocator(geo))
+	}
+	shortenerService := shortener.NewService(linkRepo, statsRepo, tracer, serviceOpts...)
+	moderationService := moderation.NewService(linkRepo, reportRepo, tracer, newModerationOptions()...)
+	codecs := codec.Default()
+	router, err := initRouters.NewRouter(initRouters.Deps{
+		Shortener:  shortenerService,
+		Moderation: moderationService,
+		Auth:       authenticator,
+		Health:     checker,
+		Metrics:    registry,
+		Tracer:     tracer,
+		Errors:     newErrorWriter(codecs),
+		Codecs:     codecs,
+		Validation: openapi.Validation{
+			Requests:  os.Getenv("OPENAPI_VALIDATE_REQUESTS") == "true",
+			Responses: os.Getenv("OPENAPI_VALIDATE_RESPONSES") == "true",
+		},
+		Policy:  policy,
+		Admins:  admins,
+		QRCache: newQRCache(),
+		QRLogo:  loadQRLogo(os.Getenv("QR_LOGO_FILE")),
+
+		TrustedProxies: newTrustedProxies(),
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	server := &http.Server{
 		Addr:    ":8080",
 		Handler: router,
 	}
 
-	log.Println("Server starting on :8080")
-	log.Fatal(server.ListenAndServe())
+	grpcServer := grpcTransport.NewServer(shortenerService, authenticator, registry)
+
+	grpcAddr := os.Getenv("GRPC_ADDR")
+	if grpcAddr == "" {
+		grpcAddr = ":9090"
+	}
+	grpcListener, err := net.Listen("tcp", grpcAddr)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go policy.Watch(ctx)
+
+	go func() {
+		log.Println("Server starting on :8080")
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	go func() {
+		log.Printf("gRPC server starting on %s", grpcAddr)
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			log.Fatal(err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Println("Shutting down")
+
+	checker.SetShuttingDown()
+	time.Sleep(readinessDrainDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("shutdown: %v", err)
+	}
+	stopGRPC(shutdownCtx, grpcServer)
+	if err := tracer.Shutdown(shutdownCtx); err != nil {
+		log.Printf("tracer shutdown: %v", err)
+	}
+}
+
+// stopGRPC дожидается завершения активных RPC, но не дольше таймаута остановки;
+// стримы WatchClicks сами не заканчиваются, поэтому их обрывает Stop.
+func stopGRPC(ctx context.Context, server *grpc.Server) {
+	done := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		server.Stop()
+	}
+}
+
+// ERROR_FORMAT=problem переключает ответы об ошибках на application/problem+json.
+func newErrorWriter(codecs *codec.Registry) contract.ErrorWriter {
+	if os.Getenv("ERROR_FORMAT") != "problem" {
+		return apierror.New(apierror.WithCodecs(codecs))
+	}
+	typeBase := os.Getenv("PROBLEM_TYPE_BASE")
+	if typeBase == "" {
+		typeBase = "/problems/"
+	}
+	return apierror.New(apierror.WithCodecs(codecs), apierror.WithProblemDetails(typeBase))
+}
+
+// newURLValidator читает правила проверки адресов назначения из окружения.
+func newURLValidator() *urlvalidate.Validator {
+	cfg := urlvalidate.Config{
+		StripFragment: os.Getenv("URL_STRIP_FRAGMENT") == "true",
+		AllowPrivate:  os.Getenv("URL_ALLOW_PRIVATE") == "true",
+	}
+	cfg.Schemes = splitList(os.Getenv("URL_SCHEMES"))
+	for _, scheme := range cfg.Schemes {
+		if urlvalidate.UnsafeScheme(scheme) {
+			log.Fatalf("URL_SCHEMES: %s links cannot be shortened", scheme)
+		}
+	}
+	if n, err := strconv.Atoi(os.Getenv("URL_MAX_LENGTH")); err == nil {
+		cfg.MaxLength = n
+	}
+	if os.Getenv("URL_RESOLVE_HOSTS") == "true" {
+		cfg.Resolver = net.DefaultResolver
+	}
+	return urlvalidate.New(cfg)
+}
+
+// newChainPolicy: SHORT_DOMAINS — наши домены, SHORTENER_DOMAINS — сторонние
+// сокращатели, которые раскрываются по сети; SHORT_LINK_CHAINS=reject запрещает цепочки.
+func newChainPolicy() shortener.ChainPolicy {
+	policy := shortener.ChainPolicy{
+		Domains: splitList(os.Getenv("SHORT_DOMAINS")),
+		Reject:  os.Getenv("SHORT_LINK_CHAINS") == "reject",
+	}
+	if domains := splitList(os.Getenv("SHORTENER_DOMAINS")); len(domains) > 0 {
+		policy.Expander = unshorten.New(unshorten.Config{Domains: domains})
+	}
+	if n, err := strconv.Atoi(os.Getenv("MAX_REDIRECT_HOPS")); err == nil {
+		policy.MaxHops = n
+	}
+	return policy
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+// newTrustedProxies: TRUSTED_PROXIES — адреса и сети балансировщиков, чей
+// X-Forwarded-For задаёт адрес клиента для жалоб, статистики и геоправил. Без них
+// сервис должен принимать соединения напрямую.
+func newTrustedProxies() []netip.Prefix {
+	proxies, err := middleware.ParseTrustedProxies(splitList(os.Getenv("TRUSTED_PROXIES")))
+	if err != nil {
+		log.Fatalf("TRUSTED_PROXIES: %v", err)
+	}
+	return proxies
+}
+
+// newModerationOptions: REPORT_THRESHOLD разных отправителей за REPORT_WINDOW
+// отключают ссылку до решения модератора; REPORT_THRESHOLD=0 выключает автоотключение.
+func newModerationOptions() []moderation.Option {
+	var opts []moderation.Option
+	if n, err := strconv.Atoi(os.Getenv("REPORT_THRESHOLD")); err == nil {
+		opts = append(opts, moderation.WithReportThreshold(n))
+	}
+	if d, err := time.ParseDuration(os.Getenv("REPORT_WINDOW")); err == nil && d > 0 {
+		opts = append(opts, moderation.WithReportWindow(d))
+	}
+	return opts
+}
+
+// newQRCache: QR_CACHE_SIZE — сколько готовых QR-кодов держать в памяти, 0 отключает кэш.
+func newQRCache() *qr.Cache {
+	size := 512
+	if n, err := strconv.Atoi(os.Getenv("QR_CACHE_SIZE")); err == nil {
+		size = n
+	}
+	return qr.NewCache(size)
+}
+
+// loadQRLogo читает PNG или JPEG из QR_LOGO_FILE; без файла логотипы недоступны.
+func loadQRLogo(path string) image.Image {
+	if path == "" {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	logo, _, err := image.Decode(f)
+	if err != nil {
+		log.Fatalf("decode %s: %v", path, err)
+	}
+	return logo
 }
@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"shorted/internal/domain/domainerr"
+	"strings"
+)
+
+type Principal struct {
+	ID string
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (Principal, error)
+}
+
+// StaticKeys сопоставляет API-ключи владельцам; задаётся строкой "key1:owner1,key2:owner2".
+type StaticKeys struct {
+	keys map[string]string
+}
+
+func ParseStaticKeys(s string) *StaticKeys {
+	keys := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		key, owner, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if ok && key != "" && owner != "" {
+			keys[key] = owner
+		}
+	}
+	return &StaticKeys{keys: keys}
+}
+
+func (k *StaticKeys) Enabled() bool {
+	return len(k.keys) > 0
+}
+
+func (k *StaticKeys) Authenticate(ctx context.Context, token string) (Principal, error) {
+	for key, owner := range k.keys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(token)) == 1 {
+			return Principal{ID: owner}, nil
+		}
+	}
+	return Principal{}, domainerr.ErrUnauthenticated
+}
+
+// BearerToken достаёт токен из значения заголовка Authorization.
+func BearerToken(header string) string {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
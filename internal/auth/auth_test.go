@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"shorted/internal/domain/domainerr"
+	"testing"
+)
+
+func TestStaticKeys(t *testing.T) {
+	keys := ParseStaticKeys(" k1:alice , k2:bob,broken,:nobody,k3:,k4:carol:x")
+
+	tests := []struct {
+		token   string
+		want    string
+		wantErr bool
+	}{
+		{token: "k1", want: "alice"},
+		{token: "k2", want: "bob"},
+		// всё после первого двоеточия — владелец
+		{token: "k4", want: "carol:x"},
+		{token: "broken", wantErr: true},
+		{token: "k3", wantErr: true},
+		{token: "", wantErr: true},
+		{token: "k1 ", wantErr: true},
+	}
+	for _, tt := range tests {
+		p, err := keys.Authenticate(context.Background(), tt.token)
+		if tt.wantErr {
+			if !errors.Is(err, domainerr.ErrUnauthenticated) {
+				t.Errorf("Authenticate(%q) error = %v, want unauthenticated", tt.token, err)
+			}
+			continue
+		}
+		if err != nil || p.ID != tt.want {
+			t.Errorf("Authenticate(%q) = %q, %v; want %q", tt.token, p.ID, err, tt.want)
+		}
+	}
+
+	if !keys.Enabled() {
+		t.Error("Enabled() = false with keys")
+	}
+	if ParseStaticKeys("").Enabled() || ParseStaticKeys(" , broken").Enabled() {
+		t.Error("Enabled() = true without valid keys")
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{header: "Bearer abc", want: "abc"},
+		{header: "bearer  abc ", want: "abc"},
+		{header: "Basic abc"},
+		{header: "Bearer"},
+		{header: "abc"},
+		{header: ""},
+	}
+	for _, tt := range tests {
+		if got := BearerToken(tt.header); got != tt.want {
+			t.Errorf("BearerToken(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestPrincipal(t *testing.T) {
+	if _, ok := PrincipalFrom(context.Background()); ok {
+		t.Error("principal in an empty context")
+	}
+	ctx := WithPrincipal(context.Background(), Principal{ID: "alice"})
+	if p, ok := PrincipalFrom(ctx); !ok || p.ID != "alice" {
+		t.Errorf("PrincipalFrom() = %v, %v", p, ok)
+	}
+}
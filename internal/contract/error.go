@@ -3,6 +3,8 @@ package contract
 import "net/http"
 
 type ErrorWriter interface {
-	WriteError(rw http.ResponseWriter, status int, message string)
-	WriteWithCode(rw http.ResponseWriter, status int, errorCode, message string, details interface{})
+	WriteError(rw http.ResponseWriter, r *http.Request, status int, message string)
+	WriteWithCode(rw http.ResponseWriter, r *http.Request, status int, errorCode, message string, details interface{})
+	// WriteErr сам выбирает статус и код по типу ошибки сервиса.
+	WriteErr(rw http.ResponseWriter, r *http.Request, err error)
 }
@@ -0,0 +1,7 @@
+package contract
+
+import "net/http"
+
+type RequestDecoder interface {
+	Decode(r *http.Request, v interface{}) error
+}
@@ -3,5 +3,5 @@ package contract
 import "net/http"
 
 type ResponseWriter interface {
-	Write(rw http.ResponseWriter, status int, data interface{})
+	Write(rw http.ResponseWriter, r *http.Request, status int, data interface{})
 }
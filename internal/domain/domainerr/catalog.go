@@ -0,0 +1,28 @@
+package domainerr
+
+var (
+	ErrValidation            = New(KindInvalid, "validation_failed", "request validation failed")
+	ErrInvalidBody           = New(KindInvalid, "invalid_body", "request body is malformed")
+	ErrUnsupportedType       = New(KindUnsupportedType, "unsupported_media_type", "request content type is not supported")
+	ErrNotAcceptable         = New(KindNotAcceptable, "not_acceptable", "none of the accepted response types is supported")
+	ErrLinkNotFound          = New(KindNotFound, "link_not_found", "link not found")
+	ErrLinkExists            = New(KindConflict, "link_already_exists", "link already exists")
+	ErrCodeExhausted         = New(KindUnavailable, "code_space_exhausted", "could not allocate a unique short code")
+	ErrUnauthenticated       = New(KindUnauthenticated, "unauthenticated", "missing or invalid credentials")
+	ErrForbidden             = New(KindForbidden, "forbidden", "you do not have access to this link")
+	ErrBatchTooLarge         = New(KindInvalid, "batch_too_large", "batch contains too many items")
+	ErrBatchEmpty            = New(KindInvalid, "batch_empty", "batch contains no items")
+	ErrJobNotFound           = New(KindNotFound, "job_not_found", "batch job not found")
+	ErrIdempotencyKeyInvalid = New(KindInvalid, "idempotency_key_invalid", "Idempotency-Key must be 1 to 255 characters")
+	ErrIdempotencyKeyReused  = New(KindUnprocessable, "idempotency_key_reused", "Idempotency-Key was already used with a different request")
+	ErrBodyTooLarge          = New(KindTooLarge, "body_too_large", "request body is too large")
+	ErrLinkDisabled          = New(KindGone, "link_disabled", "link has been disabled")
+	ErrAdminRequired         = New(KindForbidden, "admin_required", "admin access required")
+	ErrPolicyEntryNotFound   = New(KindNotFound, "policy_entry_not_found", "policy entry not found")
+	ErrPolicyEntryReadOnly   = New(KindConflict, "policy_entry_read_only", "entries loaded from files can only be changed in the file")
+	ErrReportNotFound        = New(KindNotFound, "report_not_found", "report not found")
+	ErrSplitNotFound         = New(KindNotFound, "split_not_found", "link has no split test")
+	ErrClickNotFound         = New(KindNotFound, "click_not_found", "click not found")
+	ErrCampaignNotFound      = New(KindNotFound, "campaign_not_found", "campaign not found")
+	ErrInternal              = New(KindInternal, "internal", "internal server error")
+)
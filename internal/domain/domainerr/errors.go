@@ -0,0 +1,108 @@
+package domainerr
+
+import (
+	"errors"
+	"shorted/internal/domain/repositories"
+)
+
+type Kind string
+
+const (
+	KindInvalid         Kind = "invalid"
+	KindUnauthenticated Kind = "unauthenticated"
+	KindForbidden       Kind = "forbidden"
+	KindNotFound        Kind = "not_found"
+	KindNotAcceptable   Kind = "not_acceptable"
+	KindConflict        Kind = "conflict"
+	KindGone            Kind = "gone"
+	KindUnsupportedType Kind = "unsupported_media_type"
+	KindTooLarge        Kind = "too_large"
+	KindUnprocessable   Kind = "unprocessable"
+	KindRateLimited     Kind = "rate_limited"
+	KindUnavailable     Kind = "unavailable"
+	KindInternal        Kind = "internal"
+)
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error — типизированная ошибка домена. Code стабилен и используется клиентами,
+// Message предназначен для человека.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Fields  []FieldError
+	Err     error
+}
+
+func New(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is сравнивает ошибки по коду, чтобы errors.Is работал с копиями из каталога.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+func (e *Error) WithCause(err error) *Error {
+	c := *e
+	c.Err = err
+	return &c
+}
+
+func (e *Error) WithMessage(message string) *Error {
+	c := *e
+	c.Message = message
+	return &c
+}
+
+func (e *Error) WithFields(fields ...FieldError) *Error {
+	c := *e
+	c.Fields = append(append([]FieldError(nil), e.Fields...), fields...)
+	return &c
+}
+
+// Validation собирает ошибки по полям в одну ошибку KindInvalid.
+func Validation(fields ...FieldError) *Error {
+	return ErrValidation.WithFields(fields...)
+}
+
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return KindInternal
+}
+
+// From приводит любую ошибку сервиса или репозитория к *Error. Неизвестные ошибки
+// становятся ErrInternal, исходная ошибка сохраняется в Err.
+func From(err error) *Error {
+	var de *Error
+	switch {
+	case errors.As(err, &de):
+		return de
+	case errors.Is(err, repositories.ErrNotFound):
+		return ErrLinkNotFound.WithCause(err)
+	case errors.Is(err, repositories.ErrAlreadyExists):
+		return ErrLinkExists.WithCause(err)
+	default:
+		return ErrInternal.WithCause(err)
+	}
+}
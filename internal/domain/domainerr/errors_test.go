@@ -0,0 +1,88 @@
+package domainerr
+
+import (
+	"errors"
+	"fmt"
+	"shorted/internal/domain/repositories"
+	"testing"
+)
+
+func TestErrorIsMatchesByCode(t *testing.T) {
+	cause := errors.New("boom")
+	derived := ErrLinkNotFound.WithCause(cause).WithMessage("no such link")
+
+	if !errors.Is(derived, ErrLinkNotFound) {
+		t.Error("copy from the catalog does not match the original")
+	}
+	if errors.Is(derived, ErrForbidden) {
+		t.Error("errors with different codes match")
+	}
+	if !errors.Is(fmt.Errorf("wrapped: %w", derived), cause) {
+		t.Error("cause is not reachable through Unwrap")
+	}
+	if derived.Error() != "no such link: boom" {
+		t.Errorf("Error() = %q", derived.Error())
+	}
+	if ErrLinkNotFound.Message != "link not found" || ErrLinkNotFound.Err != nil {
+		t.Error("With* modified the catalog entry")
+	}
+}
+
+func TestWithFieldsDoesNotShareSlices(t *testing.T) {
+	base := Validation(FieldError{Field: "a"})
+	one := base.WithFields(FieldError{Field: "b"})
+	two := base.WithFields(FieldError{Field: "c"})
+
+	if len(base.Fields) != 1 || one.Fields[1].Field != "b" || two.Fields[1].Field != "c" {
+		t.Errorf("fields leaked between copies: base=%v one=%v two=%v", base.Fields, one.Fields, two.Fields)
+	}
+	if len(ErrValidation.Fields) != 0 {
+		t.Error("Validation modified ErrValidation")
+	}
+}
+
+func TestFrom(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want *Error
+		kind Kind
+	}{
+		{"domain error", ErrJobNotFound, ErrJobNotFound, KindNotFound},
+		{"wrapped domain error", fmt.Errorf("x: %w", ErrAdminRequired), ErrAdminRequired, KindForbidden},
+		{"repository not found", repositories.ErrNotFound, ErrLinkNotFound, KindNotFound},
+		{"repository exists", fmt.Errorf("save: %w", repositories.ErrAlreadyExists), ErrLinkExists, KindConflict},
+		{"unknown", errors.New("disk full"), ErrInternal, KindInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := From(tt.err)
+			if !errors.Is(got, tt.want) {
+				t.Errorf("From = %s, want %s", got.Code, tt.want.Code)
+			}
+			if KindOf(got) != tt.kind {
+				t.Errorf("KindOf = %s, want %s", KindOf(got), tt.kind)
+			}
+			if got.Kind == KindInternal && !errors.Is(got, tt.err) {
+				t.Error("internal error lost its cause")
+			}
+		})
+	}
+}
+
+func TestCatalogCodesAreUnique(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, err := range []*Error{
+		ErrValidation, ErrInvalidBody, ErrUnsupportedType, ErrNotAcceptable, ErrLinkNotFound, ErrLinkExists,
+		ErrCodeExhausted, ErrUnauthenticated, ErrForbidden, ErrBatchTooLarge, ErrBatchEmpty, ErrJobNotFound,
+		ErrIdempotencyKeyInvalid, ErrIdempotencyKeyReused, ErrBodyTooLarge, ErrLinkDisabled, ErrAdminRequired,
+		ErrPolicyEntryNotFound, ErrPolicyEntryReadOnly, ErrReportNotFound, ErrSplitNotFound, ErrClickNotFound,
+		ErrCampaignNotFound, ErrInternal,
+	} {
+		if seen[err.Code] {
+			t.Errorf("duplicate code %s", err.Code)
+		}
+		seen[err.Code] = true
+	}
+}
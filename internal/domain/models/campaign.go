@@ -0,0 +1,28 @@
+package models
+
+// Campaign объединяет ссылки владельца для общей статистики; ссылка может входить
+// в несколько кампаний.
+type Campaign struct {
+	ID          string `json:"id"`
+	OwnerID     string `json:"owner_id,omitempty"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	CreatedAt   int64  `json:"created_at"`
+	UpdatedAt   int64  `json:"updated_at,omitempty"`
+}
+
+// CampaignStats — статистика, сложенная по всем ссылкам кампании.
+type CampaignStats struct {
+	CampaignID  string `json:"campaign_id"`
+	Links       int    `json:"links"`
+	Clicks      int64  `json:"clicks"`
+	LastClickAt int64  `json:"last_click_at,omitempty"`
+	// Conversions — конверсии по событиям для всех переходов по ссылкам кампании.
+	Conversions []ConversionStats `json:"conversions,omitempty"`
+}
+
+// TagCount — метка и число ссылок владельца с ней.
+type TagCount struct {
+	Name  string `json:"name"`
+	Links int    `json:"links"`
+}
@@ -0,0 +1,34 @@
+package models
+
+type Click struct {
+	ShortCode string `json:"short_code"`
+	At        int64  `json:"at"`
+	Referrer  string `json:"referrer,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+	// IP, AcceptLanguage, Query и Path нужны только для выбора и построения
+	// адреса и не сохраняются. Query — строка запроса к короткой ссылке, Path —
+	// путь после кода.
+	IP             string `json:"-"`
+	AcceptLanguage string `json:"-"`
+	Query          string `json:"-"`
+	Path           string `json:"-"`
+	// Rule — правило маршрутизации, выбравшее адрес: device:ios, geo:<имя>,
+	// variant:<имя>.
+	Rule string `json:"rule,omitempty"`
+	// ClickID — идентификатор клика, добавленный к адресу для атрибуции конверсий.
+	ClickID string `json:"click_id,omitempty"`
+	// Variant — вариант A/B-теста. На входе Resolve это вариант, за которым
+	// посетитель уже закреплён, на выходе — выбранный.
+	Variant string `json:"variant,omitempty"`
+}
+
+type LinkStats struct {
+	ShortCode   string `json:"short_code"`
+	Clicks      int64  `json:"clicks"`
+	LastClickAt int64  `json:"last_click_at,omitempty"`
+	// Rules — переходы по каждому сработавшему правилу маршрутизации; остальные
+	// переходы ушли на основной адрес.
+	Rules map[string]int64 `json:"rules,omitempty"`
+	// Conversions — конверсии по событиям для всей ссылки, её вариантов и кампаний.
+	Conversions []ConversionStats `json:"conversions,omitempty"`
+}
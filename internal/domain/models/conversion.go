@@ -0,0 +1,36 @@
+package models
+
+// TrackedClick — переход, которому выдан идентификатор клика для атрибуции
+// конверсий.
+type TrackedClick struct {
+	ID        string
+	ShortCode string
+	Variant   string
+	// Campaign — utm_campaign адреса, на который ушёл посетитель.
+	Campaign string
+	At       int64
+}
+
+// Conversion — событие на стороне рекламодателя, присланное по идентификатору клика.
+type Conversion struct {
+	ClickID   string  `json:"click_id"`
+	ShortCode string  `json:"short_code"`
+	Variant   string  `json:"variant,omitempty"`
+	Campaign  string  `json:"campaign,omitempty"`
+	Event     string  `json:"event"`
+	Value     float64 `json:"value,omitempty"`
+	At        int64   `json:"at"`
+}
+
+// ConversionStats — конверсии одного события в срезе переходов по ссылке.
+type ConversionStats struct {
+	// Segment — пустой для всех переходов, variant:<имя> или campaign:<имя>.
+	Segment string `json:"segment,omitempty"`
+	Event   string `json:"event"`
+	// Clicks — переходы среза с выданным идентификатором клика.
+	Clicks      int64   `json:"clicks"`
+	Conversions int64   `json:"conversions"`
+	Value       float64 `json:"value"`
+	// Rate — Conversions / Clicks.
+	Rate float64 `json:"rate"`
+}
@@ -0,0 +1,30 @@
+package models
+
+// MergeMode — как поступать с параметром, который уже есть в адресе назначения.
+type MergeMode string
+
+const (
+	// MergeKeep оставляет параметр адреса назначения, новый не добавляется.
+	MergeKeep MergeMode = "keep"
+	// MergeOverride заменяет параметр адреса назначения новым значением.
+	MergeOverride MergeMode = "override"
+	// MergeAppend добавляет новое значение рядом с существующим.
+	MergeAppend MergeMode = "append"
+)
+
+// Forwarding — что добавить к адресу назначения при переходе.
+type Forwarding struct {
+	// Params — параметры вроде UTM-меток. В значениях подставляются {code},
+	// {rule} и {variant}.
+	Params map[string]string `json:"params,omitempty"`
+	// Merge применяется и к Params, и к параметрам, переданным через Passthrough;
+	// по умолчанию MergeKeep.
+	Merge MergeMode `json:"merge,omitempty"`
+	// Passthrough передаёт параметры запроса к короткой ссылке (/abc?ref=x).
+	Passthrough bool `json:"passthrough,omitempty"`
+	// ForwardPath дописывает путь после кода (/abc/extra) к пути адреса назначения.
+	ForwardPath bool `json:"forward_path,omitempty"`
+	// ClickIDParam — имя параметра, в котором адрес получает идентификатор клика
+	// для отправки конверсий; пустое — идентификатор не выдаётся.
+	ClickIDParam string `json:"click_id_param,omitempty"`
+}
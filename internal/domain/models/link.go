@@ -3,5 +3,32 @@ package models
 type Link struct {
 	ShortCode   string `json:"short_code"`
 	OriginalURL string `json:"original_url"`
+	OwnerID     string `json:"owner_id,omitempty"`
 	CreatedAt   int64  `json:"created_at"`
+	UpdatedAt   int64  `json:"updated_at,omitempty"`
+	// Canonical — ссылка, которую переиспользуют для того же адреса того же владельца.
+	Canonical bool `json:"-"`
+	// DisabledAt не равен нулю у ссылки, отключённой модератором; причина видна при переходе.
+	DisabledAt     int64  `json:"disabled_at,omitempty"`
+	DisabledReason string `json:"disabled_reason,omitempty"`
+	// Metadata заполняется асинхронно после создания ссылки и может отсутствовать.
+	Metadata *LinkMetadata `json:"metadata,omitempty"`
+	// Routing — правила выбора адреса по устройству и местоположению посетителя.
+	Routing *Routing `json:"routing,omitempty"`
+	// Forwarding — параметры и путь, которые добавляются к адресу при переходе.
+	Forwarding *Forwarding `json:"forwarding,omitempty"`
+	// Tags — метки владельца в нижнем регистре, по возрастанию.
+	Tags []string `json:"tags,omitempty"`
+	// Campaigns — идентификаторы кампаний, в которые входит ссылка.
+	Campaigns []string `json:"campaigns,omitempty"`
+}
+
+// LinkMetadata — сведения о странице назначения: заголовок, описание, картинки.
+type LinkMetadata struct {
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Image       string `json:"image,omitempty"`
+	Favicon     string `json:"favicon,omitempty"`
+	SiteName    string `json:"site_name,omitempty"`
+	FetchedAt   int64  `json:"fetched_at"`
 }
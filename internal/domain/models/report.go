@@ -0,0 +1,26 @@
+package models
+
+type ReportState string
+
+const (
+	ReportOpen      ReportState = "open"
+	ReportReviewing ReportState = "reviewing"
+	ReportActioned  ReportState = "actioned"
+	ReportDismissed ReportState = "dismissed"
+)
+
+// Report — жалоба на короткую ссылку.
+type Report struct {
+	ID        string `json:"id"`
+	ShortCode string `json:"short_code"`
+	Reason    string `json:"reason"`
+	Comment   string `json:"comment,omitempty"`
+	// Reporter — владелец ключа или IP анонимного отправителя; по нему считаются
+	// разные отправители для автоматического отключения.
+	Reporter string      `json:"reporter"`
+	State    ReportState `json:"state"`
+	// Note — комментарий модератора.
+	Note      string `json:"note,omitempty"`
+	CreatedAt int64  `json:"created_at"`
+	UpdatedAt int64  `json:"updated_at,omitempty"`
+}
@@ -0,0 +1,60 @@
+package models
+
+// Routing — правила выбора адреса назначения для конкретного перехода. Правила
+// по устройству проверяются раньше географических; посетители, не попавшие ни под
+// одно правило, делятся между вариантами Split, а без него уходят на OriginalURL.
+type Routing struct {
+	Devices []DeviceRule `json:"devices,omitempty"`
+	Geo     []GeoRule    `json:"geo,omitempty"`
+	Split   *Split       `json:"split,omitempty"`
+}
+
+// Platform — группа устройств в правилах ссылки.
+type Platform string
+
+const (
+	PlatformIOS     Platform = "ios"
+	PlatformAndroid Platform = "android"
+	PlatformDesktop Platform = "desktop"
+)
+
+// DeviceRule отправляет посетителей с платформы Platform на URL. Если задан
+// AppURL (схема приложения вроде myapp://), сначала пробуется открыть приложение,
+// а через AppTimeoutMs без ответа — URL или OriginalURL.
+type DeviceRule struct {
+	Platform     Platform `json:"platform"`
+	URL          string   `json:"url,omitempty"`
+	AppURL       string   `json:"app_url,omitempty"`
+	AppTimeoutMs int      `json:"app_timeout_ms,omitempty"`
+}
+
+// GeoRule отправляет на URL посетителей, подходящих под все заданные условия;
+// внутри условия достаточно совпадения с одним значением. Правила проверяются
+// по порядку, срабатывает первое подходящее.
+type GeoRule struct {
+	// Name — имя правила в статистике переходов.
+	Name string `json:"name"`
+	// Countries — коды ISO 3166-1 alpha-2, Regions — коды ISO 3166-2 (US-CA).
+	Countries []string `json:"countries,omitempty"`
+	Regions   []string `json:"regions,omitempty"`
+	// Languages — теги BCP 47; сравниваются с первым языком из Accept-Language,
+	// тег без региона (de) подходит и для de-AT.
+	Languages []string `json:"languages,omitempty"`
+	URL       string   `json:"url"`
+}
+
+// Split делит трафик между вариантами пропорционально весам. Посетитель
+// закрепляется за вариантом и видит его при повторных переходах.
+type Split struct {
+	Variants []Variant `json:"variants"`
+	// Winner — вариант, который после завершения теста получает весь трафик.
+	Winner string `json:"winner,omitempty"`
+}
+
+type Variant struct {
+	// Name — имя варианта в статистике переходов и в cookie посетителя.
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	// Weight — доля трафика относительно суммы весов; 0 ставит вариант на паузу.
+	Weight int `json:"weight"`
+}
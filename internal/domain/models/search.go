@@ -0,0 +1,10 @@
+package models
+
+// SearchResult — ссылка, найденная поиском, с подсвеченными совпадениями.
+type SearchResult struct {
+	Link  *Link   `json:"link"`
+	Score float64 `json:"score"`
+	// Highlights — поле -> отрывок с совпадениями в <mark></mark>: short_code,
+	// original_url, title, description, tags (метки через запятую).
+	Highlights map[string]string `json:"highlights,omitempty"`
+}
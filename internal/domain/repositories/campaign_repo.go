@@ -0,0 +1,21 @@
+package repositories
+
+import (
+	"context"
+	"shorted/internal/domain/models"
+)
+
+type CampaignRepository interface {
+	Save(ctx context.Context, campaign *models.Campaign) error
+	// FindByID возвращает ErrCampaignNotFound для неизвестного идентификатора.
+	FindByID(ctx context.Context, id string) (*models.Campaign, error)
+	// FindByIDs загружает несколько кампаний; отсутствующие пропускаются.
+	FindByIDs(ctx context.Context, ids []string) ([]*models.Campaign, error)
+	Update(ctx context.Context, campaign *models.Campaign) error
+	// Delete удаляет только саму кампанию, из ссылок её убирает
+	// LinkRepository.RemoveCampaign.
+	Delete(ctx context.Context, id string) error
+	// List возвращает кампании владельца по возрастанию ID; пустой ownerID —
+	// кампании без владельца.
+	List(ctx context.Context, ownerID string) ([]*models.Campaign, error)
+}
@@ -0,0 +1,28 @@
+package repositories
+
+import (
+	"context"
+	"shorted/internal/domain/models"
+)
+
+type ConversionRepository interface {
+	SaveClick(ctx context.Context, click *models.TrackedClick) error
+	// FindClick возвращает ErrClickNotFound для неизвестного идентификатора.
+	FindClick(ctx context.Context, id string) (*models.TrackedClick, error)
+	// SaveConversion сохраняет конверсию. Если такое событие по клику уже есть,
+	// conv заполняется сохранённой конверсией и created=false.
+	SaveConversion(ctx context.Context, conv *models.Conversion) (created bool, err error)
+	// Counts возвращает счётчики по ссылкам в разрезе варианта, кампании и события.
+	Counts(ctx context.Context, shortCodes []string) ([]ConversionCount, error)
+}
+
+// ConversionCount — строка счётчиков; строка с пустым Event считает переходы с
+// выданным идентификатором клика.
+type ConversionCount struct {
+	ShortCode string
+	Variant   string
+	Campaign  string
+	Event     string
+	Count     int64
+	Value     float64
+}
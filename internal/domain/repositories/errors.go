@@ -5,4 +5,9 @@ import "errors"
 var (
 	ErrNotFound      = errors.New("link not found")
 	ErrAlreadyExists = errors.New("link already exists")
+	// ErrDestinationExists — у владельца уже есть каноническая ссылка на этот адрес.
+	ErrDestinationExists = errors.New("canonical link for destination already exists")
+	ErrReportNotFound    = errors.New("report not found")
+	ErrClickNotFound     = errors.New("click not found")
+	ErrCampaignNotFound  = errors.New("campaign not found")
 )
@@ -1,8 +1,64 @@
 package repositories
 
-import "shorted/internal/domain/models"
+import (
+	"context"
+	"shorted/internal/domain/models"
+)
 
 type LinkRepository interface {
-	Save(link *models.Link) error
-	FindByCode(shortCode string) (*models.Link, error)
+	Save(ctx context.Context, link *models.Link) error
+	// SaveMany сохраняет пачку ссылок одной транзакцией. Занятые коды не прерывают
+	// пачку: для них в errs на той же позиции возвращается ErrAlreadyExists.
+	SaveMany(ctx context.Context, links []*models.Link) (errs []error, err error)
+	FindByCode(ctx context.Context, shortCode string) (*models.Link, error)
+	// FindByDestination ищет каноническую ссылку владельца на адрес.
+	FindByDestination(ctx context.Context, ownerID, originalURL string) (*models.Link, error)
+	// FindByCodes загружает несколько ссылок за один запрос; отсутствующие коды пропускаются.
+	FindByCodes(ctx context.Context, shortCodes []string) ([]*models.Link, error)
+	Update(ctx context.Context, link *models.Link) error
+	// SetMetadata сохраняет метаданные, только если адрес ссылки всё ещё originalURL,
+	// иначе возвращает ErrNotFound: метаданные устарели, пока загружались.
+	SetMetadata(ctx context.Context, shortCode, originalURL string, meta *models.LinkMetadata) error
+	Delete(ctx context.Context, shortCode string) error
+	// List возвращает ссылки по возрастанию кода, начиная после ListOptions.After.
+	List(ctx context.Context, opts ListOptions) ([]*models.Link, error)
+	// EditTags добавляет и убирает метки у ссылок shortCodes; отсутствующие коды
+	// пропускаются.
+	EditTags(ctx context.Context, shortCodes, add, remove []string, updatedAt int64) error
+	// Tags считает ссылки владельца по меткам; пустой ownerID — ссылки без владельца.
+	Tags(ctx context.Context, ownerID string) ([]models.TagCount, error)
+	// RemoveCampaign убирает удалённую кампанию из всех ссылок.
+	RemoveCampaign(ctx context.Context, campaignID string, updatedAt int64) error
+	// Search ищет ссылки владельца по коду, адресу, заголовку, описанию и меткам;
+	// лучшие совпадения первыми.
+	Search(ctx context.Context, opts SearchOptions) ([]SearchHit, error)
+}
+
+type ListOptions struct {
+	After   string
+	Limit   int
+	OwnerID string
+	// OwnerOnly ограничивает выдачу ссылками OwnerID, даже если он пустой.
+	OwnerOnly bool
+	Tag       string
+	Campaign  string
+}
+
+type SearchOptions struct {
+	Query string
+	// OwnerID — чьи ссылки искать; пустой — ссылки без владельца.
+	OwnerID string
+	Limit   int
+}
+
+// SearchHit — найденная ссылка и её оценка; оценки разных хранилищ между собой
+// не сравнимы.
+type SearchHit struct {
+	Link  *models.Link
+	Score float64
+}
+
+// Pinger реализуют хранилища с внешним соединением, которое нужно проверять для readiness.
+type Pinger interface {
+	Ping(ctx context.Context) error
 }
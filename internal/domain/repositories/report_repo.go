@@ -0,0 +1,24 @@
+package repositories
+
+import (
+	"context"
+	"shorted/internal/domain/models"
+)
+
+type ReportRepository interface {
+	Save(ctx context.Context, report *models.Report) error
+	FindByID(ctx context.Context, id string) (*models.Report, error)
+	Update(ctx context.Context, report *models.Report) error
+	// List возвращает жалобы по возрастанию ID, начиная после ReportListOptions.After.
+	List(ctx context.Context, opts ReportListOptions) ([]*models.Report, error)
+	// CountReporters считает разных отправителей жалоб на ссылку, поступивших начиная
+	// с since и ещё не разобранных модератором (open и reviewing).
+	CountReporters(ctx context.Context, shortCode string, since int64) (int, error)
+}
+
+type ReportListOptions struct {
+	After     string
+	Limit     int
+	State     models.ReportState
+	ShortCode string
+}
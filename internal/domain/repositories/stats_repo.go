@@ -0,0 +1,14 @@
+package repositories
+
+import (
+	"context"
+	"shorted/internal/domain/models"
+)
+
+type StatsRepository interface {
+	RecordClick(ctx context.Context, click *models.Click) error
+	// Stats возвращает нулевую статистику для ссылки без переходов.
+	Stats(ctx context.Context, shortCode string) (*models.LinkStats, error)
+	// StatsByCodes возвращает статистику для каждого из кодов в том же порядке.
+	StatsByCodes(ctx context.Context, shortCodes []string) ([]*models.LinkStats, error)
+}
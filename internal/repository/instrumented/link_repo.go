@@ -0,0 +1,152 @@
+package instrumented
+
+import (
+	"context"
+	"errors"
+	"shorted/internal/domain/models"
+	"shorted/internal/domain/repositories"
+	"shorted/pkg/metrics"
+	"shorted/pkg/tracing"
+	"time"
+)
+
+// LinkRepo оборачивает любую реализацию LinkRepository и снимает метрики и трассы операций.
+type LinkRepo struct {
+	next     repositories.LinkRepository
+	tracer   *tracing.Tracer
+	duration *metrics.HistogramVec
+	errors   *metrics.CounterVec
+}
+
+func NewLinkRepo(next repositories.LinkRepository, registry *metrics.Registry, tracer *tracing.Tracer) *LinkRepo {
+	return &LinkRepo{
+		next:     next,
+		tracer:   tracer,
+		duration: registry.NewHistogramVec("repository_operation_duration_seconds", "Link repository operation latency.", nil, "operation"),
+		errors:   registry.NewCounterVec("repository_operation_errors_total", "Link repository errors by operation and type.", "operation", "type"),
+	}
+}
+
+func (r *LinkRepo) Save(ctx context.Context, link *models.Link) (err error) {
+	ctx, done := r.start(ctx, "save")
+	defer func() { done(err) }()
+
+	return r.next.Save(ctx, link)
+}
+
+func (r *LinkRepo) SaveMany(ctx context.Context, links []*models.Link) (errs []error, err error) {
+	ctx, done := r.start(ctx, "save_many")
+	defer func() { done(err) }()
+
+	return r.next.SaveMany(ctx, links)
+}
+
+func (r *LinkRepo) FindByCode(ctx context.Context, shortCode string) (link *models.Link, err error) {
+	ctx, done := r.start(ctx, "find_by_code")
+	defer func() { done(err) }()
+
+	return r.next.FindByCode(ctx, shortCode)
+}
+
+func (r *LinkRepo) FindByDestination(ctx context.Context, ownerID, originalURL string) (link *models.Link, err error) {
+	ctx, done := r.start(ctx, "find_by_destination")
+	defer func() { done(err) }()
+
+	return r.next.FindByDestination(ctx, ownerID, originalURL)
+}
+
+func (r *LinkRepo) FindByCodes(ctx context.Context, shortCodes []string) (links []*models.Link, err error) {
+	ctx, done := r.start(ctx, "find_by_codes")
+	defer func() { done(err) }()
+
+	return r.next.FindByCodes(ctx, shortCodes)
+}
+
+func (r *LinkRepo) Update(ctx context.Context, link *models.Link) (err error) {
+	ctx, done := r.start(ctx, "update")
+	defer func() { done(err) }()
+
+	return r.next.Update(ctx, link)
+}
+
+func (r *LinkRepo) SetMetadata(ctx context.Context, shortCode, originalURL string, meta *models.LinkMetadata) (err error) {
+	ctx, done := r.start(ctx, "set_metadata")
+	defer func() { done(err) }()
+
+	return r.next.SetMetadata(ctx, shortCode, originalURL, meta)
+}
+
+func (r *LinkRepo) Delete(ctx context.Context, shortCode string) (err error) {
+	ctx, done := r.start(ctx, "delete")
+	defer func() { done(err) }()
+
+	return r.next.Delete(ctx, shortCode)
+}
+
+func (r *LinkRepo) List(ctx context.Context, opts repositories.ListOptions) (links []*models.Link, err error) {
+	ctx, done := r.start(ctx, "list")
+	defer func() { done(err) }()
+
+	return r.next.List(ctx, opts)
+}
+
+func (r *LinkRepo) EditTags(ctx context.Context, shortCodes, add, remove []string, updatedAt int64) (err error) {
+	ctx, done := r.start(ctx, "edit_tags")
+	defer func() { done(err) }()
+
+	return r.next.EditTags(ctx, shortCodes, add, remove, updatedAt)
+}
+
+func (r *LinkRepo) Tags(ctx context.Context, ownerID string) (tags []models.TagCount, err error) {
+	ctx, done := r.start(ctx, "tags")
+	defer func() { done(err) }()
+
+	return r.next.Tags(ctx, ownerID)
+}
+
+func (r *LinkRepo) RemoveCampaign(ctx context.Context, campaignID string, updatedAt int64) (err error) {
+	ctx, done := r.start(ctx, "remove_campaign")
+	defer func() { done(err) }()
+
+	return r.next.RemoveCampaign(ctx, campaignID, updatedAt)
+}
+
+func (r *LinkRepo) Search(ctx context.Context, opts repositories.SearchOptions) (_ []repositories.SearchHit, err error) {
+	ctx, done := r.start(ctx, "search")
+	defer func() { done(err) }()
+
+	return r.next.Search(ctx, opts)
+}
+
+func (r *LinkRepo) start(ctx context.Context, operation string) (context.Context, func(error)) {
+	start := time.Now()
+	ctx, span := r.tracer.Start(ctx, "repository."+operation, tracing.SpanKindClient)
+	span.SetAttribute("db.operation", operation)
+
+	return ctx, func(err error) {
+		r.duration.WithLabelValues(operation).ObserveSince(start)
+		if err != nil {
+			kind := errorType(err)
+			r.errors.WithLabelValues(operation, kind).Inc()
+			span.SetAttribute("error.type", kind)
+			// отсутствие ссылки или занятый код — штатный результат, а не сбой хранилища
+			if kind == "internal" {
+				span.RecordError(err)
+			}
+		}
+		span.End()
+	}
+}
+
+func errorType(err error) string {
+	switch {
+	case errors.Is(err, repositories.ErrNotFound):
+		return "not_found"
+	case errors.Is(err, repositories.ErrAlreadyExists):
+		return "already_exists"
+	case errors.Is(err, repositories.ErrDestinationExists):
+		return "destination_exists"
+	default:
+		return "internal"
+	}
+}
@@ -0,0 +1,103 @@
+package instrumented
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"shorted/internal/domain/models"
+	"shorted/internal/domain/repositories"
+	"shorted/pkg/metrics"
+	"shorted/pkg/tracing"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// stubRepo отвечает на FindByCode заданной ошибкой; остальные методы не вызываются.
+type stubRepo struct {
+	repositories.LinkRepository
+	err error
+}
+
+func (r stubRepo) FindByCode(ctx context.Context, shortCode string) (*models.Link, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	return &models.Link{ShortCode: shortCode}, nil
+}
+
+type recordingExporter struct {
+	mu    sync.Mutex
+	spans []tracing.SpanData
+}
+
+func (e *recordingExporter) Export(ctx context.Context, spans []tracing.SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func TestLinkRepoObservesOperations(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantType  string
+		wantError bool
+	}{
+		{"success", nil, "", false},
+		{"not found", repositories.ErrNotFound, "not_found", false},
+		{"already exists", fmt.Errorf("save: %w", repositories.ErrAlreadyExists), "already_exists", false},
+		{"destination exists", repositories.ErrDestinationExists, "destination_exists", false},
+		{"storage failure", errors.New("connection reset"), "internal", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := metrics.NewRegistry()
+			exporter := &recordingExporter{}
+			tracer := tracing.NewTracer(tracing.AlwaysSample(), exporter)
+			r := NewLinkRepo(stubRepo{err: tt.err}, registry, tracer)
+
+			if _, err := r.FindByCode(context.Background(), "abc"); err != tt.err {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+
+			var out bytes.Buffer
+			registry.Write(&out)
+			if want := `repository_operation_duration_seconds_count{operation="find_by_code"} 1`; !strings.Contains(out.String(), want) {
+				t.Errorf("metrics do not contain %s:\n%s", want, out.String())
+			}
+			errorsLine := `repository_operation_errors_total{operation="find_by_code",type="` + tt.wantType + `"} 1`
+			if got := strings.Contains(out.String(), errorsLine); got != (tt.wantType != "") {
+				t.Errorf("errors counter present = %v:\n%s", got, out.String())
+			}
+
+			if err := tracer.Shutdown(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if len(exporter.spans) != 1 {
+				t.Fatalf("spans = %d, want 1", len(exporter.spans))
+			}
+			span := exporter.spans[0]
+			if span.Name != "repository.find_by_code" || span.Kind != tracing.SpanKindClient {
+				t.Errorf("span = %s kind %d", span.Name, span.Kind)
+			}
+			attrs := map[string]any{}
+			for _, a := range span.Attributes {
+				attrs[a.Key] = a.Value
+			}
+			if attrs["db.operation"] != "find_by_code" {
+				t.Errorf("db.operation = %v", attrs["db.operation"])
+			}
+			if tt.wantType != "" && attrs["error.type"] != tt.wantType {
+				t.Errorf("error.type = %v, want %s", attrs["error.type"], tt.wantType)
+			}
+			// штатные ошибки не помечают спан как сбой
+			if got := span.StatusCode == tracing.StatusError; got != tt.wantError {
+				t.Errorf("span status = %d, want error %v", span.StatusCode, tt.wantError)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package memory
+
+import (
+	"context"
+	"shorted/internal/domain/models"
+	"shorted/internal/domain/repositories"
+	"sort"
+	"sync"
+)
+
+type CampaignRepo struct {
+	mu        sync.Mutex
+	campaigns map[string]*models.Campaign
+}
+
+func NewCampaignRepo() *CampaignRepo {
+	return &CampaignRepo{campaigns: make(map[string]*models.Campaign)}
+}
+
+func (r *CampaignRepo) Save(ctx context.Context, campaign *models.Campaign) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.campaigns[campaign.ID]; exists {
+		return repositories.ErrAlreadyExists
+	}
+	stored := *campaign
+	r.campaigns[campaign.ID] = &stored
+	return nil
+}
+
+func (r *CampaignRepo) FindByID(ctx context.Context, id string) (*models.Campaign, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	campaign, exists := r.campaigns[id]
+	if !exists {
+		return nil, repositories.ErrCampaignNotFound
+	}
+	found := *campaign
+	return &found, nil
+}
+
+func (r *CampaignRepo) FindByIDs(ctx context.Context, ids []string) ([]*models.Campaign, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	campaigns := make([]*models.Campaign, 0, len(ids))
+	for _, id := range ids {
+		if campaign, exists := r.campaigns[id]; exists {
+			found := *campaign
+			campaigns = append(campaigns, &found)
+		}
+	}
+	return campaigns, nil
+}
+
+func (r *CampaignRepo) Update(ctx context.Context, campaign *models.Campaign) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.campaigns[campaign.ID]; !exists {
+		return repositories.ErrCampaignNotFound
+	}
+	stored := *campaign
+	r.campaigns[campaign.ID] = &stored
+	return nil
+}
+
+func (r *CampaignRepo) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.campaigns[id]; !exists {
+		return repositories.ErrCampaignNotFound
+	}
+	delete(r.campaigns, id)
+	return nil
+}
+
+func (r *CampaignRepo) List(ctx context.Context, ownerID string) ([]*models.Campaign, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var campaigns []*models.Campaign
+	for _, campaign := range r.campaigns {
+		if campaign.OwnerID == ownerID {
+			found := *campaign
+			campaigns = append(campaigns, &found)
+		}
+	}
+	sort.Slice(campaigns, func(i, j int) bool { return campaigns[i].ID < campaigns[j].ID })
+	return campaigns, nil
+}
@@ -0,0 +1,112 @@
+package memory
+
+import (
+	"context"
+	"shorted/internal/domain/models"
+	"shorted/internal/domain/repositories"
+	"sync"
+)
+
+type conversionKey struct {
+	clickID, event string
+}
+
+type countKey struct {
+	variant, campaign, event string
+}
+
+type ConversionRepo struct {
+	mu          sync.Mutex
+	clicks      map[string]*models.TrackedClick
+	conversions map[conversionKey]*models.Conversion
+	counts      map[string]map[countKey]*repositories.ConversionCount
+}
+
+func NewConversionRepo() *ConversionRepo {
+	return &ConversionRepo{
+		clicks:      make(map[string]*models.TrackedClick),
+		conversions: make(map[conversionKey]*models.Conversion),
+		counts:      make(map[string]map[countKey]*repositories.ConversionCount),
+	}
+}
+
+func (r *ConversionRepo) SaveClick(ctx context.Context, click *models.TrackedClick) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.clicks[click.ID]; exists {
+		return repositories.ErrAlreadyExists
+	}
+	stored := *click
+	r.clicks[click.ID] = &stored
+	r.count(click, "").Count++
+	return nil
+}
+
+func (r *ConversionRepo) FindClick(ctx context.Context, id string) (*models.TrackedClick, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	click, exists := r.clicks[id]
+	if !exists {
+		return nil, repositories.ErrClickNotFound
+	}
+	found := *click
+	return &found, nil
+}
+
+func (r *ConversionRepo) SaveConversion(ctx context.Context, conv *models.Conversion) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	click, exists := r.clicks[conv.ClickID]
+	if !exists {
+		return false, repositories.ErrClickNotFound
+	}
+	key := conversionKey{clickID: conv.ClickID, event: conv.Event}
+	if existing, ok := r.conversions[key]; ok {
+		*conv = *existing
+		return false, nil
+	}
+	stored := *conv
+	r.conversions[key] = &stored
+
+	count := r.count(click, conv.Event)
+	count.Count++
+	count.Value += conv.Value
+	return true, nil
+}
+
+func (r *ConversionRepo) Counts(ctx context.Context, shortCodes []string) ([]repositories.ConversionCount, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var result []repositories.ConversionCount
+	for _, code := range shortCodes {
+		for _, count := range r.counts[code] {
+			result = append(result, *count)
+		}
+	}
+	return result, nil
+}
+
+// count возвращает счётчик ссылки клика для события; пустое событие — переходы.
+func (r *ConversionRepo) count(click *models.TrackedClick, event string) *repositories.ConversionCount {
+	byKey, ok := r.counts[click.ShortCode]
+	if !ok {
+		byKey = make(map[countKey]*repositories.ConversionCount)
+		r.counts[click.ShortCode] = byKey
+	}
+	key := countKey{variant: click.Variant, campaign: click.Campaign, event: event}
+	count, ok := byKey[key]
+	if !ok {
+		count = &repositories.ConversionCount{
+			ShortCode: click.ShortCode,
+			Variant:   click.Variant,
+			Campaign:  click.Campaign,
+			Event:     event,
+		}
+		byKey[key] = count
+	}
+	return count
+}
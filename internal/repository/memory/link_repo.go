@@ -1,30 +1,89 @@
 package memory
 
 import (
+	"context"
+	"iter"
+	"maps"
 	"shorted/internal/domain/models"
 	"shorted/internal/domain/repositories"
+	"shorted/pkg/textsearch"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
 )
 
+type codeSet map[string]struct{}
+
 type LinkRepo struct {
 	mu    sync.Mutex
 	links map[string]*models.Link
+	// destinations — вторичный индекс канонических ссылок: владелец и адрес -> код
+	destinations map[destinationKey]string
+	// tags — владелец -> метка -> коды ссылок, campaigns — кампания -> коды
+	tags      map[string]map[string]codeSet
+	campaigns map[string]codeSet
+	// search — полнотекстовый индекс по коду, адресу, метаданным и меткам
+	search *textsearch.Index
+}
+
+type destinationKey struct {
+	owner string
+	url   string
 }
 
 func NewLinkRepo() *LinkRepo {
 	return &LinkRepo{
-		links: make(map[string]*models.Link),
+		links:        make(map[string]*models.Link),
+		destinations: make(map[destinationKey]string),
+		tags:         make(map[string]map[string]codeSet),
+		campaigns:    make(map[string]codeSet),
+		search:       textsearch.NewIndex(),
 	}
 }
 
-func (r *LinkRepo) Save(link *models.Link) error {
+func (r *LinkRepo) Save(ctx context.Context, link *models.Link) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	r.links[link.ShortCode] = link
+
+	return r.save(link)
+}
+
+// save вызывается под r.mu.
+func (r *LinkRepo) save(link *models.Link) error {
+	if _, exists := r.links[link.ShortCode]; exists {
+		return repositories.ErrAlreadyExists
+	}
+	if link.Canonical {
+		if _, exists := r.destinations[destinationOf(link)]; exists {
+			return repositories.ErrDestinationExists
+		}
+	}
+	r.store(link)
 	return nil
 }
 
-func (r *LinkRepo) FindByCode(shortCode string) (*models.Link, error) {
+// store сохраняет копию ссылки и индексирует её; вызывается под r.mu.
+func (r *LinkRepo) store(link *models.Link) {
+	stored := *link
+	stored.Tags = slices.Clone(link.Tags)
+	stored.Campaigns = slices.Clone(link.Campaigns)
+	r.links[link.ShortCode] = &stored
+	r.index(&stored)
+}
+
+func (r *LinkRepo) SaveMany(ctx context.Context, links []*models.Link) ([]error, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	errs := make([]error, len(links))
+	for i, link := range links {
+		errs[i] = r.save(link)
+	}
+	return errs, nil
+}
+
+func (r *LinkRepo) FindByCode(ctx context.Context, shortCode string) (*models.Link, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
@@ -34,5 +93,299 @@ func (r *LinkRepo) FindByCode(shortCode string) (*models.Link, error) {
 
 	}
 
-	return link, nil
+	found := *link
+	return &found, nil
+}
+
+func (r *LinkRepo) FindByDestination(ctx context.Context, ownerID, originalURL string) (*models.Link, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	code, exists := r.destinations[destinationKey{owner: ownerID, url: originalURL}]
+	if !exists {
+		return nil, repositories.ErrNotFound
+	}
+	found := *r.links[code]
+	return &found, nil
+}
+
+func (r *LinkRepo) FindByCodes(ctx context.Context, shortCodes []string) ([]*models.Link, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	links := make([]*models.Link, 0, len(shortCodes))
+	for _, code := range shortCodes {
+		if link, exists := r.links[code]; exists {
+			found := *link
+			links = append(links, &found)
+		}
+	}
+	return links, nil
+}
+
+func (r *LinkRepo) Update(ctx context.Context, link *models.Link) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, exists := r.links[link.ShortCode]
+	if !exists {
+		return repositories.ErrNotFound
+	}
+	if link.Canonical {
+		code, taken := r.destinations[destinationOf(link)]
+		if taken && code != link.ShortCode {
+			return repositories.ErrDestinationExists
+		}
+	}
+
+	r.unindex(current)
+	r.store(link)
+	return nil
+}
+
+func (r *LinkRepo) SetMetadata(ctx context.Context, shortCode, originalURL string, meta *models.LinkMetadata) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	link, exists := r.links[shortCode]
+	if !exists || link.OriginalURL != originalURL {
+		return repositories.ErrNotFound
+	}
+	stored := *meta
+	link.Metadata = &stored
+	r.search.Put(link.ShortCode, searchFields(link)...)
+	return nil
+}
+
+func (r *LinkRepo) Delete(ctx context.Context, shortCode string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	link, exists := r.links[shortCode]
+	if !exists {
+		return repositories.ErrNotFound
+	}
+	r.unindex(link)
+	delete(r.links, shortCode)
+	return nil
+}
+
+// index добавляет ссылку во вторичные индексы; вызывается под r.mu.
+func (r *LinkRepo) index(link *models.Link) {
+	if link.Canonical {
+		r.destinations[destinationOf(link)] = link.ShortCode
+	}
+	for _, tag := range link.Tags {
+		byTag, ok := r.tags[link.OwnerID]
+		if !ok {
+			byTag = make(map[string]codeSet)
+			r.tags[link.OwnerID] = byTag
+		}
+		addCode(byTag, tag, link.ShortCode)
+	}
+	for _, id := range link.Campaigns {
+		addCode(r.campaigns, id, link.ShortCode)
+	}
+	r.search.Put(link.ShortCode, searchFields(link)...)
+}
+
+// unindex убирает ссылку из вторичных индексов; вызывается под r.mu.
+func (r *LinkRepo) unindex(link *models.Link) {
+	key := destinationOf(link)
+	if link.Canonical && r.destinations[key] == link.ShortCode {
+		delete(r.destinations, key)
+	}
+	for _, tag := range link.Tags {
+		removeCode(r.tags[link.OwnerID], tag, link.ShortCode)
+	}
+	if len(r.tags[link.OwnerID]) == 0 {
+		delete(r.tags, link.OwnerID)
+	}
+	for _, id := range link.Campaigns {
+		removeCode(r.campaigns, id, link.ShortCode)
+	}
+	r.search.Remove(link.ShortCode)
+}
+
+func addCode(index map[string]codeSet, key, code string) {
+	codes, ok := index[key]
+	if !ok {
+		codes = make(codeSet)
+		index[key] = codes
+	}
+	codes[code] = struct{}{}
+}
+
+func removeCode(index map[string]codeSet, key, code string) {
+	delete(index[key], code)
+	if len(index[key]) == 0 {
+		delete(index, key)
+	}
+}
+
+func destinationOf(link *models.Link) destinationKey {
+	return destinationKey{owner: link.OwnerID, url: link.OriginalURL}
+}
+
+func (r *LinkRepo) List(ctx context.Context, opts repositories.ListOptions) ([]*models.Link, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var codes []string
+	for code := range r.candidates(opts) {
+		link := r.links[code]
+		if (opts.OwnerID != "" || opts.OwnerOnly) && link.OwnerID != opts.OwnerID {
+			continue
+		}
+		if opts.Tag != "" && !slices.Contains(link.Tags, opts.Tag) {
+			continue
+		}
+		if opts.Campaign != "" && !slices.Contains(link.Campaigns, opts.Campaign) {
+			continue
+		}
+		if code > opts.After {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	if opts.Limit > 0 && len(codes) > opts.Limit {
+		codes = codes[:opts.Limit]
+	}
+
+	links := make([]*models.Link, 0, len(codes))
+	for _, code := range codes {
+		link := *r.links[code]
+		links = append(links, &link)
+	}
+	return links, nil
+}
+
+// candidates сужает перебор в List по индексам меток и кампаний; вызывается под r.mu.
+func (r *LinkRepo) candidates(opts repositories.ListOptions) iter.Seq[string] {
+	var index codeSet
+	switch {
+	case opts.Campaign != "":
+		index = r.campaigns[opts.Campaign]
+	case opts.Tag != "" && (opts.OwnerID != "" || opts.OwnerOnly):
+		index = r.tags[opts.OwnerID][opts.Tag]
+	case opts.Tag != "":
+		index = make(codeSet)
+		for _, byTag := range r.tags {
+			for code := range byTag[opts.Tag] {
+				index[code] = struct{}{}
+			}
+		}
+	default:
+		return maps.Keys(r.links)
+	}
+	return maps.Keys(index)
+}
+
+func (r *LinkRepo) EditTags(ctx context.Context, shortCodes, add, remove []string, updatedAt int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, code := range shortCodes {
+		current, exists := r.links[code]
+		if !exists {
+			continue
+		}
+		link := *current
+		link.Tags = editTags(current.Tags, add, remove)
+		link.UpdatedAt = updatedAt
+		r.unindex(current)
+		r.store(&link)
+	}
+	return nil
+}
+
+// editTags возвращает метки tags с добавленными add и без remove, по возрастанию.
+func editTags(tags, add, remove []string) []string {
+	result := make([]string, 0, len(tags)+len(add))
+	for _, tag := range slices.Concat(tags, add) {
+		if !slices.Contains(remove, tag) {
+			result = append(result, tag)
+		}
+	}
+	slices.Sort(result)
+	result = slices.Compact(result)
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+func (r *LinkRepo) Tags(ctx context.Context, ownerID string) ([]models.TagCount, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	byTag := r.tags[ownerID]
+	result := make([]models.TagCount, 0, len(byTag))
+	for _, tag := range slices.Sorted(maps.Keys(byTag)) {
+		result = append(result, models.TagCount{Name: tag, Links: len(byTag[tag])})
+	}
+	return result, nil
+}
+
+func (r *LinkRepo) RemoveCampaign(ctx context.Context, campaignID string, updatedAt int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// unindex меняет r.campaigns, поэтому коды собираются заранее
+	for _, code := range slices.Collect(maps.Keys(r.campaigns[campaignID])) {
+		current := r.links[code]
+		link := *current
+		link.Campaigns = slices.DeleteFunc(slices.Clone(current.Campaigns), func(id string) bool { return id == campaignID })
+		if len(link.Campaigns) == 0 {
+			link.Campaigns = nil
+		}
+		link.UpdatedAt = updatedAt
+		r.unindex(current)
+		r.store(&link)
+	}
+	return nil
+}
+
+func (r *LinkRepo) Search(ctx context.Context, opts repositories.SearchOptions) ([]repositories.SearchHit, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var hits []repositories.SearchHit
+	for _, hit := range r.search.Search(opts.Query) {
+		link := r.links[hit.ID]
+		if link.OwnerID != opts.OwnerID {
+			continue
+		}
+		found := *link
+		hits = append(hits, repositories.SearchHit{Link: &found, Score: hit.Score})
+		if opts.Limit > 0 && len(hits) == opts.Limit {
+			break
+		}
+	}
+	return hits, nil
+}
+
+// searchFields — поля ссылки для поиска с весами: код и метки важнее заголовка,
+// заголовок — адреса, адрес — описания.
+func searchFields(link *models.Link) []textsearch.Field {
+	fields := []textsearch.Field{
+		{Text: link.ShortCode, Weight: 1},
+		{Text: strings.Join(link.Tags, " "), Weight: 1},
+		{Text: withoutScheme(link.OriginalURL), Weight: 0.4},
+	}
+	if link.Metadata != nil {
+		fields = append(fields,
+			textsearch.Field{Text: link.Metadata.Title, Weight: 0.7},
+			textsearch.Field{Text: link.Metadata.Description, Weight: 0.2},
+		)
+	}
+	return fields
+}
+
+// withoutScheme убирает схему, чтобы "https" не находил все ссылки подряд.
+func withoutScheme(rawURL string) string {
+	if _, rest, ok := strings.Cut(rawURL, "://"); ok {
+		return rest
+	}
+	return rawURL
 }
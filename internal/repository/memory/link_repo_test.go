@@ -0,0 +1,135 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"shorted/internal/domain/models"
+	"shorted/internal/domain/repositories"
+	"strings"
+	"testing"
+)
+
+func TestLinkRepoDestinationIndex(t *testing.T) {
+	ctx := context.Background()
+	canonical := func(code, owner, url string) *models.Link {
+		return &models.Link{ShortCode: code, OwnerID: owner, OriginalURL: url, Canonical: true}
+	}
+
+	t.Run("save", func(t *testing.T) {
+		r := NewLinkRepo()
+		if err := r.Save(ctx, canonical("a", "alice", "https://x/")); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Save(ctx, canonical("b", "alice", "https://x/")); !errors.Is(err, repositories.ErrDestinationExists) {
+			t.Errorf("second canonical link: %v", err)
+		}
+		if err := r.Save(ctx, canonical("c", "bob", "https://x/")); err != nil {
+			t.Errorf("other owner: %v", err)
+		}
+		// неканонические ссылки в индекс не попадают и не мешают ему
+		if err := r.Save(ctx, &models.Link{ShortCode: "d", OwnerID: "alice", OriginalURL: "https://x/"}); err != nil {
+			t.Errorf("non-canonical link: %v", err)
+		}
+		if err := r.Save(ctx, canonical("a", "carol", "https://y/")); !errors.Is(err, repositories.ErrAlreadyExists) {
+			t.Errorf("taken code: %v", err)
+		}
+
+		found, err := r.FindByDestination(ctx, "alice", "https://x/")
+		if err != nil || found.ShortCode != "a" {
+			t.Errorf("FindByDestination = %v, %v", found, err)
+		}
+		if _, err := r.FindByDestination(ctx, "carol", "https://y/"); !errors.Is(err, repositories.ErrNotFound) {
+			t.Errorf("failed save left an index entry: %v", err)
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		r := NewLinkRepo()
+		r.Save(ctx, canonical("a", "alice", "https://x/"))
+		r.Save(ctx, canonical("b", "alice", "https://y/"))
+
+		moved := canonical("b", "alice", "https://x/")
+		if err := r.Update(ctx, moved); !errors.Is(err, repositories.ErrDestinationExists) {
+			t.Errorf("update onto a taken destination: %v", err)
+		}
+
+		moved.OriginalURL, moved.Canonical = "https://z/", false
+		if err := r.Update(ctx, moved); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.FindByDestination(ctx, "alice", "https://y/"); !errors.Is(err, repositories.ErrNotFound) {
+			t.Errorf("old destination still indexed: %v", err)
+		}
+		if err := r.Save(ctx, canonical("c", "alice", "https://y/")); err != nil {
+			t.Errorf("freed destination: %v", err)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		r := NewLinkRepo()
+		r.Save(ctx, canonical("a", "alice", "https://x/"))
+		if err := r.Delete(ctx, "a"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.FindByDestination(ctx, "alice", "https://x/"); !errors.Is(err, repositories.ErrNotFound) {
+			t.Errorf("deleted link still indexed: %v", err)
+		}
+		if err := r.Delete(ctx, "a"); !errors.Is(err, repositories.ErrNotFound) {
+			t.Errorf("second delete: %v", err)
+		}
+	})
+
+	t.Run("returned links are copies", func(t *testing.T) {
+		r := NewLinkRepo()
+		r.Save(ctx, canonical("a", "alice", "https://x/"))
+		found, _ := r.FindByCode(ctx, "a")
+		found.OriginalURL = "https://changed/"
+		again, _ := r.FindByCode(ctx, "a")
+		if again.OriginalURL != "https://x/" {
+			t.Error("caller modified the stored link")
+		}
+	})
+}
+
+func TestLinkRepoRemoveCampaign(t *testing.T) {
+	ctx := context.Background()
+	r := NewLinkRepo()
+	r.Save(ctx, &models.Link{ShortCode: "a", OriginalURL: "https://x/", Campaigns: []string{"c1", "c2"}, UpdatedAt: 1})
+	r.Save(ctx, &models.Link{ShortCode: "b", OriginalURL: "https://y/", Campaigns: []string{"c1"}, UpdatedAt: 1})
+	r.Save(ctx, &models.Link{ShortCode: "c", OriginalURL: "https://z/", Campaigns: []string{"c2"}, UpdatedAt: 1})
+	before, _ := r.FindByCode(ctx, "a")
+
+	if err := r.RemoveCampaign(ctx, "c1", 2); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		code          string
+		wantCampaigns string
+		wantUpdatedAt int64
+	}{
+		{code: "a", wantCampaigns: "c2", wantUpdatedAt: 2},
+		{code: "b", wantCampaigns: "", wantUpdatedAt: 2},
+		{code: "c", wantCampaigns: "c2", wantUpdatedAt: 1},
+	}
+	for _, tt := range tests {
+		link, err := r.FindByCode(ctx, tt.code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(link.Campaigns, ","); got != tt.wantCampaigns || link.UpdatedAt != tt.wantUpdatedAt {
+			t.Errorf("%s: campaigns %q updated at %d, want %q at %d", tt.code, got, link.UpdatedAt, tt.wantCampaigns, tt.wantUpdatedAt)
+		}
+	}
+
+	for campaign, want := range map[string]int{"c1": 0, "c2": 2} {
+		links, err := r.List(ctx, repositories.ListOptions{Campaign: campaign})
+		if err != nil || len(links) != want {
+			t.Errorf("List(%s) = %d links, %v; want %d", campaign, len(links), err, want)
+		}
+	}
+	// ранее отданная копия не меняется
+	if strings.Join(before.Campaigns, ",") != "c1,c2" || before.UpdatedAt != 1 {
+		t.Errorf("returned link changed: %+v", before)
+	}
+}
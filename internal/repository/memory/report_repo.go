@@ -0,0 +1,97 @@
+package memory
+
+import (
+	"context"
+	"shorted/internal/domain/models"
+	"shorted/internal/domain/repositories"
+	"sort"
+	"sync"
+)
+
+type ReportRepo struct {
+	mu      sync.Mutex
+	reports map[string]*models.Report
+}
+
+func NewReportRepo() *ReportRepo {
+	return &ReportRepo{reports: make(map[string]*models.Report)}
+}
+
+func (r *ReportRepo) Save(ctx context.Context, report *models.Report) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.reports[report.ID]; exists {
+		return repositories.ErrAlreadyExists
+	}
+	stored := *report
+	r.reports[report.ID] = &stored
+	return nil
+}
+
+func (r *ReportRepo) FindByID(ctx context.Context, id string) (*models.Report, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report, exists := r.reports[id]
+	if !exists {
+		return nil, repositories.ErrReportNotFound
+	}
+	found := *report
+	return &found, nil
+}
+
+func (r *ReportRepo) Update(ctx context.Context, report *models.Report) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.reports[report.ID]; !exists {
+		return repositories.ErrReportNotFound
+	}
+	stored := *report
+	r.reports[report.ID] = &stored
+	return nil
+}
+
+func (r *ReportRepo) List(ctx context.Context, opts repositories.ReportListOptions) ([]*models.Report, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids := make([]string, 0, len(r.reports))
+	for id, report := range r.reports {
+		if opts.State != "" && report.State != opts.State {
+			continue
+		}
+		if opts.ShortCode != "" && report.ShortCode != opts.ShortCode {
+			continue
+		}
+		if id > opts.After {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	if opts.Limit > 0 && len(ids) > opts.Limit {
+		ids = ids[:opts.Limit]
+	}
+
+	reports := make([]*models.Report, 0, len(ids))
+	for _, id := range ids {
+		report := *r.reports[id]
+		reports = append(reports, &report)
+	}
+	return reports, nil
+}
+
+func (r *ReportRepo) CountReporters(ctx context.Context, shortCode string, since int64) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	reporters := make(map[string]struct{})
+	for _, report := range r.reports {
+		pending := report.State == models.ReportOpen || report.State == models.ReportReviewing
+		if report.ShortCode == shortCode && report.CreatedAt >= since && pending {
+			reporters[report.Reporter] = struct{}{}
+		}
+	}
+	return len(reporters), nil
+}
@@ -0,0 +1,70 @@
+package memory
+
+import (
+	"context"
+	"maps"
+	"shorted/internal/domain/models"
+	"sync"
+)
+
+type StatsRepo struct {
+	mu    sync.Mutex
+	stats map[string]*models.LinkStats
+}
+
+func NewStatsRepo() *StatsRepo {
+	return &StatsRepo{
+		stats: make(map[string]*models.LinkStats),
+	}
+}
+
+func (r *StatsRepo) RecordClick(ctx context.Context, click *models.Click) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats, exists := r.stats[click.ShortCode]
+	if !exists {
+		stats = &models.LinkStats{ShortCode: click.ShortCode}
+		r.stats[click.ShortCode] = stats
+	}
+	stats.Clicks++
+	if click.At > stats.LastClickAt {
+		stats.LastClickAt = click.At
+	}
+	if click.Rule != "" {
+		if stats.Rules == nil {
+			stats.Rules = make(map[string]int64)
+		}
+		stats.Rules[click.Rule]++
+	}
+	return nil
+}
+
+func (r *StatsRepo) Stats(ctx context.Context, shortCode string) (*models.LinkStats, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats, exists := r.stats[shortCode]
+	if !exists {
+		return &models.LinkStats{ShortCode: shortCode}, nil
+	}
+	found := *stats
+	found.Rules = maps.Clone(stats.Rules)
+	return &found, nil
+}
+
+func (r *StatsRepo) StatsByCodes(ctx context.Context, shortCodes []string) ([]*models.LinkStats, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := make([]*models.LinkStats, 0, len(shortCodes))
+	for _, code := range shortCodes {
+		found := models.LinkStats{ShortCode: code}
+		if stats, exists := r.stats[code]; exists {
+			found = *stats
+			found.Rules = maps.Clone(stats.Rules)
+		}
+		result = append(result, &found)
+	}
+	return result, nil
+}
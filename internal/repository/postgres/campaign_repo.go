@@ -0,0 +1,131 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"shorted/internal/domain/models"
+	"shorted/internal/domain/repositories"
+
+	"github.com/lib/pq"
+)
+
+const campaignColumns = `id, owner_id, name, description, created_at, updated_at`
+
+type CampaignRepo struct {
+	db *sql.DB
+}
+
+func NewCampaignRepo(db *sql.DB) *CampaignRepo {
+	return &CampaignRepo{db: db}
+}
+
+func (r *CampaignRepo) Save(ctx context.Context, campaign *models.Campaign) error {
+	res, err := r.db.ExecContext(ctx,
+		`INSERT INTO campaigns (`+campaignColumns+`)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 ON CONFLICT (id) DO NOTHING`,
+		campaign.ID, campaign.OwnerID, campaign.Name, campaign.Description, campaign.CreatedAt, campaign.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return repositories.ErrAlreadyExists
+	}
+	return nil
+}
+
+func (r *CampaignRepo) FindByID(ctx context.Context, id string) (*models.Campaign, error) {
+	campaign, err := scanCampaign(r.db.QueryRowContext(ctx,
+		`SELECT `+campaignColumns+` FROM campaigns WHERE id = $1`,
+		id,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, repositories.ErrCampaignNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return campaign, nil
+}
+
+func (r *CampaignRepo) FindByIDs(ctx context.Context, ids []string) ([]*models.Campaign, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+campaignColumns+` FROM campaigns WHERE id = ANY($1)`,
+		pq.Array(ids),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanCampaigns(rows)
+}
+
+func (r *CampaignRepo) Update(ctx context.Context, campaign *models.Campaign) error {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE campaigns SET name = $2, description = $3, updated_at = $4 WHERE id = $1`,
+		campaign.ID, campaign.Name, campaign.Description, campaign.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	return expectCampaign(res)
+}
+
+func (r *CampaignRepo) Delete(ctx context.Context, id string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM campaigns WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	return expectCampaign(res)
+}
+
+func (r *CampaignRepo) List(ctx context.Context, ownerID string) ([]*models.Campaign, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+campaignColumns+` FROM campaigns WHERE owner_id = $1 ORDER BY id`,
+		ownerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanCampaigns(rows)
+}
+
+func scanCampaigns(rows *sql.Rows) ([]*models.Campaign, error) {
+	defer rows.Close()
+
+	var campaigns []*models.Campaign
+	for rows.Next() {
+		campaign, err := scanCampaign(rows)
+		if err != nil {
+			return nil, err
+		}
+		campaigns = append(campaigns, campaign)
+	}
+	return campaigns, rows.Err()
+}
+
+func scanCampaign(row scanner) (*models.Campaign, error) {
+	var c models.Campaign
+	err := row.Scan(&c.ID, &c.OwnerID, &c.Name, &c.Description, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func expectCampaign(res sql.Result) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return repositories.ErrCampaignNotFound
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"shorted/internal/domain/models"
+	"shorted/internal/domain/repositories"
+	"testing"
+)
+
+func TestCampaignRepo(t *testing.T) {
+	ctx := context.Background()
+	r := NewCampaignRepo(testDB(t))
+
+	for _, c := range []*models.Campaign{
+		{ID: "c2", OwnerID: "alice", Name: "Autumn", CreatedAt: 100},
+		{ID: "c1", OwnerID: "alice", Name: "Spring", Description: "Spring sale", CreatedAt: 100},
+		{ID: "c3", OwnerID: "bob", Name: "Bob's", CreatedAt: 100},
+	} {
+		if err := r.Save(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.Save(ctx, &models.Campaign{ID: "c1", OwnerID: "bob", Name: "Taken"}); !errors.Is(err, repositories.ErrAlreadyExists) {
+		t.Errorf("taken id: %v", err)
+	}
+
+	got, err := r.FindByID(ctx, "c1")
+	if err != nil || *got != (models.Campaign{ID: "c1", OwnerID: "alice", Name: "Spring", Description: "Spring sale", CreatedAt: 100}) {
+		t.Errorf("FindByID = %+v, %v", got, err)
+	}
+	if _, err := r.FindByID(ctx, "missing"); !errors.Is(err, repositories.ErrCampaignNotFound) {
+		t.Errorf("missing id: %v", err)
+	}
+
+	list, err := r.List(ctx, "alice")
+	if err != nil || len(list) != 2 || list[0].ID != "c1" || list[1].ID != "c2" {
+		t.Errorf("List = %v, %v", list, err)
+	}
+	found, err := r.FindByIDs(ctx, []string{"c1", "c3", "missing"})
+	if err != nil || len(found) != 2 {
+		t.Errorf("FindByIDs = %v, %v", found, err)
+	}
+
+	// владелец и дата создания не меняются
+	if err := r.Update(ctx, &models.Campaign{ID: "c1", OwnerID: "bob", Name: "Summer", UpdatedAt: 200}); err != nil {
+		t.Fatal(err)
+	}
+	got, err = r.FindByID(ctx, "c1")
+	if err != nil || *got != (models.Campaign{ID: "c1", OwnerID: "alice", Name: "Summer", CreatedAt: 100, UpdatedAt: 200}) {
+		t.Errorf("after Update = %+v, %v", got, err)
+	}
+	if err := r.Update(ctx, &models.Campaign{ID: "missing"}); !errors.Is(err, repositories.ErrCampaignNotFound) {
+		t.Errorf("update of a missing campaign: %v", err)
+	}
+
+	if err := r.Delete(ctx, "c1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Delete(ctx, "c1"); !errors.Is(err, repositories.ErrCampaignNotFound) {
+		t.Errorf("second delete: %v", err)
+	}
+}
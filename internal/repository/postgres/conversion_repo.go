@@ -0,0 +1,106 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"shorted/internal/domain/models"
+	"shorted/internal/domain/repositories"
+
+	"github.com/lib/pq"
+)
+
+// конверсия по клику, которого нет в tracked_clicks
+const foreignKeyViolation = "23503"
+
+type ConversionRepo struct {
+	db *sql.DB
+}
+
+func NewConversionRepo(db *sql.DB) *ConversionRepo {
+	return &ConversionRepo{db: db}
+}
+
+func (r *ConversionRepo) SaveClick(ctx context.Context, click *models.TrackedClick) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO tracked_clicks (id, short_code, variant, campaign, at) VALUES ($1, $2, $3, $4, $5)`,
+		click.ID, click.ShortCode, click.Variant, click.Campaign, click.At,
+	)
+	if hasCode(err, uniqueViolation) {
+		return repositories.ErrAlreadyExists
+	}
+	return err
+}
+
+func (r *ConversionRepo) FindClick(ctx context.Context, id string) (*models.TrackedClick, error) {
+	click := models.TrackedClick{ID: id}
+	err := r.db.QueryRowContext(ctx,
+		`SELECT short_code, variant, campaign, at FROM tracked_clicks WHERE id = $1`,
+		id,
+	).Scan(&click.ShortCode, &click.Variant, &click.Campaign, &click.At)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, repositories.ErrClickNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &click, nil
+}
+
+func (r *ConversionRepo) SaveConversion(ctx context.Context, conv *models.Conversion) (bool, error) {
+	res, err := r.db.ExecContext(ctx,
+		`INSERT INTO conversions (click_id, event, value, at) VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (click_id, event) DO NOTHING`,
+		conv.ClickID, conv.Event, conv.Value, conv.At,
+	)
+	if hasCode(err, foreignKeyViolation) {
+		return false, repositories.ErrClickNotFound
+	}
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return n > 0, err
+	}
+
+	// повторная отправка: отдаём то, что сохранили в первый раз
+	err = r.db.QueryRowContext(ctx,
+		`SELECT value, at FROM conversions WHERE click_id = $1 AND event = $2`,
+		conv.ClickID, conv.Event,
+	).Scan(&conv.Value, &conv.At)
+	return false, err
+}
+
+func (r *ConversionRepo) Counts(ctx context.Context, shortCodes []string) ([]repositories.ConversionCount, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT short_code, variant, campaign, '', count(*), 0
+		 FROM tracked_clicks
+		 WHERE short_code = ANY($1)
+		 GROUP BY short_code, variant, campaign
+		 UNION ALL
+		 SELECT t.short_code, t.variant, t.campaign, c.event, count(*), sum(c.value)
+		 FROM conversions c JOIN tracked_clicks t ON t.id = c.click_id
+		 WHERE t.short_code = ANY($1)
+		 GROUP BY t.short_code, t.variant, t.campaign, c.event`,
+		pq.Array(shortCodes),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []repositories.ConversionCount
+	for rows.Next() {
+		var c repositories.ConversionCount
+		if err := rows.Scan(&c.ShortCode, &c.Variant, &c.Campaign, &c.Event, &c.Count, &c.Value); err != nil {
+			return nil, err
+		}
+		result = append(result, c)
+	}
+	return result, rows.Err()
+}
+
+func hasCode(err error, code pq.ErrorCode) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == code
+}
@@ -1 +1,446 @@
 package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"shorted/internal/domain/models"
+	"shorted/internal/domain/repositories"
+	"shorted/pkg/textsearch"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+const (
+	linkColumns = `short_code, original_url, owner_id, created_at, updated_at, canonical, disabled_at, disabled_reason, metadata, routing, forwarding, tags, campaigns`
+	// не больше 65535 параметров на запрос: по 13 на ссылку
+	saveManyChunk = 1000
+
+	destinationIndex = "links_owner_destination_idx"
+	uniqueViolation  = "23505"
+)
+
+type LinkRepo struct {
+	db *sql.DB
+}
+
+func NewLinkRepo(db *sql.DB) *LinkRepo {
+	return &LinkRepo{db: db}
+}
+
+func (r *LinkRepo) Save(ctx context.Context, link *models.Link) error {
+	docs, err := documentValues(link)
+	if err != nil {
+		return err
+	}
+	res, err := r.db.ExecContext(ctx,
+		`INSERT INTO links (`+linkColumns+`)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		 ON CONFLICT (short_code) DO NOTHING`,
+		link.ShortCode, link.OriginalURL, link.OwnerID, link.CreatedAt, link.UpdatedAt, link.Canonical,
+		link.DisabledAt, link.DisabledReason, docs[0], docs[1], docs[2], textArray(link.Tags), textArray(link.Campaigns),
+	)
+	if err != nil {
+		return mapUniqueViolation(err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return repositories.ErrAlreadyExists
+	}
+
+	return nil
+}
+
+func (r *LinkRepo) SaveMany(ctx context.Context, links []*models.Link) (_ []error, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	inserted := make(map[string]struct{}, len(links))
+	for start := 0; start < len(links); start += saveManyChunk {
+		chunk := links[start:min(start+saveManyChunk, len(links))]
+		if err := insertLinks(ctx, tx, chunk, inserted); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	// код, повторённый внутри пачки, вставлен только для первого вхождения
+	errs := make([]error, len(links))
+	for i, link := range links {
+		if _, ok := inserted[link.ShortCode]; ok {
+			delete(inserted, link.ShortCode)
+			continue
+		}
+		errs[i] = repositories.ErrAlreadyExists
+	}
+	return errs, nil
+}
+
+// insertLinks вставляет ссылки одним запросом и отмечает в inserted коды, которые
+// не были заняты.
+func insertLinks(ctx context.Context, tx *sql.Tx, links []*models.Link, inserted map[string]struct{}) error {
+	var query strings.Builder
+	query.WriteString(`INSERT INTO links (` + linkColumns + `) VALUES `)
+	args := make([]any, 0, len(links)*13)
+	for i, link := range links {
+		docs, err := documentValues(link)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		n := len(args)
+		fmt.Fprintf(&query, "($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)",
+			n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8, n+9, n+10, n+11, n+12, n+13)
+		args = append(args, link.ShortCode, link.OriginalURL, link.OwnerID, link.CreatedAt, link.UpdatedAt, link.Canonical,
+			link.DisabledAt, link.DisabledReason, docs[0], docs[1], docs[2], textArray(link.Tags), textArray(link.Campaigns))
+	}
+	query.WriteString(` ON CONFLICT (short_code) DO NOTHING RETURNING short_code`)
+
+	rows, err := tx.QueryContext(ctx, query.String(), args...)
+	if err != nil {
+		return mapUniqueViolation(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return err
+		}
+		inserted[code] = struct{}{}
+	}
+	return rows.Err()
+}
+
+func (r *LinkRepo) FindByCode(ctx context.Context, shortCode string) (*models.Link, error) {
+	link, err := scanLink(r.db.QueryRowContext(ctx,
+		`SELECT `+linkColumns+` FROM links WHERE short_code = $1`,
+		shortCode,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, repositories.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return link, nil
+}
+
+func (r *LinkRepo) FindByDestination(ctx context.Context, ownerID, originalURL string) (*models.Link, error) {
+	link, err := scanLink(r.db.QueryRowContext(ctx,
+		`SELECT `+linkColumns+` FROM links
+		 WHERE owner_id = $1 AND md5(original_url) = md5($2) AND original_url = $2 AND canonical`,
+		ownerID, originalURL,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, repositories.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return link, nil
+}
+
+func (r *LinkRepo) FindByCodes(ctx context.Context, shortCodes []string) ([]*models.Link, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+linkColumns+` FROM links WHERE short_code = ANY($1)`,
+		pq.Array(shortCodes),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanLinks(rows)
+}
+
+func (r *LinkRepo) Update(ctx context.Context, link *models.Link) error {
+	docs, err := documentValues(link)
+	if err != nil {
+		return err
+	}
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE links
+		 SET original_url = $2, owner_id = $3, updated_at = $4, canonical = $5, disabled_at = $6, disabled_reason = $7,
+		     metadata = $8, routing = $9, forwarding = $10, tags = $11, campaigns = $12
+		 WHERE short_code = $1`,
+		link.ShortCode, link.OriginalURL, link.OwnerID, link.UpdatedAt, link.Canonical, link.DisabledAt, link.DisabledReason,
+		docs[0], docs[1], docs[2], textArray(link.Tags), textArray(link.Campaigns),
+	)
+	if err != nil {
+		return mapUniqueViolation(err)
+	}
+	return expectOneRow(res)
+}
+
+func (r *LinkRepo) SetMetadata(ctx context.Context, shortCode, originalURL string, meta *models.LinkMetadata) error {
+	value, err := jsonValue(meta)
+	if err != nil {
+		return err
+	}
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE links SET metadata = $3 WHERE short_code = $1 AND original_url = $2`,
+		shortCode, originalURL, value,
+	)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(res)
+}
+
+func (r *LinkRepo) Delete(ctx context.Context, shortCode string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM links WHERE short_code = $1`, shortCode)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(res)
+}
+
+func (r *LinkRepo) List(ctx context.Context, opts repositories.ListOptions) ([]*models.Link, error) {
+	limit := sql.NullInt64{Int64: int64(opts.Limit), Valid: opts.Limit > 0}
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+linkColumns+` FROM links
+		 WHERE short_code > $1 AND ($3 = '' AND NOT $4 OR owner_id = $3)
+		   AND ($5 = '' OR tags @> ARRAY[$5::text])
+		   AND ($6 = '' OR campaigns @> ARRAY[$6::text])
+		 ORDER BY short_code
+		 LIMIT $2`,
+		opts.After, limit, opts.OwnerID, opts.OwnerOnly, opts.Tag, opts.Campaign,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanLinks(rows)
+}
+
+func (r *LinkRepo) EditTags(ctx context.Context, shortCodes, add, remove []string, updatedAt int64) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE links
+		 SET tags = ARRAY(
+		         SELECT DISTINCT tag FROM unnest(tags || $2::text[]) AS tag
+		         WHERE tag <> ALL($3::text[])
+		         ORDER BY tag
+		     ),
+		     updated_at = $4
+		 WHERE short_code = ANY($1)`,
+		pq.Array(shortCodes), textArray(add), textArray(remove), updatedAt,
+	)
+	return err
+}
+
+func (r *LinkRepo) Tags(ctx context.Context, ownerID string) ([]models.TagCount, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT tag, count(*) FROM links, unnest(tags) AS tag
+		 WHERE owner_id = $1
+		 GROUP BY tag
+		 ORDER BY tag`,
+		ownerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.TagCount
+	for rows.Next() {
+		var tag models.TagCount
+		if err := rows.Scan(&tag.Name, &tag.Links); err != nil {
+			return nil, err
+		}
+		result = append(result, tag)
+	}
+	return result, rows.Err()
+}
+
+func (r *LinkRepo) RemoveCampaign(ctx context.Context, campaignID string, updatedAt int64) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE links SET campaigns = array_remove(campaigns, $1), updated_at = $2
+		 WHERE campaigns @> ARRAY[$1::text]`,
+		campaignID, updatedAt,
+	)
+	return err
+}
+
+// Search ищет по словам и префиксам в search_vector и с опечатками по
+// триграммам search_text; обе колонки заполняет триггер (миграция 0012).
+func (r *LinkRepo) Search(ctx context.Context, opts repositories.SearchOptions) ([]repositories.SearchHit, error) {
+	terms := textsearch.Terms(opts.Query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	prefixes := make([]string, len(terms))
+	for i, term := range terms {
+		prefixes[i] = term + ":*"
+	}
+
+	limit := sql.NullInt64{Int64: int64(opts.Limit), Valid: opts.Limit > 0}
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+linkColumns+`, ts_rank(search_vector, q) + word_similarity($2, search_text) AS score
+		 FROM links, to_tsquery('simple', $1) AS q
+		 WHERE owner_id = $3 AND (search_vector @@ q OR $2 <% search_text)
+		 ORDER BY score DESC, short_code
+		 LIMIT $4`,
+		strings.Join(prefixes, " & "), strings.Join(terms, " "), opts.OwnerID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hits []repositories.SearchHit
+	for rows.Next() {
+		var hit repositories.SearchHit
+		if hit.Link, err = scanLink(scoredRow{rows, &hit.Score}); err != nil {
+			return nil, err
+		}
+		hits = append(hits, hit)
+	}
+	return hits, rows.Err()
+}
+
+// scoredRow дочитывает колонку оценки после колонок ссылки.
+type scoredRow struct {
+	scanner
+	score *float64
+}
+
+func (r scoredRow) Scan(dest ...any) error {
+	return r.scanner.Scan(append(dest, r.score)...)
+}
+
+func (r *LinkRepo) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanLinks(rows *sql.Rows) ([]*models.Link, error) {
+	defer rows.Close()
+
+	var links []*models.Link
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+
+	return links, rows.Err()
+}
+
+func scanLink(row scanner) (*models.Link, error) {
+	var (
+		link                      models.Link
+		meta, routing, forwarding []byte
+	)
+	err := row.Scan(&link.ShortCode, &link.OriginalURL, &link.OwnerID, &link.CreatedAt, &link.UpdatedAt, &link.Canonical,
+		&link.DisabledAt, &link.DisabledReason, &meta, &routing, &forwarding, (*pq.StringArray)(&link.Tags),
+		(*pq.StringArray)(&link.Campaigns))
+	if err != nil {
+		return nil, err
+	}
+	if link.Metadata, err = scanJSON[models.LinkMetadata](meta); err != nil {
+		return nil, err
+	}
+	if link.Routing, err = scanJSON[models.Routing](routing); err != nil {
+		return nil, err
+	}
+	if link.Forwarding, err = scanJSON[models.Forwarding](forwarding); err != nil {
+		return nil, err
+	}
+	// пустой массив отдаётся так же, как из памяти: nil
+	if len(link.Tags) == 0 {
+		link.Tags = nil
+	}
+	if len(link.Campaigns) == 0 {
+		link.Campaigns = nil
+	}
+	return &link, nil
+}
+
+// documentValues кодирует JSONB-колонки ссылки в порядке linkColumns:
+// metadata, routing, forwarding.
+func documentValues(link *models.Link) ([3]any, error) {
+	var (
+		docs [3]any
+		err  error
+	)
+	if docs[0], err = jsonValue(link.Metadata); err != nil {
+		return docs, err
+	}
+	if docs[1], err = jsonValue(link.Routing); err != nil {
+		return docs, err
+	}
+	if docs[2], err = jsonValue(link.Forwarding); err != nil {
+		return docs, err
+	}
+	return docs, nil
+}
+
+// jsonValue кодирует значение для колонки JSONB; nil становится NULL.
+func jsonValue[T any](v *T) (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
+
+func scanJSON[T any](data []byte) (*T, error) {
+	if data == nil {
+		return nil, nil
+	}
+	v := new(T)
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func expectOneRow(res sql.Result) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return repositories.ErrNotFound
+	}
+	return nil
+}
+
+// mapUniqueViolation переводит нарушение уникального индекса по адресу в ErrDestinationExists.
+func mapUniqueViolation(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation && pqErr.Constraint == destinationIndex {
+		return repositories.ErrDestinationExists
+	}
+	return err
+}
+
+// textArray не даёт nil-срезу стать NULL в колонках NOT NULL.
+func textArray(values []string) pq.StringArray {
+	if values == nil {
+		return pq.StringArray{}
+	}
+	return values
+}
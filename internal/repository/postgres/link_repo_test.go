@@ -0,0 +1,292 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"shorted/internal/domain/models"
+	"shorted/internal/domain/repositories"
+	"testing"
+)
+
+func TestLinkRepoRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	r := NewLinkRepo(testDB(t))
+
+	link := &models.Link{
+		ShortCode:   "abc",
+		OriginalURL: "https://example.com/",
+		OwnerID:     "alice",
+		CreatedAt:   100,
+		Routing: &models.Routing{Geo: []models.GeoRule{
+			{Name: "de", Countries: []string{"DE"}, URL: "https://example.de/"},
+		}},
+		Forwarding: &models.Forwarding{Params: map[string]string{"utm_source": "{code}"}},
+		Tags:       []string{"promo"},
+		Campaigns:  []string{"c1"},
+	}
+	if err := r.Save(ctx, link); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Save(ctx, &models.Link{ShortCode: "abc", OriginalURL: "https://other.example/"}); !errors.Is(err, repositories.ErrAlreadyExists) {
+		t.Errorf("taken code: %v", err)
+	}
+
+	got, err := r.FindByCode(ctx, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.OriginalURL != link.OriginalURL || got.OwnerID != "alice" || got.CreatedAt != 100 ||
+		got.Routing == nil || got.Routing.Geo[0].URL != "https://example.de/" ||
+		got.Forwarding == nil || got.Forwarding.Params["utm_source"] != "{code}" ||
+		fmt.Sprint(got.Tags) != "[promo]" || fmt.Sprint(got.Campaigns) != "[c1]" {
+		t.Errorf("FindByCode = %+v", got)
+	}
+	if _, err := r.FindByCode(ctx, "missing"); !errors.Is(err, repositories.ErrNotFound) {
+		t.Errorf("missing code: %v", err)
+	}
+
+	// пустые массивы читаются как nil, как и из памяти
+	got.Tags, got.Campaigns, got.UpdatedAt = nil, nil, 200
+	if err := r.Update(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = r.FindByCode(ctx, "abc"); err != nil || got.Tags != nil || got.Campaigns != nil || got.UpdatedAt != 200 {
+		t.Errorf("after Update = %+v, %v", got, err)
+	}
+	if err := r.Update(ctx, &models.Link{ShortCode: "missing"}); !errors.Is(err, repositories.ErrNotFound) {
+		t.Errorf("update of a missing link: %v", err)
+	}
+
+	meta := &models.LinkMetadata{Title: "Example"}
+	if err := r.SetMetadata(ctx, "abc", "https://stale.example/", meta); !errors.Is(err, repositories.ErrNotFound) {
+		t.Errorf("metadata for a stale url: %v", err)
+	}
+	if err := r.SetMetadata(ctx, "abc", "https://example.com/", meta); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = r.FindByCode(ctx, "abc"); err != nil || got.Metadata == nil || got.Metadata.Title != "Example" {
+		t.Errorf("after SetMetadata = %+v, %v", got, err)
+	}
+
+	if err := r.Delete(ctx, "abc"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Delete(ctx, "abc"); !errors.Is(err, repositories.ErrNotFound) {
+		t.Errorf("second delete: %v", err)
+	}
+}
+
+func TestLinkRepoDestinationIndex(t *testing.T) {
+	ctx := context.Background()
+	r := NewLinkRepo(testDB(t))
+	canonical := func(code, owner, url string) *models.Link {
+		return &models.Link{ShortCode: code, OwnerID: owner, OriginalURL: url, Canonical: true}
+	}
+
+	if err := r.Save(ctx, canonical("a", "alice", "https://x/")); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Save(ctx, canonical("b", "alice", "https://x/")); !errors.Is(err, repositories.ErrDestinationExists) {
+		t.Errorf("second canonical link: %v", err)
+	}
+	if err := r.Save(ctx, canonical("c", "bob", "https://x/")); err != nil {
+		t.Errorf("other owner: %v", err)
+	}
+	if err := r.Save(ctx, &models.Link{ShortCode: "d", OwnerID: "alice", OriginalURL: "https://x/"}); err != nil {
+		t.Errorf("non-canonical link: %v", err)
+	}
+
+	found, err := r.FindByDestination(ctx, "alice", "https://x/")
+	if err != nil || found.ShortCode != "a" {
+		t.Errorf("FindByDestination = %v, %v", found, err)
+	}
+	if _, err := r.FindByDestination(ctx, "alice", "https://y/"); !errors.Is(err, repositories.ErrNotFound) {
+		t.Errorf("unknown destination: %v", err)
+	}
+
+	moved := canonical("c", "alice", "https://x/")
+	if err := r.Update(ctx, moved); !errors.Is(err, repositories.ErrDestinationExists) {
+		t.Errorf("update onto a taken destination: %v", err)
+	}
+}
+
+func TestLinkRepoSaveMany(t *testing.T) {
+	ctx := context.Background()
+	r := NewLinkRepo(testDB(t))
+	if err := r.Save(ctx, &models.Link{ShortCode: "taken", OriginalURL: "https://example.com/"}); err != nil {
+		t.Fatal(err)
+	}
+
+	links := []*models.Link{
+		{ShortCode: "a", OriginalURL: "https://example.com/a", Tags: []string{"x"}},
+		{ShortCode: "taken", OriginalURL: "https://example.com/b"},
+		{ShortCode: "c", OriginalURL: "https://example.com/c"},
+		// код, повторённый в пачке, достаётся первому вхождению
+		{ShortCode: "a", OriginalURL: "https://example.com/d"},
+	}
+	errs, err := r.SaveMany(ctx, links)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []error{nil, repositories.ErrAlreadyExists, nil, repositories.ErrAlreadyExists}
+	for i := range want {
+		if !errors.Is(errs[i], want[i]) || (want[i] == nil && errs[i] != nil) {
+			t.Errorf("errs[%d] = %v, want %v", i, errs[i], want[i])
+		}
+	}
+
+	found, err := r.FindByCodes(ctx, []string{"a", "c", "missing"})
+	if err != nil || len(found) != 2 {
+		t.Fatalf("FindByCodes = %v, %v", found, err)
+	}
+	for _, link := range found {
+		if link.ShortCode == "a" && (link.OriginalURL != "https://example.com/a" || fmt.Sprint(link.Tags) != "[x]") {
+			t.Errorf("link a = %+v", link)
+		}
+	}
+}
+
+func TestLinkRepoList(t *testing.T) {
+	ctx := context.Background()
+	r := NewLinkRepo(testDB(t))
+	for _, link := range []*models.Link{
+		{ShortCode: "a1", OwnerID: "alice", OriginalURL: "https://example.com/1", Tags: []string{"promo"}, Campaigns: []string{"c1"}},
+		{ShortCode: "a2", OwnerID: "alice", OriginalURL: "https://example.com/2", Tags: []string{"promo", "news"}},
+		{ShortCode: "a3", OwnerID: "alice", OriginalURL: "https://example.com/3"},
+		{ShortCode: "b1", OwnerID: "bob", OriginalURL: "https://example.com/4", Tags: []string{"promo"}},
+		{ShortCode: "n1", OriginalURL: "https://example.com/5"},
+	} {
+		if err := r.Save(ctx, link); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		opts repositories.ListOptions
+		want string
+	}{
+		{"everything", repositories.ListOptions{}, "[a1 a2 a3 b1 n1]"},
+		{"owner", repositories.ListOptions{OwnerID: "alice"}, "[a1 a2 a3]"},
+		{"ownerless only", repositories.ListOptions{OwnerOnly: true}, "[n1]"},
+		{"page", repositories.ListOptions{OwnerID: "alice", After: "a1", Limit: 1}, "[a2]"},
+		{"tag", repositories.ListOptions{OwnerID: "alice", Tag: "promo"}, "[a1 a2]"},
+		{"campaign", repositories.ListOptions{OwnerID: "alice", Campaign: "c1"}, "[a1]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			links, err := r.List(ctx, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			codes := make([]string, len(links))
+			for i, link := range links {
+				codes[i] = link.ShortCode
+			}
+			if got := fmt.Sprint(codes); got != tt.want {
+				t.Errorf("List = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinkRepoTags(t *testing.T) {
+	ctx := context.Background()
+	r := NewLinkRepo(testDB(t))
+	for _, link := range []*models.Link{
+		{ShortCode: "a1", OwnerID: "alice", OriginalURL: "https://example.com/1", Tags: []string{"old"}, Campaigns: []string{"c1", "c2"}},
+		{ShortCode: "a2", OwnerID: "alice", OriginalURL: "https://example.com/2", Campaigns: []string{"c1"}},
+		{ShortCode: "b1", OwnerID: "bob", OriginalURL: "https://example.com/3", Tags: []string{"promo"}},
+	} {
+		if err := r.Save(ctx, link); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// метка из обоих списков убирается, повторы не появляются, отсутствующий код пропускается
+	if err := r.EditTags(ctx, []string{"a1", "a2", "missing"}, []string{"promo", "promo", "gone"}, []string{"old", "gone"}, 300); err != nil {
+		t.Fatal(err)
+	}
+	links, err := r.FindByCodes(ctx, []string{"a1", "a2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, link := range links {
+		if fmt.Sprint(link.Tags) != "[promo]" || link.UpdatedAt != 300 {
+			t.Errorf("link %s tags = %v, updated_at = %d", link.ShortCode, link.Tags, link.UpdatedAt)
+		}
+	}
+
+	counts, err := r.Tags(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(counts) != fmt.Sprint([]models.TagCount{{Name: "promo", Links: 2}}) {
+		t.Errorf("Tags = %+v", counts)
+	}
+
+	if err := r.RemoveCampaign(ctx, "c1", 400); err != nil {
+		t.Fatal(err)
+	}
+	a1, err := r.FindByCode(ctx, "a1")
+	if err != nil || fmt.Sprint(a1.Campaigns) != "[c2]" || a1.UpdatedAt != 400 {
+		t.Errorf("a1 after RemoveCampaign = %+v, %v", a1, err)
+	}
+	a2, err := r.FindByCode(ctx, "a2")
+	if err != nil || a2.Campaigns != nil {
+		t.Errorf("a2 after RemoveCampaign = %+v, %v", a2, err)
+	}
+	b1, err := r.FindByCode(ctx, "b1")
+	if err != nil || b1.UpdatedAt != 0 {
+		t.Errorf("link without the campaign was touched: %+v, %v", b1, err)
+	}
+}
+
+func TestLinkRepoSearch(t *testing.T) {
+	ctx := context.Background()
+	r := NewLinkRepo(testDB(t))
+	for _, link := range []*models.Link{
+		{ShortCode: "sale", OwnerID: "alice", OriginalURL: "https://example.com/offers"},
+		{ShortCode: "tagged", OwnerID: "alice", OriginalURL: "https://example.com/a", Tags: []string{"shoes"}},
+		{ShortCode: "path", OwnerID: "alice", OriginalURL: "https://example.com/shoes"},
+		{ShortCode: "other", OwnerID: "bob", OriginalURL: "https://example.com/shoes", Tags: []string{"spring"}},
+	} {
+		if err := r.Save(ctx, link); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// заголовок попадает в индекс через триггер при обновлении metadata
+	if err := r.SetMetadata(ctx, "sale", "https://example.com/offers", &models.LinkMetadata{Title: "Spring sale"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		owner string
+		want  string
+	}{
+		{"prefix", "spri", "alice", "[sale]"},
+		{"typo", "sprng", "alice", "[sale]"},
+		{"other owner", "spring", "bob", "[other]"},
+		{"ownerless", "spring", "", "[]"},
+		{"tag outranks url", "shoes", "alice", "[tagged path]"},
+		{"no terms", "!!", "alice", "[]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits, err := r.Search(ctx, repositories.SearchOptions{Query: tt.query, OwnerID: tt.owner})
+			if err != nil {
+				t.Fatal(err)
+			}
+			codes := make([]string, len(hits))
+			for i, hit := range hits {
+				codes[i] = hit.Link.ShortCode
+			}
+			if got := fmt.Sprint(codes); got != tt.want {
+				t.Errorf("Search(%q) = %s, want %s", tt.query, got, tt.want)
+			}
+		})
+	}
+}
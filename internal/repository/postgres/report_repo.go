@@ -0,0 +1,119 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"shorted/internal/domain/models"
+	"shorted/internal/domain/repositories"
+)
+
+const reportColumns = `id, short_code, reason, comment, reporter, state, note, created_at, updated_at`
+
+type ReportRepo struct {
+	db *sql.DB
+}
+
+func NewReportRepo(db *sql.DB) *ReportRepo {
+	return &ReportRepo{db: db}
+}
+
+func (r *ReportRepo) Save(ctx context.Context, report *models.Report) error {
+	res, err := r.db.ExecContext(ctx,
+		`INSERT INTO link_reports (`+reportColumns+`)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		 ON CONFLICT (id) DO NOTHING`,
+		report.ID, report.ShortCode, report.Reason, report.Comment, report.Reporter,
+		report.State, report.Note, report.CreatedAt, report.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return repositories.ErrAlreadyExists
+	}
+	return nil
+}
+
+func (r *ReportRepo) FindByID(ctx context.Context, id string) (*models.Report, error) {
+	report, err := scanReport(r.db.QueryRowContext(ctx,
+		`SELECT `+reportColumns+` FROM link_reports WHERE id = $1`,
+		id,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, repositories.ErrReportNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func (r *ReportRepo) Update(ctx context.Context, report *models.Report) error {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE link_reports SET state = $2, note = $3, updated_at = $4 WHERE id = $1`,
+		report.ID, report.State, report.Note, report.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return repositories.ErrReportNotFound
+	}
+	return nil
+}
+
+func (r *ReportRepo) List(ctx context.Context, opts repositories.ReportListOptions) ([]*models.Report, error) {
+	limit := sql.NullInt64{Int64: int64(opts.Limit), Valid: opts.Limit > 0}
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+reportColumns+` FROM link_reports
+		 WHERE id > $1 AND ($3 = '' OR state = $3) AND ($4 = '' OR short_code = $4)
+		 ORDER BY id
+		 LIMIT $2`,
+		opts.After, limit, opts.State, opts.ShortCode,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reports []*models.Report
+	for rows.Next() {
+		report, err := scanReport(rows)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	return reports, rows.Err()
+}
+
+func (r *ReportRepo) CountReporters(ctx context.Context, shortCode string, since int64) (int, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx,
+		`SELECT COUNT(DISTINCT reporter) FROM link_reports
+		 WHERE short_code = $1 AND created_at >= $2 AND state IN ($3, $4)`,
+		shortCode, since, models.ReportOpen, models.ReportReviewing,
+	).Scan(&n)
+	return n, err
+}
+
+func scanReport(row scanner) (*models.Report, error) {
+	var report models.Report
+	err := row.Scan(&report.ID, &report.ShortCode, &report.Reason, &report.Comment, &report.Reporter,
+		&report.State, &report.Note, &report.CreatedAt, &report.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
@@ -0,0 +1,122 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"shorted/internal/domain/models"
+
+	"github.com/lib/pq"
+)
+
+type StatsRepo struct {
+	db *sql.DB
+}
+
+func NewStatsRepo(db *sql.DB) *StatsRepo {
+	return &StatsRepo{db: db}
+}
+
+func (r *StatsRepo) RecordClick(ctx context.Context, click *models.Click) error {
+	// оба счётчика обновляются одним запросом: CTE с INSERT выполняется, даже
+	// если на него не ссылаются
+	_, err := r.db.ExecContext(ctx,
+		`WITH totals AS (
+		     INSERT INTO link_stats (short_code, clicks, last_click_at)
+		     VALUES ($1, 1, $2)
+		     ON CONFLICT (short_code) DO UPDATE
+		     SET clicks = link_stats.clicks + 1,
+		         last_click_at = GREATEST(link_stats.last_click_at, EXCLUDED.last_click_at)
+		 )
+		 INSERT INTO link_rule_stats (short_code, rule, clicks)
+		 SELECT $1, $3::text, 1 WHERE $3::text <> ''
+		 ON CONFLICT (short_code, rule) DO UPDATE
+		 SET clicks = link_rule_stats.clicks + 1`,
+		click.ShortCode, click.At, click.Rule,
+	)
+	return err
+}
+
+func (r *StatsRepo) Stats(ctx context.Context, shortCode string) (*models.LinkStats, error) {
+	stats := models.LinkStats{ShortCode: shortCode}
+	err := r.db.QueryRowContext(ctx,
+		`SELECT clicks, last_click_at FROM link_stats WHERE short_code = $1`,
+		shortCode,
+	).Scan(&stats.Clicks, &stats.LastClickAt)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	byCode := map[string]*models.LinkStats{shortCode: &stats}
+	if err := r.ruleStats(ctx, []string{shortCode}, byCode); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+func (r *StatsRepo) StatsByCodes(ctx context.Context, shortCodes []string) ([]*models.LinkStats, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT short_code, clicks, last_click_at FROM link_stats WHERE short_code = ANY($1)`,
+		pq.Array(shortCodes),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	found := make(map[string]*models.LinkStats, len(shortCodes))
+	for rows.Next() {
+		var stats models.LinkStats
+		if err := rows.Scan(&stats.ShortCode, &stats.Clicks, &stats.LastClickAt); err != nil {
+			return nil, err
+		}
+		found[stats.ShortCode] = &stats
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := r.ruleStats(ctx, shortCodes, found); err != nil {
+		return nil, err
+	}
+
+	result := make([]*models.LinkStats, 0, len(shortCodes))
+	for _, code := range shortCodes {
+		stats, ok := found[code]
+		if !ok {
+			stats = &models.LinkStats{ShortCode: code}
+		}
+		result = append(result, stats)
+	}
+	return result, nil
+}
+
+// ruleStats дописывает переходы по правилам маршрутизации в статистику из byCode.
+func (r *StatsRepo) ruleStats(ctx context.Context, shortCodes []string, byCode map[string]*models.LinkStats) error {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT short_code, rule, clicks FROM link_rule_stats WHERE short_code = ANY($1)`,
+		pq.Array(shortCodes),
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			code, rule string
+			clicks     int64
+		)
+		if err := rows.Scan(&code, &rule, &clicks); err != nil {
+			return err
+		}
+		stats, ok := byCode[code]
+		if !ok {
+			continue
+		}
+		if stats.Rules == nil {
+			stats.Rules = make(map[string]int64)
+		}
+		stats.Rules[rule] = clicks
+	}
+	return rows.Err()
+}
@@ -0,0 +1,314 @@
+package moderation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"shorted/internal/auth"
+	"shorted/internal/domain/domainerr"
+	"shorted/internal/domain/models"
+	"shorted/internal/domain/repositories"
+	"shorted/pkg/tracing"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxCommentLength = 2000
+	maxReasonLength  = 500
+
+	defaultPageSize = 50
+	maxPageSize     = 500
+
+	defaultThreshold = 5
+	defaultWindow    = time.Hour
+)
+
+// Reasons — допустимые категории жалоб.
+var Reasons = []string{"phishing", "malware", "spam", "illegal", "other"}
+
+type Service struct {
+	links     repositories.LinkRepository
+	reports   repositories.ReportRepository
+	tracer    *tracing.Tracer
+	threshold int
+	window    time.Duration
+}
+
+type Option func(*Service)
+
+// WithReportThreshold задаёт, сколько разных отправителей за окно отключают ссылку
+// автоматически; n <= 0 выключает автоотключение.
+func WithReportThreshold(n int) Option {
+	return func(s *Service) {
+		s.threshold = n
+	}
+}
+
+// WithReportWindow задаёт окно, за которое считаются жалобы для автоотключения.
+func WithReportWindow(d time.Duration) Option {
+	return func(s *Service) {
+		s.window = d
+	}
+}
+
+func NewService(links repositories.LinkRepository, reports repositories.ReportRepository, tracer *tracing.Tracer, opts ...Option) *Service {
+	s := &Service{
+		links:     links,
+		reports:   reports,
+		tracer:    tracer,
+		threshold: defaultThreshold,
+		window:    defaultWindow,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+type ReportInput struct {
+	Reason  string
+	Comment string
+	// Reporter — IP анонимного отправителя; для запросов с ключом берётся владелец.
+	Reporter string
+}
+
+// Report принимает жалобу на ссылку и, если жалоб за окно набралось достаточно,
+// отключает её до решения модератора.
+func (s *Service) Report(ctx context.Context, shortCode string, in ReportInput) (_ *models.Report, err error) {
+	ctx, span := s.tracer.Start(ctx, "moderation.Report", tracing.SpanKindInternal)
+	span.SetAttribute("link.short_code", shortCode)
+	defer func() { endSpan(span, err) }()
+
+	if err := validateReport(in); err != nil {
+		return nil, err
+	}
+	link, err := s.findLink(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+
+	reporter := in.Reporter
+	if p, ok := auth.PrincipalFrom(ctx); ok {
+		reporter = "owner:" + p.ID
+	}
+	id, err := newReportID()
+	if err != nil {
+		return nil, err
+	}
+	report := &models.Report{
+		ID:        id,
+		ShortCode: link.ShortCode,
+		Reason:    in.Reason,
+		Comment:   strings.TrimSpace(in.Comment),
+		Reporter:  reporter,
+		State:     models.ReportOpen,
+		CreatedAt: time.Now().Unix(),
+	}
+	if err := s.reports.Save(ctx, report); err != nil {
+		return nil, err
+	}
+
+	if s.threshold > 0 && link.DisabledAt == 0 {
+		since := time.Now().Add(-s.window).Unix()
+		n, err := s.reports.CountReporters(ctx, link.ShortCode, since)
+		if err != nil {
+			return nil, err
+		}
+		if n >= s.threshold {
+			span.SetAttribute("link.auto_disabled", true)
+			if _, err := s.disable(ctx, link, "This link was reported by several people and is under review."); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// Reports отдаёт страницу очереди модерации; пустой next означает последнюю страницу.
+func (s *Service) Reports(ctx context.Context, opts repositories.ReportListOptions) (_ []*models.Report, next string, err error) {
+	ctx, span := s.tracer.Start(ctx, "moderation.Reports", tracing.SpanKindInternal)
+	defer func() { endSpan(span, err) }()
+
+	if opts.State != "" && !validState(opts.State) {
+		return nil, "", invalidState()
+	}
+
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	limit = min(limit, maxPageSize)
+
+	opts.Limit = limit + 1
+	reports, err := s.reports.List(ctx, opts)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(reports) > limit {
+		reports = reports[:limit]
+		next = reports[limit-1].ID
+	}
+	return reports, next, nil
+}
+
+// UpdateReport переводит жалобу в другое состояние; note, если не пуст, заменяет
+// комментарий модератора.
+func (s *Service) UpdateReport(ctx context.Context, id string, state models.ReportState, note string) (_ *models.Report, err error) {
+	ctx, span := s.tracer.Start(ctx, "moderation.UpdateReport", tracing.SpanKindInternal)
+	span.SetAttribute("report.id", id)
+	defer func() { endSpan(span, err) }()
+
+	if !validState(state) {
+		return nil, invalidState()
+	}
+
+	report, err := s.reports.FindByID(ctx, id)
+	if err != nil {
+		return nil, reportNotFound(err)
+	}
+	report.State = state
+	if note = strings.TrimSpace(note); note != "" {
+		report.Note = note
+	}
+	report.UpdatedAt = time.Now().Unix()
+	if err := s.reports.Update(ctx, report); err != nil {
+		return nil, reportNotFound(err)
+	}
+	return report, nil
+}
+
+// DisableLink отключает ссылку; reason увидят те, кто по ней перейдёт.
+func (s *Service) DisableLink(ctx context.Context, shortCode, reason string) (_ *models.Link, err error) {
+	ctx, span := s.tracer.Start(ctx, "moderation.DisableLink", tracing.SpanKindInternal)
+	span.SetAttribute("link.short_code", shortCode)
+	defer func() { endSpan(span, err) }()
+
+	reason = strings.TrimSpace(reason)
+	if reason == "" || utf8.RuneCountInString(reason) > maxReasonLength {
+		return nil, domainerr.Validation(domainerr.FieldError{
+			Field:   "reason",
+			Code:    "invalid_reason",
+			Message: fmt.Sprintf("reason must be 1 to %d characters", maxReasonLength),
+		})
+	}
+
+	link, err := s.findLink(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	return s.disable(ctx, link, reason)
+}
+
+func (s *Service) EnableLink(ctx context.Context, shortCode string) (_ *models.Link, err error) {
+	ctx, span := s.tracer.Start(ctx, "moderation.EnableLink", tracing.SpanKindInternal)
+	span.SetAttribute("link.short_code", shortCode)
+	defer func() { endSpan(span, err) }()
+
+	link, err := s.findLink(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	link.DisabledAt = 0
+	link.DisabledReason = ""
+	link.UpdatedAt = time.Now().Unix()
+	if err := s.links.Update(ctx, link); err != nil {
+		return nil, linkNotFound(err)
+	}
+	return link, nil
+}
+
+func (s *Service) disable(ctx context.Context, link *models.Link, reason string) (*models.Link, error) {
+	link.DisabledAt = time.Now().Unix()
+	link.DisabledReason = reason
+	link.UpdatedAt = link.DisabledAt
+	if err := s.links.Update(ctx, link); err != nil {
+		return nil, linkNotFound(err)
+	}
+	return link, nil
+}
+
+func (s *Service) findLink(ctx context.Context, shortCode string) (*models.Link, error) {
+	link, err := s.links.FindByCode(ctx, shortCode)
+	if err != nil {
+		return nil, linkNotFound(err)
+	}
+	return link, nil
+}
+
+func validateReport(in ReportInput) error {
+	var fields []domainerr.FieldError
+	known := false
+	for _, r := range Reasons {
+		known = known || r == in.Reason
+	}
+	if !known {
+		fields = append(fields, domainerr.FieldError{
+			Field:   "reason",
+			Code:    "invalid_reason",
+			Message: "reason must be one of " + strings.Join(Reasons, ", "),
+		})
+	}
+	if utf8.RuneCountInString(in.Comment) > maxCommentLength {
+		fields = append(fields, domainerr.FieldError{
+			Field:   "comment",
+			Code:    "too_long",
+			Message: fmt.Sprintf("comment must be at most %d characters", maxCommentLength),
+		})
+	}
+	if len(fields) > 0 {
+		return domainerr.Validation(fields...)
+	}
+	return nil
+}
+
+func validState(state models.ReportState) bool {
+	switch state {
+	case models.ReportOpen, models.ReportReviewing, models.ReportActioned, models.ReportDismissed:
+		return true
+	}
+	return false
+}
+
+func invalidState() error {
+	return domainerr.Validation(domainerr.FieldError{
+		Field:   "state",
+		Code:    "invalid_state",
+		Message: "state must be one of open, reviewing, actioned, dismissed",
+	})
+}
+
+// newReportID начинается с времени в миллисекундах, поэтому порядок ID совпадает
+// с порядком поступления жалоб.
+func newReportID() (string, error) {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%012x%s", time.Now().UnixMilli(), hex.EncodeToString(suffix)), nil
+}
+
+func linkNotFound(err error) error {
+	if errors.Is(err, repositories.ErrNotFound) {
+		return domainerr.ErrLinkNotFound.WithCause(err)
+	}
+	return err
+}
+
+func reportNotFound(err error) error {
+	if errors.Is(err, repositories.ErrReportNotFound) {
+		return domainerr.ErrReportNotFound.WithCause(err)
+	}
+	return err
+}
+
+func endSpan(span *tracing.Span, err error) {
+	if err != nil && domainerr.KindOf(err) != domainerr.KindNotFound {
+		span.RecordError(err)
+	}
+	span.End()
+}
@@ -0,0 +1,248 @@
+package moderation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"shorted/internal/auth"
+	"shorted/internal/domain/domainerr"
+	"shorted/internal/domain/models"
+	"shorted/internal/domain/repositories"
+	"shorted/internal/repository/memory"
+	"shorted/pkg/tracing"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestService(t *testing.T, opts ...Option) (*Service, *memory.LinkRepo, *memory.ReportRepo) {
+	t.Helper()
+	links, reports := memory.NewLinkRepo(), memory.NewReportRepo()
+	link := &models.Link{ShortCode: "abc", OriginalURL: "https://example.com/", CreatedAt: time.Now().Unix()}
+	if err := links.Save(context.Background(), link); err != nil {
+		t.Fatal(err)
+	}
+	return NewService(links, reports, tracing.NewTracer(tracing.NeverSample(), nil), opts...), links, reports
+}
+
+func TestReport(t *testing.T) {
+	tests := []struct {
+		name       string
+		code       string
+		in         ReportInput
+		wantErr    *domainerr.Error
+		wantFields []string
+	}{
+		{name: "valid", code: "abc", in: ReportInput{Reason: "phishing", Comment: "  fake bank  ", Reporter: "192.0.2.1"}},
+		{name: "unknown reason", code: "abc", in: ReportInput{Reason: "boring"}, wantErr: domainerr.ErrValidation, wantFields: []string{"reason"}},
+		{
+			name: "long comment and no reason", code: "abc",
+			in:      ReportInput{Comment: strings.Repeat("я", maxCommentLength+1)},
+			wantErr: domainerr.ErrValidation, wantFields: []string{"reason", "comment"},
+		},
+		{name: "comment at the limit", code: "abc", in: ReportInput{Reason: "spam", Comment: strings.Repeat("я", maxCommentLength)}},
+		{name: "missing link", code: "nope", in: ReportInput{Reason: "spam"}, wantErr: domainerr.ErrLinkNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _, _ := newTestService(t)
+			report, err := s.Report(context.Background(), tt.code, tt.in)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %s", err, tt.wantErr.Code)
+				}
+				var fields []string
+				for _, f := range domainerr.From(err).Fields {
+					fields = append(fields, f.Field)
+				}
+				if fmt.Sprint(fields) != fmt.Sprint(tt.wantFields) {
+					t.Errorf("fields = %v, want %v", fields, tt.wantFields)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.State != models.ReportOpen || report.Reporter != tt.in.Reporter || report.Comment != strings.TrimSpace(tt.in.Comment) {
+				t.Errorf("report = %+v", report)
+			}
+		})
+	}
+}
+
+func TestReportFromKeyOwner(t *testing.T) {
+	s, _, _ := newTestService(t)
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{ID: "alice"})
+	report, err := s.Report(ctx, "abc", ReportInput{Reason: "spam", Reporter: "192.0.2.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Reporter != "owner:alice" {
+		t.Errorf("Reporter = %q, want owner:alice", report.Reporter)
+	}
+}
+
+func TestAutoDisable(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold int
+		reporters []string
+		// stale — сколько жалоб сдвинуть за пределы окна
+		stale        int
+		dismissed    int
+		wantDisabled bool
+	}{
+		{name: "below threshold", threshold: 3, reporters: []string{"a", "b"}},
+		{name: "at threshold", threshold: 3, reporters: []string{"a", "b", "c"}, wantDisabled: true},
+		{name: "same reporter counts once", threshold: 3, reporters: []string{"a", "a", "a", "b"}},
+		{name: "reports outside the window", threshold: 3, reporters: []string{"a", "b", "c"}, stale: 1},
+		{name: "dismissed reports", threshold: 3, reporters: []string{"a", "b", "c"}, dismissed: 1},
+		{name: "disabled threshold", threshold: 0, reporters: []string{"a", "b", "c", "d", "e", "f"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, links, reports := newTestService(t, WithReportThreshold(tt.threshold), WithReportWindow(time.Hour))
+			ctx := context.Background()
+
+			for i, reporter := range tt.reporters {
+				report, err := s.Report(ctx, "abc", ReportInput{Reason: "spam", Reporter: reporter})
+				if err != nil {
+					t.Fatal(err)
+				}
+				// последнюю жалобу не трогаем: именно она проверяет порог
+				if i >= len(tt.reporters)-1 {
+					continue
+				}
+				switch {
+				case i < tt.stale:
+					report.CreatedAt = time.Now().Add(-2 * time.Hour).Unix()
+				case i < tt.stale+tt.dismissed:
+					report.State = models.ReportDismissed
+				default:
+					continue
+				}
+				if err := reports.Update(ctx, report); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			link, err := links.FindByCode(ctx, "abc")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if disabled := link.DisabledAt != 0; disabled != tt.wantDisabled {
+				t.Fatalf("disabled = %v, want %v", disabled, tt.wantDisabled)
+			}
+			if tt.wantDisabled && link.DisabledReason == "" {
+				t.Error("auto-disabled link has no reason")
+			}
+		})
+	}
+}
+
+func TestReportsPagination(t *testing.T) {
+	s, _, _ := newTestService(t)
+	ctx := context.Background()
+	var ids []string
+	for i := 0; i < 5; i++ {
+		report, err := s.Report(ctx, "abc", ReportInput{Reason: "spam", Reporter: fmt.Sprint(i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, report.ID)
+		// ID начинается с миллисекунд: порядок должен совпадать с порядком поступления
+		time.Sleep(2 * time.Millisecond)
+	}
+	if _, err := s.UpdateReport(ctx, ids[1], models.ReportDismissed, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	opts := repositories.ReportListOptions{Limit: 2, State: models.ReportOpen}
+	for page := 0; ; page++ {
+		reports, next, err := s.Reports(ctx, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range reports {
+			got = append(got, r.ID)
+		}
+		if next == "" {
+			break
+		}
+		if page > 5 {
+			t.Fatal("pagination does not end")
+		}
+		opts.After = next
+	}
+
+	want := []string{ids[0], ids[2], ids[3], ids[4]}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("open reports = %v, want %v", got, want)
+	}
+
+	if _, _, err := s.Reports(ctx, repositories.ReportListOptions{State: "closed"}); !errors.Is(err, domainerr.ErrValidation) {
+		t.Errorf("Reports(closed) error = %v, want validation error", err)
+	}
+}
+
+func TestUpdateReport(t *testing.T) {
+	s, _, _ := newTestService(t)
+	ctx := context.Background()
+	report, err := s.Report(ctx, "abc", ReportInput{Reason: "spam"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updated, err := s.UpdateReport(ctx, report.ID, models.ReportReviewing, " checking ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.State != models.ReportReviewing || updated.Note != "checking" || updated.UpdatedAt == 0 {
+		t.Errorf("updated = %+v", updated)
+	}
+	// пустая заметка не стирает прежнюю
+	updated, err = s.UpdateReport(ctx, report.ID, models.ReportActioned, "")
+	if err != nil || updated.Note != "checking" {
+		t.Errorf("updated = %+v, %v", updated, err)
+	}
+
+	if _, err := s.UpdateReport(ctx, report.ID, "closed", ""); !errors.Is(err, domainerr.ErrValidation) {
+		t.Errorf("invalid state: error = %v", err)
+	}
+	if _, err := s.UpdateReport(ctx, "missing", models.ReportDismissed, ""); !errors.Is(err, domainerr.ErrReportNotFound) {
+		t.Errorf("missing report: error = %v", err)
+	}
+}
+
+func TestDisableAndEnableLink(t *testing.T) {
+	s, links, _ := newTestService(t)
+	ctx := context.Background()
+
+	for _, reason := range []string{"", "   ", strings.Repeat("x", maxReasonLength+1)} {
+		if _, err := s.DisableLink(ctx, "abc", reason); !errors.Is(err, domainerr.ErrValidation) {
+			t.Errorf("DisableLink(%q) error = %v, want validation error", reason, err)
+		}
+	}
+	if _, err := s.DisableLink(ctx, "nope", "malware"); !errors.Is(err, domainerr.ErrLinkNotFound) {
+		t.Errorf("DisableLink(missing) error = %v", err)
+	}
+
+	if _, err := s.DisableLink(ctx, "abc", " Distributes malware "); err != nil {
+		t.Fatal(err)
+	}
+	link, _ := links.FindByCode(ctx, "abc")
+	if link.DisabledAt == 0 || link.DisabledReason != "Distributes malware" {
+		t.Errorf("disabled link = %+v", link)
+	}
+
+	if _, err := s.EnableLink(ctx, "abc"); err != nil {
+		t.Fatal(err)
+	}
+	link, _ = links.FindByCode(ctx, "abc")
+	if link.DisabledAt != 0 || link.DisabledReason != "" {
+		t.Errorf("enabled link = %+v", link)
+	}
+}
@@ -0,0 +1,140 @@
+package shortener
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"shorted/internal/auth"
+	"shorted/internal/domain/domainerr"
+	"shorted/internal/domain/models"
+	"shorted/internal/domain/repositories"
+	"shorted/pkg/tracing"
+	"time"
+)
+
+const (
+	// MaxBatchSize — предел для синхронной пачки; большие пачки идут через StartBatch.
+	MaxBatchSize = 1000
+	MaxJobSize   = 100000
+
+	// batchChunkSize — сколько ссылок уходит в один вызов SaveMany (одну транзакцию).
+	batchChunkSize = 500
+)
+
+// BatchItem — элемент пачки: адрес и те же настройки, что у CreateShortURL.
+// ForceNew не учитывается: пачка всегда выдаёт новые коды.
+type BatchItem struct {
+	URL string
+	CreateOptions
+}
+
+// BatchResult — итог по одному элементу пачки: либо Link, либо Err.
+type BatchResult struct {
+	Link *models.Link
+	Err  error
+}
+
+// CreateShortURLs создаёт ссылки пачкой. Каждый элемент проверяется отдельно, и
+// ошибка одного элемента не мешает остальным.
+func (s *Service) CreateShortURLs(ctx context.Context, items []BatchItem) (_ []BatchResult, err error) {
+	ctx, span := s.tracer.Start(ctx, "shortener.CreateShortURLs", tracing.SpanKindInternal)
+	span.SetAttribute("batch.size", len(items))
+	defer func() { endSpan(span, err) }()
+
+	if err := checkBatchSize(len(items), MaxBatchSize); err != nil {
+		return nil, err
+	}
+	return s.createBatch(ctx, items, nil), nil
+}
+
+// createBatch сообщает в progress число обработанных элементов после каждого чанка.
+func (s *Service) createBatch(ctx context.Context, items []BatchItem, progress func(processed int)) []BatchResult {
+	owner, _ := auth.PrincipalFrom(ctx)
+	now := time.Now().Unix()
+
+	results := make([]BatchResult, len(items))
+	links := make([]*models.Link, len(items))
+	pending := make([]int, 0, len(items))
+	for i, item := range items {
+		link, err := s.newLink(ctx, owner.ID, item.URL, item.CreateOptions)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		link.CreatedAt = now
+		links[i] = link
+		pending = append(pending, i)
+	}
+
+	processed := len(items) - len(pending)
+	for start := 0; start < len(pending); start += batchChunkSize {
+		chunk := pending[start:min(start+batchChunkSize, len(pending))]
+		if err := s.saveChunk(ctx, links, chunk, results); err != nil {
+			for _, i := range chunk {
+				if results[i].Link == nil && results[i].Err == nil {
+					results[i].Err = err
+				}
+			}
+		}
+
+		processed += len(chunk)
+		if progress != nil {
+			progress(processed)
+		}
+	}
+
+	return results
+}
+
+// saveChunk сохраняет ссылки с индексами idx, перегенерируя коды, которые оказались заняты.
+func (s *Service) saveChunk(ctx context.Context, links []*models.Link, idx []int, results []BatchResult) error {
+	for attempt := 0; ; attempt++ {
+		batch := make([]*models.Link, len(idx))
+		for j, i := range idx {
+			code, err := generateCode()
+			if err != nil {
+				return err
+			}
+			links[i].ShortCode = code
+			batch[j] = links[i]
+		}
+
+		errs, err := s.repo.SaveMany(ctx, batch)
+		if err != nil {
+			return err
+		}
+
+		var conflicted []int
+		for j, i := range idx {
+			switch {
+			case errs[j] == nil:
+				results[i].Link = links[i]
+				s.enqueueMetadata(links[i])
+			case errors.Is(errs[j], repositories.ErrAlreadyExists):
+				conflicted = append(conflicted, i)
+			default:
+				results[i].Err = errs[j]
+			}
+		}
+		if len(conflicted) == 0 {
+			return nil
+		}
+		if attempt >= maxSaveRetries {
+			for _, i := range conflicted {
+				results[i].Err = domainerr.ErrCodeExhausted
+			}
+			return nil
+		}
+		idx = conflicted
+	}
+}
+
+func checkBatchSize(n, limit int) error {
+	if n == 0 {
+		return domainerr.ErrBatchEmpty
+	}
+	if n > limit {
+		return domainerr.ErrBatchTooLarge.WithMessage(fmt.Sprintf("batch contains %d items, the limit is %d", n, limit))
+	}
+	return nil
+}
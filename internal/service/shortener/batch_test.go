@@ -0,0 +1,272 @@
+package shortener
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"shorted/internal/domain/domainerr"
+	"shorted/internal/domain/models"
+	"shorted/internal/domain/repositories"
+	"shorted/internal/repository/memory"
+	"shorted/pkg/pagemeta"
+	"shorted/pkg/tracing"
+	"sync"
+	"testing"
+	"time"
+)
+
+func urlItems(urls ...string) []BatchItem {
+	items := make([]BatchItem, len(urls))
+	for i, u := range urls {
+		items[i].URL = u
+	}
+	return items
+}
+
+func TestCreateShortURLs(t *testing.T) {
+	s := newTestService()
+	results, err := s.CreateShortURLs(as("alice"), urlItems(
+		"https://example.com/a",
+		"ftp://example.com/",
+		"https://example.com/b",
+		"http://127.0.0.1/",
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantErr := []string{"", "validation_failed", "", "validation_failed"}
+	for i, res := range results {
+		if wantErr[i] == "" {
+			if res.Err != nil || res.Link == nil || res.Link.OwnerID != "alice" {
+				t.Errorf("item %d = %+v", i, res)
+			}
+			continue
+		}
+		if res.Link != nil || domainerr.From(res.Err).Code != wantErr[i] {
+			t.Errorf("item %d error = %v, want %s", i, res.Err, wantErr[i])
+		}
+	}
+	if results[0].Link.ShortCode == results[2].Link.ShortCode {
+		t.Error("items got the same code")
+	}
+}
+
+// countingFetcher считает запросы метаданных.
+type countingFetcher struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (f *countingFetcher) Fetch(ctx context.Context, rawURL string) (*pagemeta.Metadata, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	return &pagemeta.Metadata{}, nil
+}
+
+func (f *countingFetcher) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+func TestCreateShortURLsItemOptions(t *testing.T) {
+	fetcher := &countingFetcher{}
+	s := newTestService(WithCampaigns(memory.NewCampaignRepo()), WithMetadataFetcher(fetcher))
+	name := "spring"
+	campaign, err := s.CreateCampaign(as("alice"), CampaignInput{Name: &name})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := s.CreateShortURLs(as("alice"), []BatchItem{
+		{URL: "https://example.com/a", CreateOptions: CreateOptions{Tags: []string{"Promo"}, Campaigns: []string{campaign.ID}}},
+		{URL: "https://example.com/b", CreateOptions: CreateOptions{Forwarding: &models.Forwarding{Params: map[string]string{"utm_source": "batch"}}}},
+		{URL: "https://example.com/c", CreateOptions: CreateOptions{Tags: []string{"bad tag!"}}},
+		{URL: "https://example.com/d", CreateOptions: CreateOptions{Campaigns: []string{"missing"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := results[0].Link
+	if fmt.Sprint(a.Tags) != "[promo]" || fmt.Sprint(a.Campaigns) != "["+campaign.ID+"]" {
+		t.Errorf("item 0 = %+v", a)
+	}
+	b := results[1].Link
+	if b.Forwarding == nil || b.Forwarding.Params["utm_source"] != "batch" {
+		t.Errorf("item 1 forwarding = %+v", b.Forwarding)
+	}
+	// ошибка настроек одного элемента не мешает остальным
+	if results[2].Err == nil || results[3].Err == nil {
+		t.Errorf("invalid items = %+v, %+v", results[2], results[3])
+	}
+
+	// метаданные запрашиваются для каждой созданной ссылки, как и при одиночном создании
+	deadline := time.Now().Add(5 * time.Second)
+	for fetcher.count() < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := fetcher.count(); got != 2 {
+		t.Errorf("metadata fetched for %d links, want 2", got)
+	}
+}
+
+func TestCheckBatchSize(t *testing.T) {
+	tests := []struct {
+		n       int
+		wantErr *domainerr.Error
+	}{
+		{0, domainerr.ErrBatchEmpty},
+		{1, nil},
+		{MaxBatchSize, nil},
+		{MaxBatchSize + 1, domainerr.ErrBatchTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.n), func(t *testing.T) {
+			err := checkBatchSize(tt.n, MaxBatchSize)
+			if (tt.wantErr == nil) != (err == nil) || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+				t.Errorf("checkBatchSize(%d) = %v, want %v", tt.n, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// conflictingRepo отвечает ErrAlreadyExists на первые conflicts попыток сохранить ссылку.
+type conflictingRepo struct {
+	repositories.LinkRepository
+	mu        sync.Mutex
+	conflicts int
+	calls     []int
+}
+
+func (r *conflictingRepo) SaveMany(ctx context.Context, links []*models.Link) ([]error, error) {
+	r.mu.Lock()
+	r.calls = append(r.calls, len(links))
+	r.mu.Unlock()
+
+	errs := make([]error, len(links))
+	var free []*models.Link
+	for i, link := range links {
+		r.mu.Lock()
+		conflict := r.conflicts > 0
+		if conflict {
+			r.conflicts--
+		}
+		r.mu.Unlock()
+		if conflict {
+			errs[i] = repositories.ErrAlreadyExists
+		} else {
+			free = append(free, link)
+		}
+	}
+	saved, err := r.LinkRepository.SaveMany(ctx, free)
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, 0; i < len(errs); i++ {
+		if errs[i] == nil {
+			errs[i] = saved[j]
+			j++
+		}
+	}
+	return errs, nil
+}
+
+func TestCreateBatchRetriesTakenCodes(t *testing.T) {
+	tests := []struct {
+		name      string
+		conflicts int
+		wantCalls []int
+		wantErr   *domainerr.Error
+	}{
+		{name: "no conflicts", wantCalls: []int{3}},
+		// повторно уходят только ссылки с занятыми кодами
+		{name: "two conflicts", conflicts: 2, wantCalls: []int{3, 2}},
+		{name: "codes exhausted", conflicts: 1000, wantCalls: []int{3, 3, 3, 3, 3, 3}, wantErr: domainerr.ErrCodeExhausted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &conflictingRepo{LinkRepository: memory.NewLinkRepo(), conflicts: tt.conflicts}
+			s := NewService(repo, memory.NewStatsRepo(), tracing.NewTracer(tracing.NeverSample(), nil))
+
+			results, err := s.CreateShortURLs(context.Background(), urlItems("https://a.example/", "https://b.example/", "https://c.example/"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(repo.calls) != fmt.Sprint(tt.wantCalls) {
+				t.Errorf("SaveMany calls = %v, want %v", repo.calls, tt.wantCalls)
+			}
+			for i, res := range results {
+				if tt.wantErr == nil && (res.Err != nil || res.Link == nil) {
+					t.Errorf("item %d = %+v", i, res)
+				}
+				if tt.wantErr != nil && !errors.Is(res.Err, tt.wantErr) {
+					t.Errorf("item %d error = %v, want %s", i, res.Err, tt.wantErr.Code)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateBatchReportsProgressPerChunk(t *testing.T) {
+	s := newTestService()
+	items := make([]BatchItem, 2*batchChunkSize+10)
+	for i := range items {
+		items[i].URL = fmt.Sprintf("https://example.com/%d", i)
+	}
+	items[0].URL = "not a url"
+
+	var progress []int
+	results := s.createBatch(context.Background(), items, func(processed int) {
+		progress = append(progress, processed)
+	})
+
+	// неверные адреса засчитываются сразу, остальные — по чанкам
+	want := fmt.Sprint([]int{batchChunkSize + 1, 2*batchChunkSize + 1, len(items)})
+	if fmt.Sprint(progress) != want {
+		t.Errorf("progress = %v, want %v", progress, want)
+	}
+	if results[0].Err == nil || results[len(items)-1].Link == nil {
+		t.Errorf("results = %+v ... %+v", results[0], results[len(items)-1])
+	}
+}
+
+func TestStartBatch(t *testing.T) {
+	s := newTestService()
+	done := make(chan *BatchJob, 1)
+	job, err := s.StartBatch(as("alice"), urlItems("https://example.com/", "bad"), func(job *BatchJob) {
+		done <- job
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != JobPending || job.Total != 2 || job.OwnerID != "alice" {
+		t.Errorf("job = %+v", job)
+	}
+
+	select {
+	case finished := <-done:
+		if finished.Status != JobDone || finished.Processed != 2 || len(finished.Results) != 2 {
+			t.Errorf("finished = %+v", finished)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("batch did not finish")
+	}
+
+	got, err := s.BatchJob(as("alice"), job.ID)
+	if err != nil || got.Status != JobDone || got.Results[0].Err != nil || got.Results[1].Err == nil {
+		t.Errorf("BatchJob = %+v, %v", got, err)
+	}
+	for _, caller := range []string{"bob", ""} {
+		if _, err := s.BatchJob(as(caller), job.ID); !errors.Is(err, domainerr.ErrJobNotFound) {
+			t.Errorf("job visible to %q: %v", caller, err)
+		}
+	}
+	if _, err := s.StartBatch(as("alice"), nil, nil); !errors.Is(err, domainerr.ErrBatchEmpty) {
+		t.Errorf("empty batch: %v", err)
+	}
+}
@@ -0,0 +1,306 @@
+package shortener
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"shorted/internal/auth"
+	"shorted/internal/domain/domainerr"
+	"shorted/internal/domain/models"
+	"shorted/internal/domain/repositories"
+	"shorted/pkg/tracing"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxCampaignName        = 100
+	maxCampaignDescription = 1000
+	maxLinkCampaigns       = 20
+	maxLinkTags            = 20
+	// MaxTagEditLinks — сколько ссылок можно поменять одним запросом EditTags.
+	MaxTagEditLinks = 1000
+)
+
+var tagPattern = regexp.MustCompile(`^[\p{L}\p{N}][\p{L}\p{N}_.:/-]{0,63}$`)
+
+// WithCampaigns включает кампании; без него ссылки нельзя добавить в кампанию.
+func WithCampaigns(repo repositories.CampaignRepository) Option {
+	return func(s *Service) {
+		s.campaigns = repo
+	}
+}
+
+// CampaignInput — поля кампании при создании и изменении; nil в UpdateCampaign
+// оставляет поле как есть.
+type CampaignInput struct {
+	Name        *string
+	Description *string
+}
+
+func (s *Service) CreateCampaign(ctx context.Context, in CampaignInput) (_ *models.Campaign, err error) {
+	ctx, span := s.tracer.Start(ctx, "shortener.CreateCampaign", tracing.SpanKindInternal)
+	defer func() { endSpan(span, err) }()
+
+	if s.campaigns == nil {
+		return nil, domainerr.ErrCampaignNotFound
+	}
+	if in.Name == nil {
+		return nil, domainerr.Validation(domainerr.FieldError{Field: "name", Code: "required", Message: "name is required"})
+	}
+	campaign := &models.Campaign{CreatedAt: time.Now().Unix()}
+	if err := applyCampaign(campaign, in); err != nil {
+		return nil, err
+	}
+	if owner, ok := auth.PrincipalFrom(ctx); ok {
+		campaign.OwnerID = owner.ID
+	}
+	if campaign.ID, err = newCampaignID(); err != nil {
+		return nil, err
+	}
+	if err := s.campaigns.Save(ctx, campaign); err != nil {
+		return nil, err
+	}
+	span.SetAttribute("campaign.id", campaign.ID)
+	return campaign, nil
+}
+
+func (s *Service) GetCampaign(ctx context.Context, id string) (_ *models.Campaign, err error) {
+	ctx, span := s.tracer.Start(ctx, "shortener.GetCampaign", tracing.SpanKindInternal)
+	span.SetAttribute("campaign.id", id)
+	defer func() { endSpan(span, err) }()
+
+	return s.ownedCampaign(ctx, id)
+}
+
+// ListCampaigns отдаёт кампании владельца запроса, для анонимного запроса —
+// кампании без владельца.
+func (s *Service) ListCampaigns(ctx context.Context) (_ []*models.Campaign, err error) {
+	ctx, span := s.tracer.Start(ctx, "shortener.ListCampaigns", tracing.SpanKindInternal)
+	defer func() { endSpan(span, err) }()
+
+	if s.campaigns == nil {
+		return nil, nil
+	}
+	owner, _ := auth.PrincipalFrom(ctx)
+	return s.campaigns.List(ctx, owner.ID)
+}
+
+func (s *Service) UpdateCampaign(ctx context.Context, id string, in CampaignInput) (_ *models.Campaign, err error) {
+	ctx, span := s.tracer.Start(ctx, "shortener.UpdateCampaign", tracing.SpanKindInternal)
+	span.SetAttribute("campaign.id", id)
+	defer func() { endSpan(span, err) }()
+
+	campaign, err := s.ownedCampaign(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyCampaign(campaign, in); err != nil {
+		return nil, err
+	}
+	campaign.UpdatedAt = time.Now().Unix()
+	if err := s.campaigns.Update(ctx, campaign); err != nil {
+		return nil, campaignNotFound(err)
+	}
+	return campaign, nil
+}
+
+// DeleteCampaign удаляет кампанию; сами ссылки остаются.
+func (s *Service) DeleteCampaign(ctx context.Context, id string) (err error) {
+	ctx, span := s.tracer.Start(ctx, "shortener.DeleteCampaign", tracing.SpanKindInternal)
+	span.SetAttribute("campaign.id", id)
+	defer func() { endSpan(span, err) }()
+
+	if _, err := s.ownedCampaign(ctx, id); err != nil {
+		return err
+	}
+	// сначала ссылки: если удаление кампании не пройдёт, повтор доведёт его до конца
+	if err := s.repo.RemoveCampaign(ctx, id, time.Now().Unix()); err != nil {
+		return err
+	}
+	return campaignNotFound(s.campaigns.Delete(ctx, id))
+}
+
+// CampaignStats складывает статистику всех ссылок кампании. Конверсии считаются
+// по всем переходам ссылок, без срезов по вариантам.
+func (s *Service) CampaignStats(ctx context.Context, id string) (_ *models.CampaignStats, err error) {
+	ctx, span := s.tracer.Start(ctx, "shortener.CampaignStats", tracing.SpanKindInternal)
+	span.SetAttribute("campaign.id", id)
+	defer func() { endSpan(span, err) }()
+
+	if _, err := s.ownedCampaign(ctx, id); err != nil {
+		return nil, err
+	}
+	links, err := s.repo.List(ctx, repositories.ListOptions{Campaign: id})
+	if err != nil {
+		return nil, err
+	}
+	codes := make([]string, 0, len(links))
+	for _, link := range links {
+		codes = append(codes, link.ShortCode)
+	}
+
+	result := &models.CampaignStats{CampaignID: id, Links: len(links)}
+	if len(codes) == 0 {
+		return result, nil
+	}
+	stats, err := s.StatsByCodes(ctx, codes)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, st := range stats {
+		result.Clicks += st.Clicks
+		result.LastClickAt = max(result.LastClickAt, st.LastClickAt)
+	}
+	if s.conversions == nil {
+		return result, nil
+	}
+
+	// переходы с идентификатором клика считаются по всем ссылкам кампании,
+	// а не только по тем, где уже были конверсии этого события
+	counts, err := s.conversions.Counts(ctx, codes)
+	if err != nil {
+		return nil, err
+	}
+	var clicks int64
+	events := make(map[string]*models.ConversionStats)
+	for _, c := range counts {
+		if c.Event == "" {
+			clicks += c.Count
+			continue
+		}
+		ev, ok := events[c.Event]
+		if !ok {
+			ev = &models.ConversionStats{Event: c.Event}
+			events[c.Event] = ev
+		}
+		ev.Conversions += c.Count
+		ev.Value += c.Value
+	}
+	for _, ev := range events {
+		ev.Clicks = clicks
+		if ev.Clicks > 0 {
+			ev.Rate = float64(ev.Conversions) / float64(ev.Clicks)
+		}
+		result.Conversions = append(result.Conversions, *ev)
+	}
+	slices.SortFunc(result.Conversions, func(a, b models.ConversionStats) int {
+		return strings.Compare(a.Event, b.Event)
+	})
+	return result, nil
+}
+
+// SetLinkCampaigns заменяет кампании ссылки; кампании должны принадлежать
+// владельцу ссылки.
+func (s *Service) SetLinkCampaigns(ctx context.Context, shortCode string, ids []string) (_ *models.Link, err error) {
+	ctx, span := s.tracer.Start(ctx, "shortener.SetLinkCampaigns", tracing.SpanKindInternal)
+	span.SetAttribute("link.short_code", shortCode)
+	defer func() { endSpan(span, err) }()
+
+	link, err := s.ownedLink(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	if link.Campaigns, err = s.linkCampaigns(ctx, link.OwnerID, ids); err != nil {
+		return nil, err
+	}
+	link.UpdatedAt = time.Now().Unix()
+	if err := s.repo.Update(ctx, link); err != nil {
+		return nil, notFound(err)
+	}
+	return link, nil
+}
+
+// linkCampaigns проверяет, что кампании существуют и принадлежат owner, и
+// убирает повторы.
+func (s *Service) linkCampaigns(ctx context.Context, owner string, ids []string) ([]string, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	if len(ids) > maxLinkCampaigns {
+		return nil, domainerr.Validation(domainerr.FieldError{
+			Field: "campaigns", Code: "too_many_campaigns", Message: fmt.Sprintf("a link can be in at most %d campaigns", maxLinkCampaigns),
+		})
+	}
+	ids = slices.Compact(slices.Sorted(slices.Values(ids)))
+
+	var found []*models.Campaign
+	if s.campaigns != nil {
+		var err error
+		if found, err = s.campaigns.FindByIDs(ctx, ids); err != nil {
+			return nil, err
+		}
+	}
+	owned := make(map[string]bool, len(found))
+	for _, c := range found {
+		owned[c.ID] = c.OwnerID == owner
+	}
+	for _, id := range ids {
+		if !owned[id] {
+			return nil, domainerr.Validation(domainerr.FieldError{
+				Field: "campaigns", Code: "unknown_campaign", Message: "campaign " + id + " does not exist",
+			})
+		}
+	}
+	return ids, nil
+}
+
+func (s *Service) ownedCampaign(ctx context.Context, id string) (*models.Campaign, error) {
+	if s.campaigns == nil {
+		return nil, domainerr.ErrCampaignNotFound
+	}
+	campaign, err := s.campaigns.FindByID(ctx, id)
+	if err != nil {
+		return nil, campaignNotFound(err)
+	}
+	if campaign.OwnerID != "" {
+		if p, ok := auth.PrincipalFrom(ctx); !ok || p.ID != campaign.OwnerID {
+			return nil, domainerr.ErrForbidden.WithMessage("you do not have access to this campaign")
+		}
+	}
+	return campaign, nil
+}
+
+func applyCampaign(campaign *models.Campaign, in CampaignInput) error {
+	if in.Name != nil {
+		name := strings.TrimSpace(*in.Name)
+		if name == "" || utf8.RuneCountInString(name) > maxCampaignName {
+			return domainerr.Validation(domainerr.FieldError{
+				Field: "name", Code: "invalid_name", Message: fmt.Sprintf("name must be 1 to %d characters", maxCampaignName),
+			})
+		}
+		campaign.Name = name
+	}
+	if in.Description != nil {
+		if utf8.RuneCountInString(*in.Description) > maxCampaignDescription {
+			return domainerr.Validation(domainerr.FieldError{
+				Field: "description", Code: "too_long", Message: fmt.Sprintf("description must be at most %d characters", maxCampaignDescription),
+			})
+		}
+		campaign.Description = *in.Description
+	}
+	return nil
+}
+
+// newCampaignID начинается с времени, поэтому кампании в списке идут в порядке
+// создания.
+func newCampaignID() (string, error) {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%012x%s", time.Now().UnixMilli(), hex.EncodeToString(suffix)), nil
+}
+
+func campaignNotFound(err error) error {
+	if errors.Is(err, repositories.ErrCampaignNotFound) {
+		return domainerr.ErrCampaignNotFound.WithCause(err)
+	}
+	return err
+}
@@ -0,0 +1,227 @@
+package shortener
+
+import (
+	"context"
+	"errors"
+	"shorted/internal/domain/domainerr"
+	"shorted/internal/domain/models"
+	"shorted/internal/domain/repositories"
+	"shorted/internal/repository/memory"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+func mustCampaign(t *testing.T, s *Service, owner, name string) *models.Campaign {
+	t.Helper()
+	campaign, err := s.CreateCampaign(as(owner), CampaignInput{Name: &name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return campaign
+}
+
+func TestCampaignInput(t *testing.T) {
+	tests := []struct {
+		name      string
+		in        CampaignInput
+		wantName  string
+		wantField string
+		wantCode  string
+	}{
+		{name: "trimmed name", in: CampaignInput{Name: ptr("  Spring sale ")}, wantName: "Spring sale"},
+		{name: "missing name", in: CampaignInput{Description: ptr("x")}, wantField: "name", wantCode: "required"},
+		{name: "blank name", in: CampaignInput{Name: ptr("   ")}, wantField: "name", wantCode: "invalid_name"},
+		{name: "long name", in: CampaignInput{Name: ptr(strings.Repeat("я", maxCampaignName+1))}, wantField: "name", wantCode: "invalid_name"},
+		{name: "longest name", in: CampaignInput{Name: ptr(strings.Repeat("я", maxCampaignName))}, wantName: strings.Repeat("я", maxCampaignName)},
+		{
+			name: "long description", in: CampaignInput{Name: ptr("a"), Description: ptr(strings.Repeat("x", maxCampaignDescription+1))},
+			wantField: "description", wantCode: "too_long",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(WithCampaigns(memory.NewCampaignRepo()))
+			campaign, err := s.CreateCampaign(as("alice"), tt.in)
+			if tt.wantCode != "" {
+				if !errors.Is(err, domainerr.ErrValidation) {
+					t.Fatalf("error = %v, want a validation error", err)
+				}
+				f := domainerr.From(err).Fields[0]
+				if f.Field != tt.wantField || f.Code != tt.wantCode {
+					t.Errorf("field error = %s %s, want %s %s", f.Field, f.Code, tt.wantField, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if campaign.Name != tt.wantName || campaign.OwnerID != "alice" || campaign.ID == "" {
+				t.Errorf("campaign = %+v", campaign)
+			}
+		})
+	}
+}
+
+func TestCampaignsDisabled(t *testing.T) {
+	s := newTestService()
+	if _, err := s.CreateCampaign(as("alice"), CampaignInput{Name: ptr("a")}); !errors.Is(err, domainerr.ErrCampaignNotFound) {
+		t.Errorf("CreateCampaign: error = %v", err)
+	}
+	if _, _, err := s.CreateShortURL(as("alice"), "https://example.com/", CreateOptions{Campaigns: []string{"x"}}); fieldCode(err) != "unknown_campaign" {
+		t.Errorf("CreateShortURL: error = %v, want unknown_campaign", err)
+	}
+}
+
+func TestOwnedCampaign(t *testing.T) {
+	tests := []struct {
+		name    string
+		owner   string
+		caller  string
+		wantErr *domainerr.Error
+	}{
+		{name: "owner", owner: "alice", caller: "alice"},
+		{name: "other owner", owner: "alice", caller: "bob", wantErr: domainerr.ErrForbidden},
+		{name: "anonymous caller", owner: "alice", wantErr: domainerr.ErrForbidden},
+		// кампании без владельца общие, как и список ListCampaigns для анонимных запросов
+		{name: "ownerless campaign", caller: "bob"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(WithCampaigns(memory.NewCampaignRepo()))
+			campaign := mustCampaign(t, s, tt.owner, "spring")
+
+			_, err := s.UpdateCampaign(as(tt.caller), campaign.ID, CampaignInput{Description: ptr("new")})
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("update: error = %v, want %s", err, tt.wantErr.Code)
+			}
+			if err := s.DeleteCampaign(as(tt.caller), campaign.ID); !errors.Is(err, tt.wantErr) {
+				t.Errorf("delete: error = %v, want %s", err, tt.wantErr.Code)
+			}
+			if _, err := s.GetCampaign(as(tt.owner), campaign.ID); err != nil {
+				t.Errorf("campaign is gone after a rejected delete: %v", err)
+			}
+		})
+	}
+}
+
+func TestLinkCampaigns(t *testing.T) {
+	s := newTestService(WithCampaigns(memory.NewCampaignRepo()))
+	a := mustCampaign(t, s, "alice", "a")
+	b := mustCampaign(t, s, "alice", "b")
+	other := mustCampaign(t, s, "bob", "other")
+
+	tooMany := make([]string, maxLinkCampaigns+1)
+	for i := range tooMany {
+		tooMany[i] = a.ID
+	}
+
+	tests := []struct {
+		name     string
+		ids      []string
+		want     []string
+		wantCode string
+	}{
+		{name: "none"},
+		{name: "sorted without repeats", ids: []string{b.ID, a.ID, b.ID}, want: slices.Sorted(slices.Values([]string{a.ID, b.ID}))},
+		{name: "unknown", ids: []string{a.ID, "nope"}, wantCode: "unknown_campaign"},
+		{name: "someone else's", ids: []string{other.ID}, wantCode: "unknown_campaign"},
+		{name: "too many", ids: tooMany, wantCode: "too_many_campaigns"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.linkCampaigns(context.Background(), "alice", tt.ids)
+			if tt.wantCode != "" {
+				if fieldCode(err) != tt.wantCode {
+					t.Fatalf("error = %v, want %s", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("linkCampaigns() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeleteCampaignKeepsLinks(t *testing.T) {
+	s := newTestService(WithCampaigns(memory.NewCampaignRepo()))
+	spring := mustCampaign(t, s, "alice", "spring")
+	autumn := mustCampaign(t, s, "alice", "autumn")
+	both := mustCreate(t, s, "alice", "https://example.com/both", CreateOptions{Campaigns: []string{spring.ID, autumn.ID}})
+	only := mustCreate(t, s, "alice", "https://example.com/only", CreateOptions{Campaigns: []string{spring.ID}})
+
+	if err := s.DeleteCampaign(as("alice"), spring.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetCampaign(as("alice"), spring.ID); !errors.Is(err, domainerr.ErrCampaignNotFound) {
+		t.Errorf("deleted campaign: error = %v", err)
+	}
+
+	tests := []struct {
+		code string
+		want string
+	}{
+		{code: both.ShortCode, want: autumn.ID},
+		{code: only.ShortCode, want: ""},
+	}
+	for _, tt := range tests {
+		link := mustGet(t, s, tt.code)
+		if strings.Join(link.Campaigns, ",") != tt.want {
+			t.Errorf("%s campaigns = %v, want %q", tt.code, link.Campaigns, tt.want)
+		}
+	}
+
+	links, err := s.repo.List(context.Background(), repositories.ListOptions{Campaign: spring.ID})
+	if err != nil || len(links) != 0 {
+		t.Errorf("links in the deleted campaign = %v, %v", links, err)
+	}
+}
+
+func TestCampaignStats(t *testing.T) {
+	s := newTestService(WithCampaigns(memory.NewCampaignRepo()), WithConversions(memory.NewConversionRepo()))
+	spring := mustCampaign(t, s, "alice", "spring")
+	empty := mustCampaign(t, s, "alice", "empty")
+	fwd := &models.Forwarding{ClickIDParam: "cid"}
+	a := mustCreate(t, s, "alice", "https://example.com/a", CreateOptions{Campaigns: []string{spring.ID}, Forwarding: fwd})
+	b := mustCreate(t, s, "alice", "https://example.com/b", CreateOptions{Campaigns: []string{spring.ID}, Forwarding: fwd})
+	mustCreate(t, s, "alice", "https://example.com/c", CreateOptions{})
+
+	clickID := clickThrough(t, s, a.ShortCode, models.Click{})
+	clickThrough(t, s, a.ShortCode, models.Click{})
+	clickThrough(t, s, b.ShortCode, models.Click{})
+	if _, _, err := s.RecordConversion(as("alice"), ConversionInput{ClickID: clickID, Value: 5}); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := s.CampaignStats(as("alice"), spring.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Links != 2 || stats.Clicks != 3 {
+		t.Errorf("stats = %+v, want 2 links and 3 clicks", stats)
+	}
+	want := models.ConversionStats{Event: defaultConversionEvent, Clicks: 3, Conversions: 1, Value: 5, Rate: 1.0 / 3}
+	if len(stats.Conversions) != 1 || stats.Conversions[0] != want {
+		t.Errorf("conversions = %+v, want %+v", stats.Conversions, want)
+	}
+
+	stats, err = s.CampaignStats(as("alice"), empty.ID)
+	if err != nil || stats.Links != 0 || stats.Clicks != 0 || stats.Conversions != nil {
+		t.Errorf("empty campaign stats = %+v, %v", stats, err)
+	}
+}
@@ -0,0 +1,125 @@
+package shortener
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"shorted/internal/domain/repositories"
+	"shorted/pkg/urlpolicy"
+	"strings"
+)
+
+const defaultMaxHops = 5
+
+// Expander раскрывает ссылку стороннего сокращателя на один шаг. ok=false — адрес
+// не относится к сокращателю.
+type Expander interface {
+	Expand(ctx context.Context, rawURL string) (next string, ok bool, err error)
+}
+
+type ChainPolicy struct {
+	// Domains — домены, на которых сервис отдаёт короткие ссылки.
+	Domains []string
+	// Expander, если задан, раскрывает ссылки сторонних сокращателей.
+	Expander Expander
+	MaxHops  int
+	// Reject запрещает сокращать короткие ссылки вместо того, чтобы разворачивать
+	// цепочку до конечного адреса.
+	Reject bool
+}
+
+type chainPolicy struct {
+	domains  map[string]bool
+	expander Expander
+	maxHops  int
+	reject   bool
+}
+
+// WithChainPolicy задаёт, как поступать с адресами, которые сами являются
+// короткими ссылками — нашими или сторонних сокращателей.
+func WithChainPolicy(p ChainPolicy) Option {
+	return func(s *Service) {
+		domains := make(map[string]bool, len(p.Domains))
+		for _, d := range p.Domains {
+			if d = strings.ToLower(strings.TrimSpace(d)); d != "" {
+				domains[d] = true
+			}
+		}
+		if p.MaxHops <= 0 {
+			p.MaxHops = defaultMaxHops
+		}
+		s.chains = chainPolicy{domains: domains, expander: p.Expander, maxHops: p.MaxHops, reject: p.Reject}
+	}
+}
+
+// destination проверяет адрес и разворачивает цепочку коротких ссылок до конечного
+// адреса. self — код ссылки, которую меняют: указывать на саму себя ей нельзя.
+func (s *Service) destination(ctx context.Context, raw, self string) (string, error) {
+	dest, err := s.normalizeURL(ctx, raw)
+	if err != nil {
+		return "", err
+	}
+
+	seen := map[string]bool{dest: true}
+	for hop := 0; ; hop++ {
+		// каждый шаг цепочки проверяется: сокращатель может прятать заблокированный адрес
+		if s.verdict(dest) == urlpolicy.Block {
+			return "", invalidURL("url_blocked", "url is blocked by policy")
+		}
+
+		next, ok, err := s.nextHop(ctx, dest, self)
+		if err != nil || !ok {
+			return dest, err
+		}
+		if s.chains.reject {
+			return "", invalidURL("shortened_url", "url must not point to another short link")
+		}
+		if hop >= s.chains.maxHops {
+			return "", invalidURL("too_many_redirects", "url redirects through too many short links")
+		}
+
+		if dest, err = s.normalizeURL(ctx, next); err != nil {
+			return "", err
+		}
+		if seen[dest] {
+			return "", invalidURL("redirect_loop", "url leads to a redirect loop")
+		}
+		seen[dest] = true
+	}
+}
+
+// nextHop возвращает адрес, на который ведёт короткая ссылка; ok=false — dest
+// уже конечный адрес.
+func (s *Service) nextHop(ctx context.Context, dest, self string) (string, bool, error) {
+	u, err := url.Parse(dest)
+	if err != nil {
+		return "", false, err
+	}
+
+	if s.chains.domains[u.Hostname()] {
+		code := strings.TrimPrefix(u.Path, "/")
+		if code == "" || strings.Contains(code, "/") {
+			return "", false, invalidURL("self_reference", "url must not point to this service")
+		}
+		if code == self {
+			return "", false, invalidURL("redirect_loop", "link must not point to itself")
+		}
+		link, err := s.repo.FindByCode(ctx, code)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return "", false, invalidURL("self_reference", "url points to a short link that does not exist")
+		}
+		if err != nil {
+			return "", false, err
+		}
+		return link.OriginalURL, true, nil
+	}
+
+	if s.chains.expander == nil {
+		return "", false, nil
+	}
+	next, ok, err := s.chains.expander.Expand(ctx, dest)
+	if err != nil {
+		return "", false, invalidURL("unresolvable_redirect", "short link in url could not be expanded")
+	}
+	return next, ok, nil
+}
@@ -0,0 +1,137 @@
+package shortener
+
+import (
+	"context"
+	"errors"
+	"shorted/internal/domain/domainerr"
+	"shorted/pkg/urlpolicy"
+	"strings"
+	"testing"
+)
+
+// mapExpander раскрывает адреса по таблице; адреса вне таблицы — не сокращатель.
+type mapExpander map[string]string
+
+func (m mapExpander) Expand(_ context.Context, rawURL string) (string, bool, error) {
+	next, ok := m[rawURL]
+	if next == "error" {
+		return "", true, errors.New("connection refused")
+	}
+	return next, ok, nil
+}
+
+// fieldCode возвращает код первой ошибки поля из ошибки валидации.
+func fieldCode(err error) string {
+	var derr *domainerr.Error
+	if !errors.As(err, &derr) || len(derr.Fields) == 0 {
+		return ""
+	}
+	return derr.Fields[0].Code
+}
+
+func TestCreateShortURLChains(t *testing.T) {
+	expander := mapExpander{
+		"https://bit.ly/a":     "https://example.com/final",
+		"https://bit.ly/b":     "https://bit.ly/a",
+		"https://bit.ly/loop":  "https://bit.ly/loop2",
+		"https://bit.ly/loop2": "https://bit.ly/loop",
+		"https://bit.ly/deep":  "https://bit.ly/b",
+		"https://bit.ly/down":  "error",
+		"https://bit.ly/bad":   "https://evil.example/",
+	}
+
+	tests := []struct {
+		name     string
+		url      string
+		reject   bool
+		maxHops  int
+		want     string
+		wantCode string
+	}{
+		{name: "plain url", url: "https://example.com/", want: "https://example.com/"},
+		{name: "own short link", url: "https://sho.rt/{target}", want: "https://example.com/target"},
+		{name: "own short link with www", url: "https://www.sho.rt/{target}", want: "https://example.com/target"},
+		{name: "third-party shortener", url: "https://bit.ly/a", want: "https://example.com/final"},
+		{name: "chain of shorteners", url: "https://bit.ly/b", want: "https://example.com/final"},
+		{name: "our link to a shortener", url: "https://sho.rt/{viabitly}", want: "https://example.com/final"},
+		{name: "loop", url: "https://bit.ly/loop", wantCode: "redirect_loop"},
+		{name: "too many hops", url: "https://bit.ly/deep", maxHops: 2, wantCode: "too_many_redirects"},
+		{name: "hop limit is inclusive", url: "https://bit.ly/deep", maxHops: 3, want: "https://example.com/final"},
+		{name: "shortener is down", url: "https://bit.ly/down", wantCode: "unresolvable_redirect"},
+		{name: "blocked final destination", url: "https://bit.ly/bad", wantCode: "url_blocked"},
+		{name: "unknown own code", url: "https://sho.rt/missing", wantCode: "self_reference"},
+		{name: "own root page", url: "https://sho.rt/", wantCode: "self_reference"},
+		{name: "own api path", url: "https://sho.rt/api/shorten", wantCode: "self_reference"},
+		{name: "reject own short link", url: "https://sho.rt/{target}", reject: true, wantCode: "shortened_url"},
+		{name: "reject third-party short link", url: "https://bit.ly/a", reject: true, wantCode: "shortened_url"},
+		{name: "reject keeps plain urls", url: "https://example.com/", reject: true, want: "https://example.com/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(
+				WithPolicy(mustPolicy(t, "evil.example")),
+				WithChainPolicy(ChainPolicy{
+					Domains:  []string{"sho.rt", "www.sho.rt"},
+					Expander: expander,
+					MaxHops:  tt.maxHops,
+				}),
+			)
+			target := mustCreate(t, s, "", "https://example.com/target", CreateOptions{})
+			viaBitly := mustCreate(t, s, "", "https://bit.ly/a", CreateOptions{})
+			s.chains.reject = tt.reject
+
+			raw := strings.NewReplacer("{target}", target.ShortCode, "{viabitly}", viaBitly.ShortCode).Replace(tt.url)
+			link, _, err := s.CreateShortURL(as("alice"), raw, CreateOptions{ForceNew: true})
+			if tt.wantCode != "" {
+				if !errors.Is(err, domainerr.ErrValidation) || fieldCode(err) != tt.wantCode {
+					t.Fatalf("error = %v, want %s", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if link.OriginalURL != tt.want {
+				t.Errorf("OriginalURL = %q, want %q", link.OriginalURL, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateLinkCannotPointToItself(t *testing.T) {
+	s := newTestService(WithChainPolicy(ChainPolicy{Domains: []string{"sho.rt"}}))
+	first := mustCreate(t, s, "alice", "https://example.com/", CreateOptions{})
+	// вторая ссылка разворачивается при создании, поэтому цикла через неё не будет
+	second := mustCreate(t, s, "alice", "https://sho.rt/"+first.ShortCode, CreateOptions{})
+	if second.OriginalURL != "https://example.com/" {
+		t.Fatalf("second.OriginalURL = %q", second.OriginalURL)
+	}
+
+	_, err := s.UpdateLink(as("alice"), first.ShortCode, "https://sho.rt/"+first.ShortCode)
+	if fieldCode(err) != "redirect_loop" {
+		t.Errorf("self reference: error = %v, want redirect_loop", err)
+	}
+
+	link, err := s.UpdateLink(as("alice"), first.ShortCode, "https://sho.rt/"+second.ShortCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link.OriginalURL != "https://example.com/" {
+		t.Errorf("OriginalURL = %q", link.OriginalURL)
+	}
+}
+
+func mustPolicy(t *testing.T, blocked ...string) *urlpolicy.Engine {
+	t.Helper()
+	engine, err := urlpolicy.New(urlpolicy.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pattern := range blocked {
+		if _, err := engine.Add(pattern, urlpolicy.Block, "test"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return engine
+}
@@ -0,0 +1,133 @@
+package shortener
+
+import (
+	"context"
+	"log"
+	"shorted/internal/domain/domainerr"
+	"shorted/internal/domain/models"
+	"shorted/pkg/tracing"
+	"sync"
+	"sync/atomic"
+)
+
+const clickBuffer = 64
+
+// clickHub рассылает клики подписчикам WatchClicks. Медленный подписчик теряет
+// события, но не тормозит редиректы.
+type clickHub struct {
+	mu   sync.RWMutex
+	subs map[chan models.Click]string
+	// dropped — события, не поместившиеся в буфер подписчика
+	dropped atomic.Int64
+}
+
+func newClickHub() *clickHub {
+	return &clickHub{subs: make(map[chan models.Click]string)}
+}
+
+func (h *clickHub) subscribe(shortCode string) chan models.Click {
+	ch := make(chan models.Click, clickBuffer)
+	h.mu.Lock()
+	h.subs[ch] = shortCode
+	h.mu.Unlock()
+	return ch
+}
+
+func (h *clickHub) unsubscribe(ch chan models.Click) {
+	h.mu.Lock()
+	delete(h.subs, ch)
+	h.mu.Unlock()
+	close(ch)
+}
+
+func (h *clickHub) publish(click models.Click) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for ch, code := range h.subs {
+		if code != click.ShortCode {
+			continue
+		}
+		select {
+		case ch <- click:
+		default:
+			h.dropped.Add(1)
+		}
+	}
+}
+
+// depth — сколько событий ждёт в буферах подписчиков.
+func (h *clickHub) depth() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	n := 0
+	for ch := range h.subs {
+		n += len(ch)
+	}
+	return n
+}
+
+func (s *Service) recordClick(ctx context.Context, click models.Click) {
+	if err := s.stats.RecordClick(ctx, &click); err != nil {
+		log.Printf("record click %s: %v trace_id=%s", click.ShortCode, err, tracing.TraceIDFromContext(ctx))
+	}
+	s.clicks.publish(click)
+}
+
+func (s *Service) Stats(ctx context.Context, shortCode string) (_ *models.LinkStats, err error) {
+	ctx, span := s.tracer.Start(ctx, "shortener.Stats", tracing.SpanKindInternal)
+	span.SetAttribute("link.short_code", shortCode)
+	defer func() { endSpan(span, err) }()
+
+	if _, err := s.findLink(ctx, shortCode); err != nil {
+		return nil, err
+	}
+	stats, err := s.stats.Stats(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.conversionStats(ctx, []*models.LinkStats{stats}); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// StatsByCodes отдаёт статистику пачкой, не проверяя существование ссылок.
+func (s *Service) StatsByCodes(ctx context.Context, shortCodes []string) (_ map[string]*models.LinkStats, err error) {
+	ctx, span := s.tracer.Start(ctx, "shortener.StatsByCodes", tracing.SpanKindInternal)
+	span.SetAttribute("link.count", len(shortCodes))
+	defer func() { endSpan(span, err) }()
+
+	stats, err := s.stats.StatsByCodes(ctx, shortCodes)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.conversionStats(ctx, stats); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]*models.LinkStats, len(stats))
+	for _, st := range stats {
+		result[st.ShortCode] = st
+	}
+	return result, nil
+}
+
+// WatchClicks возвращает поток кликов по ссылке, который закрывается вместе с
+// ctx. Смотреть клики может только тот, кто может менять ссылку.
+func (s *Service) WatchClicks(ctx context.Context, shortCode string) (<-chan models.Click, error) {
+	if shortCode == "" {
+		return nil, domainerr.Validation(domainerr.FieldError{Field: "short_code", Code: "required", Message: "short_code is required"})
+	}
+	if _, err := s.ownedLink(ctx, shortCode); err != nil {
+		return nil, err
+	}
+
+	ch := s.clicks.subscribe(shortCode)
+	go func() {
+		<-ctx.Done()
+		s.clicks.unsubscribe(ch)
+	}()
+	return ch, nil
+}
@@ -0,0 +1,197 @@
+package shortener
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"log"
+	"math"
+	"net/url"
+	"regexp"
+	"shorted/internal/domain/domainerr"
+	"shorted/internal/domain/models"
+	"shorted/internal/domain/repositories"
+	"shorted/pkg/tracing"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	defaultConversionEvent = "conversion"
+	maxConversionValue     = 1e12
+)
+
+var eventPattern = regexp.MustCompile(`^[A-Za-z0-9_.:-]{1,64}$`)
+
+// WithConversions включает выдачу идентификаторов клика ссылкам с
+// forwarding.click_id_param и приём конверсий по ним.
+func WithConversions(repo repositories.ConversionRepository) Option {
+	return func(s *Service) {
+		s.conversions = repo
+	}
+}
+
+// ConversionInput — конверсия, присланная рекламодателем.
+type ConversionInput struct {
+	ClickID string
+	// Event по умолчанию "conversion".
+	Event string
+	Value float64
+}
+
+// RecordConversion привязывает конверсию к переходу, а через него — к ссылке,
+// варианту и кампании. Повторная отправка того же события по тому же клику
+// возвращает сохранённую конверсию и created=false.
+func (s *Service) RecordConversion(ctx context.Context, in ConversionInput) (_ *models.Conversion, created bool, err error) {
+	ctx, span := s.tracer.Start(ctx, "shortener.RecordConversion", tracing.SpanKindInternal)
+	defer func() { endSpan(span, err) }()
+
+	if s.conversions == nil {
+		return nil, false, domainerr.ErrClickNotFound
+	}
+	if in.ClickID == "" {
+		return nil, false, domainerr.Validation(domainerr.FieldError{
+			Field: "click_id", Code: "required", Message: "click_id is required",
+		})
+	}
+	if in.Event == "" {
+		in.Event = defaultConversionEvent
+	}
+	if !eventPattern.MatchString(in.Event) {
+		return nil, false, domainerr.Validation(domainerr.FieldError{
+			Field: "event", Code: "invalid_event", Message: "event must be 1 to 64 letters, digits, dots, colons, dashes or underscores",
+		})
+	}
+	if math.IsNaN(in.Value) || in.Value < 0 || in.Value > maxConversionValue {
+		return nil, false, domainerr.Validation(domainerr.FieldError{
+			Field: "value", Code: "invalid_value", Message: "value must be between 0 and 1e12",
+		})
+	}
+
+	click, err := s.conversions.FindClick(ctx, in.ClickID)
+	if err != nil {
+		return nil, false, clickNotFound(err)
+	}
+	span.SetAttribute("link.short_code", click.ShortCode)
+
+	// конверсию присылает сервер рекламодателя с ключом владельца ссылки
+	if _, err := s.ownedLink(ctx, click.ShortCode); err != nil {
+		return nil, false, err
+	}
+
+	conv := &models.Conversion{
+		ClickID:   click.ID,
+		ShortCode: click.ShortCode,
+		Variant:   click.Variant,
+		Campaign:  click.Campaign,
+		Event:     in.Event,
+		Value:     in.Value,
+		At:        time.Now().Unix(),
+	}
+	created, err = s.conversions.SaveConversion(ctx, conv)
+	if err != nil {
+		return nil, false, clickNotFound(err)
+	}
+	return conv, created, nil
+}
+
+// trackClick запоминает переход с выданным идентификатором клика. Ошибка не
+// мешает переходу: конверсия по такому клику просто не найдёт его.
+func (s *Service) trackClick(ctx context.Context, visit models.Click, dest string) {
+	click := &models.TrackedClick{
+		ID:        visit.ClickID,
+		ShortCode: visit.ShortCode,
+		Variant:   visit.Variant,
+		At:        visit.At,
+	}
+	if u, err := url.Parse(dest); err == nil {
+		click.Campaign = u.Query().Get("utm_campaign")
+	}
+	if err := s.conversions.SaveClick(ctx, click); err != nil {
+		log.Printf("track click %s: %v trace_id=%s", visit.ShortCode, err, tracing.TraceIDFromContext(ctx))
+	}
+}
+
+// conversionStats дописывает в статистику ссылок конверсии по событиям: для всей
+// ссылки, для каждого варианта и для каждой кампании.
+func (s *Service) conversionStats(ctx context.Context, stats []*models.LinkStats) error {
+	if s.conversions == nil || len(stats) == 0 {
+		return nil
+	}
+	codes := make([]string, 0, len(stats))
+	for _, st := range stats {
+		codes = append(codes, st.ShortCode)
+	}
+	counts, err := s.conversions.Counts(ctx, codes)
+	if err != nil {
+		return err
+	}
+
+	type segmentKey struct{ code, segment string }
+	type eventKey struct{ code, segment, event string }
+	clicks := make(map[segmentKey]int64)
+	events := make(map[eventKey]*models.ConversionStats)
+	for _, c := range counts {
+		segments := []string{""}
+		if c.Variant != "" {
+			segments = append(segments, "variant:"+c.Variant)
+		}
+		if c.Campaign != "" {
+			segments = append(segments, "campaign:"+c.Campaign)
+		}
+		for _, segment := range segments {
+			if c.Event == "" {
+				clicks[segmentKey{c.ShortCode, segment}] += c.Count
+				continue
+			}
+			key := eventKey{c.ShortCode, segment, c.Event}
+			ev, ok := events[key]
+			if !ok {
+				ev = &models.ConversionStats{Segment: segment, Event: c.Event}
+				events[key] = ev
+			}
+			ev.Conversions += c.Count
+			ev.Value += c.Value
+		}
+	}
+
+	byCode := make(map[string]*models.LinkStats, len(stats))
+	for _, st := range stats {
+		byCode[st.ShortCode] = st
+	}
+	for key, ev := range events {
+		ev.Clicks = clicks[segmentKey{key.code, key.segment}]
+		if ev.Clicks > 0 {
+			ev.Rate = float64(ev.Conversions) / float64(ev.Clicks)
+		}
+		if st, ok := byCode[key.code]; ok {
+			st.Conversions = append(st.Conversions, *ev)
+		}
+	}
+	for _, st := range stats {
+		slices.SortFunc(st.Conversions, func(a, b models.ConversionStats) int {
+			if c := strings.Compare(a.Segment, b.Segment); c != 0 {
+				return c
+			}
+			return strings.Compare(a.Event, b.Event)
+		})
+	}
+	return nil
+}
+
+func newClickID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func clickNotFound(err error) error {
+	if errors.Is(err, repositories.ErrClickNotFound) {
+		return domainerr.ErrClickNotFound.WithCause(err)
+	}
+	return err
+}
@@ -0,0 +1,210 @@
+package shortener
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/url"
+	"shorted/internal/domain/domainerr"
+	"shorted/internal/domain/models"
+	"shorted/internal/domain/repositories"
+	"shorted/internal/repository/memory"
+	"testing"
+)
+
+// clickThrough переходит по ссылке и возвращает выданный идентификатор клика.
+func clickThrough(t *testing.T, s *Service, code string, visit models.Click) string {
+	t.Helper()
+	res, err := s.Resolve(context.Background(), code, visit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(res.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := u.Query().Get("cid")
+	if id == "" {
+		t.Fatalf("no click id in %s", res.URL)
+	}
+	return id
+}
+
+func TestRecordConversionValidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		in        ConversionInput
+		wantField string
+		wantCode  string
+	}{
+		{name: "missing click id", in: ConversionInput{}, wantField: "click_id", wantCode: "required"},
+		{name: "bad event", in: ConversionInput{ClickID: "x", Event: "sign up"}, wantField: "event", wantCode: "invalid_event"},
+		{name: "negative value", in: ConversionInput{ClickID: "x", Value: -1}, wantField: "value", wantCode: "invalid_value"},
+		{name: "NaN value", in: ConversionInput{ClickID: "x", Value: math.NaN()}, wantField: "value", wantCode: "invalid_value"},
+		{name: "huge value", in: ConversionInput{ClickID: "x", Value: 2e12}, wantField: "value", wantCode: "invalid_value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(WithConversions(memory.NewConversionRepo()))
+			_, _, err := s.RecordConversion(as("alice"), tt.in)
+			if !errors.Is(err, domainerr.ErrValidation) {
+				t.Fatalf("error = %v, want a validation error", err)
+			}
+			f := domainerr.From(err).Fields[0]
+			if f.Field != tt.wantField || f.Code != tt.wantCode {
+				t.Errorf("field error = %s %s, want %s %s", f.Field, f.Code, tt.wantField, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestRecordConversion(t *testing.T) {
+	s := newTestService(WithConversions(memory.NewConversionRepo()))
+	link := mustCreate(t, s, "alice", "https://example.com/?utm_campaign=spring", CreateOptions{
+		Forwarding: &models.Forwarding{ClickIDParam: "cid"},
+	})
+	clickID := clickThrough(t, s, link.ShortCode, models.Click{})
+
+	tests := []struct {
+		name        string
+		caller      string
+		in          ConversionInput
+		wantErr     *domainerr.Error
+		wantCreated bool
+		wantEvent   string
+	}{
+		{name: "unknown click", caller: "alice", in: ConversionInput{ClickID: "nope"}, wantErr: domainerr.ErrClickNotFound},
+		{name: "anonymous", in: ConversionInput{ClickID: clickID}, wantErr: domainerr.ErrUnauthenticated},
+		{name: "someone else's link", caller: "bob", in: ConversionInput{ClickID: clickID}, wantErr: domainerr.ErrForbidden},
+		{name: "default event", caller: "alice", in: ConversionInput{ClickID: clickID, Value: 10}, wantCreated: true, wantEvent: defaultConversionEvent},
+		// повтор того же события не создаёт вторую конверсию
+		{name: "repeated event", caller: "alice", in: ConversionInput{ClickID: clickID, Event: "conversion", Value: 99}, wantEvent: defaultConversionEvent},
+		{name: "another event", caller: "alice", in: ConversionInput{ClickID: clickID, Event: "signup"}, wantCreated: true, wantEvent: "signup"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conv, created, err := s.RecordConversion(as(tt.caller), tt.in)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %s", err, tt.wantErr.Code)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if created != tt.wantCreated {
+				t.Errorf("created = %v, want %v", created, tt.wantCreated)
+			}
+			if conv.Event != tt.wantEvent || conv.ShortCode != link.ShortCode || conv.Campaign != "spring" {
+				t.Errorf("conversion = %+v", conv)
+			}
+		})
+	}
+
+	// повтор вернул сохранённое значение, а не присланное
+	conv, _, err := s.RecordConversion(as("alice"), ConversionInput{ClickID: clickID})
+	if err != nil || conv.Value != 10 {
+		t.Errorf("repeated conversion = %+v, %v; want the stored value 10", conv, err)
+	}
+}
+
+func TestRecordConversionDisabled(t *testing.T) {
+	s := newTestService()
+	if _, _, err := s.RecordConversion(as("alice"), ConversionInput{ClickID: "x"}); !errors.Is(err, domainerr.ErrClickNotFound) {
+		t.Errorf("error = %v, want click_not_found", err)
+	}
+}
+
+func TestResolveIssuesClickIDs(t *testing.T) {
+	tests := []struct {
+		name   string
+		param  string
+		wantID bool
+	}{
+		{name: "click id param", param: "cid", wantID: true},
+		{name: "no click id param"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(WithConversions(memory.NewConversionRepo()))
+			opts := CreateOptions{}
+			if tt.param != "" {
+				opts.Forwarding = &models.Forwarding{ClickIDParam: tt.param}
+			}
+			link := mustCreate(t, s, "alice", "https://example.com/", opts)
+
+			seen := map[string]bool{}
+			for range 3 {
+				res, err := s.Resolve(context.Background(), link.ShortCode, models.Click{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				u, _ := url.Parse(res.URL)
+				id := u.Query().Get("cid")
+				if (id != "") != tt.wantID {
+					t.Fatalf("URL = %s", res.URL)
+				}
+				if id != "" && seen[id] {
+					t.Fatalf("click id %s issued twice", id)
+				}
+				seen[id] = true
+			}
+		})
+	}
+}
+
+func TestConversionStats(t *testing.T) {
+	counts := []repositories.ConversionCount{
+		{ShortCode: "a", Variant: "x", Campaign: "spring", Count: 3},
+		{ShortCode: "a", Variant: "y", Count: 1},
+		{ShortCode: "a", Variant: "x", Campaign: "spring", Event: "buy", Count: 2, Value: 30},
+		{ShortCode: "a", Variant: "y", Event: "buy", Count: 1, Value: 5},
+		{ShortCode: "b", Event: "buy", Count: 1},
+	}
+	s := newTestService(WithConversions(countsRepo(counts)))
+	a := &models.LinkStats{ShortCode: "a"}
+	b := &models.LinkStats{ShortCode: "b"}
+	if err := s.conversionStats(context.Background(), []*models.LinkStats{a, b}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []models.ConversionStats{
+		{Segment: "", Event: "buy", Clicks: 4, Conversions: 3, Value: 35, Rate: 0.75},
+		{Segment: "campaign:spring", Event: "buy", Clicks: 3, Conversions: 2, Value: 30, Rate: 2.0 / 3},
+		{Segment: "variant:x", Event: "buy", Clicks: 3, Conversions: 2, Value: 30, Rate: 2.0 / 3},
+		{Segment: "variant:y", Event: "buy", Clicks: 1, Conversions: 1, Value: 5, Rate: 1},
+	}
+	if len(a.Conversions) != len(want) {
+		t.Fatalf("conversions = %+v", a.Conversions)
+	}
+	for i := range want {
+		if a.Conversions[i] != want[i] {
+			t.Errorf("conversions[%d] = %+v, want %+v", i, a.Conversions[i], want[i])
+		}
+	}
+	// конверсии без выданных кликов не дают деления на ноль
+	if len(b.Conversions) != 1 || b.Conversions[0].Clicks != 0 || b.Conversions[0].Rate != 0 {
+		t.Errorf("b conversions = %+v", b.Conversions)
+	}
+}
+
+// countsRepo отдаёт заранее заданные счётчики конверсий.
+type countsRepo []repositories.ConversionCount
+
+func (r countsRepo) SaveClick(context.Context, *models.TrackedClick) error { return nil }
+
+func (r countsRepo) FindClick(context.Context, string) (*models.TrackedClick, error) {
+	return nil, repositories.ErrClickNotFound
+}
+
+func (r countsRepo) SaveConversion(context.Context, *models.Conversion) (bool, error) {
+	return false, repositories.ErrClickNotFound
+}
+
+func (r countsRepo) Counts(context.Context, []string) ([]repositories.ConversionCount, error) {
+	return r, nil
+}
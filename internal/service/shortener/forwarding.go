@@ -0,0 +1,200 @@
+package shortener
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"shorted/internal/domain/domainerr"
+	"shorted/internal/domain/models"
+	"shorted/pkg/tracing"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	maxForwardParams   = 30
+	maxForwardParamLen = 512
+)
+
+var placeholderPattern = regexp.MustCompile(`\{[^{}]*\}`)
+
+// Подстановки в значениях параметров.
+var placeholders = map[string]bool{"{code}": true, "{rule}": true, "{variant}": true}
+
+// SetForwarding заменяет параметры и путь, добавляемые к адресу при переходе;
+// nil или пустые настройки их удаляют.
+func (s *Service) SetForwarding(ctx context.Context, shortCode string, fwd *models.Forwarding) (_ *models.Link, err error) {
+	ctx, span := s.tracer.Start(ctx, "shortener.SetForwarding", tracing.SpanKindInternal)
+	span.SetAttribute("link.short_code", shortCode)
+	defer func() { endSpan(span, err) }()
+
+	fwd, err = s.forwarding(fwd)
+	if err != nil {
+		return nil, err
+	}
+
+	link, err := s.ownedLink(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	link.Forwarding = fwd
+	link.UpdatedAt = time.Now().Unix()
+	if err := s.repo.Update(ctx, link); err != nil {
+		return nil, notFound(err)
+	}
+	return link, nil
+}
+
+// forwarding проверяет настройки и убирает пустые.
+func (s *Service) forwarding(fwd *models.Forwarding) (*models.Forwarding, error) {
+	if fwd == nil || len(fwd.Params) == 0 && !fwd.Passthrough && !fwd.ForwardPath && fwd.ClickIDParam == "" {
+		return nil, nil
+	}
+
+	invalid := func(field, code, message string) error {
+		return domainerr.Validation(domainerr.FieldError{Field: field, Code: code, Message: message})
+	}
+	result := *fwd
+	switch result.Merge {
+	case "":
+		result.Merge = models.MergeKeep
+	case models.MergeKeep, models.MergeOverride, models.MergeAppend:
+	default:
+		return nil, invalid("forwarding.merge", "invalid_merge", "merge must be keep, override or append")
+	}
+
+	if len(fwd.Params) > maxForwardParams {
+		return nil, invalid("forwarding.params", "too_many_params", fmt.Sprintf("at most %d params are allowed", maxForwardParams))
+	}
+	result.Params = nil
+	if len(fwd.Params) > 0 {
+		result.Params = make(map[string]string, len(fwd.Params))
+	}
+	for key, value := range fwd.Params {
+		field := "forwarding.params." + key
+		key = strings.TrimSpace(key)
+		if key == "" || len(key) > maxForwardParamLen || len(value) > maxForwardParamLen {
+			return nil, invalid(field, "invalid_param", fmt.Sprintf("param names must be non-empty, names and values at most %d bytes", maxForwardParamLen))
+		}
+		for _, p := range placeholderPattern.FindAllString(value, -1) {
+			if !placeholders[p] {
+				return nil, invalid(field, "unknown_placeholder", fmt.Sprintf("unknown placeholder %s, use {code}, {rule} or {variant}", p))
+			}
+		}
+		result.Params[key] = value
+	}
+
+	if result.ClickIDParam != "" {
+		field := "forwarding.click_id_param"
+		result.ClickIDParam = strings.TrimSpace(result.ClickIDParam)
+		if s.conversions == nil {
+			return nil, invalid(field, "conversions_disabled", "conversion tracking is not enabled on this server")
+		}
+		if result.ClickIDParam == "" || len(result.ClickIDParam) > maxForwardParamLen {
+			return nil, invalid(field, "invalid_param", fmt.Sprintf("click_id_param must be non-empty and at most %d bytes", maxForwardParamLen))
+		}
+		if _, ok := result.Params[result.ClickIDParam]; ok {
+			return nil, invalid(field, "duplicate_param", "click_id_param must not repeat a key of params")
+		}
+	}
+	return &result, nil
+}
+
+// forward дописывает к адресу назначения путь и параметры по настройкам ссылки.
+// Адрес, который не удалось разобрать, возвращается без изменений.
+func forward(link *models.Link, dest string, visit *models.Click) string {
+	fwd := link.Forwarding
+	if fwd == nil {
+		return dest
+	}
+	u, err := url.Parse(dest)
+	if err != nil {
+		return dest
+	}
+
+	if fwd.ForwardPath && visit.Path != "" {
+		// Clean от корня не даёт выйти ".." за пределы пути назначения
+		extra := path.Clean("/" + visit.Path)
+		if extra != "/" {
+			u.Path = strings.TrimSuffix(u.Path, "/") + extra
+			u.RawPath = ""
+		}
+	}
+
+	query := splitQuery(u.RawQuery)
+	if len(fwd.Params) > 0 {
+		replacer := strings.NewReplacer("{code}", link.ShortCode, "{rule}", visit.Rule, "{variant}", visit.Variant)
+		keys := make([]string, 0, len(fwd.Params))
+		for key := range fwd.Params {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		added := make([]string, 0, len(keys))
+		for _, key := range keys {
+			added = append(added, url.QueryEscape(key)+"="+url.QueryEscape(replacer.Replace(fwd.Params[key])))
+		}
+		query = mergeQuery(query, added, fwd.Merge)
+	}
+	if fwd.Passthrough {
+		query = mergeQuery(query, splitQuery(visit.Query), fwd.Merge)
+	}
+	// идентификатор клика всегда наш, даже если такой параметр уже есть
+	if visit.ClickID != "" {
+		query = mergeQuery(query, []string{url.QueryEscape(fwd.ClickIDParam) + "=" + visit.ClickID}, models.MergeOverride)
+	}
+	u.RawQuery = strings.Join(query, "&")
+	return u.String()
+}
+
+// splitQuery делит строку запроса на пары, не перекодируя их: параметры адреса
+// назначения остаются в том виде, в каком их записал владелец.
+func splitQuery(raw string) []string {
+	var parts []string
+	for _, part := range strings.Split(raw, "&") {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+// mergeQuery добавляет пары added к query; порядок существующих пар сохраняется.
+func mergeQuery(query, added []string, mode models.MergeMode) []string {
+	if len(added) == 0 {
+		return query
+	}
+	existing := make(map[string]bool, len(query))
+	for _, part := range query {
+		existing[queryKey(part)] = true
+	}
+
+	switch mode {
+	case models.MergeAppend:
+		return append(query, added...)
+	case models.MergeOverride:
+		replaced := make(map[string]bool, len(added))
+		for _, part := range added {
+			replaced[queryKey(part)] = true
+		}
+		query = slices.DeleteFunc(query, func(part string) bool { return replaced[queryKey(part)] })
+		return append(query, added...)
+	default:
+		for _, part := range added {
+			if !existing[queryKey(part)] {
+				query = append(query, part)
+			}
+		}
+		return query
+	}
+}
+
+func queryKey(part string) string {
+	key, _, _ := strings.Cut(part, "=")
+	if unescaped, err := url.QueryUnescape(key); err == nil {
+		return unescaped
+	}
+	return key
+}
@@ -0,0 +1,218 @@
+package shortener
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"shorted/internal/domain/domainerr"
+	"shorted/internal/domain/models"
+	"shorted/internal/repository/memory"
+	"strings"
+	"testing"
+)
+
+func TestForward(t *testing.T) {
+	tests := []struct {
+		name  string
+		dest  string
+		fwd   *models.Forwarding
+		visit models.Click
+		want  string
+	}{
+		{name: "no forwarding", dest: "https://example.com/a?x=1", visit: models.Click{Query: "y=2"}, want: "https://example.com/a?x=1"},
+		{
+			name: "params with placeholders", dest: "https://example.com/",
+			fwd:   &models.Forwarding{Params: map[string]string{"utm_source": "short", "utm_content": "{code}-{rule}-{variant}"}},
+			visit: models.Click{Rule: "variant:b", Variant: "b"},
+			want:  "https://example.com/?utm_content=abc-variant%3Ab-b&utm_source=short",
+		},
+		{
+			name: "keep existing", dest: "https://example.com/?utm_source=mail",
+			fwd:  &models.Forwarding{Params: map[string]string{"utm_source": "short", "utm_medium": "qr"}, Merge: models.MergeKeep},
+			want: "https://example.com/?utm_source=mail&utm_medium=qr",
+		},
+		{
+			name: "override existing", dest: "https://example.com/?utm_source=mail&x=1",
+			fwd:  &models.Forwarding{Params: map[string]string{"utm_source": "short"}, Merge: models.MergeOverride},
+			want: "https://example.com/?x=1&utm_source=short",
+		},
+		{
+			name: "append", dest: "https://example.com/?tag=a",
+			fwd:  &models.Forwarding{Params: map[string]string{"tag": "b"}, Merge: models.MergeAppend},
+			want: "https://example.com/?tag=a&tag=b",
+		},
+		{
+			name: "passthrough keeps the owner's params", dest: "https://example.com/?ref=owner",
+			fwd:   &models.Forwarding{Passthrough: true},
+			visit: models.Click{Query: "ref=visitor&q=%E2%9C%93"},
+			want:  "https://example.com/?ref=owner&q=%E2%9C%93",
+		},
+		{
+			name: "passthrough override", dest: "https://example.com/?ref=owner",
+			fwd:   &models.Forwarding{Passthrough: true, Merge: models.MergeOverride},
+			visit: models.Click{Query: "ref=visitor"},
+			want:  "https://example.com/?ref=visitor",
+		},
+		{
+			name: "encoded keys are compared decoded", dest: "https://example.com/?a%20b=1",
+			fwd:   &models.Forwarding{Passthrough: true},
+			visit: models.Click{Query: "a+b=2"},
+			want:  "https://example.com/?a%20b=1",
+		},
+		{
+			name: "path", dest: "https://example.com/docs/",
+			fwd:   &models.Forwarding{ForwardPath: true},
+			visit: models.Click{Path: "guide/intro"},
+			want:  "https://example.com/docs/guide/intro",
+		},
+		{
+			name: "path cannot escape", dest: "https://example.com/docs",
+			fwd:   &models.Forwarding{ForwardPath: true},
+			visit: models.Click{Path: "../../admin"},
+			want:  "https://example.com/docs/admin",
+		},
+		{
+			name: "path and query", dest: "https://example.com/docs?lang=en",
+			fwd:   &models.Forwarding{ForwardPath: true, Passthrough: true},
+			visit: models.Click{Path: "a b", Query: "page=2"},
+			want:  "https://example.com/docs/a%20b?lang=en&page=2",
+		},
+		{
+			name: "click id always wins", dest: "https://example.com/?cid=old",
+			fwd:   &models.Forwarding{ClickIDParam: "cid", Passthrough: true},
+			visit: models.Click{ClickID: "c123", Query: "cid=forged"},
+			want:  "https://example.com/?cid=c123",
+		},
+		{
+			name: "unparsable destination", dest: "https://example.com/%zz",
+			fwd:  &models.Forwarding{Params: map[string]string{"a": "1"}},
+			want: "https://example.com/%zz",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			link := &models.Link{ShortCode: "abc", OriginalURL: tt.dest, Forwarding: tt.fwd}
+			if got := forward(link, tt.dest, &tt.visit); got != tt.want {
+				t.Errorf("forward() = %q\nwant        %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForwardingValidation(t *testing.T) {
+	tests := []struct {
+		name        string
+		fwd         *models.Forwarding
+		conversions bool
+		wantField   string
+		wantCode    string
+		// wantNil — пустые настройки удаляются
+		wantNil bool
+	}{
+		{name: "nil", wantNil: true},
+		{name: "empty", fwd: &models.Forwarding{Merge: models.MergeOverride}, wantNil: true},
+		{name: "valid", fwd: &models.Forwarding{Params: map[string]string{" utm_source ": "{code}"}, Passthrough: true}},
+		{name: "bad merge", fwd: &models.Forwarding{Passthrough: true, Merge: "replace"}, wantField: "forwarding.merge", wantCode: "invalid_merge"},
+		{name: "too many params", fwd: &models.Forwarding{Params: manyParams(maxForwardParams + 1)}, wantField: "forwarding.params", wantCode: "too_many_params"},
+		{name: "empty key", fwd: &models.Forwarding{Params: map[string]string{" ": "x"}}, wantField: "forwarding.params. ", wantCode: "invalid_param"},
+		{name: "long value", fwd: &models.Forwarding{Params: map[string]string{"a": strings.Repeat("x", maxForwardParamLen+1)}}, wantField: "forwarding.params.a", wantCode: "invalid_param"},
+		{name: "unknown placeholder", fwd: &models.Forwarding{Params: map[string]string{"a": "{owner}"}}, wantField: "forwarding.params.a", wantCode: "unknown_placeholder"},
+		{name: "click id without conversions", fwd: &models.Forwarding{ClickIDParam: "cid"}, wantField: "forwarding.click_id_param", wantCode: "conversions_disabled"},
+		{name: "click id", fwd: &models.Forwarding{ClickIDParam: " cid "}, conversions: true},
+		{name: "blank click id", fwd: &models.Forwarding{ClickIDParam: "  "}, conversions: true, wantField: "forwarding.click_id_param", wantCode: "invalid_param"},
+		{
+			name: "click id repeats a param", fwd: &models.Forwarding{ClickIDParam: "cid", Params: map[string]string{"cid": "x"}},
+			conversions: true, wantField: "forwarding.click_id_param", wantCode: "duplicate_param",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []Option
+			if tt.conversions {
+				opts = append(opts, WithConversions(memory.NewConversionRepo()))
+			}
+			s := newTestService(opts...)
+			got, err := s.forwarding(tt.fwd)
+			if tt.wantCode != "" {
+				if !errors.Is(err, domainerr.ErrValidation) {
+					t.Fatalf("error = %v, want a validation error", err)
+				}
+				f := domainerr.From(err).Fields[0]
+				if f.Field != tt.wantField || f.Code != tt.wantCode {
+					t.Errorf("field error = %q %s, want %q %s", f.Field, f.Code, tt.wantField, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (got == nil) != tt.wantNil {
+				t.Fatalf("forwarding() = %+v", got)
+			}
+			if got == nil {
+				return
+			}
+			if got.Merge != models.MergeKeep && tt.fwd.Merge == "" {
+				t.Errorf("Merge = %q, want keep by default", got.Merge)
+			}
+			for key := range got.Params {
+				if key != strings.TrimSpace(key) {
+					t.Errorf("param key %q is not trimmed", key)
+				}
+			}
+			if tt.fwd.ClickIDParam != "" && got.ClickIDParam != strings.TrimSpace(tt.fwd.ClickIDParam) {
+				t.Errorf("ClickIDParam = %q", got.ClickIDParam)
+			}
+		})
+	}
+}
+
+func TestResolveForwarding(t *testing.T) {
+	s := newTestService()
+	plain := mustCreate(t, s, "alice", "https://example.com/", CreateOptions{ForceNew: true})
+	docs := mustCreate(t, s, "alice", "https://example.com/docs", CreateOptions{Forwarding: &models.Forwarding{
+		ForwardPath: true, Passthrough: true, Params: map[string]string{"utm_source": "{code}"},
+	}})
+
+	tests := []struct {
+		name    string
+		code    string
+		visit   models.Click
+		want    string
+		wantErr error
+	}{
+		{name: "plain link ignores the query", code: plain.ShortCode, visit: models.Click{Query: "a=1"}, want: "https://example.com/"},
+		{name: "plain link has no subpaths", code: plain.ShortCode, visit: models.Click{Path: "extra"}, wantErr: domainerr.ErrLinkNotFound},
+		{
+			name: "forwarding link", code: docs.ShortCode, visit: models.Click{Path: "api", Query: "v=2"},
+			want: fmt.Sprintf("https://example.com/docs/api?utm_source=%s&v=2", docs.ShortCode),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := s.Resolve(context.Background(), tt.code, tt.visit)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.URL != tt.want {
+				t.Errorf("URL = %q, want %q", res.URL, tt.want)
+			}
+		})
+	}
+}
+
+func manyParams(n int) map[string]string {
+	params := make(map[string]string, n)
+	for i := range n {
+		params[fmt.Sprintf("p%d", i)] = "x"
+	}
+	return params
+}
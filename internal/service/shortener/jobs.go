@@ -0,0 +1,151 @@
+package shortener
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"shorted/internal/auth"
+	"shorted/internal/domain/domainerr"
+	"sync"
+	"time"
+)
+
+const (
+	// сколько пачек обрабатывается одновременно; остальные ждут в статусе pending
+	maxRunningJobs = 2
+	// сколько хранится результат завершённой задачи
+	jobRetention = 24 * time.Hour
+)
+
+type JobStatus string
+
+const (
+	JobPending JobStatus = "pending"
+	JobRunning JobStatus = "running"
+	JobDone    JobStatus = "done"
+)
+
+// BatchJob — асинхронная обработка большой пачки. Results заполнен только в статусе JobDone.
+type BatchJob struct {
+	ID         string
+	OwnerID    string
+	Status     JobStatus
+	Total      int
+	Processed  int
+	CreatedAt  int64
+	FinishedAt int64
+	Results    []BatchResult
+}
+
+// batchJobs хранит задачи в памяти процесса: после рестарта незавершённые задачи теряются.
+type batchJobs struct {
+	mu      sync.Mutex
+	jobs    map[string]*BatchJob
+	running chan struct{}
+}
+
+func newBatchJobs() *batchJobs {
+	return &batchJobs{
+		jobs:    make(map[string]*BatchJob),
+		running: make(chan struct{}, maxRunningJobs),
+	}
+}
+
+// StartBatch ставит пачку в очередь и сразу возвращает задачу. onDone вызывается
+// после обработки всех элементов.
+func (s *Service) StartBatch(ctx context.Context, items []BatchItem, onDone func(*BatchJob)) (*BatchJob, error) {
+	if err := checkBatchSize(len(items), MaxJobSize); err != nil {
+		return nil, err
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		return nil, err
+	}
+	owner, _ := auth.PrincipalFrom(ctx)
+	job := &BatchJob{
+		ID:        id,
+		OwnerID:   owner.ID,
+		Status:    JobPending,
+		Total:     len(items),
+		CreatedAt: time.Now().Unix(),
+	}
+
+	s.jobs.mu.Lock()
+	s.jobs.sweep()
+	s.jobs.jobs[id] = job
+	snapshot := *job
+	s.jobs.mu.Unlock()
+
+	// задача переживает запрос, но сохраняет владельца и трассу из его контекста
+	go s.runBatch(context.WithoutCancel(ctx), job, items, onDone)
+
+	return &snapshot, nil
+}
+
+func (s *Service) runBatch(ctx context.Context, job *BatchJob, items []BatchItem, onDone func(*BatchJob)) {
+	s.jobs.running <- struct{}{}
+	defer func() { <-s.jobs.running }()
+
+	s.jobs.update(func() { job.Status = JobRunning })
+
+	results := s.createBatch(ctx, items, func(processed int) {
+		s.jobs.update(func() { job.Processed = processed })
+	})
+
+	var snapshot BatchJob
+	s.jobs.update(func() {
+		job.Status = JobDone
+		job.Processed = job.Total
+		job.FinishedAt = time.Now().Unix()
+		job.Results = results
+		snapshot = *job
+	})
+
+	if onDone != nil {
+		onDone(&snapshot)
+	}
+}
+
+// BatchJob отдаёт состояние задачи. Чужие задачи не видны, как и несуществующие.
+func (s *Service) BatchJob(ctx context.Context, id string) (*BatchJob, error) {
+	s.jobs.mu.Lock()
+	defer s.jobs.mu.Unlock()
+
+	job, ok := s.jobs.jobs[id]
+	if !ok {
+		return nil, domainerr.ErrJobNotFound
+	}
+	if job.OwnerID != "" {
+		if p, ok := auth.PrincipalFrom(ctx); !ok || p.ID != job.OwnerID {
+			return nil, domainerr.ErrJobNotFound
+		}
+	}
+
+	snapshot := *job
+	return &snapshot, nil
+}
+
+func (j *batchJobs) update(fn func()) {
+	j.mu.Lock()
+	fn()
+	j.mu.Unlock()
+}
+
+// sweep удаляет давно завершённые задачи; вызывается под j.mu.
+func (j *batchJobs) sweep() {
+	cutoff := time.Now().Add(-jobRetention).Unix()
+	for id, job := range j.jobs {
+		if job.Status == JobDone && job.FinishedAt < cutoff {
+			delete(j.jobs, id)
+		}
+	}
+}
+
+func newJobID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
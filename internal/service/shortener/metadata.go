@@ -0,0 +1,101 @@
+package shortener
+
+import (
+	"context"
+	"errors"
+	"log"
+	"shorted/internal/domain/models"
+	"shorted/internal/domain/repositories"
+	"shorted/pkg/pagemeta"
+	"shorted/pkg/tracing"
+	"shorted/pkg/urlpolicy"
+	"strings"
+	"time"
+)
+
+const (
+	metadataQueueSize = 1024
+	metadataWorkers   = 4
+	metadataTimeout   = 15 * time.Second
+)
+
+// MetadataFetcher загружает заголовок, описание и картинки страницы назначения.
+type MetadataFetcher interface {
+	Fetch(ctx context.Context, rawURL string) (*pagemeta.Metadata, error)
+}
+
+// WithMetadataFetcher включает фоновую загрузку метаданных страниц для новых
+// ссылок и ссылок, у которых сменился адрес.
+func WithMetadataFetcher(f MetadataFetcher) Option {
+	return func(s *Service) {
+		s.fetcher = f
+	}
+}
+
+type metadataTask struct {
+	shortCode   string
+	originalURL string
+}
+
+func (s *Service) startMetadataWorkers() {
+	s.metadata = make(chan metadataTask, metadataQueueSize)
+	for range metadataWorkers {
+		go func() {
+			for task := range s.metadata {
+				s.fetchMetadata(task)
+			}
+		}()
+	}
+}
+
+// enqueueMetadata не блокирует создание ссылки: при переполненной очереди задача
+// теряется, и у ссылки просто не будет метаданных.
+func (s *Service) enqueueMetadata(link *models.Link) {
+	if s.fetcher == nil {
+		return
+	}
+	if !strings.HasPrefix(link.OriginalURL, "http://") && !strings.HasPrefix(link.OriginalURL, "https://") {
+		return
+	}
+	// подозрительные страницы не загружаем даже ради заголовка
+	if s.verdict(link.OriginalURL) != urlpolicy.Allow {
+		return
+	}
+
+	select {
+	case s.metadata <- metadataTask{shortCode: link.ShortCode, originalURL: link.OriginalURL}:
+	default:
+		s.metadataDropped.Add(1)
+		log.Printf("metadata queue is full, skipping %s", link.ShortCode)
+	}
+}
+
+func (s *Service) fetchMetadata(task metadataTask) {
+	ctx, cancel := context.WithTimeout(context.Background(), metadataTimeout)
+	defer cancel()
+
+	ctx, span := s.tracer.Start(ctx, "shortener.FetchMetadata", tracing.SpanKindInternal)
+	span.SetAttribute("link.short_code", task.shortCode)
+	defer span.End()
+
+	page, err := s.fetcher.Fetch(ctx, task.originalURL)
+	if err != nil {
+		// недоступная или не-HTML страница — обычное дело, это не ошибка сервиса
+		span.SetAttribute("metadata.error", err.Error())
+		return
+	}
+
+	err = s.repo.SetMetadata(ctx, task.shortCode, task.originalURL, &models.LinkMetadata{
+		Title:       page.Title,
+		Description: page.Description,
+		Image:       page.Image,
+		Favicon:     page.Favicon,
+		SiteName:    page.SiteName,
+		FetchedAt:   time.Now().Unix(),
+	})
+	// ErrNotFound: ссылку удалили или сменили адрес, пока шла загрузка
+	if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		span.RecordError(err)
+		log.Printf("save metadata %s: %v trace_id=%s", task.shortCode, err, tracing.TraceIDFromContext(ctx))
+	}
+}
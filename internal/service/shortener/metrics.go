@@ -0,0 +1,27 @@
+package shortener
+
+import "shorted/pkg/metrics"
+
+// WithMetrics публикует в registry длину очередей фоновой обработки и число
+// событий, которые из них пропали.
+func WithMetrics(registry *metrics.Registry) Option {
+	return func(s *Service) {
+		s.registry = registry
+	}
+}
+
+// registerMetrics вызывается из NewService, когда очередь метаданных уже создана.
+func (s *Service) registerMetrics(registry *metrics.Registry) {
+	registry.NewGaugeFunc("shortener_click_stream_queue_depth", "Click events buffered for WatchClicks subscribers.", func() float64 {
+		return float64(s.clicks.depth())
+	})
+	registry.NewCounterFunc("shortener_click_stream_dropped_total", "Click events dropped because a WatchClicks subscriber fell behind.", func() float64 {
+		return float64(s.clicks.dropped.Load())
+	})
+	registry.NewGaugeFunc("shortener_metadata_queue_depth", "Links waiting for a page metadata fetch.", func() float64 {
+		return float64(len(s.metadata))
+	})
+	registry.NewCounterFunc("shortener_metadata_dropped_total", "Metadata fetches skipped because the queue was full.", func() float64 {
+		return float64(s.metadataDropped.Load())
+	})
+}
@@ -0,0 +1,79 @@
+package shortener
+
+import (
+	"context"
+	"fmt"
+	"shorted/internal/domain/models"
+	"shorted/internal/repository/memory"
+	"shorted/pkg/metrics"
+	"shorted/pkg/pagemeta"
+	"shorted/pkg/tracing"
+	"strings"
+	"testing"
+)
+
+// blockedFetcher держит воркеров метаданных, пока не закрыт release.
+type blockedFetcher struct {
+	release chan struct{}
+}
+
+func (f blockedFetcher) Fetch(ctx context.Context, rawURL string) (*pagemeta.Metadata, error) {
+	<-f.release
+	return nil, context.Canceled
+}
+
+func scrape(registry *metrics.Registry) string {
+	var b strings.Builder
+	registry.Write(&b)
+	return b.String()
+}
+
+func TestQueueMetrics(t *testing.T) {
+	registry := metrics.NewRegistry()
+	fetcher := blockedFetcher{release: make(chan struct{})}
+	defer close(fetcher.release)
+	s := NewService(memory.NewLinkRepo(), memory.NewStatsRepo(), tracing.NewTracer(tracing.NeverSample(), nil),
+		WithMetrics(registry), WithMetadataFetcher(fetcher))
+
+	want := []string{
+		"shortener_click_stream_queue_depth 0\n",
+		"shortener_click_stream_dropped_total 0\n",
+		"shortener_metadata_queue_depth 0\n",
+		"shortener_metadata_dropped_total 0\n",
+	}
+	for _, line := range want {
+		if out := scrape(registry); !strings.Contains(out, line) {
+			t.Errorf("missing %q in\n%s", line, out)
+		}
+	}
+
+	// подписчик не читает: буфер заполняется, остальное теряется
+	s.clicks.subscribe("abc")
+	for range clickBuffer + 3 {
+		s.clicks.publish(models.Click{ShortCode: "abc"})
+	}
+
+	// воркеры заняты, очередь заполняется, остальное теряется
+	for i := range metadataQueueSize + metadataWorkers + 5 {
+		s.enqueueMetadata(&models.Link{ShortCode: fmt.Sprint(i), OriginalURL: "https://example.com/"})
+	}
+
+	out := scrape(registry)
+	want = []string{
+		fmt.Sprintf("shortener_click_stream_queue_depth %d\n", clickBuffer),
+		"shortener_click_stream_dropped_total 3\n",
+		fmt.Sprintf("shortener_metadata_queue_depth %d\n", metadataQueueSize),
+	}
+	for _, line := range want {
+		if !strings.Contains(out, line) {
+			t.Errorf("missing %q in\n%s", line, out)
+		}
+	}
+	// воркеры могли ещё не забрать первые задачи, поэтому потерь от 5 до 5+workers
+	var dropped int
+	_, line, _ := strings.Cut(out, "\nshortener_metadata_dropped_total ")
+	fmt.Sscanf(line, "%d", &dropped)
+	if dropped < 5 || dropped > 5+metadataWorkers {
+		t.Errorf("metadata dropped = %d, want 5 to %d", dropped, 5+metadataWorkers)
+	}
+}
@@ -0,0 +1,34 @@
+package shortener
+
+import (
+	"context"
+	"shorted/internal/domain/models"
+	"shorted/pkg/tracing"
+	"shorted/pkg/urlpolicy"
+)
+
+// Preview — сведения о ссылке для страницы предпросмотра.
+type Preview struct {
+	Link   *models.Link
+	Clicks int64
+	// Verdict — решение политики об адресе; Block значит, что переход отключён.
+	Verdict urlpolicy.Verdict
+}
+
+// Preview показывает, куда ведёт ссылка, не считая это переходом.
+func (s *Service) Preview(ctx context.Context, shortCode string) (_ *Preview, err error) {
+	ctx, span := s.tracer.Start(ctx, "shortener.Preview", tracing.SpanKindInternal)
+	span.SetAttribute("link.short_code", shortCode)
+	defer func() { endSpan(span, err) }()
+
+	link, err := s.findLink(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := s.stats.Stats(ctx, link.ShortCode)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Preview{Link: link, Clicks: stats.Clicks, Verdict: s.verdict(link.OriginalURL)}, nil
+}
@@ -0,0 +1,151 @@
+package shortener
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestCreateShortURLReuse(t *testing.T) {
+	type create struct {
+		owner string
+		url   string
+		opts  CreateOptions
+	}
+	tests := []struct {
+		name        string
+		reuse       bool
+		first, then create
+		wantSame    bool
+	}{
+		{
+			name:  "reuse disabled",
+			first: create{owner: "alice", url: "https://example.com/"},
+			then:  create{owner: "alice", url: "https://example.com/"},
+		},
+		{
+			name:     "same destination",
+			reuse:    true,
+			first:    create{owner: "alice", url: "https://example.com/a?x=1"},
+			then:     create{owner: "alice", url: "https://example.com/a?x=1"},
+			wantSame: true,
+		},
+		{
+			name:     "normalized destination",
+			reuse:    true,
+			first:    create{owner: "alice", url: "https://example.com/"},
+			then:     create{owner: "alice", url: "HTTPS://Example.COM:443"},
+			wantSame: true,
+		},
+		{
+			name:     "anonymous callers share ownerless links",
+			reuse:    true,
+			first:    create{url: "https://example.com/"},
+			then:     create{url: "https://example.com/"},
+			wantSame: true,
+		},
+		{
+			name:  "different owner",
+			reuse: true,
+			first: create{owner: "alice", url: "https://example.com/"},
+			then:  create{owner: "bob", url: "https://example.com/"},
+		},
+		{
+			name:  "different destination",
+			reuse: true,
+			first: create{owner: "alice", url: "https://example.com/a"},
+			then:  create{owner: "alice", url: "https://example.com/b"},
+		},
+		{
+			name:  "force new",
+			reuse: true,
+			first: create{owner: "alice", url: "https://example.com/"},
+			then:  create{owner: "alice", url: "https://example.com/", opts: CreateOptions{ForceNew: true}},
+		},
+		{
+			name:  "forced link is not reused later",
+			reuse: true,
+			first: create{owner: "alice", url: "https://example.com/", opts: CreateOptions{ForceNew: true}},
+			then:  create{owner: "alice", url: "https://example.com/"},
+		},
+		{
+			name:  "link with tags",
+			reuse: true,
+			first: create{owner: "alice", url: "https://example.com/"},
+			then:  create{owner: "alice", url: "https://example.com/", opts: CreateOptions{Tags: []string{"promo"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []Option
+			if tt.reuse {
+				opts = append(opts, WithReuseExisting())
+			}
+			s := newTestService(opts...)
+
+			first, created, err := s.CreateShortURL(as(tt.first.owner), tt.first.url, tt.first.opts)
+			if err != nil || !created {
+				t.Fatalf("first create: created = %v, err = %v", created, err)
+			}
+			then, created, err := s.CreateShortURL(as(tt.then.owner), tt.then.url, tt.then.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if same := then.ShortCode == first.ShortCode; same != tt.wantSame || created == tt.wantSame {
+				t.Errorf("same = %v, created = %v, want same = %v", same, created, tt.wantSame)
+			}
+		})
+	}
+}
+
+func TestUpdatedLinkIsNoLongerReused(t *testing.T) {
+	s := newTestService(WithReuseExisting())
+	link := mustCreate(t, s, "alice", "https://example.com/old", CreateOptions{})
+	if _, err := s.UpdateLink(as("alice"), link.ShortCode, "https://example.com/new"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, url := range []string{"https://example.com/old", "https://example.com/new"} {
+		again, created, err := s.CreateShortURL(as("alice"), url, CreateOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !created || again.ShortCode == link.ShortCode {
+			t.Errorf("%s reused the updated link", url)
+		}
+	}
+}
+
+func TestConcurrentCreateReturnsOneLink(t *testing.T) {
+	s := newTestService(WithReuseExisting())
+
+	var wg sync.WaitGroup
+	codes := make([]string, 20)
+	created := make([]bool, len(codes))
+	for i := range codes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			link, ok, err := s.CreateShortURL(as("alice"), "https://example.com/", CreateOptions{})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			codes[i], created[i] = link.ShortCode, ok
+		}()
+	}
+	wg.Wait()
+
+	var n int
+	for i, code := range codes {
+		if code != codes[0] {
+			t.Fatalf("got different codes %s and %s", codes[0], code)
+		}
+		if created[i] {
+			n++
+		}
+	}
+	if n != 1 {
+		t.Errorf("%d requests report a new link, want 1", n)
+	}
+}
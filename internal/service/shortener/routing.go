@@ -0,0 +1,409 @@
+package shortener
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"shorted/internal/domain/domainerr"
+	"shorted/internal/domain/models"
+	"shorted/pkg/geoip"
+	"shorted/pkg/tracing"
+	"shorted/pkg/urlpolicy"
+	"shorted/pkg/urlvalidate"
+	"shorted/pkg/useragent"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/language"
+)
+
+const (
+	defaultAppTimeout = 1500 * time.Millisecond
+	maxAppTimeout     = 10 * time.Second
+	maxGeoRules       = 50
+)
+
+var (
+	ruleNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+	countryPattern  = regexp.MustCompile(`^[A-Z]{2}$`)
+	regionPattern   = regexp.MustCompile(`^[A-Z]{2}-[A-Z0-9]{1,3}$`)
+)
+
+// GeoLocator определяет страну и регион посетителя по IP-адресу.
+type GeoLocator interface {
+	Lookup(addr netip.Addr) (geoip.Location, error)
+}
+
+// WithGeoLocator включает условия по стране и региону в географических правилах;
+// без него такие правила никогда не срабатывают, а условия по языку работают.
+func WithGeoLocator(l GeoLocator) Option {
+	return func(s *Service) {
+		s.geo = l
+	}
+}
+
+// appScheme сообщает, можно ли открывать scheme как схему приложения: веб-адреса
+// задаются в url, а небезопасные схемы исполняют код или читают локальные данные
+// в контексте нашей страницы.
+func appScheme(scheme string) bool {
+	scheme = strings.ToLower(scheme)
+	return scheme != "" && scheme != "http" && scheme != "https" && !urlvalidate.UnsafeScheme(scheme)
+}
+
+// Resolution — куда отправить посетителя короткой ссылки.
+type Resolution struct {
+	Link *models.Link
+	// URL — адрес назначения с учётом правил ссылки.
+	URL string
+	// AppURL — адрес в схеме приложения: страница-переходник открывает его и,
+	// если приложение не ответило за AppTimeout, уходит на URL.
+	AppURL     string
+	AppTimeout time.Duration
+	// Interstitial — адрес помечен политикой, перед переходом нужно предупредить.
+	Interstitial bool
+	// Variant — вариант A/B-теста, за которым нужно закрепить посетителя.
+	Variant string
+}
+
+// SetRouting заменяет правила выбора адреса; nil или пустые правила их удаляют.
+func (s *Service) SetRouting(ctx context.Context, shortCode string, routing *models.Routing) (_ *models.Link, err error) {
+	ctx, span := s.tracer.Start(ctx, "shortener.SetRouting", tracing.SpanKindInternal)
+	span.SetAttribute("link.short_code", shortCode)
+	defer func() { endSpan(span, err) }()
+
+	routing, err = s.routing(ctx, routing, shortCode)
+	if err != nil {
+		return nil, err
+	}
+
+	link, err := s.ownedLink(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	link.Routing = routing
+	link.UpdatedAt = time.Now().Unix()
+	if err := s.repo.Update(ctx, link); err != nil {
+		return nil, notFound(err)
+	}
+	return link, nil
+}
+
+// routing проверяет правила и приводит адреса в них к каноническому виду так же,
+// как основной адрес ссылки.
+func (s *Service) routing(ctx context.Context, routing *models.Routing, self string) (*models.Routing, error) {
+	if routing == nil || len(routing.Devices) == 0 && len(routing.Geo) == 0 && routing.Split == nil {
+		return nil, nil
+	}
+
+	result := &models.Routing{}
+	var err error
+	if result.Devices, err = s.deviceRules(ctx, routing.Devices, self); err != nil {
+		return nil, err
+	}
+	if result.Geo, err = s.geoRules(ctx, routing.Geo, self); err != nil {
+		return nil, err
+	}
+	if result.Split, err = s.split(ctx, routing.Split, self); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *Service) deviceRules(ctx context.Context, rules []models.DeviceRule, self string) ([]models.DeviceRule, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	result := make([]models.DeviceRule, 0, len(rules))
+	seen := make(map[models.Platform]bool, len(rules))
+	for i, rule := range rules {
+		field := fmt.Sprintf("routing.devices[%d]", i)
+		switch rule.Platform {
+		case models.PlatformIOS, models.PlatformAndroid, models.PlatformDesktop:
+		default:
+			return nil, domainerr.Validation(domainerr.FieldError{
+				Field: field + ".platform", Code: "invalid_platform", Message: "platform must be ios, android or desktop",
+			})
+		}
+		if seen[rule.Platform] {
+			return nil, domainerr.Validation(domainerr.FieldError{
+				Field: field + ".platform", Code: "duplicate_platform", Message: "only one rule per platform is allowed",
+			})
+		}
+		seen[rule.Platform] = true
+
+		if rule.URL == "" && rule.AppURL == "" {
+			return nil, domainerr.Validation(domainerr.FieldError{
+				Field: field, Code: "empty_rule", Message: "rule needs url, app_url or both",
+			})
+		}
+		if rule.URL != "" {
+			dest, err := s.destination(ctx, rule.URL, self)
+			if err != nil {
+				return nil, atField(err, field+".url")
+			}
+			rule.URL = dest
+		}
+		if rule.AppURL != "" {
+			u, err := url.Parse(rule.AppURL)
+			if err != nil || !appScheme(u.Scheme) {
+				return nil, domainerr.Validation(domainerr.FieldError{
+					Field: field + ".app_url", Code: "invalid_app_url", Message: "app_url must use the app's own scheme, web addresses go to url",
+				})
+			}
+			// адрес приложения открывается так же, как url, и проверяется той же политикой
+			if s.verdict(rule.AppURL) == urlpolicy.Block {
+				return nil, atField(invalidURL("url_blocked", "url is blocked by policy"), field+".app_url")
+			}
+		}
+		if rule.AppTimeoutMs < 0 || time.Duration(rule.AppTimeoutMs)*time.Millisecond > maxAppTimeout {
+			return nil, domainerr.Validation(domainerr.FieldError{
+				Field: field + ".app_timeout_ms", Code: "invalid_app_timeout", Message: fmt.Sprintf("app_timeout_ms must be between 0 and %d", maxAppTimeout.Milliseconds()),
+			})
+		}
+		result = append(result, rule)
+	}
+	return result, nil
+}
+
+func (s *Service) geoRules(ctx context.Context, rules []models.GeoRule, self string) ([]models.GeoRule, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	if len(rules) > maxGeoRules {
+		return nil, domainerr.Validation(domainerr.FieldError{
+			Field: "routing.geo", Code: "too_many_rules", Message: fmt.Sprintf("at most %d geo rules are allowed", maxGeoRules),
+		})
+	}
+
+	invalid := func(field, code, message string) error {
+		return domainerr.Validation(domainerr.FieldError{Field: field, Code: code, Message: message})
+	}
+	result := make([]models.GeoRule, 0, len(rules))
+	names := make(map[string]bool, len(rules))
+	for i, rule := range rules {
+		field := fmt.Sprintf("routing.geo[%d]", i)
+
+		// без имени правило называется по номеру, но тогда статистика
+		// перепутается при перестановке правил
+		if rule.Name == "" {
+			rule.Name = strconv.Itoa(i + 1)
+		}
+		if !ruleNamePattern.MatchString(rule.Name) {
+			return nil, invalid(field+".name", "invalid_rule_name", "name must be 1 to 64 letters, digits, dots, dashes or underscores")
+		}
+		if names[rule.Name] {
+			return nil, invalid(field+".name", "duplicate_rule_name", "rule names must be unique")
+		}
+		names[rule.Name] = true
+
+		if len(rule.Countries)+len(rule.Regions)+len(rule.Languages) == 0 {
+			return nil, invalid(field, "empty_rule", "rule needs countries, regions or languages")
+		}
+		// нормализуем копии: исходные срезы принадлежат вызывающему и могут
+		// читаться параллельно
+		countries := slices.Clone(rule.Countries)
+		for j, c := range countries {
+			countries[j] = strings.ToUpper(strings.TrimSpace(c))
+			if !countryPattern.MatchString(countries[j]) {
+				return nil, invalid(fmt.Sprintf("%s.countries[%d]", field, j), "invalid_country", "country must be an ISO 3166-1 alpha-2 code such as DE")
+			}
+		}
+		regions := slices.Clone(rule.Regions)
+		for j, r := range regions {
+			regions[j] = strings.ToUpper(strings.TrimSpace(r))
+			if !regionPattern.MatchString(regions[j]) {
+				return nil, invalid(fmt.Sprintf("%s.regions[%d]", field, j), "invalid_region", "region must be an ISO 3166-2 code such as US-CA")
+			}
+		}
+		languages := slices.Clone(rule.Languages)
+		for j, l := range languages {
+			tag, err := language.Parse(strings.TrimSpace(l))
+			if err != nil || tag == language.Und {
+				return nil, invalid(fmt.Sprintf("%s.languages[%d]", field, j), "invalid_language", "language must be a BCP 47 tag such as de or pt-BR")
+			}
+			languages[j] = tag.String()
+		}
+		rule.Countries, rule.Regions, rule.Languages = countries, regions, languages
+
+		if rule.URL == "" {
+			return nil, invalid(field+".url", "required", "url is required")
+		}
+		dest, err := s.destination(ctx, rule.URL, self)
+		if err != nil {
+			return nil, atField(err, field+".url")
+		}
+		rule.URL = dest
+		result = append(result, rule)
+	}
+	return result, nil
+}
+
+// route выбирает адрес для посетителя и отмечает в visit сработавшее правило.
+func (s *Service) route(link *models.Link, visit *models.Click) *Resolution {
+	// в клик попадает только вариант, выбранный для этого перехода
+	sticky := visit.Variant
+	visit.Variant = ""
+
+	res := &Resolution{Link: link, URL: link.OriginalURL}
+	if link.Routing == nil {
+		return res
+	}
+
+	ua := useragent.Parse(visit.UserAgent)
+	platform := devicePlatform(ua.Platform)
+	for _, rule := range link.Routing.Devices {
+		if rule.Platform != platform {
+			continue
+		}
+		visit.Rule = "device:" + string(rule.Platform)
+		if rule.URL != "" {
+			res.URL = rule.URL
+		}
+		// роботы не выполняют скрипт переходника и должны сразу получить адрес
+		if rule.AppURL != "" && !ua.Bot {
+			res.AppURL = rule.AppURL
+			res.AppTimeout = defaultAppTimeout
+			if rule.AppTimeoutMs > 0 {
+				res.AppTimeout = time.Duration(rule.AppTimeoutMs) * time.Millisecond
+			}
+		}
+		return res
+	}
+
+	if rule := s.matchGeo(link.Routing.Geo, visit); rule != nil {
+		visit.Rule = "geo:" + rule.Name
+		res.URL = rule.URL
+		return res
+	}
+
+	if split := link.Routing.Split; split != nil {
+		if v := pickVariant(link.ShortCode, split, sticky, visit); v != nil {
+			visit.Rule = "variant:" + v.Name
+			visit.Variant = v.Name
+			res.URL = v.URL
+			// после объявления победителя закреплять посетителя незачем
+			if split.Winner == "" {
+				res.Variant = v.Name
+			}
+		}
+	}
+	return res
+}
+
+// matchGeo возвращает первое подходящее географическое правило. Адрес и язык
+// определяются, только если до них дошла проверка.
+func (s *Service) matchGeo(rules []models.GeoRule, visit *models.Click) *models.GeoRule {
+	var (
+		loc  *geoip.Location
+		lang *language.Tag
+	)
+	for i := range rules {
+		rule := &rules[i]
+		if len(rule.Countries) > 0 || len(rule.Regions) > 0 {
+			if loc == nil {
+				loc = s.locate(visit.IP)
+			}
+			if len(rule.Countries) > 0 && !slices.Contains(rule.Countries, loc.Country) {
+				continue
+			}
+			if len(rule.Regions) > 0 && !slices.Contains(rule.Regions, loc.Region) {
+				continue
+			}
+		}
+		if len(rule.Languages) > 0 {
+			if lang == nil {
+				lang = primaryLanguage(visit.AcceptLanguage)
+			}
+			if !matchLanguage(rule.Languages, *lang) {
+				continue
+			}
+		}
+		return rule
+	}
+	return nil
+}
+
+func (s *Service) locate(ip string) *geoip.Location {
+	loc := &geoip.Location{}
+	addr, err := netip.ParseAddr(ip)
+	if s.geo == nil || err != nil {
+		return loc
+	}
+	if *loc, err = s.geo.Lookup(addr); err != nil {
+		log.Printf("geoip lookup %s: %v", ip, err)
+	}
+	return loc
+}
+
+// primaryLanguage — самый предпочтительный язык из Accept-Language или Und.
+func primaryLanguage(header string) *language.Tag {
+	tag := language.Und
+	if tags, _, err := language.ParseAcceptLanguage(header); err == nil && len(tags) > 0 {
+		tag = tags[0]
+	}
+	return &tag
+}
+
+// matchLanguage: тег правила совпадает с языком посетителя целиком, а тег из
+// одного языка (de) — и с любым его вариантом (de-AT, de-CH).
+func matchLanguage(rules []string, visitor language.Tag) bool {
+	if visitor == language.Und {
+		return false
+	}
+	base, _ := visitor.Base()
+	for _, r := range rules {
+		tag, err := language.Parse(r)
+		if err != nil {
+			continue
+		}
+		if tag == visitor {
+			return true
+		}
+		if rb, conf := tag.Base(); conf == language.Exact && tag.String() == rb.String() && rb == base {
+			return true
+		}
+	}
+	return false
+}
+
+func devicePlatform(p useragent.Platform) models.Platform {
+	switch {
+	case p == useragent.IOS:
+		return models.PlatformIOS
+	case p == useragent.Android:
+		return models.PlatformAndroid
+	case p.Desktop():
+		return models.PlatformDesktop
+	}
+	return ""
+}
+
+// stricter возвращает более строгий из двух вердиктов политики.
+func stricter(a, b urlpolicy.Verdict) urlpolicy.Verdict {
+	rank := map[urlpolicy.Verdict]int{urlpolicy.Allow: 0, urlpolicy.Warn: 1, urlpolicy.Block: 2}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
+
+// atField переносит ошибки проверки адреса на поле field вложенного правила.
+func atField(err error, field string) error {
+	var de *domainerr.Error
+	if !errors.As(err, &de) || len(de.Fields) == 0 {
+		return err
+	}
+	fields := make([]domainerr.FieldError, len(de.Fields))
+	for i, f := range de.Fields {
+		f.Field = field
+		fields[i] = f
+	}
+	return domainerr.Validation(fields...)
+}
@@ -0,0 +1,367 @@
+package shortener
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"shorted/internal/domain/domainerr"
+	"shorted/internal/domain/models"
+	"shorted/pkg/geoip"
+	"shorted/pkg/urlpolicy"
+	"testing"
+	"time"
+)
+
+const (
+	iPhoneUA  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148"
+	androidUA = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 Chrome/120.0 Mobile Safari/537.36"
+	desktopUA = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 Chrome/120.0 Safari/537.36"
+	botUA     = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) (compatible; Googlebot/2.1)"
+)
+
+func TestAppScheme(t *testing.T) {
+	tests := []struct {
+		scheme string
+		want   bool
+	}{
+		{scheme: "myapp", want: true},
+		{scheme: "fb", want: true},
+		{scheme: "itms-apps", want: true},
+		{scheme: ""},
+		{scheme: "http"},
+		{scheme: "HTTPS"},
+		{scheme: "javascript"},
+		{scheme: "JavaScript"},
+		{scheme: "vbscript"},
+		{scheme: "data"},
+		{scheme: "file"},
+		{scheme: "blob"},
+		{scheme: "about"},
+	}
+	for _, tt := range tests {
+		if got := appScheme(tt.scheme); got != tt.want {
+			t.Errorf("appScheme(%q) = %v, want %v", tt.scheme, got, tt.want)
+		}
+	}
+}
+
+func TestDeviceRulesValidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		rules     []models.DeviceRule
+		wantField string
+		wantCode  string
+	}{
+		{name: "valid", rules: []models.DeviceRule{
+			{Platform: models.PlatformIOS, URL: "https://apps.apple.com/app/id1", AppURL: "myapp://open", AppTimeoutMs: 2000},
+			{Platform: models.PlatformAndroid, AppURL: "intent://open#Intent;scheme=myapp;end"},
+			{Platform: models.PlatformDesktop, URL: "https://example.com/desktop"},
+		}},
+		{name: "unknown platform", rules: []models.DeviceRule{{Platform: "tv", URL: "https://example.com/"}}, wantField: "routing.devices[0].platform", wantCode: "invalid_platform"},
+		{
+			name: "duplicate platform",
+			rules: []models.DeviceRule{
+				{Platform: models.PlatformIOS, URL: "https://example.com/a"},
+				{Platform: models.PlatformIOS, URL: "https://example.com/b"},
+			},
+			wantField: "routing.devices[1].platform", wantCode: "duplicate_platform",
+		},
+		{name: "empty rule", rules: []models.DeviceRule{{Platform: models.PlatformIOS}}, wantField: "routing.devices[0]", wantCode: "empty_rule"},
+		{name: "invalid url", rules: []models.DeviceRule{{Platform: models.PlatformIOS, URL: "ftp://example.com/"}}, wantField: "routing.devices[0].url", wantCode: "scheme_not_allowed"},
+		{name: "web app url", rules: []models.DeviceRule{{Platform: models.PlatformIOS, AppURL: "https://example.com/"}}, wantField: "routing.devices[0].app_url", wantCode: "invalid_app_url"},
+		{name: "javascript app url", rules: []models.DeviceRule{{Platform: models.PlatformIOS, AppURL: "javascript:alert(1)"}}, wantField: "routing.devices[0].app_url", wantCode: "invalid_app_url"},
+		{name: "data app url", rules: []models.DeviceRule{{Platform: models.PlatformIOS, AppURL: "DATA:text/html,x"}}, wantField: "routing.devices[0].app_url", wantCode: "invalid_app_url"},
+		{name: "relative app url", rules: []models.DeviceRule{{Platform: models.PlatformIOS, AppURL: "open/thing"}}, wantField: "routing.devices[0].app_url", wantCode: "invalid_app_url"},
+		{name: "negative timeout", rules: []models.DeviceRule{{Platform: models.PlatformIOS, AppURL: "myapp://", AppTimeoutMs: -1}}, wantField: "routing.devices[0].app_timeout_ms", wantCode: "invalid_app_timeout"},
+		{name: "long timeout", rules: []models.DeviceRule{{Platform: models.PlatformIOS, AppURL: "myapp://", AppTimeoutMs: 10001}}, wantField: "routing.devices[0].app_timeout_ms", wantCode: "invalid_app_timeout"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService()
+			link := mustCreate(t, s, "alice", "https://example.com/", CreateOptions{})
+
+			_, err := s.SetRouting(as("alice"), link.ShortCode, &models.Routing{Devices: tt.rules})
+			if tt.wantCode == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if !errors.Is(err, domainerr.ErrValidation) {
+				t.Fatalf("error = %v, want a validation error", err)
+			}
+			f := domainerr.From(err).Fields[0]
+			if f.Field != tt.wantField || f.Code != tt.wantCode {
+				t.Errorf("field error = %s %s, want %s %s", f.Field, f.Code, tt.wantField, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestResolveDeviceRules(t *testing.T) {
+	s := newTestService()
+	link := mustCreate(t, s, "alice", "https://example.com/", CreateOptions{})
+	_, err := s.SetRouting(as("alice"), link.ShortCode, &models.Routing{Devices: []models.DeviceRule{
+		{Platform: models.PlatformIOS, URL: "https://apps.apple.com/app/id1", AppURL: "myapp://open", AppTimeoutMs: 2000},
+		{Platform: models.PlatformAndroid, AppURL: "myapp://open"},
+		{Platform: models.PlatformDesktop, URL: "https://example.com/desktop"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		ua          string
+		wantURL     string
+		wantAppURL  string
+		wantTimeout time.Duration
+	}{
+		{name: "ios", ua: iPhoneUA, wantURL: "https://apps.apple.com/app/id1", wantAppURL: "myapp://open", wantTimeout: 2 * time.Second},
+		// без своего url правило уходит на основной адрес, таймаут по умолчанию
+		{name: "android", ua: androidUA, wantURL: "https://example.com/", wantAppURL: "myapp://open", wantTimeout: defaultAppTimeout},
+		{name: "desktop", ua: desktopUA, wantURL: "https://example.com/desktop"},
+		{name: "bot gets the web address", ua: botUA, wantURL: "https://apps.apple.com/app/id1"},
+		{name: "unknown device", ua: "SomethingElse/1.0", wantURL: "https://example.com/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := s.Resolve(context.Background(), link.ShortCode, models.Click{UserAgent: tt.ua})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.URL != tt.wantURL || res.AppURL != tt.wantAppURL || res.AppTimeout != tt.wantTimeout {
+				t.Errorf("Resolve() = url %q app %q timeout %s; want %q %q %s",
+					res.URL, res.AppURL, res.AppTimeout, tt.wantURL, tt.wantAppURL, tt.wantTimeout)
+			}
+		})
+	}
+}
+
+func TestSetRoutingClearsRules(t *testing.T) {
+	s := newTestService()
+	link := mustCreate(t, s, "alice", "https://example.com/", CreateOptions{})
+	if _, err := s.SetRouting(as("alice"), link.ShortCode, &models.Routing{Devices: []models.DeviceRule{
+		{Platform: models.PlatformDesktop, URL: "https://example.com/desktop"},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, routing := range []*models.Routing{{}, nil} {
+		updated, err := s.SetRouting(as("alice"), link.ShortCode, routing)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if updated.Routing != nil {
+			t.Errorf("SetRouting(%v) kept rules: %+v", routing, updated.Routing)
+		}
+	}
+	if _, err := s.SetRouting(as("bob"), link.ShortCode, nil); !errors.Is(err, domainerr.ErrForbidden) {
+		t.Errorf("other owner: error = %v, want forbidden", err)
+	}
+}
+
+// fakeLocator определяет местоположение по таблице адресов.
+type fakeLocator map[string]geoip.Location
+
+func (f fakeLocator) Lookup(addr netip.Addr) (geoip.Location, error) {
+	loc, ok := f[addr.String()]
+	if !ok {
+		return geoip.Location{}, errors.New("not found")
+	}
+	return loc, nil
+}
+
+func TestResolveGeoRules(t *testing.T) {
+	locator := fakeLocator{
+		"203.0.113.1": {Country: "DE", Region: "DE-BY"},
+		"203.0.113.2": {Country: "US", Region: "US-CA"},
+		"203.0.113.3": {Country: "US", Region: "US-NY"},
+	}
+
+	tests := []struct {
+		name     string
+		geo      GeoLocator
+		ip       string
+		lang     string
+		ua       string
+		wantURL  string
+		wantRule string
+	}{
+		{name: "country", geo: locator, ip: "203.0.113.1", wantURL: "https://example.com/de", wantRule: "geo:germany"},
+		{name: "region", geo: locator, ip: "203.0.113.2", wantURL: "https://example.com/ca", wantRule: "geo:california"},
+		{name: "country without matching region", geo: locator, ip: "203.0.113.3", wantURL: "https://example.com/"},
+		{name: "language variant", geo: locator, ip: "198.51.100.1", lang: "pt-BR,pt;q=0.9", wantURL: "https://example.com/pt", wantRule: "geo:portuguese"},
+		{name: "language by base", geo: locator, ip: "198.51.100.1", lang: "fr-CA", wantURL: "https://example.com/fr", wantRule: "geo:french"},
+		{name: "exact language only", geo: locator, ip: "198.51.100.1", lang: "pt-PT", wantURL: "https://example.com/"},
+		{name: "first rule wins", geo: locator, ip: "203.0.113.1", lang: "fr", wantURL: "https://example.com/de", wantRule: "geo:germany"},
+		{name: "no locator", ip: "203.0.113.1", lang: "fr", wantURL: "https://example.com/fr", wantRule: "geo:french"},
+		{name: "lookup error", geo: locator, ip: "192.0.2.50", wantURL: "https://example.com/"},
+		{name: "bad ip", geo: locator, ip: "not-an-ip", wantURL: "https://example.com/"},
+		{name: "device rule wins", geo: locator, ip: "203.0.113.1", ua: desktopUA, wantURL: "https://example.com/desktop", wantRule: "device:desktop"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []Option
+			if tt.geo != nil {
+				opts = append(opts, WithGeoLocator(tt.geo))
+			}
+			s := newTestService(opts...)
+			link := mustCreate(t, s, "alice", "https://example.com/", CreateOptions{})
+			_, err := s.SetRouting(as("alice"), link.ShortCode, &models.Routing{
+				Devices: []models.DeviceRule{{Platform: models.PlatformDesktop, URL: "https://example.com/desktop"}},
+				Geo: []models.GeoRule{
+					{Name: "germany", Countries: []string{"de", "AT"}, URL: "https://example.com/de"},
+					{Name: "california", Regions: []string{"us-ca"}, URL: "https://example.com/ca"},
+					{Name: "portuguese", Languages: []string{"pt-BR"}, URL: "https://example.com/pt"},
+					{Name: "french", Languages: []string{"fr"}, URL: "https://example.com/fr"},
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			visit := models.Click{IP: tt.ip, AcceptLanguage: tt.lang, UserAgent: tt.ua}
+			res := s.route(mustGet(t, s, link.ShortCode), &visit)
+			if res.URL != tt.wantURL || visit.Rule != tt.wantRule {
+				t.Errorf("route() = %q rule %q, want %q rule %q", res.URL, visit.Rule, tt.wantURL, tt.wantRule)
+			}
+		})
+	}
+}
+
+func TestGeoRulesValidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		rules     []models.GeoRule
+		wantField string
+		wantCode  string
+	}{
+		{name: "valid", rules: []models.GeoRule{{Name: "eu.de-1", Countries: []string{" de "}, URL: "https://example.com/"}}},
+		{name: "unnamed rules get numbers", rules: []models.GeoRule{
+			{Countries: []string{"DE"}, URL: "https://example.com/a"},
+			{Countries: []string{"FR"}, URL: "https://example.com/b"},
+		}},
+		{name: "bad name", rules: []models.GeoRule{{Name: "with space", Countries: []string{"DE"}, URL: "https://example.com/"}}, wantField: "routing.geo[0].name", wantCode: "invalid_rule_name"},
+		{
+			name: "duplicate name",
+			rules: []models.GeoRule{
+				{Name: "a", Countries: []string{"DE"}, URL: "https://example.com/"},
+				{Name: "a", Countries: []string{"FR"}, URL: "https://example.com/"},
+			},
+			wantField: "routing.geo[1].name", wantCode: "duplicate_rule_name",
+		},
+		{
+			name: "number clashes with a name",
+			rules: []models.GeoRule{
+				{Name: "2", Countries: []string{"DE"}, URL: "https://example.com/"},
+				{Countries: []string{"FR"}, URL: "https://example.com/"},
+			},
+			wantField: "routing.geo[1].name", wantCode: "duplicate_rule_name",
+		},
+		{name: "no conditions", rules: []models.GeoRule{{Name: "a", URL: "https://example.com/"}}, wantField: "routing.geo[0]", wantCode: "empty_rule"},
+		{name: "bad country", rules: []models.GeoRule{{Countries: []string{"DE", "Germany"}, URL: "https://example.com/"}}, wantField: "routing.geo[0].countries[1]", wantCode: "invalid_country"},
+		{name: "bad region", rules: []models.GeoRule{{Regions: []string{"CA"}, URL: "https://example.com/"}}, wantField: "routing.geo[0].regions[0]", wantCode: "invalid_region"},
+		{name: "bad language", rules: []models.GeoRule{{Languages: []string{"not a tag"}, URL: "https://example.com/"}}, wantField: "routing.geo[0].languages[0]", wantCode: "invalid_language"},
+		{name: "undetermined language", rules: []models.GeoRule{{Languages: []string{"und"}, URL: "https://example.com/"}}, wantField: "routing.geo[0].languages[0]", wantCode: "invalid_language"},
+		{name: "missing url", rules: []models.GeoRule{{Countries: []string{"DE"}}}, wantField: "routing.geo[0].url", wantCode: "required"},
+		{name: "invalid url", rules: []models.GeoRule{{Countries: []string{"DE"}, URL: "http://127.0.0.1/"}}, wantField: "routing.geo[0].url", wantCode: "private_address"},
+		{name: "too many rules", rules: make([]models.GeoRule, maxGeoRules+1), wantField: "routing.geo", wantCode: "too_many_rules"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService()
+			link := mustCreate(t, s, "alice", "https://example.com/", CreateOptions{})
+
+			_, err := s.SetRouting(as("alice"), link.ShortCode, &models.Routing{Geo: tt.rules})
+			if tt.wantCode == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if !errors.Is(err, domainerr.ErrValidation) {
+				t.Fatalf("error = %v, want a validation error", err)
+			}
+			f := domainerr.From(err).Fields[0]
+			if f.Field != tt.wantField || f.Code != tt.wantCode {
+				t.Errorf("field error = %s %s, want %s %s", f.Field, f.Code, tt.wantField, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestGeoRulesKeepInput(t *testing.T) {
+	s := newTestService()
+	link := mustCreate(t, s, "alice", "https://example.com/", CreateOptions{})
+	rules := []models.GeoRule{{Countries: []string{" de "}, Regions: []string{"us-ca"}, Languages: []string{"PT-br"}, URL: "https://example.com/geo"}}
+
+	if _, err := s.SetRouting(as("alice"), link.ShortCode, &models.Routing{Geo: rules}); err != nil {
+		t.Fatal(err)
+	}
+	// запрос вызывающего не меняется, нормализуются только сохранённые правила
+	if rule := rules[0]; rule.Countries[0] != " de " || rule.Regions[0] != "us-ca" || rule.Languages[0] != "PT-br" {
+		t.Errorf("input rule changed: %+v", rule)
+	}
+	stored := mustGet(t, s, link.ShortCode).Routing.Geo[0]
+	if stored.Countries[0] != "DE" || stored.Regions[0] != "US-CA" || stored.Languages[0] != "pt-BR" {
+		t.Errorf("stored rule = %+v", stored)
+	}
+}
+
+func TestRoutingPolicy(t *testing.T) {
+	policy := mustPolicy(t, "blocked.example")
+	s := newTestService(WithPolicy(policy))
+	link := mustCreate(t, s, "alice", "https://example.com/", CreateOptions{})
+
+	_, err := s.SetRouting(as("alice"), link.ShortCode, &models.Routing{Devices: []models.DeviceRule{
+		{Platform: models.PlatformIOS, AppURL: "myapp://blocked.example/open"},
+	}})
+	if f := domainerr.From(err).Fields; len(f) == 0 || f[0].Field != "routing.devices[0].app_url" || f[0].Code != "url_blocked" {
+		t.Fatalf("blocked app_url: %v", err)
+	}
+
+	_, err = s.SetRouting(as("alice"), link.ShortCode, &models.Routing{
+		Devices: []models.DeviceRule{{Platform: models.PlatformIOS, AppURL: "myapp://app.example/open"}},
+		Geo:     []models.GeoRule{{Languages: []string{"de"}, URL: "https://de.example/"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// политика меняется после создания ссылки, поэтому адреса правил проверяются при переходе
+	for _, pattern := range []string{"app.example", "de.example"} {
+		if _, err := policy.Add(pattern, urlpolicy.Block, "test"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		visit models.Click
+		want  error
+	}{
+		{"blocked app url", models.Click{UserAgent: iPhoneUA}, domainerr.ErrLinkDisabled},
+		{"blocked geo rule", models.Click{UserAgent: desktopUA, AcceptLanguage: "de"}, domainerr.ErrLinkDisabled},
+		{"main url", models.Click{UserAgent: desktopUA, AcceptLanguage: "fr"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.Resolve(context.Background(), link.ShortCode, tt.visit); !errors.Is(err, tt.want) {
+				t.Errorf("Resolve() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func mustGet(t *testing.T, s *Service, code string) *models.Link {
+	t.Helper()
+	link, err := s.GetLink(context.Background(), code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return link
+}
@@ -0,0 +1,83 @@
+package shortener
+
+import (
+	"context"
+	"fmt"
+	"shorted/internal/auth"
+	"shorted/internal/domain/domainerr"
+	"shorted/internal/domain/models"
+	"shorted/internal/domain/repositories"
+	"shorted/pkg/textsearch"
+	"shorted/pkg/tracing"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxSearchQuery     = 200
+	maxSearchTerms     = 10
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+	// длина отрывка с подсветкой в символах
+	highlightWidth = 160
+)
+
+// Search ищет ссылки владельца запроса по коду, адресу, заголовку, описанию и
+// меткам. Слова запроса совпадают целиком, как начало слова или с опечаткой;
+// найденные ссылки содержат все слова.
+func (s *Service) Search(ctx context.Context, query string, limit int) (_ []models.SearchResult, err error) {
+	ctx, span := s.tracer.Start(ctx, "shortener.Search", tracing.SpanKindInternal)
+	defer func() { endSpan(span, err) }()
+
+	query = strings.TrimSpace(query)
+	terms := textsearch.Terms(query)
+	if len(terms) == 0 || utf8.RuneCountInString(query) > maxSearchQuery || len(terms) > maxSearchTerms {
+		return nil, domainerr.Validation(domainerr.FieldError{
+			Field: "q", Code: "invalid_query",
+			Message: fmt.Sprintf("q must have 1 to %d words and at most %d characters", maxSearchTerms, maxSearchQuery),
+		})
+	}
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	limit = min(limit, maxSearchLimit)
+
+	owner, _ := auth.PrincipalFrom(ctx)
+	hits, err := s.repo.Search(ctx, repositories.SearchOptions{Query: query, OwnerID: owner.ID, Limit: limit})
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttribute("search.results", len(hits))
+
+	results := make([]models.SearchResult, 0, len(hits))
+	for _, hit := range hits {
+		results = append(results, models.SearchResult{
+			Link:       hit.Link,
+			Score:      hit.Score,
+			Highlights: highlights(hit.Link, terms),
+		})
+	}
+	return results, nil
+}
+
+// highlights подсвечивает совпадения в полях ссылки. Подсветка своя для всех
+// хранилищ, поэтому отрывки одинаковы в памяти и в Postgres.
+func highlights(link *models.Link, terms []string) map[string]string {
+	fields := map[string]string{
+		"short_code":   link.ShortCode,
+		"original_url": link.OriginalURL,
+		"tags":         strings.Join(link.Tags, ", "),
+	}
+	if link.Metadata != nil {
+		fields["title"] = link.Metadata.Title
+		fields["description"] = link.Metadata.Description
+	}
+
+	result := make(map[string]string)
+	for field, text := range fields {
+		if snippet, ok := textsearch.Highlight(text, terms, highlightWidth); ok {
+			result[field] = snippet
+		}
+	}
+	return result
+}
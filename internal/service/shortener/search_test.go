@@ -0,0 +1,113 @@
+package shortener
+
+import (
+	"context"
+	"shorted/internal/domain/models"
+	"strings"
+	"testing"
+)
+
+func TestSearchValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		ok    bool
+	}{
+		{name: "word", query: "sale", ok: true},
+		{name: "empty", query: "  "},
+		{name: "punctuation only", query: "?!"},
+		{name: "too many words", query: strings.Repeat("a ", maxSearchTerms+1)},
+		{name: "most words", query: strings.Repeat("a ", maxSearchTerms), ok: true},
+		{name: "too long", query: strings.Repeat("a", maxSearchQuery+1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newTestService().Search(as("alice"), tt.query, 0)
+			if tt.ok {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if fieldCode(err) != "invalid_query" {
+				t.Errorf("error = %v, want invalid_query", err)
+			}
+		})
+	}
+}
+
+func TestSearch(t *testing.T) {
+	s := newTestService()
+	shoes := mustCreate(t, s, "alice", "https://shop.example.com/spring/shoes", CreateOptions{Tags: []string{"spring"}})
+	mustCreate(t, s, "alice", "https://shop.example.com/autumn", CreateOptions{Tags: []string{"autumn"}})
+	mustCreate(t, s, "bob", "https://shop.example.com/spring", CreateOptions{Tags: []string{"spring"}})
+	sale := mustCreate(t, s, "alice", "https://blog.example.com/post", CreateOptions{})
+	err := s.repo.SetMetadata(context.Background(), sale.ShortCode, sale.OriginalURL, &models.LinkMetadata{
+		Title: "Spring <sale>", Description: "Everything must go",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		caller string
+		query  string
+		limit  int
+		want   []string
+	}{
+		// метка весит больше заголовка
+		{name: "tag before title", caller: "alice", query: "spring", want: []string{shoes.ShortCode, sale.ShortCode}},
+		{name: "limit", caller: "alice", query: "spring", limit: 1, want: []string{shoes.ShortCode}},
+		{name: "all words", caller: "alice", query: "spring shoes", want: []string{shoes.ShortCode}},
+		{name: "typo", caller: "alice", query: "sprinh", want: []string{shoes.ShortCode, sale.ShortCode}},
+		// в слове из шести букв допускается одна опечатка
+		{name: "two typos", caller: "alice", query: "sprnig"},
+		{name: "prefix", caller: "alice", query: "spri", want: []string{shoes.ShortCode, sale.ShortCode}},
+		{name: "description", caller: "alice", query: "everything", want: []string{sale.ShortCode}},
+		{name: "short code", caller: "alice", query: sale.ShortCode, want: []string{sale.ShortCode}},
+		// схема адреса не индексируется
+		{name: "scheme", caller: "alice", query: "https"},
+		{name: "someone else's links", caller: "carol", query: "spring"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := s.Search(as(tt.caller), tt.query, tt.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, r := range results {
+				got = append(got, r.Link.ShortCode)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchHighlights(t *testing.T) {
+	link := &models.Link{
+		ShortCode:   "abc",
+		OriginalURL: "https://example.com/spring",
+		Tags:        []string{"sale", "spring"},
+		Metadata:    &models.LinkMetadata{Title: "Spring <b>sale</b>", Description: "nothing here"},
+	}
+	got := highlights(link, []string{"spring"})
+	want := map[string]string{
+		"original_url": "https://example.com/<mark>spring</mark>",
+		"tags":         "sale, <mark>spring</mark>",
+		"title":        "<mark>Spring</mark> &lt;b&gt;sale&lt;/b&gt;",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("highlights = %v", got)
+	}
+	for field, snippet := range want {
+		if got[field] != snippet {
+			t.Errorf("%s = %q, want %q", field, got[field], snippet)
+		}
+	}
+}
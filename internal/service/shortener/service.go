@@ -1,22 +1,474 @@
 package shortener
 
-import "shorted/internal/domain/repositories"
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"shorted/internal/auth"
+	"shorted/internal/domain/domainerr"
+	"shorted/internal/domain/models"
+	"shorted/internal/domain/repositories"
+	"shorted/pkg/metrics"
+	"shorted/pkg/tracing"
+	"shorted/pkg/urlpolicy"
+	"shorted/pkg/urlvalidate"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	codeLength     = 7
+	codeAlphabet   = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	maxSaveRetries = 5
+
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
 
 type Service struct {
-	repo repositories.LinkRepository
+	repo   repositories.LinkRepository
+	stats  repositories.StatsRepository
+	clicks *clickHub
+	jobs   *batchJobs
+	tracer *tracing.Tracer
+	urls   *urlvalidate.Validator
+	policy *urlpolicy.Engine
+	chains chainPolicy
+	reuse  bool
+
+	fetcher  MetadataFetcher
+	metadata chan metadataTask
+	// metadataDropped — задачи, не поместившиеся в очередь metadata
+	metadataDropped atomic.Int64
+	geo             GeoLocator
+
+	registry *metrics.Registry
+
+	conversions repositories.ConversionRepository
+	campaigns   repositories.CampaignRepository
+
+	// admins — владельцы ключей, которым можно менять ссылки без владельца
+	admins map[string]bool
+}
+
+type Option func(*Service)
+
+// WithReuseExisting включает режим, в котором CreateShortURL отдаёт уже существующую
+// ссылку владельца на тот же адрес вместо новой.
+func WithReuseExisting() Option {
+	return func(s *Service) {
+		s.reuse = true
+	}
+}
+
+// WithURLValidator задаёт правила проверки адресов назначения; по умолчанию
+// разрешены только публичные http(s)-адреса.
+func WithURLValidator(v *urlvalidate.Validator) Option {
+	return func(s *Service) {
+		s.urls = v
+	}
+}
+
+// WithPolicy включает проверку адресов по спискам правил при создании ссылок
+// и при переходе по ним.
+func WithPolicy(p *urlpolicy.Engine) Option {
+	return func(s *Service) {
+		s.policy = p
+	}
+}
+
+// WithAdmins разрешает владельцам ключей ids менять ссылки без владельца:
+// созданные анонимно или до включения API-ключей.
+func WithAdmins(ids ...string) Option {
+	return func(s *Service) {
+		for _, id := range ids {
+			s.admins[id] = true
+		}
+	}
+}
+
+func NewService(repo repositories.LinkRepository, stats repositories.StatsRepository, tracer *tracing.Tracer, opts ...Option) *Service {
+	s := &Service{
+		repo:   repo,
+		stats:  stats,
+		clicks: newClickHub(),
+		jobs:   newBatchJobs(),
+		tracer: tracer,
+		urls:   urlvalidate.New(urlvalidate.Config{}),
+		chains: chainPolicy{maxHops: defaultMaxHops},
+		admins: make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.fetcher != nil {
+		s.startMetadataWorkers()
+	}
+	if s.registry != nil {
+		s.registerMetrics(s.registry)
+	}
+	return s
+}
+
+type CreateOptions struct {
+	// ForceNew выдаёт новый код даже в режиме WithReuseExisting.
+	ForceNew bool
+	// Routing — правила выбора адреса; ссылка с правилами всегда создаётся новой.
+	Routing *models.Routing
+	// Forwarding — параметры и путь для адреса назначения; ссылка с ними тоже
+	// всегда создаётся новой.
+	Forwarding *models.Forwarding
+	// Tags и Campaigns — метки и кампании новой ссылки; ссылка с ними тоже
+	// всегда создаётся новой.
+	Tags      []string
+	Campaigns []string
+}
+
+// CreateShortURL возвращает ссылку и created=false, если отдана существующая ссылка.
+func (s *Service) CreateShortURL(ctx context.Context, originalURL string, opts CreateOptions) (_ *models.Link, created bool, err error) {
+	ctx, span := s.tracer.Start(ctx, "shortener.CreateShortURL", tracing.SpanKindInternal)
+	defer func() { endSpan(span, err) }()
+
+	owner, _ := auth.PrincipalFrom(ctx)
+	link, err := s.newLink(ctx, owner.ID, originalURL, opts)
+	if err != nil {
+		return nil, false, err
+	}
+	destination := link.OriginalURL
+	canonical := s.reuse && !opts.ForceNew && link.Routing == nil && link.Forwarding == nil && link.Tags == nil && link.Campaigns == nil
+	if canonical {
+		link, err := s.repo.FindByDestination(ctx, owner.ID, destination)
+		if err == nil {
+			span.SetAttribute("link.short_code", link.ShortCode)
+			span.SetAttribute("link.reused", true)
+			return link, false, nil
+		}
+		if !errors.Is(err, repositories.ErrNotFound) {
+			return nil, false, err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		code, err := generateCode()
+		if err != nil {
+			return nil, false, err
+		}
+
+		link.ShortCode = code
+		link.CreatedAt = time.Now().Unix()
+		link.Canonical = canonical
+
+		err = s.repo.Save(ctx, link)
+		if errors.Is(err, repositories.ErrAlreadyExists) {
+			if attempt < maxSaveRetries {
+				continue
+			}
+			return nil, false, domainerr.ErrCodeExhausted.WithCause(err)
+		}
+		if errors.Is(err, repositories.ErrDestinationExists) {
+			// параллельный запрос успел создать каноническую ссылку первым
+			existing, err := s.repo.FindByDestination(ctx, owner.ID, destination)
+			if err != nil {
+				return nil, false, err
+			}
+			return existing, false, nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+
+		span.SetAttribute("link.short_code", code)
+		s.enqueueMetadata(link)
+		return link, true, nil
+	}
+}
+
+// newLink проверяет адрес и настройки новой ссылки владельца ownerID и
+// собирает её без кода.
+func (s *Service) newLink(ctx context.Context, ownerID, originalURL string, opts CreateOptions) (*models.Link, error) {
+	destination, err := s.destination(ctx, originalURL, "")
+	if err != nil {
+		return nil, err
+	}
+	routing, err := s.routing(ctx, opts.Routing, "")
+	if err != nil {
+		return nil, err
+	}
+	fwd, err := s.forwarding(opts.Forwarding)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := normalizeTags("tags", opts.Tags)
+	if err != nil {
+		return nil, err
+	}
+	campaigns, err := s.linkCampaigns(ctx, ownerID, opts.Campaigns)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Link{
+		OriginalURL: destination,
+		OwnerID:     ownerID,
+		Routing:     routing,
+		Forwarding:  fwd,
+		Tags:        tags,
+		Campaigns:   campaigns,
+	}, nil
+}
+
+func (s *Service) GetLink(ctx context.Context, shortCode string) (_ *models.Link, err error) {
+	ctx, span := s.tracer.Start(ctx, "shortener.GetLink", tracing.SpanKindInternal)
+	span.SetAttribute("link.short_code", shortCode)
+	defer func() { endSpan(span, err) }()
+
+	return s.findLink(ctx, shortCode)
+}
+
+// Resolve находит ссылку для перехода, выбирает адрес по правилам ссылки и
+// учитывает клик. В visit.Query и visit.Path — строка запроса и путь после кода
+// для ссылок, которые их пробрасывают. Отключённая модератором ссылка или заблокированный адрес дают
+// ErrLinkDisabled; у отключённой ссылки в Message причина.
+func (s *Service) Resolve(ctx context.Context, shortCode string, visit models.Click) (_ *Resolution, err error) {
+	ctx, span := s.tracer.Start(ctx, "shortener.Resolve", tracing.SpanKindInternal)
+	span.SetAttribute("link.short_code", shortCode)
+	defer func() { endSpan(span, err) }()
+
+	link, err := s.findLink(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+
+	if link.DisabledAt != 0 {
+		span.SetAttribute("link.disabled", true)
+		return nil, domainerr.ErrLinkDisabled.WithMessage(link.DisabledReason)
+	}
+
+	// путь после кода есть только у ссылок, которые его пробрасывают
+	if visit.Path != "" && (link.Forwarding == nil || !link.Forwarding.ForwardPath) {
+		return nil, domainerr.ErrLinkNotFound
+	}
+
+	res := s.route(link, &visit)
+	if link.Forwarding != nil && link.Forwarding.ClickIDParam != "" && s.conversions != nil {
+		if visit.ClickID, err = newClickID(); err != nil {
+			return nil, err
+		}
+	}
+	res.URL = forward(link, res.URL, &visit)
+	// заблокированный основной адрес отключает и все правила ссылки; адреса
+	// правил и приложения проверяются на каждом переходе, потому что политика
+	// могла измениться после создания ссылки
+	verdict := s.verdict(link.OriginalURL)
+	if res.URL != link.OriginalURL {
+		verdict = stricter(verdict, s.verdict(res.URL))
+	}
+	if res.AppURL != "" {
+		verdict = stricter(verdict, s.verdict(res.AppURL))
+	}
+	span.SetAttribute("link.verdict", string(verdict))
+	if verdict == urlpolicy.Block {
+		return nil, domainerr.ErrLinkDisabled
+	}
+	res.Interstitial = verdict == urlpolicy.Warn
+
+	visit.ShortCode = link.ShortCode
+	visit.At = time.Now().Unix()
+	if visit.ClickID != "" {
+		s.trackClick(ctx, visit, res.URL)
+	}
+	s.recordClick(ctx, visit)
+
+	return res, nil
 }
 
-func NewService(repo repositories.LinkRepository) *Service {
-	return &Service{repo: repo}
+func (s *Service) UpdateLink(ctx context.Context, shortCode, originalURL string) (_ *models.Link, err error) {
+	ctx, span := s.tracer.Start(ctx, "shortener.UpdateLink", tracing.SpanKindInternal)
+	span.SetAttribute("link.short_code", shortCode)
+	defer func() { endSpan(span, err) }()
+
+	destination, err := s.destination(ctx, originalURL, shortCode)
+	if err != nil {
+		return nil, err
+	}
+
+	link, err := s.ownedLink(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+
+	// ссылка с новым адресом больше не служит канонической для старого, а метаданные
+	// старой страницы к ней больше не относятся
+	changed := link.OriginalURL != destination
+	if changed {
+		link.Canonical = false
+		link.Metadata = nil
+	}
+	link.OriginalURL = destination
+	link.UpdatedAt = time.Now().Unix()
+	if err := s.repo.Update(ctx, link); err != nil {
+		return nil, notFound(err)
+	}
+
+	if changed {
+		s.enqueueMetadata(link)
+	}
+	return link, nil
 }
 
-func (s *Service) CreateShortURL() {
-	// структура данных
-	// рера
-	// ответ
+func (s *Service) DeleteLink(ctx context.Context, shortCode string) (err error) {
+	ctx, span := s.tracer.Start(ctx, "shortener.DeleteLink", tracing.SpanKindInternal)
+	span.SetAttribute("link.short_code", shortCode)
+	defer func() { endSpan(span, err) }()
+
+	if _, err := s.ownedLink(ctx, shortCode); err != nil {
+		return err
+	}
+
+	return notFound(s.repo.Delete(ctx, shortCode))
 }
 
-func (s *Service) GetOriginalURL() {
-	//репа
-	//ответ
+// GetLinks загружает ссылки пачкой; в результате только найденные коды.
+func (s *Service) GetLinks(ctx context.Context, shortCodes []string) (_ map[string]*models.Link, err error) {
+	ctx, span := s.tracer.Start(ctx, "shortener.GetLinks", tracing.SpanKindInternal)
+	span.SetAttribute("link.count", len(shortCodes))
+	defer func() { endSpan(span, err) }()
+
+	links, err := s.repo.FindByCodes(ctx, shortCodes)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]*models.Link, len(links))
+	for _, link := range links {
+		result[link.ShortCode] = link
+	}
+	return result, nil
+}
+
+// ListLinks отдаёт страницу ссылок; пустой next означает последнюю страницу.
+func (s *Service) ListLinks(ctx context.Context, opts repositories.ListOptions) (_ []*models.Link, next string, err error) {
+	ctx, span := s.tracer.Start(ctx, "shortener.ListLinks", tracing.SpanKindInternal)
+	defer func() { endSpan(span, err) }()
+
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	limit = min(limit, maxPageSize)
+
+	// берём на одну запись больше, чтобы понять, есть ли следующая страница
+	opts.Limit = limit + 1
+	links, err := s.repo.List(ctx, opts)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(links) > limit {
+		links = links[:limit]
+		next = links[limit-1].ShortCode
+	}
+
+	return links, next, nil
+}
+
+func (s *Service) findLink(ctx context.Context, shortCode string) (*models.Link, error) {
+	link, err := s.repo.FindByCode(ctx, shortCode)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return link, nil
+}
+
+// ownedLink находит ссылку, которую вызывающий вправе менять.
+func (s *Service) ownedLink(ctx context.Context, shortCode string) (*models.Link, error) {
+	link, err := s.findLink(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorize(ctx, link.OwnerID); err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// authorize проверяет право менять объект владельца ownerID: свой объект может
+// менять только владелец, объект без владельца — только администратор.
+// Анонимным запросам менять ничего нельзя.
+func (s *Service) authorize(ctx context.Context, ownerID string) error {
+	p, ok := auth.PrincipalFrom(ctx)
+	switch {
+	case !ok:
+		return domainerr.ErrUnauthenticated
+	case ownerID == "":
+		if !s.admins[p.ID] {
+			return domainerr.ErrAdminRequired.WithMessage("only admins can change links without an owner")
+		}
+	case p.ID != ownerID:
+		return domainerr.ErrForbidden
+	}
+	return nil
+}
+
+// normalizeURL проверяет адрес назначения и возвращает его канонический вид,
+// по которому, в частности, ищутся дубликаты.
+func (s *Service) normalizeURL(ctx context.Context, raw string) (string, error) {
+	normalized, err := s.urls.Normalize(ctx, raw)
+	var invalid *urlvalidate.Error
+	if errors.As(err, &invalid) {
+		return "", invalidURL(invalid.Code, invalid.Message)
+	}
+	return normalized, err
+}
+
+// CheckURL показывает, какой вердикт политика вынесет адресу после нормализации.
+func (s *Service) CheckURL(ctx context.Context, rawURL string) (urlpolicy.Decision, error) {
+	destination, err := s.normalizeURL(ctx, rawURL)
+	if err != nil {
+		return urlpolicy.Decision{}, err
+	}
+	if s.policy == nil {
+		return urlpolicy.Decision{Verdict: urlpolicy.Allow}, nil
+	}
+	return s.policy.Check(destination), nil
+}
+
+func (s *Service) verdict(destination string) urlpolicy.Verdict {
+	if s.policy == nil {
+		return urlpolicy.Allow
+	}
+	return s.policy.Check(destination).Verdict
+}
+
+func invalidURL(code, message string) error {
+	return domainerr.Validation(domainerr.FieldError{Field: "url", Code: code, Message: message})
+}
+
+func notFound(err error) error {
+	if errors.Is(err, repositories.ErrNotFound) {
+		return domainerr.ErrLinkNotFound.WithCause(err)
+	}
+	return err
+}
+
+// endSpan не помечает спан ошибкой, если ссылка просто не найдена.
+func endSpan(span *tracing.Span, err error) {
+	if err != nil && domainerr.KindOf(err) != domainerr.KindNotFound {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+func generateCode() (string, error) {
+	b := make([]byte, codeLength)
+	max := big.NewInt(int64(len(codeAlphabet)))
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = codeAlphabet[n.Int64()]
+	}
+
+	return string(b), nil
 }
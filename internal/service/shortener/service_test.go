@@ -0,0 +1,80 @@
+package shortener
+
+import (
+	"context"
+	"errors"
+	"shorted/internal/auth"
+	"shorted/internal/domain/domainerr"
+	"shorted/internal/domain/models"
+	"shorted/internal/repository/memory"
+	"shorted/pkg/tracing"
+	"testing"
+)
+
+func newTestService(opts ...Option) *Service {
+	return NewService(memory.NewLinkRepo(), memory.NewStatsRepo(), tracing.NewTracer(tracing.NeverSample(), nil), opts...)
+}
+
+// as — контекст запроса с ключом владельца owner; пустой owner — анонимный запрос.
+func as(owner string) context.Context {
+	if owner == "" {
+		return context.Background()
+	}
+	return auth.WithPrincipal(context.Background(), auth.Principal{ID: owner})
+}
+
+func mustCreate(t *testing.T, s *Service, owner, url string, opts CreateOptions) *models.Link {
+	t.Helper()
+	link, _, err := s.CreateShortURL(as(owner), url, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return link
+}
+
+func TestOwnedLink(t *testing.T) {
+	tests := []struct {
+		name    string
+		owner   string
+		caller  string
+		wantErr *domainerr.Error
+	}{
+		{name: "owner", owner: "alice", caller: "alice"},
+		{name: "other owner", owner: "alice", caller: "bob", wantErr: domainerr.ErrForbidden},
+		{name: "anonymous caller", owner: "alice", wantErr: domainerr.ErrUnauthenticated},
+		{name: "ownerless link, anonymous caller", wantErr: domainerr.ErrUnauthenticated},
+		{name: "ownerless link, regular caller", caller: "bob", wantErr: domainerr.ErrAdminRequired},
+		{name: "ownerless link, admin", caller: "root"},
+		{name: "admin and someone else's link", owner: "alice", caller: "root", wantErr: domainerr.ErrForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(WithAdmins("root"))
+			link := mustCreate(t, s, tt.owner, "https://example.com/", CreateOptions{})
+
+			_, err := s.UpdateLink(as(tt.caller), link.ShortCode, "https://example.com/new")
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("update: %v", err)
+				}
+				if err := s.DeleteLink(as(tt.caller), link.ShortCode); err != nil {
+					t.Fatalf("delete: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("update: error = %v, want %s", err, tt.wantErr.Code)
+			}
+			if err := s.DeleteLink(as(tt.caller), link.ShortCode); !errors.Is(err, tt.wantErr) {
+				t.Errorf("delete: error = %v, want %s", err, tt.wantErr.Code)
+			}
+
+			// отклонённые изменения не трогают ссылку
+			stored, err := s.GetLink(context.Background(), link.ShortCode)
+			if err != nil || stored.OriginalURL != "https://example.com/" {
+				t.Errorf("link changed: %+v %v", stored, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package shortener
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"shorted/internal/domain/domainerr"
+	"shorted/internal/domain/models"
+	"shorted/pkg/tracing"
+	"time"
+)
+
+const (
+	minVariants = 2
+	maxVariants = 20
+)
+
+// SplitUpdate меняет веса вариантов и победителя, не трогая сами варианты.
+type SplitUpdate struct {
+	// Weights — новые веса по имени варианта; отсутствующие варианты сохраняют вес.
+	Weights map[string]int
+	// Winner, если не nil, объявляет победителя; пустая строка возобновляет тест.
+	Winner *string
+}
+
+// UpdateSplit меняет распределение трафика A/B-теста ссылки.
+func (s *Service) UpdateSplit(ctx context.Context, shortCode string, update SplitUpdate) (_ *models.Link, err error) {
+	ctx, span := s.tracer.Start(ctx, "shortener.UpdateSplit", tracing.SpanKindInternal)
+	span.SetAttribute("link.short_code", shortCode)
+	defer func() { endSpan(span, err) }()
+
+	link, err := s.ownedLink(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	if link.Routing == nil || link.Routing.Split == nil {
+		return nil, domainerr.ErrSplitNotFound
+	}
+
+	// правила копируются, чтобы не менять ссылку, если проверка не пройдёт
+	routing := *link.Routing
+	split := *routing.Split
+	split.Variants = append([]models.Variant(nil), split.Variants...)
+	for name, weight := range update.Weights {
+		i := variantIndex(split.Variants, name)
+		if i < 0 {
+			return nil, domainerr.Validation(domainerr.FieldError{
+				Field: "weights." + name, Code: "unknown_variant", Message: "link has no variant " + name,
+			})
+		}
+		split.Variants[i].Weight = weight
+	}
+	if update.Winner != nil {
+		split.Winner = *update.Winner
+	}
+	if err := validateWeights(&split, "split"); err != nil {
+		return nil, err
+	}
+
+	routing.Split = &split
+	link.Routing = &routing
+	link.UpdatedAt = time.Now().Unix()
+	if err := s.repo.Update(ctx, link); err != nil {
+		return nil, notFound(err)
+	}
+	return link, nil
+}
+
+func (s *Service) split(ctx context.Context, split *models.Split, self string) (*models.Split, error) {
+	if split == nil {
+		return nil, nil
+	}
+	if len(split.Variants) < minVariants || len(split.Variants) > maxVariants {
+		return nil, domainerr.Validation(domainerr.FieldError{
+			Field: "routing.split.variants", Code: "invalid_variant_count",
+			Message: fmt.Sprintf("split needs %d to %d variants", minVariants, maxVariants),
+		})
+	}
+
+	result := &models.Split{Variants: make([]models.Variant, 0, len(split.Variants)), Winner: split.Winner}
+	for i, v := range split.Variants {
+		field := fmt.Sprintf("routing.split.variants[%d]", i)
+		if !ruleNamePattern.MatchString(v.Name) {
+			return nil, domainerr.Validation(domainerr.FieldError{
+				Field: field + ".name", Code: "invalid_rule_name", Message: "name must be 1 to 64 letters, digits, dots, dashes or underscores",
+			})
+		}
+		if variantIndex(result.Variants, v.Name) >= 0 {
+			return nil, domainerr.Validation(domainerr.FieldError{
+				Field: field + ".name", Code: "duplicate_rule_name", Message: "variant names must be unique",
+			})
+		}
+		if v.URL == "" {
+			return nil, domainerr.Validation(domainerr.FieldError{Field: field + ".url", Code: "required", Message: "url is required"})
+		}
+		dest, err := s.destination(ctx, v.URL, self)
+		if err != nil {
+			return nil, atField(err, field+".url")
+		}
+		v.URL = dest
+		result.Variants = append(result.Variants, v)
+	}
+	if err := validateWeights(result, "routing.split"); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// validateWeights проверяет веса и победителя: хотя бы один вариант должен
+// получать трафик, иначе переходы некуда отправить.
+func validateWeights(split *models.Split, field string) error {
+	total := 0
+	for i, v := range split.Variants {
+		if v.Weight < 0 {
+			return domainerr.Validation(domainerr.FieldError{
+				Field: fmt.Sprintf("%s.variants[%d].weight", field, i), Code: "invalid_weight", Message: "weight must not be negative",
+			})
+		}
+		total += v.Weight
+	}
+	if split.Winner != "" && variantIndex(split.Variants, split.Winner) < 0 {
+		return domainerr.Validation(domainerr.FieldError{
+			Field: field + ".winner", Code: "unknown_variant", Message: "winner must name one of the variants",
+		})
+	}
+	if total == 0 && split.Winner == "" {
+		return domainerr.Validation(domainerr.FieldError{
+			Field: field + ".variants", Code: "no_traffic", Message: "at least one variant needs a positive weight",
+		})
+	}
+	return nil
+}
+
+// pickVariant выбирает вариант для посетителя. Закреплённый вариант сохраняется,
+// пока у него есть вес; новый посетитель попадает в вариант по хэшу кода, IP и
+// User-Agent, поэтому и без cookie повторный переход ведёт туда же.
+func pickVariant(code string, split *models.Split, sticky string, visit *models.Click) *models.Variant {
+	if split.Winner != "" {
+		if i := variantIndex(split.Variants, split.Winner); i >= 0 {
+			return &split.Variants[i]
+		}
+	}
+	if i := variantIndex(split.Variants, sticky); i >= 0 && split.Variants[i].Weight > 0 {
+		return &split.Variants[i]
+	}
+
+	total := 0
+	for _, v := range split.Variants {
+		total += v.Weight
+	}
+	if total == 0 {
+		return nil
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(code + "\x00" + visit.IP + "\x00" + visit.UserAgent))
+	bucket := int(h.Sum64() % uint64(total))
+	for i, v := range split.Variants {
+		if bucket < v.Weight {
+			return &split.Variants[i]
+		}
+		bucket -= v.Weight
+	}
+	return nil
+}
+
+func variantIndex(variants []models.Variant, name string) int {
+	if name == "" {
+		return -1
+	}
+	for i, v := range variants {
+		if v.Name == name {
+			return i
+		}
+	}
+	return -1
+}
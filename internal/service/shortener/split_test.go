@@ -0,0 +1,214 @@
+package shortener
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"shorted/internal/domain/domainerr"
+	"shorted/internal/domain/models"
+	"testing"
+)
+
+func variants(weights ...int) []models.Variant {
+	vs := make([]models.Variant, len(weights))
+	for i, w := range weights {
+		name := string(rune('a' + i))
+		vs[i] = models.Variant{Name: name, URL: "https://example.com/" + name, Weight: w}
+	}
+	return vs
+}
+
+func TestSplitValidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		split     *models.Split
+		wantField string
+		wantCode  string
+	}{
+		{name: "valid", split: &models.Split{Variants: variants(50, 50)}},
+		{name: "paused variant", split: &models.Split{Variants: variants(0, 1)}},
+		{name: "winner without weights", split: &models.Split{Variants: variants(0, 0), Winner: "b"}},
+		{name: "one variant", split: &models.Split{Variants: variants(1)}, wantField: "routing.split.variants", wantCode: "invalid_variant_count"},
+		{name: "too many variants", split: &models.Split{Variants: variants(make([]int, maxVariants+1)...)}, wantField: "routing.split.variants", wantCode: "invalid_variant_count"},
+		{
+			name:      "bad name",
+			split:     &models.Split{Variants: []models.Variant{{Name: "a b", URL: "https://example.com/", Weight: 1}, {Name: "c", URL: "https://example.com/", Weight: 1}}},
+			wantField: "routing.split.variants[0].name", wantCode: "invalid_rule_name",
+		},
+		{
+			name:      "duplicate name",
+			split:     &models.Split{Variants: []models.Variant{{Name: "a", URL: "https://example.com/", Weight: 1}, {Name: "a", URL: "https://example.com/", Weight: 1}}},
+			wantField: "routing.split.variants[1].name", wantCode: "duplicate_rule_name",
+		},
+		{
+			name:      "missing url",
+			split:     &models.Split{Variants: []models.Variant{{Name: "a", URL: "https://example.com/", Weight: 1}, {Name: "b", Weight: 1}}},
+			wantField: "routing.split.variants[1].url", wantCode: "required",
+		},
+		{
+			name:      "invalid url",
+			split:     &models.Split{Variants: []models.Variant{{Name: "a", URL: "javascript:alert(1)", Weight: 1}, {Name: "b", URL: "https://example.com/", Weight: 1}}},
+			wantField: "routing.split.variants[0].url", wantCode: "scheme_not_allowed",
+		},
+		{name: "negative weight", split: &models.Split{Variants: variants(1, -1)}, wantField: "routing.split.variants[1].weight", wantCode: "invalid_weight"},
+		{name: "no traffic", split: &models.Split{Variants: variants(0, 0)}, wantField: "routing.split.variants", wantCode: "no_traffic"},
+		{name: "unknown winner", split: &models.Split{Variants: variants(1, 1), Winner: "z"}, wantField: "routing.split.winner", wantCode: "unknown_variant"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService()
+			link := mustCreate(t, s, "alice", "https://example.com/", CreateOptions{})
+
+			_, err := s.SetRouting(as("alice"), link.ShortCode, &models.Routing{Split: tt.split})
+			if tt.wantCode == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if !errors.Is(err, domainerr.ErrValidation) {
+				t.Fatalf("error = %v, want a validation error", err)
+			}
+			f := domainerr.From(err).Fields[0]
+			if f.Field != tt.wantField || f.Code != tt.wantCode {
+				t.Errorf("field error = %s %s, want %s %s", f.Field, f.Code, tt.wantField, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestPickVariant(t *testing.T) {
+	tests := []struct {
+		name   string
+		split  models.Split
+		sticky string
+		// want — имя варианта, если выбор однозначен
+		want string
+	}{
+		{name: "winner", split: models.Split{Variants: variants(100, 0), Winner: "b"}, sticky: "a", want: "b"},
+		{name: "sticky", split: models.Split{Variants: variants(1, 1000)}, sticky: "a", want: "a"},
+		{name: "paused sticky", split: models.Split{Variants: variants(0, 1)}, sticky: "a", want: "b"},
+		{name: "unknown sticky", split: models.Split{Variants: variants(0, 1)}, sticky: "z", want: "b"},
+		{name: "only weighted", split: models.Split{Variants: variants(0, 5, 0)}, want: "b"},
+		{name: "no weights", split: models.Split{Variants: variants(0, 0)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := pickVariant("abc", &tt.split, tt.sticky, &models.Click{IP: "203.0.113.1", UserAgent: "ua"})
+			name := ""
+			if got != nil {
+				name = got.Name
+			}
+			if name != tt.want {
+				t.Errorf("pickVariant() = %q, want %q", name, tt.want)
+			}
+		})
+	}
+}
+
+func TestPickVariantDistribution(t *testing.T) {
+	split := &models.Split{Variants: variants(1, 3)}
+	counts := map[string]int{}
+	const visitors = 4000
+	for i := range visitors {
+		visit := &models.Click{IP: fmt.Sprintf("203.0.%d.%d", i/256, i%256), UserAgent: "ua"}
+		v := pickVariant("abc", split, "", visit)
+		counts[v.Name]++
+
+		// без cookie тот же посетитель попадает в тот же вариант
+		if again := pickVariant("abc", split, "", visit); again.Name != v.Name {
+			t.Fatalf("visitor %d moved from %s to %s", i, v.Name, again.Name)
+		}
+	}
+	// доля b — 75%; допускаем отклонение в пять процентных пунктов
+	if share := float64(counts["b"]) / visitors; share < 0.70 || share > 0.80 {
+		t.Errorf("variant b got %.2f of traffic, want about 0.75 (%v)", share, counts)
+	}
+}
+
+func TestUpdateSplit(t *testing.T) {
+	s := newTestService()
+	link := mustCreate(t, s, "alice", "https://example.com/", CreateOptions{})
+	if _, err := s.UpdateSplit(as("alice"), link.ShortCode, SplitUpdate{}); !errors.Is(err, domainerr.ErrSplitNotFound) {
+		t.Fatalf("link without split: error = %v", err)
+	}
+	if _, err := s.SetRouting(as("alice"), link.ShortCode, &models.Routing{Split: &models.Split{Variants: variants(50, 50)}}); err != nil {
+		t.Fatal(err)
+	}
+
+	winner, resume := "b", ""
+	tests := []struct {
+		name        string
+		update      SplitUpdate
+		wantErr     string
+		wantWeights []int
+		wantWinner  string
+	}{
+		{name: "weights", update: SplitUpdate{Weights: map[string]int{"a": 10}}, wantWeights: []int{10, 50}},
+		{name: "unknown variant", update: SplitUpdate{Weights: map[string]int{"z": 1}}, wantErr: "unknown_variant", wantWeights: []int{10, 50}},
+		{name: "no traffic", update: SplitUpdate{Weights: map[string]int{"a": 0, "b": 0}}, wantErr: "no_traffic", wantWeights: []int{10, 50}},
+		{name: "negative", update: SplitUpdate{Weights: map[string]int{"b": -5}}, wantErr: "invalid_weight", wantWeights: []int{10, 50}},
+		{name: "declare winner", update: SplitUpdate{Winner: &winner}, wantWeights: []int{10, 50}, wantWinner: "b"},
+		{name: "pause all after winner", update: SplitUpdate{Weights: map[string]int{"a": 0, "b": 0}}, wantWeights: []int{0, 0}, wantWinner: "b"},
+		{name: "resume needs weights", update: SplitUpdate{Winner: &resume}, wantErr: "no_traffic", wantWeights: []int{0, 0}, wantWinner: "b"},
+		{name: "resume", update: SplitUpdate{Winner: &resume, Weights: map[string]int{"a": 1}}, wantWeights: []int{1, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.UpdateSplit(as("alice"), link.ShortCode, tt.update)
+			if tt.wantErr != "" {
+				if fieldCode(err) != tt.wantErr {
+					t.Errorf("error = %v, want %s", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			// отклонённое изменение не должно задеть сохранённую ссылку
+			split := mustGet(t, s, link.ShortCode).Routing.Split
+			var weights []int
+			for _, v := range split.Variants {
+				weights = append(weights, v.Weight)
+			}
+			if fmt.Sprint(weights) != fmt.Sprint(tt.wantWeights) || split.Winner != tt.wantWinner {
+				t.Errorf("split = %v winner %q, want %v winner %q", weights, split.Winner, tt.wantWeights, tt.wantWinner)
+			}
+		})
+	}
+
+	if _, err := s.UpdateSplit(as("bob"), link.ShortCode, SplitUpdate{}); !errors.Is(err, domainerr.ErrForbidden) {
+		t.Errorf("other owner: error = %v", err)
+	}
+}
+
+func TestResolveSplit(t *testing.T) {
+	s := newTestService()
+	link := mustCreate(t, s, "alice", "https://example.com/", CreateOptions{})
+	if _, err := s.SetRouting(as("alice"), link.ShortCode, &models.Routing{Split: &models.Split{Variants: variants(1, 1)}}); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := s.Resolve(context.Background(), link.ShortCode, models.Click{IP: "203.0.113.1", Variant: "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.URL != "https://example.com/b" || res.Variant != "b" {
+		t.Errorf("sticky visitor: url %q variant %q", res.URL, res.Variant)
+	}
+
+	winner := "a"
+	if _, err := s.UpdateSplit(as("alice"), link.ShortCode, SplitUpdate{Winner: &winner}); err != nil {
+		t.Fatal(err)
+	}
+	res, err = s.Resolve(context.Background(), link.ShortCode, models.Click{IP: "203.0.113.1", Variant: "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// после объявления победителя cookie больше не выставляется
+	if res.URL != "https://example.com/a" || res.Variant != "" {
+		t.Errorf("after winner: url %q variant %q", res.URL, res.Variant)
+	}
+}
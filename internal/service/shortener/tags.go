@@ -0,0 +1,119 @@
+package shortener
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"shorted/internal/auth"
+	"shorted/internal/domain/domainerr"
+	"shorted/internal/domain/models"
+	"shorted/pkg/tracing"
+	"slices"
+	"strings"
+	"time"
+)
+
+// TagEdit — метки, которые нужно добавить и убрать у ссылок ShortCodes.
+type TagEdit struct {
+	ShortCodes []string
+	Add        []string
+	Remove     []string
+}
+
+// Tags отдаёт метки ссылок владельца запроса с числом ссылок.
+func (s *Service) Tags(ctx context.Context) (_ []models.TagCount, err error) {
+	ctx, span := s.tracer.Start(ctx, "shortener.Tags", tracing.SpanKindInternal)
+	defer func() { endSpan(span, err) }()
+
+	owner, _ := auth.PrincipalFrom(ctx)
+	return s.repo.Tags(ctx, owner.ID)
+}
+
+// EditTags меняет метки у нескольких ссылок сразу. Все ссылки должны
+// существовать и быть доступны запросу по правилам authorize, иначе не меняется
+// ни одна.
+func (s *Service) EditTags(ctx context.Context, edit TagEdit) (err error) {
+	ctx, span := s.tracer.Start(ctx, "shortener.EditTags", tracing.SpanKindInternal)
+	span.SetAttribute("link.count", len(edit.ShortCodes))
+	defer func() { endSpan(span, err) }()
+
+	if len(edit.ShortCodes) == 0 || len(edit.ShortCodes) > MaxTagEditLinks {
+		return domainerr.Validation(domainerr.FieldError{
+			Field: "short_codes", Code: "invalid_count", Message: fmt.Sprintf("short_codes must list 1 to %d links", MaxTagEditLinks),
+		})
+	}
+	add, err := normalizeTags("add", edit.Add)
+	if err != nil {
+		return err
+	}
+	remove, err := normalizeTags("remove", edit.Remove)
+	if err != nil {
+		return err
+	}
+
+	links, err := s.repo.FindByCodes(ctx, edit.ShortCodes)
+	if err != nil {
+		return err
+	}
+	byCode := make(map[string]*models.Link, len(links))
+	for _, link := range links {
+		byCode[link.ShortCode] = link
+	}
+	for i, code := range edit.ShortCodes {
+		field := fmt.Sprintf("short_codes[%d]", i)
+		link, ok := byCode[code]
+		if !ok {
+			return domainerr.Validation(domainerr.FieldError{Field: field, Code: "link_not_found", Message: "link " + code + " not found"})
+		}
+		if err := s.authorize(ctx, link.OwnerID); err != nil {
+			if errors.Is(err, domainerr.ErrUnauthenticated) {
+				return err
+			}
+			return domainerr.From(err).WithFields(domainerr.FieldError{Field: field, Code: "forbidden", Message: "you do not have access to link " + code})
+		}
+		if n := countTags(link.Tags, add, remove); n > maxLinkTags {
+			return domainerr.Validation(domainerr.FieldError{
+				Field: field, Code: "too_many_tags", Message: fmt.Sprintf("link %s would have %d tags, at most %d are allowed", code, n, maxLinkTags),
+			})
+		}
+	}
+
+	return s.repo.EditTags(ctx, edit.ShortCodes, add, remove, time.Now().Unix())
+}
+
+// normalizeTags приводит метки к нижнему регистру, проверяет их и убирает повторы.
+func normalizeTags(field string, tags []string) ([]string, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	result := make([]string, 0, len(tags))
+	for i, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if !tagPattern.MatchString(tag) {
+			return nil, domainerr.Validation(domainerr.FieldError{
+				Field: fmt.Sprintf("%s[%d]", field, i), Code: "invalid_tag",
+				Message: "tag must be 1 to 64 letters, digits, dots, colons, slashes, dashes or underscores, starting with a letter or digit",
+			})
+		}
+		result = append(result, tag)
+	}
+	slices.Sort(result)
+	result = slices.Compact(result)
+	if len(result) > maxLinkTags {
+		return nil, domainerr.Validation(domainerr.FieldError{
+			Field: field, Code: "too_many_tags", Message: fmt.Sprintf("at most %d tags are allowed", maxLinkTags),
+		})
+	}
+	return result, nil
+}
+
+// countTags — сколько меток останется у ссылки после правки.
+func countTags(tags, add, remove []string) int {
+	result := make(map[string]bool, len(tags)+len(add))
+	for _, tag := range slices.Concat(tags, add) {
+		if !slices.Contains(remove, tag) {
+			result[tag] = true
+		}
+	}
+	return len(result)
+}
@@ -0,0 +1,156 @@
+package shortener
+
+import (
+	"errors"
+	"fmt"
+	"shorted/internal/domain/domainerr"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tests := []struct {
+		name      string
+		tags      []string
+		want      []string
+		wantField string
+		wantCode  string
+	}{
+		{name: "none"},
+		{name: "lower case, sorted, no repeats", tags: []string{" Sale ", "2024", "sale", "ru/moscow"}, want: []string{"2024", "ru/moscow", "sale"}},
+		{name: "unicode", tags: []string{"Весна"}, want: []string{"весна"}},
+		{name: "empty", tags: []string{"ok", " "}, wantField: "tags[1]", wantCode: "invalid_tag"},
+		{name: "leading dash", tags: []string{"-x"}, wantField: "tags[0]", wantCode: "invalid_tag"},
+		{name: "space inside", tags: []string{"a b"}, wantField: "tags[0]", wantCode: "invalid_tag"},
+		{name: "too long", tags: []string{strings.Repeat("a", 65)}, wantField: "tags[0]", wantCode: "invalid_tag"},
+		{name: "too many", tags: numberedTags(maxLinkTags + 1), wantField: "tags", wantCode: "too_many_tags"},
+		// повторы не считаются в лимит
+		{name: "repeats within the limit", tags: append(numberedTags(maxLinkTags), "t0"), want: numberedTags(maxLinkTags)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeTags("tags", tt.tags)
+			if tt.wantCode != "" {
+				if !errors.Is(err, domainerr.ErrValidation) {
+					t.Fatalf("error = %v, want a validation error", err)
+				}
+				f := domainerr.From(err).Fields[0]
+				if f.Field != tt.wantField || f.Code != tt.wantCode {
+					t.Errorf("field error = %s %s, want %s %s", f.Field, f.Code, tt.wantField, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("normalizeTags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEditTags(t *testing.T) {
+	tests := []struct {
+		name   string
+		caller string
+		// codes — индексы ссылок: 0 и 1 принадлежат alice, 2 — bob, 3 — без владельца
+		codes     []int
+		missing   bool
+		add       []string
+		remove    []string
+		wantErr   *domainerr.Error
+		wantField string
+		wantCode  string
+		wantTags  string
+	}{
+		{name: "add and remove", caller: "alice", codes: []int{0, 1}, add: []string{"New", "b"}, remove: []string{"a"}, wantTags: "b,new"},
+		{name: "remove everything", caller: "alice", codes: []int{0}, remove: []string{"a", "b"}},
+		{name: "no links", caller: "alice", wantErr: domainerr.ErrValidation, wantField: "short_codes", wantCode: "invalid_count"},
+		{name: "missing link", caller: "alice", codes: []int{0}, missing: true, wantErr: domainerr.ErrValidation, wantField: "short_codes[1]", wantCode: "link_not_found"},
+		{name: "bad tag", caller: "alice", codes: []int{0}, remove: []string{"ok", "no way"}, wantErr: domainerr.ErrValidation, wantField: "remove[1]", wantCode: "invalid_tag"},
+		{name: "too many tags", caller: "alice", codes: []int{0}, add: numberedTags(maxLinkTags - 1), wantErr: domainerr.ErrValidation, wantField: "short_codes[0]", wantCode: "too_many_tags"},
+		{name: "someone else's link", caller: "alice", codes: []int{0, 2}, add: []string{"x"}, wantErr: domainerr.ErrForbidden, wantField: "short_codes[1]", wantCode: "forbidden"},
+		{name: "ownerless link", caller: "alice", codes: []int{3}, add: []string{"x"}, wantErr: domainerr.ErrAdminRequired, wantField: "short_codes[0]", wantCode: "forbidden"},
+		{name: "ownerless link, admin", caller: "root", codes: []int{3}, add: []string{"x"}, wantTags: "a,b,x"},
+		{name: "anonymous", codes: []int{3}, add: []string{"x"}, wantErr: domainerr.ErrUnauthenticated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(WithAdmins("root"))
+			opts := CreateOptions{Tags: []string{"a", "b"}}
+			links := []string{
+				mustCreate(t, s, "alice", "https://example.com/0", opts).ShortCode,
+				mustCreate(t, s, "alice", "https://example.com/1", opts).ShortCode,
+				mustCreate(t, s, "bob", "https://example.com/2", opts).ShortCode,
+				mustCreate(t, s, "", "https://example.com/3", opts).ShortCode,
+			}
+			var codes []string
+			for _, i := range tt.codes {
+				codes = append(codes, links[i])
+			}
+			if tt.missing {
+				codes = append(codes, "nope")
+			}
+
+			err := s.EditTags(as(tt.caller), TagEdit{ShortCodes: codes, Add: tt.add, Remove: tt.remove})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %s", err, tt.wantErr.Code)
+				}
+				if tt.wantField != "" {
+					f := domainerr.From(err).Fields[0]
+					if f.Field != tt.wantField || f.Code != tt.wantCode {
+						t.Errorf("field error = %s %s, want %s %s", f.Field, f.Code, tt.wantField, tt.wantCode)
+					}
+				}
+				// отклонённая правка не меняет ни одной ссылки
+				for _, code := range links {
+					if got := strings.Join(mustGet(t, s, code).Tags, ","); got != "a,b" {
+						t.Errorf("%s tags = %s after a rejected edit", code, got)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, code := range codes {
+				link := mustGet(t, s, code)
+				if got := strings.Join(link.Tags, ","); got != tt.wantTags {
+					t.Errorf("%s tags = %q, want %q", code, got, tt.wantTags)
+				}
+				if link.UpdatedAt == 0 {
+					t.Errorf("%s UpdatedAt is not set", code)
+				}
+			}
+		})
+	}
+}
+
+func TestTags(t *testing.T) {
+	s := newTestService()
+	mustCreate(t, s, "alice", "https://example.com/0", CreateOptions{Tags: []string{"a", "b"}})
+	mustCreate(t, s, "alice", "https://example.com/1", CreateOptions{Tags: []string{"b"}})
+	mustCreate(t, s, "bob", "https://example.com/2", CreateOptions{Tags: []string{"c"}})
+
+	tags, err := s.Tags(as("alice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(tags); got != "[{a 1} {b 2}]" {
+		t.Errorf("Tags() = %s", got)
+	}
+}
+
+func numberedTags(n int) []string {
+	tags := make([]string, n)
+	for i := range tags {
+		tags[i] = fmt.Sprintf("t%d", i)
+	}
+	slices.Sort(tags)
+	return tags
+}
@@ -0,0 +1,38 @@
+package graphql
+
+import (
+	"context"
+	"log"
+	"shorted/internal/domain/domainerr"
+	"shorted/pkg/tracing"
+)
+
+// resolverError отдаёт клиенту сообщение и код из каталога в extensions,
+// не раскрывая причину внутренних ошибок.
+type resolverError struct {
+	err *domainerr.Error
+}
+
+func (e *resolverError) Error() string {
+	return e.err.Message
+}
+
+func (e *resolverError) Extensions() map[string]any {
+	ext := map[string]any{"code": e.err.Code}
+	if len(e.err.Fields) > 0 {
+		ext["details"] = e.err.Fields
+	}
+	return ext
+}
+
+func toResolverError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	de := domainerr.From(err)
+	if de.Kind == domainerr.KindInternal {
+		log.Printf("graphql: %v trace_id=%s", err, tracing.TraceIDFromContext(ctx))
+	}
+	return &resolverError{err: de}
+}
@@ -0,0 +1,99 @@
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"shorted/internal/contract"
+	"shorted/internal/service/shortener"
+	"strings"
+	"time"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+const streamKeepAlive = 15 * time.Second
+
+type Handler struct {
+	schema   *graphql.Schema
+	service  *shortener.Service
+	request  contract.RequestDecoder
+	response contract.ResponseWriter
+	errors   contract.ErrorWriter
+}
+
+func NewHandler(service *shortener.Service, request contract.RequestDecoder, response contract.ResponseWriter, errs contract.ErrorWriter) *Handler {
+	return &Handler{
+		schema:   NewSchema(service),
+		service:  service,
+		request:  request,
+		response: response,
+		errors:   errs,
+	}
+}
+
+type graphQLRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// ServeHTTP выполняет запрос и отвечает одним документом, а при Accept: text/event-stream
+// отдаёт результаты потоком SSE (протокол graphql-sse), что нужно для подписок.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req graphQLRequest
+	if err := h.request.Decode(r, &req); err != nil {
+		h.errors.WriteErr(w, r, err)
+		return
+	}
+
+	ctx := withLoaders(r.Context(), h.service)
+	if strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		h.stream(w, r.WithContext(ctx), req)
+		return
+	}
+
+	resp := h.schema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+	h.response.Write(w, r, http.StatusOK, resp)
+}
+
+func (h *Handler) stream(w http.ResponseWriter, r *http.Request, req graphQLRequest) {
+	ctx := r.Context()
+	results, err := h.schema.Subscribe(ctx, req.Query, req.OperationName, req.Variables)
+	if err != nil {
+		h.errors.WriteErr(w, r, err)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	rc.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case result, ok := <-results:
+			if !ok {
+				fmt.Fprint(w, "event: complete\ndata:\n\n")
+				rc.Flush()
+				return
+			}
+			data, err := json.Marshal(result)
+			if err != nil {
+				return
+			}
+			fmt.Fprintf(w, "event: next\ndata: %s\n\n", data)
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case <-ctx.Done():
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
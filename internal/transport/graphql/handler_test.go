@@ -0,0 +1,382 @@
+package graphql
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"shorted/internal/auth"
+	"shorted/internal/domain/models"
+	"shorted/internal/repository/memory"
+	"shorted/internal/service/shortener"
+	"shorted/pkg/apierror"
+	"shorted/pkg/apirequest"
+	"shorted/pkg/apiresponse"
+	"shorted/pkg/codec"
+	"shorted/pkg/tracing"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type gqlResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string `json:"message"`
+		Extensions struct {
+			Code string `json:"code"`
+		} `json:"extensions"`
+	} `json:"errors"`
+}
+
+func newTestService() *shortener.Service {
+	tracer := tracing.NewTracer(tracing.NeverSample(), nil)
+	return shortener.NewService(memory.NewLinkRepo(), memory.NewStatsRepo(), tracer, shortener.WithAdmins("root"))
+}
+
+func newTestHandler(service *shortener.Service) *Handler {
+	codecs := codec.Default()
+	errs := apierror.New(apierror.WithCodecs(codecs))
+	return NewHandler(service, apirequest.New(codecs), apiresponse.New(codecs, errs), errs)
+}
+
+// as выполняет запрос от имени владельца ключа owner; пустой owner — анонимный запрос.
+func as(owner string) context.Context {
+	if owner == "" {
+		return context.Background()
+	}
+	return auth.WithPrincipal(context.Background(), auth.Principal{ID: owner})
+}
+
+func exec(t *testing.T, h *Handler, ctx context.Context, query string, vars map[string]any) gqlResponse {
+	t.Helper()
+	body, err := json.Marshal(graphQLRequest{Query: query, Variables: vars})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequestWithContext(ctx, http.MethodPost, "/api/graphql", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+
+	var resp gqlResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func errorCode(resp gqlResponse) string {
+	if len(resp.Errors) == 0 {
+		return ""
+	}
+	return resp.Errors[0].Extensions.Code
+}
+
+func TestLinkMutationsRequireOwnership(t *testing.T) {
+	const update = `mutation($code: String!) { updateLink(code: $code, url: "https://example.com/new") { originalUrl } }`
+	const remove = `mutation($code: String!) { deleteLink(code: $code) }`
+
+	tests := []struct {
+		name     string
+		owner    string
+		query    string
+		target   string
+		wantCode string
+	}{
+		{name: "owner updates own link", owner: "alice", query: update, target: "alice"},
+		{name: "owner deletes own link", owner: "alice", query: remove, target: "alice"},
+		{name: "other owner", owner: "bob", query: update, target: "alice", wantCode: "forbidden"},
+		{name: "anonymous caller", query: update, target: "alice", wantCode: "unauthenticated"},
+		{name: "anonymous caller and anonymous link", query: remove, target: "", wantCode: "unauthenticated"},
+		{name: "ownerless link without admin rights", owner: "bob", query: update, target: "", wantCode: "admin_required"},
+		{name: "admin updates ownerless link", owner: "root", query: update, target: ""},
+		// администратор не получает доступа к чужим ссылкам с владельцем
+		{name: "admin and owned link", owner: "root", query: remove, target: "alice", wantCode: "forbidden"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newTestService()
+			link, _, err := service.CreateShortURL(as(tt.target), "https://example.com/old", shortener.CreateOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp := exec(t, newTestHandler(service), as(tt.owner), tt.query, map[string]any{"code": link.ShortCode})
+			if got := errorCode(resp); got != tt.wantCode {
+				t.Fatalf("error code = %q, want %q (%s)", got, tt.wantCode, resp.Errors)
+			}
+
+			stored, err := service.GetLink(context.Background(), link.ShortCode)
+			if tt.wantCode != "" && (err != nil || stored.OriginalURL != "https://example.com/old") {
+				t.Errorf("rejected mutation changed the link: %v %v", stored, err)
+			}
+		})
+	}
+}
+
+func TestViewerLinksWithStats(t *testing.T) {
+	service := newTestService()
+	ctx := as("alice")
+	var codes []string
+	for _, u := range []string{"https://example.com/1", "https://example.com/2", "https://example.com/3"} {
+		link, _, err := service.CreateShortURL(ctx, u, shortener.CreateOptions{ForceNew: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		codes = append(codes, link.ShortCode)
+	}
+	if _, _, err := service.CreateShortURL(as("bob"), "https://example.com/bob", shortener.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		if _, err := service.Resolve(ctx, codes[0], models.Click{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h := newTestHandler(service)
+	const query = `query($after: String) {
+		viewer { id links(first: 2, after: $after) {
+			edges { node { code owner { id } stats { clicks } } }
+			pageInfo { hasNextPage endCursor }
+		} }
+	}`
+
+	var data struct {
+		Viewer struct {
+			ID    string
+			Links struct {
+				Edges []struct {
+					Node struct {
+						Code  string
+						Owner struct{ ID string }
+						Stats struct{ Clicks int }
+					}
+				}
+				PageInfo struct {
+					HasNextPage bool
+					EndCursor   *string
+				}
+			}
+		}
+	}
+
+	clicks := make(map[string]int)
+	var after any
+	for page := 0; ; page++ {
+		resp := exec(t, h, ctx, query, map[string]any{"after": after})
+		if len(resp.Errors) > 0 {
+			t.Fatal(resp.Errors)
+		}
+		if err := json.Unmarshal(resp.Data, &data); err != nil {
+			t.Fatal(err)
+		}
+		if data.Viewer.ID != "alice" {
+			t.Fatalf("viewer = %s", data.Viewer.ID)
+		}
+		for _, e := range data.Viewer.Links.Edges {
+			if e.Node.Owner.ID != "alice" {
+				t.Errorf("link %s belongs to %s", e.Node.Code, e.Node.Owner.ID)
+			}
+			clicks[e.Node.Code] = e.Node.Stats.Clicks
+		}
+		if !data.Viewer.Links.PageInfo.HasNextPage {
+			break
+		}
+		if page > len(codes) {
+			t.Fatal("pagination does not end")
+		}
+		after = *data.Viewer.Links.PageInfo.EndCursor
+	}
+
+	if len(clicks) != len(codes) {
+		t.Fatalf("listed %v, want %v", clicks, codes)
+	}
+	if clicks[codes[0]] != 2 || clicks[codes[1]] != 0 {
+		t.Errorf("clicks = %v", clicks)
+	}
+}
+
+func TestLinksAreScopedToCaller(t *testing.T) {
+	service := newTestService()
+	owned := make(map[string]string)
+	for _, owner := range []string{"alice", "bob", ""} {
+		link, _, err := service.CreateShortURL(as(owner), "https://example.com/"+owner, shortener.CreateOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		owned[owner] = link.ShortCode
+	}
+	h := newTestHandler(service)
+
+	tests := []struct {
+		name      string
+		caller    string
+		query     string
+		wantCodes []string
+		wantError string
+	}{
+		{name: "links", caller: "alice", query: `{ links { edges { node { code } } } }`, wantCodes: []string{owned["alice"]}},
+		{name: "anonymous links", query: `{ links { edges { node { code } } } }`, wantCodes: []string{owned[""]}},
+		{name: "own user", caller: "alice", query: `{ user(id: "alice") { links { edges { node { code } } } } }`, wantCodes: []string{owned["alice"]}},
+		{name: "other user", caller: "alice", query: `{ user(id: "bob") { links { edges { node { code } } } } }`, wantError: "forbidden"},
+		{name: "anonymous user", query: `{ user(id: "alice") { id } }`, wantError: "unauthenticated"},
+		{name: "owner of a foreign link", caller: "alice", query: `{ link(code: "` + owned["bob"] + `") { owner { links { edges { node { code } } } } } }`, wantError: "forbidden"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := exec(t, h, as(tt.caller), tt.query, nil)
+			if got := errorCode(resp); got != tt.wantError {
+				t.Fatalf("error code = %q, want %q (%v)", got, tt.wantError, resp.Errors)
+			}
+			if tt.wantError != "" {
+				return
+			}
+			codes := regexp.MustCompile(`"code":"([^"]+)"`).FindAllStringSubmatch(string(resp.Data), -1)
+			var got []string
+			for _, c := range codes {
+				got = append(got, c[1])
+			}
+			if !slices.Equal(got, tt.wantCodes) {
+				t.Errorf("codes = %v, want %v", got, tt.wantCodes)
+			}
+		})
+	}
+}
+
+func TestClicksSubscriptionRequiresOwner(t *testing.T) {
+	service := newTestService()
+	link, _, err := service.CreateShortURL(as("alice"), "https://example.com/", shortener.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := newTestHandler(service)
+
+	tests := []struct {
+		name   string
+		caller string
+		query  string
+		want   string
+	}{
+		{"anonymous", "", `subscription { clicks(code: "` + link.ShortCode + `") { referrer } }`, "missing or invalid credentials"},
+		{"other owner", "bob", `subscription { clicks(code: "` + link.ShortCode + `") { referrer } }`, "you do not have access to this link"},
+		// поток по всем ссылкам убран: code обязателен
+		{"without code", "alice", `subscription { clicks { referrer } }`, `Field \"clicks\" argument \"code\" of type \"String!\" is required`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequestWithContext(as(tt.caller), http.MethodPost, "/api/graphql",
+				strings.NewReader(`{"query": `+strconv.Quote(tt.query)+`}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Accept", "text/event-stream")
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if !strings.Contains(rec.Body.String(), tt.want) || strings.Contains(rec.Body.String(), `"data":{"clicks"`) {
+				t.Errorf("body = %s, want an error containing %s", rec.Body, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryLimits(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		wantCode string
+		// ограничение глубины проверяет сама библиотека, без кода в extensions
+		wantMessage string
+	}{
+		{
+			name:  "within budget",
+			query: `{ links(first: 100) { edges { node { code } } } }`,
+		},
+		{
+			// каждая страница заранее списывает свой размер из бюджета 5000
+			name:     "too complex",
+			query:    `{ a: links(first: 2000) { pageInfo { hasNextPage } } b: links(first: 2000) { pageInfo { hasNextPage } } c: links(first: 2000) { pageInfo { hasNextPage } } }`,
+			wantCode: "query_too_complex",
+		},
+		{
+			name:        "too deep",
+			query:       `{ viewer { links { edges { node { owner { links { edges { node { owner { links { edges { node { code } } } } } } } } } } } } }`,
+			wantMessage: "exceeds max depth",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := exec(t, newTestHandler(newTestService()), as("alice"), tt.query, nil)
+			if tt.wantMessage != "" {
+				if len(resp.Errors) == 0 || !strings.Contains(resp.Errors[0].Message, tt.wantMessage) {
+					t.Errorf("errors = %v, want %q", resp.Errors, tt.wantMessage)
+				}
+				return
+			}
+			if errorCode(resp) != tt.wantCode {
+				t.Errorf("error code = %q, want %q (%v)", errorCode(resp), tt.wantCode, resp.Errors)
+			}
+		})
+	}
+}
+
+func TestClicksSubscription(t *testing.T) {
+	service := newTestService()
+	link, _, err := service.CreateShortURL(as("alice"), "https://example.com/", shortener.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// клики ссылки видит только её владелец
+	h := newTestHandler(service)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), auth.Principal{ID: "alice"})))
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	body := `{"query": "subscription { clicks(code: \"` + link.ShortCode + `\") { referrer link { code } } }"}`
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, server.URL, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %s", ct)
+	}
+
+	// заголовки ответа пишутся после подписки, так что клик уже дойдёт до потока
+	if _, err := service.Resolve(ctx, link.ShortCode, models.Click{Referrer: "https://ref.example/"}); err != nil {
+		t.Fatal(err)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		want := `{"data":{"clicks":{"referrer":"https://ref.example/","link":{"code":"` + link.ShortCode + `"}}}}`
+		if data != want {
+			t.Errorf("event = %s, want %s", data, want)
+		}
+		return
+	}
+	t.Fatalf("stream ended without events: %v", scanner.Err())
+}
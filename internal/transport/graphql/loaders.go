@@ -0,0 +1,47 @@
+package graphql
+
+import (
+	"context"
+	"shorted/internal/domain/domainerr"
+	"shorted/internal/domain/models"
+	"shorted/internal/service/shortener"
+	"shorted/pkg/dataloader"
+	"sync/atomic"
+)
+
+var ErrQueryTooComplex = domainerr.New(domainerr.KindInvalid, "query_too_complex", "query exceeds the complexity limit")
+
+type loadersKey struct{}
+
+// loaders живут один запрос: ссылки и статистика, запрошенные разными полями,
+// собираются в один вызов репозитория.
+type loaders struct {
+	links  *dataloader.Loader[string, *models.Link]
+	stats  *dataloader.Loader[string, *models.LinkStats]
+	budget atomic.Int64
+}
+
+func withLoaders(ctx context.Context, service *shortener.Service) context.Context {
+	l := &loaders{
+		links: dataloader.New(func(ctx context.Context, codes []string) (map[string]*models.Link, error) {
+			return service.GetLinks(ctx, codes)
+		}),
+		stats: dataloader.New(func(ctx context.Context, codes []string) (map[string]*models.LinkStats, error) {
+			return service.StatsByCodes(ctx, codes)
+		}),
+	}
+	l.budget.Store(maxComplexity)
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+// charge списывает стоимость поля из бюджета запроса.
+func charge(ctx context.Context, cost int) error {
+	if loadersFrom(ctx).budget.Add(-int64(cost)) < 0 {
+		return ErrQueryTooComplex
+	}
+	return nil
+}
@@ -0,0 +1,394 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"math"
+	"shorted/internal/auth"
+	"shorted/internal/domain/domainerr"
+	"shorted/internal/domain/models"
+	"shorted/internal/domain/repositories"
+	"shorted/internal/service/shortener"
+	"slices"
+	"time"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+type rootResolver struct {
+	service *shortener.Service
+}
+
+type connectionArgs struct {
+	First *int32
+	After *string
+}
+
+func (r *rootResolver) Link(ctx context.Context, args struct{ Code string }) (*linkResolver, error) {
+	return loadLink(ctx, r.service, args.Code)
+}
+
+func (r *rootResolver) Links(ctx context.Context, args connectionArgs) (*connectionResolver, error) {
+	return listLinks(ctx, r.service, args)
+}
+
+// User отдаёт только самого вызывающего: чужие ссылки через user(id) не видны.
+func (r *rootResolver) User(ctx context.Context, args struct{ ID graphql.ID }) (*userResolver, error) {
+	if err := checkSelf(ctx, string(args.ID)); err != nil {
+		return nil, toResolverError(ctx, err)
+	}
+	return &userResolver{service: r.service, id: string(args.ID)}, nil
+}
+
+func (r *rootResolver) Viewer(ctx context.Context) *userResolver {
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok {
+		return nil
+	}
+	return &userResolver{service: r.service, id: p.ID}
+}
+
+func (r *rootResolver) CreateLink(ctx context.Context, args struct {
+	URL      string
+	ForceNew *bool
+}) (*linkResolver, error) {
+	var opts shortener.CreateOptions
+	if args.ForceNew != nil {
+		opts.ForceNew = *args.ForceNew
+	}
+
+	link, _, err := r.service.CreateShortURL(ctx, args.URL, opts)
+	if err != nil {
+		return nil, toResolverError(ctx, err)
+	}
+	return &linkResolver{service: r.service, link: link}, nil
+}
+
+func (r *rootResolver) UpdateLink(ctx context.Context, args struct {
+	Code string
+	URL  string
+}) (*linkResolver, error) {
+	link, err := r.service.UpdateLink(ctx, args.Code, args.URL)
+	if err != nil {
+		return nil, toResolverError(ctx, err)
+	}
+	return &linkResolver{service: r.service, link: link}, nil
+}
+
+func (r *rootResolver) DeleteLink(ctx context.Context, args struct{ Code string }) (bool, error) {
+	if err := r.service.DeleteLink(ctx, args.Code); err != nil {
+		return false, toResolverError(ctx, err)
+	}
+	return true, nil
+}
+
+func (r *rootResolver) Clicks(ctx context.Context, args struct{ Code string }) (<-chan *clickResolver, error) {
+	clicks, err := r.service.WatchClicks(ctx, args.Code)
+	if err != nil {
+		return nil, toResolverError(ctx, err)
+	}
+
+	out := make(chan *clickResolver)
+	go func() {
+		defer close(out)
+		for click := range clicks {
+			select {
+			case out <- &clickResolver{service: r.service, click: click}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+func loadLink(ctx context.Context, service *shortener.Service, code string) (*linkResolver, error) {
+	if err := charge(ctx, 1); err != nil {
+		return nil, toResolverError(ctx, err)
+	}
+
+	link, err := loadersFrom(ctx).links.Load(ctx, code)
+	if err != nil {
+		return nil, toResolverError(ctx, err)
+	}
+	if link == nil {
+		return nil, nil
+	}
+	return &linkResolver{service: service, link: link}, nil
+}
+
+// checkSelf пропускает только запрос от пользователя id.
+func checkSelf(ctx context.Context, id string) error {
+	p, ok := auth.PrincipalFrom(ctx)
+	switch {
+	case !ok:
+		return domainerr.ErrUnauthenticated
+	case p.ID != id:
+		return domainerr.ErrForbidden
+	}
+	return nil
+}
+
+// listLinks листает ссылки вызывающего, как и GET /api/links.
+func listLinks(ctx context.Context, service *shortener.Service, args connectionArgs) (*connectionResolver, error) {
+	owner, _ := auth.PrincipalFrom(ctx)
+	opts := repositories.ListOptions{OwnerID: owner.ID, OwnerOnly: true}
+	if args.First != nil {
+		opts.Limit = int(*args.First)
+	}
+	if args.After != nil {
+		opts.After = *args.After
+	}
+
+	// стоимость считаем по запрошенному размеру страницы, ещё до обращения к хранилищу
+	cost := opts.Limit
+	if cost <= 0 {
+		cost = defaultPageCost
+	}
+	if err := charge(ctx, cost); err != nil {
+		return nil, toResolverError(ctx, err)
+	}
+
+	links, next, err := service.ListLinks(ctx, opts)
+	if err != nil {
+		return nil, toResolverError(ctx, err)
+	}
+	return &connectionResolver{service: service, links: links, next: next}, nil
+}
+
+type linkResolver struct {
+	service *shortener.Service
+	link    *models.Link
+}
+
+func (r *linkResolver) Code() string {
+	return r.link.ShortCode
+}
+
+func (r *linkResolver) OriginalURL() string {
+	return r.link.OriginalURL
+}
+
+func (r *linkResolver) CreatedAt() graphql.Time {
+	return unixTime(r.link.CreatedAt)
+}
+
+func (r *linkResolver) UpdatedAt() *graphql.Time {
+	return optionalTime(r.link.UpdatedAt)
+}
+
+func (r *linkResolver) Owner() *userResolver {
+	if r.link.OwnerID == "" {
+		return nil
+	}
+	return &userResolver{service: r.service, id: r.link.OwnerID}
+}
+
+func (r *linkResolver) Stats(ctx context.Context) (*statsResolver, error) {
+	if err := charge(ctx, 1); err != nil {
+		return nil, toResolverError(ctx, err)
+	}
+
+	stats, err := loadersFrom(ctx).stats.Load(ctx, r.link.ShortCode)
+	if err != nil {
+		return nil, toResolverError(ctx, err)
+	}
+	if stats == nil {
+		stats = &models.LinkStats{ShortCode: r.link.ShortCode}
+	}
+	return &statsResolver{stats: stats}, nil
+}
+
+func (r *linkResolver) Metadata() *metadataResolver {
+	if r.link.Metadata == nil {
+		return nil
+	}
+	return &metadataResolver{meta: r.link.Metadata}
+}
+
+type metadataResolver struct {
+	meta *models.LinkMetadata
+}
+
+func (r *metadataResolver) Title() *string       { return optionalString(r.meta.Title) }
+func (r *metadataResolver) Description() *string { return optionalString(r.meta.Description) }
+func (r *metadataResolver) ImageURL() *string    { return optionalString(r.meta.Image) }
+func (r *metadataResolver) FaviconURL() *string  { return optionalString(r.meta.Favicon) }
+func (r *metadataResolver) SiteName() *string    { return optionalString(r.meta.SiteName) }
+
+func (r *metadataResolver) FetchedAt() graphql.Time {
+	return unixTime(r.meta.FetchedAt)
+}
+
+type statsResolver struct {
+	stats *models.LinkStats
+}
+
+func (r *statsResolver) Clicks() int32 {
+	return int32(min(r.stats.Clicks, math.MaxInt32))
+}
+
+func (r *statsResolver) LastClickAt() *graphql.Time {
+	return optionalTime(r.stats.LastClickAt)
+}
+
+func (r *statsResolver) Rules() []*ruleStatsResolver {
+	rules := make([]*ruleStatsResolver, 0, len(r.stats.Rules))
+	for _, rule := range slices.Sorted(maps.Keys(r.stats.Rules)) {
+		rules = append(rules, &ruleStatsResolver{rule: rule, clicks: r.stats.Rules[rule]})
+	}
+	return rules
+}
+
+func (r *statsResolver) Conversions() []*conversionStatsResolver {
+	result := make([]*conversionStatsResolver, 0, len(r.stats.Conversions))
+	for i := range r.stats.Conversions {
+		result = append(result, &conversionStatsResolver{stats: &r.stats.Conversions[i]})
+	}
+	return result
+}
+
+type ruleStatsResolver struct {
+	rule   string
+	clicks int64
+}
+
+func (r *ruleStatsResolver) Rule() string {
+	return r.rule
+}
+
+func (r *ruleStatsResolver) Clicks() int32 {
+	return int32(min(r.clicks, math.MaxInt32))
+}
+
+type conversionStatsResolver struct {
+	stats *models.ConversionStats
+}
+
+func (r *conversionStatsResolver) Segment() string { return r.stats.Segment }
+func (r *conversionStatsResolver) Event() string   { return r.stats.Event }
+func (r *conversionStatsResolver) Value() float64  { return r.stats.Value }
+func (r *conversionStatsResolver) Rate() float64   { return r.stats.Rate }
+
+func (r *conversionStatsResolver) Clicks() int32 {
+	return int32(min(r.stats.Clicks, math.MaxInt32))
+}
+
+func (r *conversionStatsResolver) Conversions() int32 {
+	return int32(min(r.stats.Conversions, math.MaxInt32))
+}
+
+type userResolver struct {
+	service *shortener.Service
+	id      string
+}
+
+func (r *userResolver) ID() graphql.ID {
+	return graphql.ID(r.id)
+}
+
+// Links доступен только самому пользователю; до чужого пользователя можно
+// дойти и через link { owner }.
+func (r *userResolver) Links(ctx context.Context, args connectionArgs) (*connectionResolver, error) {
+	if err := checkSelf(ctx, r.id); err != nil {
+		return nil, toResolverError(ctx, err)
+	}
+	return listLinks(ctx, r.service, args)
+}
+
+type connectionResolver struct {
+	service *shortener.Service
+	links   []*models.Link
+	next    string
+}
+
+func (r *connectionResolver) Edges() []*edgeResolver {
+	edges := make([]*edgeResolver, 0, len(r.links))
+	for _, link := range r.links {
+		edges = append(edges, &edgeResolver{link: &linkResolver{service: r.service, link: link}})
+	}
+	return edges
+}
+
+func (r *connectionResolver) PageInfo() *pageInfoResolver {
+	return &pageInfoResolver{next: r.next}
+}
+
+type edgeResolver struct {
+	link *linkResolver
+}
+
+// Cursor — код ссылки: список упорядочен по коду, и он же служит ключом after.
+func (r *edgeResolver) Cursor() string {
+	return r.link.link.ShortCode
+}
+
+func (r *edgeResolver) Node() *linkResolver {
+	return r.link
+}
+
+type pageInfoResolver struct {
+	next string
+}
+
+func (r *pageInfoResolver) HasNextPage() bool {
+	return r.next != ""
+}
+
+func (r *pageInfoResolver) EndCursor() *string {
+	if r.next == "" {
+		return nil
+	}
+	return &r.next
+}
+
+type clickResolver struct {
+	service *shortener.Service
+	click   models.Click
+}
+
+// Link грузится напрямую, а не через loaders: подписка живёт долго, и кеш
+// с бюджетом запроса ей не подходят.
+func (r *clickResolver) Link(ctx context.Context) (*linkResolver, error) {
+	link, err := r.service.GetLink(ctx, r.click.ShortCode)
+	if err != nil {
+		// ссылку могли удалить после клика
+		if errors.Is(err, domainerr.ErrLinkNotFound) {
+			return nil, nil
+		}
+		return nil, toResolverError(ctx, err)
+	}
+	return &linkResolver{service: r.service, link: link}, nil
+}
+
+func (r *clickResolver) At() graphql.Time {
+	return unixTime(r.click.At)
+}
+
+func (r *clickResolver) Referrer() *string {
+	return optionalString(r.click.Referrer)
+}
+
+func (r *clickResolver) UserAgent() *string {
+	return optionalString(r.click.UserAgent)
+}
+
+func unixTime(sec int64) graphql.Time {
+	return graphql.Time{Time: time.Unix(sec, 0).UTC()}
+}
+
+func optionalTime(sec int64) *graphql.Time {
+	if sec == 0 {
+		return nil
+	}
+	t := unixTime(sec)
+	return &t
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,29 @@
+package graphql
+
+import (
+	_ "embed"
+	"shorted/internal/service/shortener"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+const (
+	maxDepth       = 10
+	maxQueryLength = 16 << 10
+	// maxComplexity — бюджет запроса: каждая загруженная ссылка и статистика стоят 1,
+	// connection заранее списывает размер страницы.
+	maxComplexity = 5000
+	// столько ссылок сервис отдаёт, если first не указан
+	defaultPageCost = 50
+)
+
+//go:embed schema.graphql
+var schemaSDL string
+
+func NewSchema(service *shortener.Service) *graphql.Schema {
+	return graphql.MustParseSchema(schemaSDL, &rootResolver{service: service},
+		graphql.MaxDepth(maxDepth),
+		graphql.MaxQueryLength(maxQueryLength),
+		graphql.UseStringDescriptions(),
+	)
+}
@@ -0,0 +1,49 @@
+package grpc
+
+import (
+	"shorted/internal/domain/domainerr"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var kindCode = map[domainerr.Kind]codes.Code{
+	domainerr.KindInvalid:         codes.InvalidArgument,
+	domainerr.KindUnauthenticated: codes.Unauthenticated,
+	domainerr.KindForbidden:       codes.PermissionDenied,
+	domainerr.KindNotFound:        codes.NotFound,
+	domainerr.KindConflict:        codes.AlreadyExists,
+	domainerr.KindGone:            codes.NotFound,
+	domainerr.KindUnprocessable:   codes.FailedPrecondition,
+	domainerr.KindTooLarge:        codes.ResourceExhausted,
+	domainerr.KindRateLimited:     codes.ResourceExhausted,
+	domainerr.KindUnavailable:     codes.Unavailable,
+	domainerr.KindInternal:        codes.Internal,
+}
+
+// toStatus переводит ошибку сервиса в gRPC-статус; ошибки по полям уходят в BadRequest.
+func toStatus(err error) error {
+	de := domainerr.From(err)
+
+	code, ok := kindCode[de.Kind]
+	if !ok {
+		code = codes.Internal
+	}
+
+	st := status.New(code, de.Message)
+	if len(de.Fields) > 0 {
+		violations := make([]*errdetails.BadRequest_FieldViolation, 0, len(de.Fields))
+		for _, f := range de.Fields {
+			violations = append(violations, &errdetails.BadRequest_FieldViolation{
+				Field:       f.Field,
+				Description: f.Message,
+			})
+		}
+		if detailed, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: violations}); err == nil {
+			st = detailed
+		}
+	}
+
+	return st.Err()
+}
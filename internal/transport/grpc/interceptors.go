@@ -0,0 +1,104 @@
+package grpc
+
+import (
+	"context"
+	"log"
+	"shorted/internal/auth"
+	"shorted/pkg/metrics"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type interceptors struct {
+	authenticator auth.Authenticator
+	handled       *metrics.CounterVec
+	duration      *metrics.HistogramVec
+}
+
+func newInterceptors(authenticator auth.Authenticator, registry *metrics.Registry) *interceptors {
+	return &interceptors{
+		authenticator: authenticator,
+		handled:       registry.NewCounterVec("grpc_server_handled_total", "Number of RPCs completed by method and code.", "method", "code"),
+		duration:      registry.NewHistogramVec("grpc_server_handling_seconds", "RPC latency by method.", nil, "method"),
+	}
+}
+
+func (i *interceptors) logUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	log.Printf("grpc %s %s %s", info.FullMethod, status.Code(err), time.Since(start))
+	return resp, err
+}
+
+func (i *interceptors) logStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	log.Printf("grpc %s %s %s", info.FullMethod, status.Code(err), time.Since(start))
+	return err
+}
+
+func (i *interceptors) metricsUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	i.observe(info.FullMethod, start, err)
+	return resp, err
+}
+
+func (i *interceptors) metricsStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	i.observe(info.FullMethod, start, err)
+	return err
+}
+
+func (i *interceptors) observe(method string, start time.Time, err error) {
+	i.handled.WithLabelValues(method, status.Code(err).String()).Inc()
+	i.duration.WithLabelValues(method).ObserveSince(start)
+}
+
+func (i *interceptors) authUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := i.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (i *interceptors) authStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := i.authenticate(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+}
+
+func (i *interceptors) authenticate(ctx context.Context) (context.Context, error) {
+	if i.authenticator == nil {
+		return ctx, nil
+	}
+
+	// без заголовка authorization запрос анонимный, как и в HTTP API
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return ctx, nil
+	}
+	token := auth.BearerToken(values[0])
+	principal, err := i.authenticator.Authenticate(ctx, token)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return auth.WithPrincipal(ctx, principal), nil
+}
+
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,172 @@
+package grpc
+
+import (
+	"context"
+	shortenerv1 "shorted/api/proto/shortener/v1"
+	"shorted/internal/auth"
+	"shorted/internal/domain/models"
+	"shorted/internal/domain/repositories"
+	"shorted/internal/service/shortener"
+	"shorted/pkg/metrics"
+
+	"google.golang.org/grpc"
+)
+
+type Server struct {
+	shortenerv1.UnimplementedShortenerServiceServer
+	service *shortener.Service
+}
+
+// NewServer собирает gRPC-сервер поверх того же shortener.Service, что и HTTP.
+// Политика доступа та же, что у HTTP API: запрос без ключа выполняется
+// анонимно, неверный ключ получает Unauthenticated, а права на конкретную
+// ссылку проверяет сервис. Без authenticator (nil) все запросы анонимные.
+func NewServer(service *shortener.Service, authenticator auth.Authenticator, registry *metrics.Registry) *grpc.Server {
+	i := newInterceptors(authenticator, registry)
+	s := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(i.logUnary, i.metricsUnary, i.authUnary),
+		grpc.ChainStreamInterceptor(i.logStream, i.metricsStream, i.authStream),
+	)
+	shortenerv1.RegisterShortenerServiceServer(s, &Server{service: service})
+	return s
+}
+
+func (s *Server) Create(ctx context.Context, req *shortenerv1.CreateRequest) (*shortenerv1.Link, error) {
+	link, _, err := s.service.CreateShortURL(ctx, req.GetUrl(), shortener.CreateOptions{ForceNew: req.GetForceNew()})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProtoLink(link), nil
+}
+
+func (s *Server) Get(ctx context.Context, req *shortenerv1.GetRequest) (*shortenerv1.Link, error) {
+	link, err := s.service.GetLink(ctx, req.GetShortCode())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProtoLink(link), nil
+}
+
+func (s *Server) Resolve(ctx context.Context, req *shortenerv1.ResolveRequest) (*shortenerv1.ResolveResponse, error) {
+	res, err := s.service.Resolve(ctx, req.GetShortCode(), models.Click{
+		Referrer:       req.GetReferrer(),
+		UserAgent:      req.GetUserAgent(),
+		IP:             req.GetIp(),
+		AcceptLanguage: req.GetAcceptLanguage(),
+		Query:          req.GetQuery(),
+		Path:           req.GetPath(),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &shortenerv1.ResolveResponse{
+		OriginalUrl:  res.URL,
+		Link:         toProtoLink(res.Link),
+		Interstitial: res.Interstitial,
+		AppUrl:       res.AppURL,
+		AppTimeoutMs: int32(res.AppTimeout.Milliseconds()),
+	}, nil
+}
+
+func (s *Server) Update(ctx context.Context, req *shortenerv1.UpdateRequest) (*shortenerv1.Link, error) {
+	link, err := s.service.UpdateLink(ctx, req.GetShortCode(), req.GetUrl())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProtoLink(link), nil
+}
+
+func (s *Server) Delete(ctx context.Context, req *shortenerv1.DeleteRequest) (*shortenerv1.DeleteResponse, error) {
+	if err := s.service.DeleteLink(ctx, req.GetShortCode()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &shortenerv1.DeleteResponse{}, nil
+}
+
+func (s *Server) List(ctx context.Context, req *shortenerv1.ListRequest) (*shortenerv1.ListResponse, error) {
+	owner, _ := auth.PrincipalFrom(ctx)
+	links, next, err := s.service.ListLinks(ctx, repositories.ListOptions{
+		After:     req.GetPageToken(),
+		Limit:     int(req.GetPageSize()),
+		OwnerID:   owner.ID,
+		OwnerOnly: true,
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &shortenerv1.ListResponse{
+		Links:         make([]*shortenerv1.Link, 0, len(links)),
+		NextPageToken: next,
+	}
+	for _, link := range links {
+		resp.Links = append(resp.Links, toProtoLink(link))
+	}
+	return resp, nil
+}
+
+func (s *Server) Stats(ctx context.Context, req *shortenerv1.StatsRequest) (*shortenerv1.LinkStats, error) {
+	stats, err := s.service.Stats(ctx, req.GetShortCode())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &shortenerv1.LinkStats{
+		ShortCode:   stats.ShortCode,
+		Clicks:      stats.Clicks,
+		LastClickAt: stats.LastClickAt,
+		Rules:       stats.Rules,
+		Conversions: toProtoConversions(stats.Conversions),
+	}, nil
+}
+
+func (s *Server) WatchClicks(req *shortenerv1.WatchClicksRequest, stream grpc.ServerStreamingServer[shortenerv1.Click]) error {
+	ctx := stream.Context()
+	clicks, err := s.service.WatchClicks(ctx, req.GetShortCode())
+	if err != nil {
+		return toStatus(err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case click, ok := <-clicks:
+			if !ok {
+				return nil
+			}
+			if err := stream.Send(&shortenerv1.Click{
+				ShortCode: click.ShortCode,
+				At:        click.At,
+				Referrer:  click.Referrer,
+				UserAgent: click.UserAgent,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func toProtoLink(link *models.Link) *shortenerv1.Link {
+	return &shortenerv1.Link{
+		ShortCode:   link.ShortCode,
+		OriginalUrl: link.OriginalURL,
+		OwnerId:     link.OwnerID,
+		CreatedAt:   link.CreatedAt,
+		UpdatedAt:   link.UpdatedAt,
+	}
+}
+
+func toProtoConversions(stats []models.ConversionStats) []*shortenerv1.ConversionStats {
+	result := make([]*shortenerv1.ConversionStats, 0, len(stats))
+	for _, st := range stats {
+		result = append(result, &shortenerv1.ConversionStats{
+			Segment:     st.Segment,
+			Event:       st.Event,
+			Clicks:      st.Clicks,
+			Conversions: st.Conversions,
+			Value:       st.Value,
+			Rate:        st.Rate,
+		})
+	}
+	return result
+}
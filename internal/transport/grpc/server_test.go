@@ -0,0 +1,303 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	shortenerv1 "shorted/api/proto/shortener/v1"
+	"shorted/internal/auth"
+	"shorted/internal/domain/domainerr"
+	"shorted/internal/repository/memory"
+	"shorted/internal/service/shortener"
+	"shorted/pkg/metrics"
+	"shorted/pkg/tracing"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// startServer поднимает сервер на bufconn; ключ "alice-key" принадлежит alice,
+// "bob-key" — bob.
+func startServer(t *testing.T) (shortenerv1.ShortenerServiceClient, *metrics.Registry) {
+	t.Helper()
+	tracer := tracing.NewTracer(tracing.NeverSample(), nil)
+	service := shortener.NewService(memory.NewLinkRepo(), memory.NewStatsRepo(), tracer)
+	registry := metrics.NewRegistry()
+	server := NewServer(service, auth.ParseStaticKeys("alice-key:alice,bob-key:bob"), registry)
+
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return shortenerv1.NewShortenerServiceClient(conn), registry
+}
+
+func withKey(ctx context.Context, key string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+key)
+}
+
+func TestServerLinkLifecycle(t *testing.T) {
+	client, _ := startServer(t)
+	ctx := withKey(context.Background(), "alice-key")
+
+	created, err := client.Create(ctx, &shortenerv1.CreateRequest{Url: "https://example.com/a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.GetOwnerId() != "alice" || created.GetOriginalUrl() != "https://example.com/a" || created.GetShortCode() == "" {
+		t.Fatalf("created = %v", created)
+	}
+	code := created.GetShortCode()
+
+	resolved, err := client.Resolve(ctx, &shortenerv1.ResolveRequest{ShortCode: code})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolved.GetOriginalUrl() != "https://example.com/a" || resolved.GetInterstitial() {
+		t.Errorf("resolved = %v", resolved)
+	}
+
+	updated, err := client.Update(ctx, &shortenerv1.UpdateRequest{ShortCode: code, Url: "https://example.com/b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.GetOriginalUrl() != "https://example.com/b" {
+		t.Errorf("updated = %v", updated)
+	}
+
+	list, err := client.List(ctx, &shortenerv1.ListRequest{PageSize: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.GetLinks()) != 1 || list.GetLinks()[0].GetShortCode() != code {
+		t.Errorf("list = %v", list)
+	}
+	// список видит только ссылки вызывающего
+	for _, other := range []context.Context{withKey(context.Background(), "bob-key"), context.Background()} {
+		list, err := client.List(other, &shortenerv1.ListRequest{PageSize: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list.GetLinks()) != 0 {
+			t.Errorf("foreign list = %v", list)
+		}
+	}
+
+	stats, err := client.Stats(ctx, &shortenerv1.StatsRequest{ShortCode: code})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.GetClicks() != 1 {
+		t.Errorf("clicks = %d, want 1", stats.GetClicks())
+	}
+
+	if _, err := client.Delete(ctx, &shortenerv1.DeleteRequest{ShortCode: code}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Get(ctx, &shortenerv1.GetRequest{ShortCode: code})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("Get after Delete: %v", err)
+	}
+}
+
+func TestServerErrors(t *testing.T) {
+	client, registry := startServer(t)
+	created, err := client.Create(withKey(context.Background(), "alice-key"), &shortenerv1.CreateRequest{Url: "https://example.com/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := created.GetShortCode()
+	watch := func(ctx context.Context, code string) error {
+		stream, err := client.WatchClicks(ctx, &shortenerv1.WatchClicksRequest{ShortCode: code})
+		if err != nil {
+			return err
+		}
+		_, err = stream.Recv()
+		return err
+	}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		call func(ctx context.Context) error
+		want codes.Code
+	}{
+		{
+			name: "missing key",
+			ctx:  context.Background(),
+			call: func(ctx context.Context) error {
+				_, err := client.Update(ctx, &shortenerv1.UpdateRequest{ShortCode: code, Url: "https://example.com/b"})
+				return err
+			},
+			want: codes.Unauthenticated,
+		},
+		{
+			name: "wrong key",
+			ctx:  withKey(context.Background(), "mallory-key"),
+			call: func(ctx context.Context) error {
+				_, err := client.Get(ctx, &shortenerv1.GetRequest{ShortCode: "abc"})
+				return err
+			},
+			want: codes.Unauthenticated,
+		},
+		{
+			name: "unknown link",
+			ctx:  withKey(context.Background(), "alice-key"),
+			call: func(ctx context.Context) error {
+				_, err := client.Resolve(ctx, &shortenerv1.ResolveRequest{ShortCode: "missing"})
+				return err
+			},
+			want: codes.NotFound,
+		},
+		{
+			name: "invalid url",
+			ctx:  withKey(context.Background(), "alice-key"),
+			call: func(ctx context.Context) error {
+				_, err := client.Create(ctx, &shortenerv1.CreateRequest{Url: "not a url"})
+				return err
+			},
+			want: codes.InvalidArgument,
+		},
+		{
+			name: "stream without key",
+			ctx:  context.Background(),
+			call: func(ctx context.Context) error { return watch(ctx, code) },
+			want: codes.Unauthenticated,
+		},
+		{
+			name: "stream of another owner",
+			ctx:  withKey(context.Background(), "bob-key"),
+			call: func(ctx context.Context) error { return watch(ctx, code) },
+			want: codes.PermissionDenied,
+		},
+		{
+			name: "stream without code",
+			ctx:  withKey(context.Background(), "alice-key"),
+			call: func(ctx context.Context) error { return watch(ctx, "") },
+			want: codes.InvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := status.Code(tt.call(tt.ctx)); got != tt.want {
+				t.Errorf("code = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	var out bytes.Buffer
+	registry.Write(&out)
+	for _, want := range []string{
+		`grpc_server_handled_total{method="/shortener.v1.ShortenerService/Get",code="Unauthenticated"} 1`,
+		`grpc_server_handled_total{method="/shortener.v1.ShortenerService/Resolve",code="NotFound"} 1`,
+		`grpc_server_handling_seconds_count{method="/shortener.v1.ShortenerService/Create"} 2`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("metrics do not contain %s", want)
+		}
+	}
+}
+
+func TestWatchClicks(t *testing.T) {
+	client, _ := startServer(t)
+	ctx, cancel := context.WithTimeout(withKey(context.Background(), "alice-key"), 5*time.Second)
+	defer cancel()
+
+	created, err := client.Create(ctx, &shortenerv1.CreateRequest{Url: "https://example.com/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, err := client.WatchClicks(ctx, &shortenerv1.WatchClicksRequest{ShortCode: created.GetShortCode()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// подписка на сервере появляется не сразу, поэтому переходы повторяются до первого клика
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				client.Resolve(ctx, &shortenerv1.ResolveRequest{ShortCode: created.GetShortCode(), Referrer: "https://ref.example/"})
+			}
+		}
+	}()
+
+	click, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if click.GetShortCode() != created.GetShortCode() || click.GetReferrer() != "https://ref.example/" || click.GetAt() == 0 {
+		t.Errorf("click = %v", click)
+	}
+}
+
+func TestToStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		want       codes.Code
+		violations []string
+	}{
+		{"not found", domainerr.ErrLinkNotFound, codes.NotFound, nil},
+		{"gone", domainerr.ErrLinkDisabled, codes.NotFound, nil},
+		{"forbidden", domainerr.ErrForbidden, codes.PermissionDenied, nil},
+		{"conflict", domainerr.ErrLinkExists, codes.AlreadyExists, nil},
+		{"internal", errors.New("db down"), codes.Internal, nil},
+		{
+			"validation",
+			domainerr.Validation(domainerr.FieldError{Field: "url", Message: "bad"}, domainerr.FieldError{Field: "tags", Message: "too many"}),
+			codes.InvalidArgument,
+			[]string{"url: bad", "tags: too many"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := status.Convert(toStatus(tt.err))
+			if st.Code() != tt.want {
+				t.Errorf("code = %s, want %s", st.Code(), tt.want)
+			}
+			if tt.want == codes.Internal && strings.Contains(st.Message(), "db down") {
+				t.Errorf("internal cause leaked: %s", st.Message())
+			}
+
+			var got []string
+			for _, d := range st.Details() {
+				if br, ok := d.(*errdetails.BadRequest); ok {
+					for _, v := range br.GetFieldViolations() {
+						got = append(got, v.GetField()+": "+v.GetDescription())
+					}
+				}
+			}
+			if strings.Join(got, ", ") != strings.Join(tt.violations, ", ") {
+				t.Errorf("violations = %v, want %v", got, tt.violations)
+			}
+		})
+	}
+}
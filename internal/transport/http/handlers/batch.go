@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"shorted/internal/contract"
+	"shorted/internal/domain/domainerr"
+	"shorted/internal/domain/models"
+	"shorted/internal/service/shortener"
+	"shorted/pkg/apirequest"
+	"shorted/pkg/metrics"
+	"shorted/pkg/tracing"
+	"strings"
+)
+
+// MaxBatchBodySize ограничивает тело пачки в любом формате.
+const MaxBatchBodySize = 32 << 20
+
+type BatchHandler struct {
+	service  *shortener.Service
+	request  contract.RequestDecoder
+	response contract.ResponseWriter
+	errors   contract.ErrorWriter
+	items    *metrics.CounterVec
+}
+
+func NewBatchHandler(service *shortener.Service, request contract.RequestDecoder, response contract.ResponseWriter, errs contract.ErrorWriter, registry *metrics.Registry) *BatchHandler {
+	return &BatchHandler{
+		service:  service,
+		request:  request,
+		response: response,
+		errors:   errs,
+		items:    registry.NewCounterVec("shortener_batch_items_total", "Number of batch items processed by result.", "result"),
+	}
+}
+
+// batchItem принимает те же поля, что и POST /api/shorten, кроме force_new:
+// пачка всегда выдаёт новые коды.
+type batchItem struct {
+	URL        string             `json:"url"`
+	Routing    *models.Routing    `json:"routing,omitempty"`
+	Forwarding *models.Forwarding `json:"forwarding,omitempty"`
+	Tags       []string           `json:"tags,omitempty"`
+	Campaigns  []string           `json:"campaigns,omitempty"`
+}
+
+type batchItemResult struct {
+	Index       int             `json:"index"`
+	Status      string          `json:"status"`
+	ShortCode   string          `json:"short_code,omitempty"`
+	ShortURL    string          `json:"short_url,omitempty"`
+	OriginalURL string          `json:"original_url,omitempty"`
+	Error       *batchItemError `json:"error,omitempty"`
+}
+
+type batchItemError struct {
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Details []domainerr.FieldError `json:"details,omitempty"`
+}
+
+type batchResponse struct {
+	Created int               `json:"created"`
+	Failed  int               `json:"failed"`
+	Items   []batchItemResult `json:"items"`
+}
+
+type batchJobResponse struct {
+	JobID      string         `json:"job_id"`
+	Status     string         `json:"status"`
+	Total      int            `json:"total"`
+	Processed  int            `json:"processed"`
+	CreatedAt  int64          `json:"created_at"`
+	FinishedAt int64          `json:"finished_at,omitempty"`
+	StatusURL  string         `json:"status_url"`
+	Result     *batchResponse `json:"result,omitempty"`
+}
+
+// CreateBatch принимает JSON-массив элементов как у POST /api/shorten, CSV
+// (text/csv или файл file в multipart/form-data) с URL в первой колонке. Пачки больше shortener.MaxBatchSize
+// и запросы с ?async=true обрабатываются в фоне: ответ 202 со ссылкой на задачу.
+func (h *BatchHandler) CreateBatch(w http.ResponseWriter, r *http.Request) {
+	items, err := h.readItems(w, r)
+	if err != nil {
+		h.errors.WriteErr(w, r, err)
+		return
+	}
+
+	if r.URL.Query().Get("async") == "true" || len(items) > shortener.MaxBatchSize {
+		job, err := h.service.StartBatch(r.Context(), items, func(job *shortener.BatchJob) {
+			h.count(job.Results)
+		})
+		if err != nil {
+			h.errors.WriteErr(w, r, err)
+			return
+		}
+
+		resp := toBatchJobResponse(r, job)
+		w.Header().Set("Location", resp.StatusURL)
+		h.response.Write(w, r, http.StatusAccepted, resp)
+		return
+	}
+
+	results, err := h.service.CreateShortURLs(r.Context(), items)
+	if err != nil {
+		h.errors.WriteErr(w, r, err)
+		return
+	}
+	h.count(results)
+
+	h.response.Write(w, r, http.StatusOK, toBatchResponse(r, results))
+}
+
+func (h *BatchHandler) Job(w http.ResponseWriter, r *http.Request) {
+	job, err := h.service.BatchJob(r.Context(), r.PathValue("id"))
+	if err != nil {
+		h.errors.WriteErr(w, r, err)
+		return
+	}
+
+	h.response.Write(w, r, http.StatusOK, toBatchJobResponse(r, job))
+}
+
+func (h *BatchHandler) readItems(w http.ResponseWriter, r *http.Request) ([]shortener.BatchItem, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/csv":
+		return urlItems(readCSV(http.MaxBytesReader(w, r.Body, MaxBatchBodySize)))
+	case "multipart/form-data":
+		r.Body = http.MaxBytesReader(w, r.Body, MaxBatchBodySize)
+		file, _, err := r.FormFile("file")
+		if errors.As(err, new(*http.MaxBytesError)) {
+			return nil, domainerr.ErrBodyTooLarge.WithCause(err)
+		}
+		if err != nil {
+			return nil, domainerr.ErrInvalidBody.WithMessage("multipart upload must contain a CSV file in the file field").WithCause(err)
+		}
+		defer file.Close()
+		return urlItems(readCSV(file))
+	}
+
+	var req []batchItem
+	if err := h.request.Decode(r, &req); err != nil {
+		return nil, err
+	}
+	items := make([]shortener.BatchItem, len(req))
+	for i, item := range req {
+		items[i] = shortener.BatchItem{URL: item.URL, CreateOptions: shortener.CreateOptions{
+			Routing:    item.Routing,
+			Forwarding: item.Forwarding,
+			Tags:       item.Tags,
+			Campaigns:  item.Campaigns,
+		}}
+	}
+	return items, nil
+}
+
+// urlItems превращает адреса из CSV в элементы пачки без настроек.
+func urlItems(urls []string, err error) ([]shortener.BatchItem, error) {
+	if err != nil {
+		return nil, err
+	}
+	items := make([]shortener.BatchItem, len(urls))
+	for i, u := range urls {
+		items[i] = shortener.BatchItem{URL: u}
+	}
+	return items, nil
+}
+
+// readCSV берёт URL из первой колонки, пропуская пустые строки и заголовок "url".
+func readCSV(r io.Reader) ([]string, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	var urls []string
+	for first := true; ; first = false {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return urls, nil
+		}
+		if err != nil {
+			return nil, apirequest.BodyError(err)
+		}
+
+		value := strings.TrimSpace(record[0])
+		if value == "" || (first && strings.EqualFold(value, "url")) {
+			continue
+		}
+		urls = append(urls, value)
+	}
+}
+
+func (h *BatchHandler) count(results []shortener.BatchResult) {
+	for _, res := range results {
+		if res.Err != nil {
+			h.items.WithLabelValues("failed").Inc()
+		} else {
+			h.items.WithLabelValues("created").Inc()
+		}
+	}
+}
+
+func toBatchResponse(r *http.Request, results []shortener.BatchResult) *batchResponse {
+	resp := &batchResponse{Items: make([]batchItemResult, len(results))}
+	for i, res := range results {
+		item := batchItemResult{Index: i}
+		if res.Err != nil {
+			resp.Failed++
+			item.Status = "failed"
+			item.Error = toBatchItemError(r, res.Err)
+		} else {
+			resp.Created++
+			item.Status = "created"
+			item.ShortCode = res.Link.ShortCode
+			item.ShortURL = shortURL(r, res.Link.ShortCode)
+			item.OriginalURL = res.Link.OriginalURL
+		}
+		resp.Items[i] = item
+	}
+	return resp
+}
+
+func toBatchItemError(r *http.Request, err error) *batchItemError {
+	de := domainerr.From(err)
+	if de.Kind == domainerr.KindInternal {
+		log.Printf("batch item: %v trace_id=%s", err, tracing.TraceIDFromContext(r.Context()))
+	}
+	return &batchItemError{Code: de.Code, Message: de.Message, Details: de.Fields}
+}
+
+func toBatchJobResponse(r *http.Request, job *shortener.BatchJob) *batchJobResponse {
+	resp := &batchJobResponse{
+		JobID:      job.ID,
+		Status:     string(job.Status),
+		Total:      job.Total,
+		Processed:  job.Processed,
+		CreatedAt:  job.CreatedAt,
+		FinishedAt: job.FinishedAt,
+		StatusURL:  "/api/jobs/" + job.ID,
+	}
+	if job.Status == shortener.JobDone {
+		resp.Result = toBatchResponse(r, job.Results)
+	}
+	return resp
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"shorted/internal/repository/memory"
+	"shorted/internal/service/shortener"
+	"shorted/pkg/apierror"
+	"shorted/pkg/apirequest"
+	"shorted/pkg/apiresponse"
+	"shorted/pkg/codec"
+	"shorted/pkg/metrics"
+	"shorted/pkg/tracing"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestService(opts ...shortener.Option) *shortener.Service {
+	return shortener.NewService(memory.NewLinkRepo(), memory.NewStatsRepo(), tracing.NewTracer(tracing.NeverSample(), nil), opts...)
+}
+
+func newBatchHandler(service *shortener.Service) *BatchHandler {
+	codecs := codec.Default()
+	errs := apierror.New(apierror.WithCodecs(codecs))
+	return NewBatchHandler(service, apirequest.New(codecs), apiresponse.New(codecs, errs), errs, metrics.NewRegistry())
+}
+
+func decodeJSON[T any](t *testing.T, rec *httptest.ResponseRecorder) T {
+	t.Helper()
+	var v T
+	if err := json.Unmarshal(rec.Body.Bytes(), &v); err != nil {
+		t.Fatalf("decode %s: %v", rec.Body, err)
+	}
+	return v
+}
+
+func TestReadCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    []string
+		wantErr bool
+	}{
+		{name: "plain", in: "https://a.example/\nhttps://b.example/\n", want: []string{"https://a.example/", "https://b.example/"}},
+		{name: "header and extra columns", in: "URL,note\nhttps://a.example/,first\n", want: []string{"https://a.example/"}},
+		{name: "header only on the first line", in: "https://a.example/\nurl\n", want: []string{"https://a.example/", "url"}},
+		{name: "blank lines and spaces", in: "\n  https://a.example/  \n,\n", want: []string{"https://a.example/"}},
+		{name: "quoted comma", in: "\"https://a.example/?q=a,b\"\n", want: []string{"https://a.example/?q=a,b"}},
+		{name: "broken quotes", in: "\"https://a.example/\n", wantErr: true},
+		{name: "empty", in: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readCSV(strings.NewReader(tt.in))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v", err)
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateBatch(t *testing.T) {
+	multipartBody := func() (string, []byte) {
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		fw, _ := mw.CreateFormFile("file", "links.csv")
+		fw.Write([]byte("url\nhttps://a.example/\nbad\n"))
+		mw.Close()
+		return mw.FormDataContentType(), buf.Bytes()
+	}
+	multipartType, multipartData := multipartBody()
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantStatus  int
+		wantCreated int
+		wantFailed  int
+		wantError   string
+	}{
+		{"json", "application/json", `[{"url":"https://a.example/"},{"url":"https://b.example/"},{"url":"bad"}]`, http.StatusOK, 2, 1, ""},
+		{"json with options", "application/json", `[{"url":"https://a.example/","tags":["promo"]},{"url":"https://b.example/","tags":["bad tag!"]}]`, http.StatusOK, 1, 1, ""},
+		{"csv", "text/csv", "https://a.example/\nftp://b.example/\n", http.StatusOK, 1, 1, ""},
+		{"multipart", multipartType, string(multipartData), http.StatusOK, 1, 1, ""},
+		{"multipart without file", "multipart/form-data; boundary=x", "--x--\r\n", http.StatusBadRequest, 0, 0, "invalid_body"},
+		{"empty", "application/json", `[]`, http.StatusBadRequest, 0, 0, "batch_empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/links/batch", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			rec := httptest.NewRecorder()
+			newBatchHandler(newTestService()).CreateBatch(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantError != "" {
+				if got := decodeJSON[struct{ Error string }](t, rec).Error; got != tt.wantError {
+					t.Errorf("error = %s, want %s", got, tt.wantError)
+				}
+				return
+			}
+			resp := decodeJSON[batchResponse](t, rec)
+			if resp.Created != tt.wantCreated || resp.Failed != tt.wantFailed {
+				t.Errorf("created/failed = %d/%d, want %d/%d", resp.Created, resp.Failed, tt.wantCreated, tt.wantFailed)
+			}
+			for i, item := range resp.Items {
+				if item.Index != i {
+					t.Errorf("item %d has index %d", i, item.Index)
+				}
+				if item.Status == "created" && item.ShortURL != "http://example.com/"+item.ShortCode {
+					t.Errorf("short_url = %s", item.ShortURL)
+				}
+				if item.Status == "failed" && (item.Error == nil || item.Error.Code == "") {
+					t.Errorf("failed item without error: %+v", item)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateBatchAsync(t *testing.T) {
+	h := newBatchHandler(newTestService())
+	req := httptest.NewRequest(http.MethodPost, "/api/links/batch?async=true", strings.NewReader(`[{"url":"https://a.example/"},{"url":"bad"}]`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h.CreateBatch(rec, req)
+
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	job := decodeJSON[batchJobResponse](t, rec)
+	if loc := rec.Header().Get("Location"); loc != "/api/jobs/"+job.JobID || job.StatusURL != loc {
+		t.Errorf("Location = %s, status_url = %s", loc, job.StatusURL)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for job.Status != string(shortener.JobDone) {
+		if time.Now().After(deadline) {
+			t.Fatalf("job stuck in %s", job.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		req := httptest.NewRequest(http.MethodGet, job.StatusURL, nil)
+		req.SetPathValue("id", job.JobID)
+		rec := httptest.NewRecorder()
+		h.Job(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("job status = %d: %s", rec.Code, rec.Body)
+		}
+		job = decodeJSON[batchJobResponse](t, rec)
+	}
+	if job.Result == nil || job.Result.Created != 1 || job.Result.Failed != 1 || job.Processed != 2 {
+		t.Errorf("job = %+v, result = %+v", job, job.Result)
+	}
+}
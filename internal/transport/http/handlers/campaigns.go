@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"net/http"
+	"shorted/internal/contract"
+	"shorted/internal/domain/models"
+	"shorted/internal/service/shortener"
+)
+
+// CampaignHandler — API кампаний, объединяющих ссылки владельца.
+type CampaignHandler struct {
+	service  *shortener.Service
+	request  contract.RequestDecoder
+	response contract.ResponseWriter
+	errors   contract.ErrorWriter
+}
+
+func NewCampaignHandler(service *shortener.Service, request contract.RequestDecoder, response contract.ResponseWriter, errs contract.ErrorWriter) *CampaignHandler {
+	return &CampaignHandler{service: service, request: request, response: response, errors: errs}
+}
+
+type campaignRequest struct {
+	Name        *string `json:"name,omitempty"`
+	Description *string `json:"description,omitempty"`
+}
+
+type campaignsResponse struct {
+	Campaigns []*models.Campaign `json:"campaigns"`
+}
+
+func (h *CampaignHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req campaignRequest
+	if err := h.request.Decode(r, &req); err != nil {
+		h.errors.WriteErr(w, r, err)
+		return
+	}
+
+	campaign, err := h.service.CreateCampaign(r.Context(), shortener.CampaignInput{Name: req.Name, Description: req.Description})
+	if err != nil {
+		h.errors.WriteErr(w, r, err)
+		return
+	}
+	h.response.Write(w, r, http.StatusCreated, campaign)
+}
+
+func (h *CampaignHandler) List(w http.ResponseWriter, r *http.Request) {
+	campaigns, err := h.service.ListCampaigns(r.Context())
+	if err != nil {
+		h.errors.WriteErr(w, r, err)
+		return
+	}
+	if campaigns == nil {
+		campaigns = []*models.Campaign{}
+	}
+	h.response.Write(w, r, http.StatusOK, campaignsResponse{Campaigns: campaigns})
+}
+
+func (h *CampaignHandler) Get(w http.ResponseWriter, r *http.Request) {
+	campaign, err := h.service.GetCampaign(r.Context(), r.PathValue("id"))
+	if err != nil {
+		h.errors.WriteErr(w, r, err)
+		return
+	}
+	h.response.Write(w, r, http.StatusOK, campaign)
+}
+
+func (h *CampaignHandler) Update(w http.ResponseWriter, r *http.Request) {
+	var req campaignRequest
+	if err := h.request.Decode(r, &req); err != nil {
+		h.errors.WriteErr(w, r, err)
+		return
+	}
+
+	campaign, err := h.service.UpdateCampaign(r.Context(), r.PathValue("id"), shortener.CampaignInput{Name: req.Name, Description: req.Description})
+	if err != nil {
+		h.errors.WriteErr(w, r, err)
+		return
+	}
+	h.response.Write(w, r, http.StatusOK, campaign)
+}
+
+func (h *CampaignHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.DeleteCampaign(r.Context(), r.PathValue("id")); err != nil {
+		h.errors.WriteErr(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *CampaignHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.service.CampaignStats(r.Context(), r.PathValue("id"))
+	if err != nil {
+		h.errors.WriteErr(w, r, err)
+		return
+	}
+	h.response.Write(w, r, http.StatusOK, stats)
+}
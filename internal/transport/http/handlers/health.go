@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"net/http"
+	"shorted/internal/contract"
+	"shorted/pkg/health"
+)
+
+type HealthHandler struct {
+	checker  *health.Checker
+	response contract.ResponseWriter
+}
+
+func NewHealthHandler(checker *health.Checker, response contract.ResponseWriter) *HealthHandler {
+	return &HealthHandler{checker: checker, response: response}
+}
+
+func (h *HealthHandler) Liveness(w http.ResponseWriter, r *http.Request) {
+	h.response.Write(w, r, http.StatusOK, health.Report{Status: health.StatusUp})
+}
+
+func (h *HealthHandler) Readiness(w http.ResponseWriter, r *http.Request) {
+	report := h.checker.Ready(r.Context())
+
+	status := http.StatusOK
+	if report.Status != health.StatusUp {
+		status = http.StatusServiceUnavailable
+	}
+
+	h.response.Write(w, r, status, report)
+}
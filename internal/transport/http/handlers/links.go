@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"net/http"
+	"shorted/internal/auth"
+	"shorted/internal/domain/models"
+	"shorted/internal/domain/repositories"
+	"shorted/internal/service/shortener"
+	"strconv"
+)
+
+type linksResponse struct {
+	Links []*models.Link `json:"links"`
+	Next  string         `json:"next,omitempty"`
+}
+
+type searchResponse struct {
+	Results []models.SearchResult `json:"results"`
+}
+
+type tagsResponse struct {
+	Tags []models.TagCount `json:"tags"`
+}
+
+type editTagsRequest struct {
+	ShortCodes []string `json:"short_codes"`
+	Add        []string `json:"add,omitempty"`
+	Remove     []string `json:"remove,omitempty"`
+}
+
+type setLinkCampaignsRequest struct {
+	Campaigns []string `json:"campaigns"`
+}
+
+// ListLinks отдаёт ссылки владельца запроса; анонимный запрос видит ссылки,
+// созданные без ключа.
+func (h *ShortenerHandler) ListLinks(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	owner, _ := auth.PrincipalFrom(r.Context())
+
+	links, next, err := h.service.ListLinks(r.Context(), repositories.ListOptions{
+		After:     query.Get("after"),
+		Limit:     limit,
+		OwnerID:   owner.ID,
+		OwnerOnly: true,
+		Tag:       query.Get("tag"),
+		Campaign:  query.Get("campaign"),
+	})
+	if err != nil {
+		h.errors.WriteErr(w, r, err)
+		return
+	}
+	if links == nil {
+		links = []*models.Link{}
+	}
+	h.response.Write(w, r, http.StatusOK, linksResponse{Links: links, Next: next})
+}
+
+// SearchLinks ищет по ссылкам владельца запроса, лучшие совпадения первыми.
+func (h *ShortenerHandler) SearchLinks(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+
+	results, err := h.service.Search(r.Context(), query.Get("q"), limit)
+	if err != nil {
+		h.errors.WriteErr(w, r, err)
+		return
+	}
+	h.response.Write(w, r, http.StatusOK, searchResponse{Results: results})
+}
+
+// Tags отдаёт метки ссылок владельца запроса.
+func (h *ShortenerHandler) Tags(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.service.Tags(r.Context())
+	if err != nil {
+		h.errors.WriteErr(w, r, err)
+		return
+	}
+	if tags == nil {
+		tags = []models.TagCount{}
+	}
+	h.response.Write(w, r, http.StatusOK, tagsResponse{Tags: tags})
+}
+
+// EditTags добавляет и убирает метки сразу у многих ссылок.
+func (h *ShortenerHandler) EditTags(w http.ResponseWriter, r *http.Request) {
+	var req editTagsRequest
+	if err := h.request.Decode(r, &req); err != nil {
+		h.errors.WriteErr(w, r, err)
+		return
+	}
+
+	err := h.service.EditTags(r.Context(), shortener.TagEdit{
+		ShortCodes: req.ShortCodes,
+		Add:        req.Add,
+		Remove:     req.Remove,
+	})
+	if err != nil {
+		h.errors.WriteErr(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetLinkCampaigns заменяет кампании ссылки.
+func (h *ShortenerHandler) SetLinkCampaigns(w http.ResponseWriter, r *http.Request) {
+	var req setLinkCampaignsRequest
+	if err := h.request.Decode(r, &req); err != nil {
+		h.errors.WriteErr(w, r, err)
+		return
+	}
+
+	link, err := h.service.SetLinkCampaigns(r.Context(), r.PathValue("code"), req.Campaigns)
+	if err != nil {
+		h.errors.WriteErr(w, r, err)
+		return
+	}
+	h.response.Write(w, r, http.StatusOK, link)
+}
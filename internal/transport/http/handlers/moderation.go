@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"mime"
+	"net"
+	"net/http"
+	"shorted/internal/contract"
+	"shorted/internal/domain/domainerr"
+	"shorted/internal/domain/models"
+	"shorted/internal/domain/repositories"
+	"shorted/internal/service/moderation"
+	"shorted/pkg/apirequest"
+	"shorted/pkg/metrics"
+	"strconv"
+)
+
+// maxReportFormSize — предел тела HTML-формы жалобы.
+const maxReportFormSize = 64 << 10
+
+type ModerationHandler struct {
+	service  *moderation.Service
+	request  contract.RequestDecoder
+	response contract.ResponseWriter
+	errors   contract.ErrorWriter
+	reports  *metrics.CounterVec
+}
+
+func NewModerationHandler(service *moderation.Service, request contract.RequestDecoder, response contract.ResponseWriter, errs contract.ErrorWriter, registry *metrics.Registry) *ModerationHandler {
+	return &ModerationHandler{
+		service:  service,
+		request:  request,
+		response: response,
+		errors:   errs,
+		reports:  registry.NewCounterVec("shortener_link_reports_total", "Number of abuse reports received by reason.", "reason"),
+	}
+}
+
+type reportRequest struct {
+	Reason  string `json:"reason"`
+	Comment string `json:"comment,omitempty"`
+}
+
+type reportsResponse struct {
+	Reports []*models.Report `json:"reports"`
+	Next    string           `json:"next,omitempty"`
+}
+
+type updateReportRequest struct {
+	State models.ReportState `json:"state"`
+	Note  string             `json:"note,omitempty"`
+}
+
+type disableLinkRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ReportForm отдаёт HTML-форму жалобы для тех, кто пришёл по ссылке из браузера.
+func (h *ModerationHandler) ReportForm(w http.ResponseWriter, r *http.Request) {
+	writePage(w, http.StatusOK, reportPage, reportPageData{Code: r.PathValue("code"), Reasons: moderation.Reasons})
+}
+
+// Report принимает жалобу как JSON от API-клиентов или как отправленную HTML-форму;
+// форме в ответ приходит страница, API — созданная жалоба.
+func (h *ModerationHandler) Report(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	form := contentType == "application/x-www-form-urlencoded"
+
+	var req reportRequest
+	if form {
+		r.Body = http.MaxBytesReader(w, r.Body, maxReportFormSize)
+		if err := r.ParseForm(); err != nil {
+			h.errors.WriteErr(w, r, apirequest.BodyError(err))
+			return
+		}
+		req.Reason, req.Comment = r.PostForm.Get("reason"), r.PostForm.Get("comment")
+	} else if err := h.request.Decode(r, &req); err != nil {
+		h.errors.WriteErr(w, r, err)
+		return
+	}
+
+	report, err := h.service.Report(r.Context(), code, moderation.ReportInput{
+		Reason:   req.Reason,
+		Comment:  req.Comment,
+		Reporter: clientIP(r),
+	})
+	if form && domainerr.KindOf(err) == domainerr.KindInvalid {
+		writePage(w, http.StatusBadRequest, reportPage, reportPageData{
+			Code:    code,
+			Reasons: moderation.Reasons,
+			Error:   "Please choose what is wrong with the link and keep the details under 2000 characters.",
+		})
+		return
+	}
+	if err != nil {
+		h.errors.WriteErr(w, r, err)
+		return
+	}
+	h.reports.WithLabelValues(report.Reason).Inc()
+
+	if form {
+		writePage(w, http.StatusCreated, reportedPage, reportPageData{Code: code})
+		return
+	}
+	h.response.Write(w, r, http.StatusCreated, report)
+}
+
+func (h *ModerationHandler) Reports(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+
+	reports, next, err := h.service.Reports(r.Context(), repositories.ReportListOptions{
+		After:     query.Get("after"),
+		Limit:     limit,
+		State:     models.ReportState(query.Get("state")),
+		ShortCode: query.Get("code"),
+	})
+	if err != nil {
+		h.errors.WriteErr(w, r, err)
+		return
+	}
+	if reports == nil {
+		reports = []*models.Report{}
+	}
+	h.response.Write(w, r, http.StatusOK, reportsResponse{Reports: reports, Next: next})
+}
+
+func (h *ModerationHandler) UpdateReport(w http.ResponseWriter, r *http.Request) {
+	var req updateReportRequest
+	if err := h.request.Decode(r, &req); err != nil {
+		h.errors.WriteErr(w, r, err)
+		return
+	}
+
+	report, err := h.service.UpdateReport(r.Context(), r.PathValue("id"), req.State, req.Note)
+	if err != nil {
+		h.errors.WriteErr(w, r, err)
+		return
+	}
+	h.response.Write(w, r, http.StatusOK, report)
+}
+
+func (h *ModerationHandler) DisableLink(w http.ResponseWriter, r *http.Request) {
+	var req disableLinkRequest
+	if err := h.request.Decode(r, &req); err != nil {
+		h.errors.WriteErr(w, r, err)
+		return
+	}
+
+	link, err := h.service.DisableLink(r.Context(), r.PathValue("code"), req.Reason)
+	if err != nil {
+		h.errors.WriteErr(w, r, err)
+		return
+	}
+	h.response.Write(w, r, http.StatusOK, link)
+}
+
+func (h *ModerationHandler) EnableLink(w http.ResponseWriter, r *http.Request) {
+	link, err := h.service.EnableLink(r.Context(), r.PathValue("code"))
+	if err != nil {
+		h.errors.WriteErr(w, r, err)
+		return
+	}
+	h.response.Write(w, r, http.StatusOK, link)
+}
+
+// clientIP — адрес клиента. За прокси RemoteAddr заранее подменяет
+// middleware.RealIP, если прокси указан в доверенных.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+)
+
+// Страницы для браузера: предупреждение перед переходом, переход в приложение
+// и отключённая ссылка.
+var (
+	interstitialPage = template.Must(template.New("interstitial").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<title>Warning: suspicious link</title>
+</head>
+<body>
+<h1>This link may be unsafe</h1>
+<p>The short link you followed leads to a site that has been flagged as potentially harmful.</p>
+<p>Destination: <code>{{.Destination}}</code></p>
+<p><a href="{{.URL}}" rel="noreferrer nofollow">Continue to the site</a></p>
+<p><a href="/{{.Code}}+">More about this link</a> · <a href="/{{.Code}}/report">Report this link</a></p>
+</body>
+</html>
+`))
+
+	appPage = template.Must(template.New("app").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Opening the app</title>
+</head>
+<body>
+<h1>Opening the app…</h1>
+<p>If nothing happens, <a href="{{.AppHref}}">open the app</a> or <a href="{{.URL}}" rel="noreferrer">continue in the browser</a>.</p>
+<script>
+(function () {
+  var fallback = setTimeout(function () { window.location.replace({{.Fallback}}); }, {{.TimeoutMs}});
+  document.addEventListener("visibilitychange", function () {
+    if (document.hidden) { clearTimeout(fallback); }
+  });
+  window.location.href = {{.AppURL}};
+})();
+</script>
+</body>
+</html>
+`))
+
+	disabledPage = template.Must(template.New("disabled").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<title>Link disabled</title>
+</head>
+<body>
+<h1>This link has been disabled</h1>
+{{if .Reason}}<p>{{.Reason}}</p>
+{{else}}<p>The short link you followed pointed to a site that has been reported as harmful, so it no longer redirects.</p>
+{{end}}</body>
+</html>
+`))
+
+	reportPage = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<title>Report a link</title>
+</head>
+<body>
+<h1>Report /{{.Code}}</h1>
+{{if .Error}}<p role="alert">{{.Error}}</p>
+{{end}}<form method="post" action="/{{.Code}}/report">
+<p><label for="reason">What is wrong with this link?</label>
+<select id="reason" name="reason" required>
+{{range .Reasons}}<option value="{{.}}">{{.}}</option>
+{{end}}</select></p>
+<p><label for="comment">Details (optional)</label><br>
+<textarea id="comment" name="comment" rows="5" cols="60" maxlength="2000"></textarea></p>
+<p><button type="submit">Send report</button></p>
+</form>
+</body>
+</html>
+`))
+
+	reportedPage = template.Must(template.New("reported").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<title>Report received</title>
+</head>
+<body>
+<h1>Thank you</h1>
+<p>Your report about /{{.Code}} has been received and will be reviewed.</p>
+</body>
+</html>
+`))
+)
+
+type interstitialData struct {
+	Code        string
+	Destination string
+	// URL — обычная строка: адрес задаёт пользователь, и html/template сам
+	// заменит небезопасную схему в href.
+	URL string
+}
+
+// appData: строковые поля попадают в скрипт и экранируются как строки JS. Схему
+// AppHref шаблон сам бы вырезал, поэтому она template.URL: её проверили при
+// сохранении правил. Обычный адрес URL остаётся строкой и проходит фильтр шаблона.
+type appData struct {
+	AppURL    string
+	Fallback  string
+	TimeoutMs int64
+	AppHref   template.URL
+	URL       string
+}
+
+type disabledData struct {
+	Reason string
+}
+
+type reportPageData struct {
+	Code    string
+	Reasons []string
+	Error   string
+}
+
+func writePage(w http.ResponseWriter, status int, page *template.Template, data any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.WriteHeader(status)
+	if err := page.Execute(w, data); err != nil {
+		log.Printf("render %s page: %v", page.Name(), err)
+	}
+}
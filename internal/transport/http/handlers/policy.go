@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"shorted/internal/contract"
+	"shorted/internal/domain/domainerr"
+	"shorted/internal/service/shortener"
+	"shorted/pkg/urlpolicy"
+)
+
+// PolicyHandler — административный API списков блокировки.
+type PolicyHandler struct {
+	policy   *urlpolicy.Engine
+	service  *shortener.Service
+	request  contract.RequestDecoder
+	response contract.ResponseWriter
+	errors   contract.ErrorWriter
+}
+
+func NewPolicyHandler(policy *urlpolicy.Engine, service *shortener.Service, request contract.RequestDecoder, response contract.ResponseWriter, errs contract.ErrorWriter) *PolicyHandler {
+	return &PolicyHandler{policy: policy, service: service, request: request, response: response, errors: errs}
+}
+
+type policyEntriesResponse struct {
+	Entries []urlpolicy.Entry `json:"entries"`
+}
+
+type addPolicyEntryRequest struct {
+	Pattern string            `json:"pattern"`
+	Verdict urlpolicy.Verdict `json:"verdict,omitempty"`
+	Reason  string            `json:"reason,omitempty"`
+}
+
+type checkURLRequest struct {
+	URL string `json:"url"`
+}
+
+type checkURLResponse struct {
+	Verdict urlpolicy.Verdict `json:"verdict"`
+	Entry   *urlpolicy.Entry  `json:"entry,omitempty"`
+}
+
+func (h *PolicyHandler) Entries(w http.ResponseWriter, r *http.Request) {
+	h.response.Write(w, r, http.StatusOK, policyEntriesResponse{Entries: h.policy.Entries()})
+}
+
+func (h *PolicyHandler) AddEntry(w http.ResponseWriter, r *http.Request) {
+	var req addPolicyEntryRequest
+	if err := h.request.Decode(r, &req); err != nil {
+		h.errors.WriteErr(w, r, err)
+		return
+	}
+
+	entry, err := h.policy.Add(req.Pattern, req.Verdict, req.Reason)
+	if err != nil {
+		h.errors.WriteErr(w, r, policyError(err))
+		return
+	}
+	h.response.Write(w, r, http.StatusCreated, entry)
+}
+
+func (h *PolicyHandler) DeleteEntry(w http.ResponseWriter, r *http.Request) {
+	if err := h.policy.Remove(r.PathValue("id")); err != nil {
+		h.errors.WriteErr(w, r, policyError(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *PolicyHandler) Check(w http.ResponseWriter, r *http.Request) {
+	var req checkURLRequest
+	if err := h.request.Decode(r, &req); err != nil {
+		h.errors.WriteErr(w, r, err)
+		return
+	}
+
+	decision, err := h.service.CheckURL(r.Context(), req.URL)
+	if err != nil {
+		h.errors.WriteErr(w, r, err)
+		return
+	}
+	h.response.Write(w, r, http.StatusOK, checkURLResponse{Verdict: decision.Verdict, Entry: decision.Entry})
+}
+
+// Reload перечитывает файлы правил, не дожидаясь очередной проверки изменений.
+func (h *PolicyHandler) Reload(w http.ResponseWriter, r *http.Request) {
+	if err := h.policy.Reload(); err != nil {
+		h.errors.WriteErr(w, r, err)
+		return
+	}
+	h.response.Write(w, r, http.StatusOK, policyEntriesResponse{Entries: h.policy.Entries()})
+}
+
+func policyError(err error) error {
+	switch {
+	case errors.Is(err, urlpolicy.ErrInvalidEntry):
+		return domainerr.Validation(domainerr.FieldError{Field: "pattern", Code: "invalid_pattern", Message: err.Error()})
+	case errors.Is(err, urlpolicy.ErrEntryNotFound):
+		return domainerr.ErrPolicyEntryNotFound
+	case errors.Is(err, urlpolicy.ErrReadOnly):
+		return domainerr.ErrPolicyEntryReadOnly
+	default:
+		return err
+	}
+}
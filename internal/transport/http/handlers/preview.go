@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"html/template"
+	"mime"
+	"net/http"
+	"shorted/pkg/urlpolicy"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Состояния ссылки на странице предпросмотра.
+const (
+	previewActive   = "active"
+	previewWarning  = "warning"
+	previewDisabled = "disabled"
+)
+
+type previewResponse struct {
+	ShortCode string `json:"short_code"`
+	ShortURL  string `json:"short_url"`
+	// OriginalURL не показывается у отключённых ссылок, чтобы предпросмотр не раздавал
+	// заблокированный адрес.
+	OriginalURL    string `json:"original_url,omitempty"`
+	CreatedAt      int64  `json:"created_at"`
+	Clicks         int64  `json:"clicks"`
+	Status         string `json:"status"`
+	DisabledReason string `json:"disabled_reason,omitempty"`
+	// Заголовок, описание и картинка страницы назначения, если их уже загрузили.
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Image       string `json:"image,omitempty"`
+}
+
+// previewData: адрес назначения попадает в href обычной строкой, и html/template
+// сам заменит небезопасную схему.
+type previewData struct {
+	previewResponse
+	Created string
+}
+
+var previewPage = template.Must(template.New("preview").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<title>Preview of /{{.ShortCode}}</title>
+</head>
+<body>
+<h1>Where does /{{.ShortCode}} go?</h1>
+{{if eq .Status "disabled"}}<p>This link has been disabled{{if .DisabledReason}}: {{.DisabledReason}}{{end}}</p>
+{{else}}{{if .Title}}<h2>{{.Title}}</h2>
+{{end}}{{if .Description}}<p>{{.Description}}</p>
+{{end}}{{if .Image}}<p><img src="{{.Image}}" alt="" style="max-width: 480px"></p>
+{{end}}<p>Destination: <code>{{.OriginalURL}}</code></p>
+{{if eq .Status "warning"}}<p>This destination has been flagged as potentially harmful.</p>
+{{end}}<p><a href="{{.OriginalURL}}" rel="noreferrer nofollow">Go to the site</a></p>
+{{end}}<dl>
+<dt>Short link</dt><dd>{{.ShortURL}}</dd>
+<dt>Created</dt><dd>{{.Created}}</dd>
+<dt>Clicks</dt><dd>{{.Clicks}}</dd>
+</dl>
+<p><a href="/{{.ShortCode}}/report">Report this link</a></p>
+</body>
+</html>
+`))
+
+// Preview показывает, куда ведёт ссылка: HTML для браузеров, иначе формат по Accept.
+func (h *ShortenerHandler) Preview(w http.ResponseWriter, r *http.Request) {
+	preview, err := h.service.Preview(r.Context(), r.PathValue("code"))
+	if err != nil {
+		h.errors.WriteErr(w, r, err)
+		return
+	}
+
+	link := preview.Link
+	resp := previewResponse{
+		ShortCode:   link.ShortCode,
+		ShortURL:    shortURL(r, link.ShortCode),
+		OriginalURL: link.OriginalURL,
+		CreatedAt:   link.CreatedAt,
+		Clicks:      preview.Clicks,
+		Status:      previewActive,
+	}
+	if link.Metadata != nil {
+		resp.Title = link.Metadata.Title
+		resp.Description = link.Metadata.Description
+		resp.Image = link.Metadata.Image
+	}
+	switch {
+	case link.DisabledAt != 0 || preview.Verdict == urlpolicy.Block:
+		resp.Status = previewDisabled
+		resp.OriginalURL = ""
+		resp.Title, resp.Description, resp.Image = "", "", ""
+		resp.DisabledReason = link.DisabledReason
+	case preview.Verdict == urlpolicy.Warn:
+		resp.Status = previewWarning
+	}
+
+	if !prefersHTML(r) {
+		h.response.Write(w, r, http.StatusOK, resp)
+		return
+	}
+	writePage(w, http.StatusOK, previewPage, previewData{
+		previewResponse: resp,
+		Created:         time.Unix(link.CreatedAt, 0).UTC().Format("2 January 2006, 15:04 UTC"),
+	})
+}
+
+// prefersHTML — клиент явно просит text/html и ставит его не ниже остальных
+// форматов; */* без text/html (curl, API-клиенты) получает данные.
+func prefersHTML(r *http.Request) bool {
+	htmlQ, otherQ := 0.0, 0.0
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, err := strconv.ParseFloat(params["q"], 64); err == nil {
+			q = v
+		}
+		switch mediaType {
+		case "text/html", "application/xhtml+xml":
+			htmlQ = max(htmlQ, q)
+		case "*/*":
+		default:
+			otherQ = max(otherQ, q)
+		}
+	}
+	return htmlQ > 0 && htmlQ >= otherQ
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"shorted/internal/service/shortener"
+	"shorted/pkg/urlpolicy"
+	"strings"
+	"testing"
+)
+
+func TestPrefersHTML(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   bool
+	}{
+		{accept: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", want: true},
+		{accept: "application/xhtml+xml", want: true},
+		{accept: "text/html;q=0.5, application/json", want: false},
+		{accept: "application/json;q=0.5, text/html;q=0.5", want: true},
+		{accept: "text/html;q=0", want: false},
+		{accept: "*/*", want: false},
+		{accept: "", want: false},
+		{accept: "application/json", want: false},
+		{accept: "text/html;q=oops", want: true},
+		{accept: "garbage;;, text/html", want: true},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/abc+", nil)
+		r.Header.Set("Accept", tt.accept)
+		if got := prefersHTML(r); got != tt.want {
+			t.Errorf("prefersHTML(%q) = %v, want %v", tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestPreview(t *testing.T) {
+	tests := []struct {
+		name        string
+		verdict     urlpolicy.Verdict
+		accept      string
+		wantStatus  string
+		wantURL     bool
+		wantBody    []string
+		notWantBody []string
+	}{
+		{name: "json", accept: "application/json", wantStatus: previewActive, wantURL: true},
+		{name: "warning json", verdict: urlpolicy.Warn, wantStatus: previewWarning, wantURL: true},
+		{name: "blocked json", verdict: urlpolicy.Block, wantStatus: previewDisabled},
+		{
+			name: "html", accept: "text/html",
+			wantBody: []string{"Where does /", `href="https://example.com/a?b=1&amp;c=2"`, "<dt>Clicks</dt><dd>0</dd>"},
+		},
+		{
+			name: "warning html", verdict: urlpolicy.Warn, accept: "text/html",
+			wantBody: []string{"flagged as potentially harmful", `href="https://example.com/a?b=1&amp;c=2"`},
+		},
+		{
+			name: "blocked html", verdict: urlpolicy.Block, accept: "text/html",
+			wantBody:    []string{"This link has been disabled"},
+			notWantBody: []string{"https://example.com/a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := urlpolicy.New(urlpolicy.Config{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			service := newTestService(shortener.WithPolicy(policy))
+			link, _, err := service.CreateShortURL(context.Background(), "https://example.com/a?b=1&c=2", shortener.CreateOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.verdict != "" {
+				if _, err := policy.Add("example.com", tt.verdict, ""); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/"+link.ShortCode+"/preview", nil)
+			req.SetPathValue("code", link.ShortCode)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			newShortenerHandler(service).Preview(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+			}
+
+			if tt.wantStatus != "" {
+				resp := decodeJSON[previewResponse](t, rec)
+				if resp.Status != tt.wantStatus || resp.ShortCode != link.ShortCode {
+					t.Errorf("preview = %+v", resp)
+				}
+				if (resp.OriginalURL != "") != tt.wantURL {
+					t.Errorf("original_url = %q", resp.OriginalURL)
+				}
+				return
+			}
+			if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+				t.Errorf("Content-Type = %q", ct)
+			}
+			for _, want := range tt.wantBody {
+				if !strings.Contains(rec.Body.String(), want) {
+					t.Errorf("body does not contain %q:\n%s", want, rec.Body)
+				}
+			}
+			for _, notWant := range tt.notWantBody {
+				if strings.Contains(rec.Body.String(), notWant) {
+					t.Errorf("body contains %q:\n%s", notWant, rec.Body)
+				}
+			}
+		})
+	}
+}
+
+func TestPreviewPageEscapesDestination(t *testing.T) {
+	tests := []struct {
+		name, url, image string
+		notWant          []string
+	}{
+		{name: "javascript", url: "javascript:alert(1)", notWant: []string{`href="javascript:`}},
+		{name: "data", url: "data:text/html;base64,PHNjcmlwdD4=", notWant: []string{`href="data:`}},
+		{name: "markup in url", url: `https://example.com/"><script>alert(1)</script>`, notWant: []string{"<script>"}},
+		{name: "image", url: "https://example.com/", image: "javascript:alert(1)", notWant: []string{`src="javascript:`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			data := previewData{previewResponse: previewResponse{
+				ShortCode: "abc", OriginalURL: tt.url, Image: tt.image, Status: previewActive,
+			}}
+			if err := previewPage.Execute(&buf, data); err != nil {
+				t.Fatal(err)
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(buf.String(), notWant) {
+					t.Errorf("page contains %q:\n%s", notWant, buf.String())
+				}
+			}
+		})
+	}
+}
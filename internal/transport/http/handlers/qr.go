@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"image"
+	"net/http"
+	"shorted/internal/contract"
+	"shorted/internal/domain/domainerr"
+	"shorted/internal/service/shortener"
+	"shorted/pkg/metrics"
+	"shorted/pkg/qr"
+	"strconv"
+	"strings"
+)
+
+// qrMaxAge — сколько клиенты и CDN могут хранить код: изображение зависит только
+// от короткого адреса и параметров запроса.
+const qrMaxAge = "public, max-age=86400"
+
+type QRHandler struct {
+	service *shortener.Service
+	cache   *qr.Cache
+	logo    image.Image
+	errors  contract.ErrorWriter
+	lookups *metrics.CounterVec
+}
+
+// NewQRHandler: logo — картинка для параметра logo=true, nil отключает логотипы.
+func NewQRHandler(service *shortener.Service, cache *qr.Cache, logo image.Image, errs contract.ErrorWriter, registry *metrics.Registry) *QRHandler {
+	return &QRHandler{
+		service: service,
+		cache:   cache,
+		logo:    logo,
+		errors:  errs,
+		lookups: registry.NewCounterVec("shortener_qr_cache_total", "Number of QR code requests by cache result.", "result"),
+	}
+}
+
+// QR отдаёт QR-код полного короткого адреса. Размер и поле вне допустимых
+// диапазонов приводятся к ближайшей границе.
+func (h *QRHandler) QR(w http.ResponseWriter, r *http.Request) {
+	link, err := h.service.GetLink(r.Context(), r.PathValue("code"))
+	if err != nil {
+		h.errors.WriteErr(w, r, err)
+		return
+	}
+	if link.DisabledAt != 0 {
+		h.errors.WriteErr(w, r, domainerr.ErrLinkDisabled)
+		return
+	}
+
+	query := r.URL.Query()
+	opts := qr.Options{
+		Format: qr.Format(query.Get("format")),
+		Size:   qr.DefaultSize,
+		Margin: qr.DefaultMargin,
+		Level:  qr.Level(query.Get("ecc")),
+	}
+	if v, err := strconv.Atoi(query.Get("size")); err == nil {
+		opts.Size = v
+	}
+	if v, err := strconv.Atoi(query.Get("margin")); err == nil {
+		opts.Margin = v
+	}
+	var fields []domainerr.FieldError
+	switch opts.Format {
+	case "":
+		opts.Format = qr.PNG
+	case qr.PNG, qr.SVG:
+	default:
+		fields = append(fields, domainerr.FieldError{Field: "format", Code: "invalid_format", Message: "format must be png or svg"})
+	}
+	switch opts.Level {
+	case "":
+		opts.Level = qr.LevelM
+	case qr.LevelL, qr.LevelM, qr.LevelQ, qr.LevelH:
+	default:
+		fields = append(fields, domainerr.FieldError{Field: "ecc", Code: "invalid_ecc", Message: "ecc must be one of L, M, Q, H"})
+	}
+	if v := query.Get("fg"); v != "" {
+		if opts.Foreground, err = qr.ParseColor(v); err != nil {
+			fields = append(fields, domainerr.FieldError{Field: "fg", Code: "invalid_color", Message: err.Error()})
+		}
+	}
+	if v := query.Get("bg"); v != "" {
+		if opts.Background, err = qr.ParseColor(v); err != nil {
+			fields = append(fields, domainerr.FieldError{Field: "bg", Code: "invalid_color", Message: err.Error()})
+		}
+	}
+	if query.Get("logo") == "true" {
+		if h.logo == nil {
+			fields = append(fields, domainerr.FieldError{Field: "logo", Code: "logo_unavailable", Message: "no logo is configured on this server"})
+		}
+		opts.Logo = h.logo
+	}
+	if len(fields) > 0 {
+		h.errors.WriteErr(w, r, domainerr.Validation(fields...))
+		return
+	}
+
+	content := shortURL(r, link.ShortCode)
+	key := strings.Join([]string{
+		content, string(opts.Format), strconv.Itoa(opts.Size), strconv.Itoa(opts.Margin), string(opts.Level),
+		query.Get("fg"), query.Get("bg"), strconv.FormatBool(opts.Logo != nil),
+	}, "|")
+	sum := sha256.Sum256([]byte(key))
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set("Cache-Control", qrMaxAge)
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	body, ok := h.cache.Get(key)
+	if ok {
+		h.lookups.WithLabelValues("hit").Inc()
+	} else {
+		h.lookups.WithLabelValues("miss").Inc()
+		if body, err = qr.Render(content, opts); err != nil {
+			h.errors.WriteErr(w, r, err)
+			return
+		}
+		h.cache.Add(key, body)
+	}
+
+	w.Header().Set("Content-Type", opts.Format.ContentType())
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"context"
+	"image"
+	"net/http"
+	"net/http/httptest"
+	"shorted/internal/repository/memory"
+	"shorted/internal/service/shortener"
+	"shorted/pkg/apierror"
+	"shorted/pkg/codec"
+	"shorted/pkg/metrics"
+	"shorted/pkg/qr"
+	"shorted/pkg/tracing"
+	"strings"
+	"testing"
+)
+
+func serveQR(h *QRHandler, code, query string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/api/links/"+code+"/qr?"+query, nil)
+	req.SetPathValue("code", code)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	rec := httptest.NewRecorder()
+	h.QR(rec, req)
+	return rec
+}
+
+func newQRHandler(service *shortener.Service, cache *qr.Cache, logo image.Image) *QRHandler {
+	return NewQRHandler(service, cache, logo, apierror.New(apierror.WithCodecs(codec.Default())), metrics.NewRegistry())
+}
+
+func TestQR(t *testing.T) {
+	service := newTestService()
+	link, _, err := service.CreateShortURL(context.Background(), "https://example.com/", shortener.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		logo       bool
+		wantStatus int
+		wantType   string
+		wantFields []string
+	}{
+		{name: "png by default", wantStatus: http.StatusOK, wantType: "image/png"},
+		{name: "svg", query: "format=svg&size=128&margin=0&ecc=H&fg=%23123&bg=fff", wantStatus: http.StatusOK, wantType: "image/svg+xml"},
+		{name: "out of range values are clamped", query: "size=99999&margin=-5", wantStatus: http.StatusOK, wantType: "image/png"},
+		{name: "logo", query: "logo=true", logo: true, wantStatus: http.StatusOK, wantType: "image/png"},
+		{name: "bad format", query: "format=gif", wantStatus: http.StatusBadRequest, wantFields: []string{"format"}},
+		{
+			name: "several bad fields", query: "ecc=Z&fg=nope&bg=%2312&logo=true",
+			wantStatus: http.StatusBadRequest, wantFields: []string{"ecc", "fg", "bg", "logo"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logo image.Image
+			if tt.logo {
+				logo = image.NewNRGBA(image.Rect(0, 0, 4, 4))
+			}
+			rec := serveQR(newQRHandler(service, qr.NewCache(8), logo), link.ShortCode, tt.query, nil)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				body := decodeJSON[struct {
+					Details []struct {
+						Field string `json:"field"`
+					} `json:"details"`
+				}](t, rec)
+				var fields []string
+				for _, f := range body.Details {
+					fields = append(fields, f.Field)
+				}
+				if strings.Join(fields, ",") != strings.Join(tt.wantFields, ",") {
+					t.Errorf("fields = %v, want %v", fields, tt.wantFields)
+				}
+				return
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != tt.wantType {
+				t.Errorf("Content-Type = %q, want %q", ct, tt.wantType)
+			}
+			if rec.Header().Get("ETag") == "" || rec.Header().Get("Cache-Control") != qrMaxAge {
+				t.Errorf("caching headers = %v", rec.Header())
+			}
+			if rec.Body.Len() == 0 {
+				t.Error("empty body")
+			}
+		})
+	}
+}
+
+func TestQRCaching(t *testing.T) {
+	service := newTestService()
+	link, _, err := service.CreateShortURL(context.Background(), "https://example.com/", shortener.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := qr.NewCache(8)
+	h := newQRHandler(service, cache, nil)
+
+	first := serveQR(h, link.ShortCode, "size=64", nil)
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" {
+		t.Fatalf("first response: %d %v", first.Code, first.Header())
+	}
+
+	// тот же запрос отдаётся из кэша с тем же телом
+	second := serveQR(h, link.ShortCode, "size=64", nil)
+	if second.Body.String() != first.Body.String() || second.Header().Get("ETag") != etag {
+		t.Error("cached response differs")
+	}
+
+	// другие параметры — другой ETag
+	other := serveQR(h, link.ShortCode, "size=65", nil)
+	if other.Header().Get("ETag") == etag {
+		t.Error("different parameters share an ETag")
+	}
+
+	notModified := serveQR(h, link.ShortCode, "size=64", http.Header{"If-None-Match": {etag}})
+	if notModified.Code != http.StatusNotModified || notModified.Body.Len() != 0 {
+		t.Errorf("If-None-Match: status = %d, body = %d bytes", notModified.Code, notModified.Body.Len())
+	}
+}
+
+func TestQRUnavailableLinks(t *testing.T) {
+	repo := memory.NewLinkRepo()
+	service := shortener.NewService(repo, memory.NewStatsRepo(), tracing.NewTracer(tracing.NeverSample(), nil))
+	link, _, err := service.CreateShortURL(context.Background(), "https://example.com/", shortener.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := newQRHandler(service, qr.NewCache(8), nil)
+
+	if rec := serveQR(h, "missing", "", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("missing link: status = %d", rec.Code)
+	}
+
+	// отключённая ссылка не должна распространяться через QR-код
+	disabled := *link
+	disabled.DisabledAt = 1
+	if err := repo.Update(context.Background(), &disabled); err != nil {
+		t.Fatal(err)
+	}
+	if rec := serveQR(h, link.ShortCode, "", nil); rec.Code != http.StatusGone {
+		t.Errorf("disabled link: status = %d: %s", rec.Code, rec.Body)
+	}
+}
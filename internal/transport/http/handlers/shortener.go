@@ -1,24 +1,286 @@
 package handlers
 
 import (
+	"errors"
+	"html/template"
 	"net/http"
+	"shorted/internal/contract"
+	"shorted/internal/domain/domainerr"
+	"shorted/internal/domain/models"
 	"shorted/internal/service/shortener"
+	"shorted/pkg/metrics"
+	"time"
 )
 
 type ShortenerHandler struct {
-	service *shortener.Service
+	service   *shortener.Service
+	request   contract.RequestDecoder
+	response  contract.ResponseWriter
+	errors    contract.ErrorWriter
+	created   *metrics.Counter
+	redirects *metrics.CounterVec
 }
 
-func NewShortenerHandler(service *shortener.Service) *ShortenerHandler {
-	return &ShortenerHandler{service: service}
+func NewShortenerHandler(service *shortener.Service, request contract.RequestDecoder, response contract.ResponseWriter, errs contract.ErrorWriter, registry *metrics.Registry) *ShortenerHandler {
+	return &ShortenerHandler{
+		service:   service,
+		request:   request,
+		response:  response,
+		errors:    errs,
+		created:   registry.NewCounter("shortener_links_created_total", "Number of short links created."),
+		redirects: registry.NewCounterVec("shortener_redirects_total", "Number of redirect lookups by result.", "result"),
+	}
+}
+
+const (
+	// variantCookie закрепляет посетителя за вариантом A/B-теста; путь cookie —
+	// сама короткая ссылка, поэтому у каждой ссылки своё значение.
+	variantCookie    = "variant"
+	variantCookieTTL = 90 * 24 * time.Hour
+)
+
+type createShortURLRequest struct {
+	URL        string             `json:"url"`
+	ForceNew   bool               `json:"force_new,omitempty"`
+	Routing    *models.Routing    `json:"routing,omitempty"`
+	Forwarding *models.Forwarding `json:"forwarding,omitempty"`
+	Tags       []string           `json:"tags,omitempty"`
+	Campaigns  []string           `json:"campaigns,omitempty"`
+}
+
+type createShortURLResponse struct {
+	ShortCode   string `json:"short_code"`
+	ShortURL    string `json:"short_url"`
+	OriginalURL string `json:"original_url"`
 }
 
 func (h *ShortenerHandler) CreateShortURL(w http.ResponseWriter, r *http.Request) {
-	// обработка тела
-	// сервис
-	// респотсеs
+	var req createShortURLRequest
+	if err := h.request.Decode(r, &req); err != nil {
+		h.errors.WriteErr(w, r, err)
+		return
+	}
+
+	link, created, err := h.service.CreateShortURL(r.Context(), req.URL, shortener.CreateOptions{
+		ForceNew:   req.ForceNew,
+		Routing:    req.Routing,
+		Forwarding: req.Forwarding,
+		Tags:       req.Tags,
+		Campaigns:  req.Campaigns,
+	})
+	if err != nil {
+		h.errors.WriteErr(w, r, err)
+		return
+	}
+
+	// существующая ссылка на тот же адрес отдаётся с 200, новая — с 201
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+		h.created.Inc()
+	}
+
+	h.response.Write(w, r, status, createShortURLResponse{
+		ShortCode:   link.ShortCode,
+		ShortURL:    shortURL(r, link.ShortCode),
+		OriginalURL: link.OriginalURL,
+	})
 }
 
 func (h *ShortenerHandler) Redirect(w http.ResponseWriter, r *http.Request) {
+	visit := models.Click{
+		Referrer:       r.Referer(),
+		UserAgent:      r.UserAgent(),
+		IP:             clientIP(r),
+		AcceptLanguage: r.Header.Get("Accept-Language"),
+		Query:          r.URL.RawQuery,
+		Path:           r.PathValue("rest"),
+	}
+	if cookie, err := r.Cookie(variantCookie); err == nil {
+		visit.Variant = cookie.Value
+	}
+
+	res, err := h.service.Resolve(r.Context(), r.PathValue("code"), visit)
+	switch {
+	case errors.Is(err, domainerr.ErrLinkNotFound):
+		h.redirects.WithLabelValues("miss").Inc()
+	case errors.Is(err, domainerr.ErrLinkDisabled):
+		h.redirects.WithLabelValues("disabled").Inc()
+		writePage(w, http.StatusGone, disabledPage, disabledData{Reason: disabledReason(err)})
+		return
+	}
+	if err != nil {
+		h.errors.WriteErr(w, r, err)
+		return
+	}
+
+	routingHeaders(w, res.Link.Routing)
+	if res.Variant != "" {
+		http.SetCookie(w, &http.Cookie{
+			Name:     variantCookie,
+			Value:    res.Variant,
+			Path:     "/" + res.Link.ShortCode,
+			MaxAge:   int(variantCookieTTL.Seconds()),
+			HttpOnly: true,
+			Secure:   r.TLS != nil,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+
+	if res.Interstitial {
+		h.redirects.WithLabelValues("interstitial").Inc()
+		writePage(w, http.StatusOK, interstitialPage, interstitialData{
+			Code:        res.Link.ShortCode,
+			Destination: res.URL,
+			URL:         res.URL,
+		})
+		return
+	}
+	if res.AppURL != "" {
+		h.redirects.WithLabelValues("app").Inc()
+		writePage(w, http.StatusOK, appPage, appData{
+			AppURL:    res.AppURL,
+			Fallback:  res.URL,
+			TimeoutMs: res.AppTimeout.Milliseconds(),
+			AppHref:   template.URL(res.AppURL),
+			URL:       res.URL,
+		})
+		return
+	}
+	h.redirects.WithLabelValues("hit").Inc()
+
+	http.Redirect(w, r, res.URL, http.StatusFound)
+}
+
+// SetRouting заменяет правила выбора адреса по устройству.
+func (h *ShortenerHandler) SetRouting(w http.ResponseWriter, r *http.Request) {
+	var req models.Routing
+	if err := h.request.Decode(r, &req); err != nil {
+		h.errors.WriteErr(w, r, err)
+		return
+	}
+
+	link, err := h.service.SetRouting(r.Context(), r.PathValue("code"), &req)
+	if err != nil {
+		h.errors.WriteErr(w, r, err)
+		return
+	}
+	h.response.Write(w, r, http.StatusOK, link)
+}
+
+// SetForwarding заменяет параметры и путь, добавляемые к адресу при переходе.
+func (h *ShortenerHandler) SetForwarding(w http.ResponseWriter, r *http.Request) {
+	var req models.Forwarding
+	if err := h.request.Decode(r, &req); err != nil {
+		h.errors.WriteErr(w, r, err)
+		return
+	}
+
+	link, err := h.service.SetForwarding(r.Context(), r.PathValue("code"), &req)
+	if err != nil {
+		h.errors.WriteErr(w, r, err)
+		return
+	}
+	h.response.Write(w, r, http.StatusOK, link)
+}
+
+type recordConversionRequest struct {
+	ClickID string  `json:"click_id"`
+	Event   string  `json:"event,omitempty"`
+	Value   float64 `json:"value,omitempty"`
+}
+
+// RecordConversion принимает конверсию от сервера рекламодателя. Повторная
+// отправка того же события отвечает 200 с сохранённой конверсией.
+func (h *ShortenerHandler) RecordConversion(w http.ResponseWriter, r *http.Request) {
+	var req recordConversionRequest
+	if err := h.request.Decode(r, &req); err != nil {
+		h.errors.WriteErr(w, r, err)
+		return
+	}
+
+	conv, created, err := h.service.RecordConversion(r.Context(), shortener.ConversionInput{
+		ClickID: req.ClickID,
+		Event:   req.Event,
+		Value:   req.Value,
+	})
+	if err != nil {
+		h.errors.WriteErr(w, r, err)
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	h.response.Write(w, r, status, conv)
+}
+
+type updateSplitRequest struct {
+	Weights map[string]int `json:"weights,omitempty"`
+	// Winner: строка объявляет победителя, пустая строка возобновляет тест,
+	// отсутствие поля оставляет как есть.
+	Winner *string `json:"winner,omitempty"`
+}
+
+// UpdateSplit меняет веса вариантов или объявляет победителя A/B-теста.
+func (h *ShortenerHandler) UpdateSplit(w http.ResponseWriter, r *http.Request) {
+	var req updateSplitRequest
+	if err := h.request.Decode(r, &req); err != nil {
+		h.errors.WriteErr(w, r, err)
+		return
+	}
+
+	link, err := h.service.UpdateSplit(r.Context(), r.PathValue("code"), shortener.SplitUpdate{
+		Weights: req.Weights,
+		Winner:  req.Winner,
+	})
+	if err != nil {
+		h.errors.WriteErr(w, r, err)
+		return
+	}
+	h.response.Write(w, r, http.StatusOK, link)
+}
+
+// routingHeaders не даёт кэшам отдать адрес, выбранный правилами, другим
+// посетителям: правила по устройству зависят от User-Agent, географические — от
+// языка и IP-адреса, который в Vary не выразить, A/B-тест — от cookie.
+func routingHeaders(w http.ResponseWriter, routing *models.Routing) {
+	if routing == nil {
+		return
+	}
+	if len(routing.Devices) > 0 {
+		w.Header().Add("Vary", "User-Agent")
+	}
+	if len(routing.Geo) > 0 {
+		w.Header().Add("Vary", "Accept-Language")
+	}
+	if routing.Split != nil {
+		w.Header().Add("Vary", "Cookie")
+	}
+	if len(routing.Geo) > 0 || routing.Split != nil {
+		w.Header().Set("Cache-Control", "private")
+	}
+}
+
+// disabledReason — причина, указанная модератором; у ссылок, заблокированных
+// политикой, её нет и остаётся стандартное сообщение.
+func disabledReason(err error) string {
+	if de := domainerr.From(err); de.Message != domainerr.ErrLinkDisabled.Message {
+		return de.Message
+	}
+	return ""
+}
+
+func shortURL(r *http.Request, code string) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	// заголовок доходит сюда только от доверенного прокси (middleware.RealIP)
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		scheme = proto
+	}
 
+	return scheme + "://" + r.Host + "/" + code
 }
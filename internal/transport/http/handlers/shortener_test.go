@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"shorted/internal/auth"
+	"shorted/internal/domain/models"
+	"shorted/internal/service/shortener"
+	"shorted/pkg/apierror"
+	"shorted/pkg/apirequest"
+	"shorted/pkg/apiresponse"
+	"shorted/pkg/codec"
+	"shorted/pkg/metrics"
+	"shorted/pkg/urlpolicy"
+	"strings"
+	"testing"
+)
+
+func newShortenerHandler(service *shortener.Service) *ShortenerHandler {
+	codecs := codec.Default()
+	errs := apierror.New(apierror.WithCodecs(codecs))
+	return NewShortenerHandler(service, apirequest.New(codecs), apiresponse.New(codecs, errs), errs, metrics.NewRegistry())
+}
+
+// redirect выполняет переход по коду так, как его разбирает роутер.
+func redirect(h *ShortenerHandler, code string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/"+code, nil)
+	req.SetPathValue("code", code)
+	rec := httptest.NewRecorder()
+	h.Redirect(rec, req)
+	return rec
+}
+
+func TestRedirectPolicy(t *testing.T) {
+	tests := []struct {
+		name       string
+		url        string
+		verdict    urlpolicy.Verdict
+		wantStatus int
+		wantBody   []string
+	}{
+		{name: "allowed", url: "https://example.com/a", wantStatus: http.StatusFound},
+		{
+			name: "interstitial", url: "https://warn.example/a?q=1&r=2", verdict: urlpolicy.Warn,
+			wantStatus: http.StatusOK,
+			wantBody:   []string{"This link may be unsafe", `href="https://warn.example/a?q=1&amp;r=2"`},
+		},
+		{
+			name: "disabled", url: "https://block.example/a", verdict: urlpolicy.Block,
+			wantStatus: http.StatusGone,
+			wantBody:   []string{"This link has been disabled", "reported as harmful"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := urlpolicy.New(urlpolicy.Config{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			service := newTestService(shortener.WithPolicy(policy))
+			link, _, err := service.CreateShortURL(context.Background(), tt.url, shortener.CreateOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// правило добавляется после создания: блокировка отключает уже выданные ссылки
+			if tt.verdict != "" {
+				host := strings.Split(strings.TrimPrefix(tt.url, "https://"), "/")[0]
+				if _, err := policy.Add(host, tt.verdict, "phishing"); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			rec := redirect(newShortenerHandler(service), link.ShortCode)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus == http.StatusFound && rec.Header().Get("Location") != tt.url {
+				t.Errorf("Location = %q, want %q", rec.Header().Get("Location"), tt.url)
+			}
+			for _, want := range tt.wantBody {
+				if !strings.Contains(rec.Body.String(), want) {
+					t.Errorf("body does not contain %q:\n%s", want, rec.Body)
+				}
+			}
+		})
+	}
+}
+
+func TestInterstitialPageEscapesURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		want    string
+		notWant string
+	}{
+		{name: "https", url: "https://example.com/?a=1&b=2", want: `href="https://example.com/?a=1&amp;b=2"`},
+		{name: "javascript", url: "javascript:alert(1)", want: `href="#ZgotmplZ"`, notWant: `href="javascript:`},
+		{name: "data", url: "data:text/html,<script>alert(1)</script>", want: `href="#ZgotmplZ"`, notWant: "<script>"},
+		{name: "attribute break", url: `https://example.com/"><script>alert(1)</script>`, notWant: "<script>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := interstitialPage.Execute(&buf, interstitialData{Code: "abc", Destination: tt.url, URL: tt.url}); err != nil {
+				t.Fatal(err)
+			}
+			page := buf.String()
+			if tt.want != "" && !strings.Contains(page, tt.want) {
+				t.Errorf("page does not contain %q:\n%s", tt.want, page)
+			}
+			if tt.notWant != "" && strings.Contains(page, tt.notWant) {
+				t.Errorf("page contains %q:\n%s", tt.notWant, page)
+			}
+		})
+	}
+}
+
+func TestAppPage(t *testing.T) {
+	service := newTestService()
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{ID: "alice"})
+	link, _, err := service.CreateShortURL(ctx, "https://example.com/?a=1&b=2", shortener.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = service.SetRouting(ctx, link.ShortCode, &models.Routing{Devices: []models.DeviceRule{
+		{Platform: models.PlatformIOS, AppURL: "myapp://open?id=1", AppTimeoutMs: 500},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/"+link.ShortCode, nil)
+	req.SetPathValue("code", link.ShortCode)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)")
+	rec := httptest.NewRecorder()
+	newShortenerHandler(service).Redirect(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	for _, want := range []string{
+		`<a href="myapp://open?id=1">open the app</a>`,
+		`<a href="https://example.com/?a=1&amp;b=2" rel="noreferrer">`,
+		`window.location.href = "myapp://open?id=1"`,
+		`replace("https://example.com/?a=1\u0026b=2"); },  500 );`,
+	} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("page does not contain %q:\n%s", want, rec.Body)
+		}
+	}
+}
+
+func TestAppPageEscapesFallback(t *testing.T) {
+	var buf bytes.Buffer
+	err := appPage.Execute(&buf, appData{
+		AppURL: "myapp://open", Fallback: "javascript:alert(1)", AppHref: "myapp://open", URL: "javascript:alert(1)",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), `href="javascript:`) || !strings.Contains(buf.String(), `href="#ZgotmplZ"`) {
+		t.Errorf("fallback link is not sanitized:\n%s", buf.String())
+	}
+}
+
+func TestRedirectVariantCookie(t *testing.T) {
+	service := newTestService()
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{ID: "alice"})
+	link, _, err := service.CreateShortURL(ctx, "https://example.com/", shortener.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = service.SetRouting(ctx, link.ShortCode, &models.Routing{Split: &models.Split{Variants: []models.Variant{
+		{Name: "a", URL: "https://example.com/a", Weight: 1},
+		{Name: "b", URL: "https://example.com/b", Weight: 1},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := newShortenerHandler(service)
+
+	// посетитель с cookie остаётся в своём варианте, и cookie продлевается
+	req := httptest.NewRequest(http.MethodGet, "/"+link.ShortCode, nil)
+	req.SetPathValue("code", link.ShortCode)
+	req.AddCookie(&http.Cookie{Name: variantCookie, Value: "b"})
+	rec := httptest.NewRecorder()
+	h.Redirect(rec, req)
+
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "https://example.com/b" {
+		t.Fatalf("redirect = %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("cookies = %v", cookies)
+	}
+	c := cookies[0]
+	if c.Name != variantCookie || c.Value != "b" || c.Path != "/"+link.ShortCode || !c.HttpOnly || c.MaxAge <= 0 {
+		t.Errorf("cookie = %+v", c)
+	}
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+	"shorted/internal/auth"
+	"shorted/internal/contract"
+	"shorted/internal/domain/domainerr"
+)
+
+// Admin пускает только владельцев API-ключей из списка администраторов.
+func Admin(admins []string, errs contract.ErrorWriter) func(http.Handler) http.Handler {
+	allowed := make(map[string]bool, len(admins))
+	for _, id := range admins {
+		allowed[id] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := auth.PrincipalFrom(r.Context())
+			if !ok {
+				errs.WriteErr(w, r, domainerr.ErrUnauthenticated)
+				return
+			}
+			if !allowed[principal.ID] {
+				errs.WriteErr(w, r, domainerr.ErrAdminRequired)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+	"shorted/internal/auth"
+	"shorted/internal/contract"
+)
+
+// Auth привязывает к запросу владельца API-ключа из Authorization. Запросы без
+// заголовка проходят анонимно, неверный ключ получает 401.
+func Auth(authenticator auth.Authenticator, errs contract.ErrorWriter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if authenticator == nil {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := r.Header.Get("Authorization")
+			if header == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			principal, err := authenticator.Authenticate(r.Context(), auth.BearerToken(header))
+			if err != nil {
+				errs.WriteErr(w, r, err)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
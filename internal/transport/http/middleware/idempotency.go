@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"shorted/internal/auth"
+	"shorted/internal/contract"
+	"shorted/internal/domain/domainerr"
+	"shorted/pkg/idempotency"
+)
+
+const (
+	maxIdempotencyKey = 255
+	// тело читается целиком ради отпечатка, поэтому размер ограничен сверху
+	maxIdempotentBody = 32 << 20
+)
+
+// replayedHeaders — заголовки ответа, которые сохраняются вместе с телом.
+var replayedHeaders = []string{"Content-Type", "Location", "Vary"}
+
+// Idempotency повторяет сохранённый ответ для запроса с тем же заголовком
+// Idempotency-Key и тем же телом. Ключи у каждого владельца API-ключа свои;
+// повтор ключа с другим телом получает 422. Ответы 5xx не сохраняются.
+func Idempotency(store *idempotency.Store, errs contract.ErrorWriter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get("Idempotency-Key")
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKey {
+				errs.WriteErr(w, r, domainerr.ErrIdempotencyKeyInvalid)
+				return
+			}
+
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxIdempotentBody+1))
+			if err != nil {
+				errs.WriteErr(w, r, domainerr.ErrInvalidBody.WithCause(err))
+				return
+			}
+			if len(body) > maxIdempotentBody {
+				errs.WriteErr(w, r, domainerr.ErrBodyTooLarge)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			owner, _ := auth.PrincipalFrom(r.Context())
+			scope := owner.ID + "\x00" + r.Method + " " + r.URL.Path + "\x00" + key
+
+			replay, commit, err := store.Acquire(r.Context(), scope, fingerprint(r, body))
+			if errors.Is(err, idempotency.ErrMismatch) {
+				errs.WriteErr(w, r, domainerr.ErrIdempotencyKeyReused)
+				return
+			}
+			if err != nil {
+				// клиент ушёл, пока ждал первый запрос с этим ключом
+				return
+			}
+
+			if replay != nil {
+				for name, values := range replay.Header {
+					w.Header()[name] = values
+				}
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(replay.Status)
+				w.Write(replay.Body)
+				return
+			}
+
+			rec := &bodyRecorder{ResponseWriter: w}
+			defer func() {
+				// при панике обработчика резерв снимается, чтобы ждущие запросы не зависли
+				if p := recover(); p != nil {
+					commit(nil)
+					panic(p)
+				}
+			}()
+			next.ServeHTTP(rec, r)
+
+			if rec.Status() >= http.StatusInternalServerError {
+				commit(nil)
+				return
+			}
+			commit(rec.response())
+		})
+	}
+}
+
+// fingerprint различает запросы с одним ключом по запросу и его телу.
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.URL.RawQuery+"\x00"+r.Header.Get("Content-Type")+"\x00")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+type bodyRecorder struct {
+	http.ResponseWriter
+	status int
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (r *bodyRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+		r.header = make(http.Header)
+		for _, name := range replayedHeaders {
+			if values := r.ResponseWriter.Header().Values(name); len(values) > 0 {
+				r.header[name] = append([]string(nil), values...)
+			}
+		}
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *bodyRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.WriteHeader(http.StatusOK)
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *bodyRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (r *bodyRecorder) Status() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
+
+func (r *bodyRecorder) response() *idempotency.Response {
+	return &idempotency.Response{
+		Status: r.Status(),
+		Header: r.header,
+		Body:   bytes.Clone(r.body.Bytes()),
+	}
+}
@@ -0,0 +1,191 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"shorted/internal/auth"
+	"shorted/pkg/apierror"
+	"shorted/pkg/idempotency"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type idempotentRequest struct {
+	owner  string
+	key    string
+	body   string
+	query  string
+	status int
+	// replayed — ответ должен быть повтором сохранённого
+	replayed bool
+	// wantError — код ошибки вместо ответа обработчика
+	wantError string
+}
+
+func TestIdempotency(t *testing.T) {
+	tests := []struct {
+		name     string
+		failing  bool
+		requests []idempotentRequest
+		// calls — сколько раз обработчик должен выполниться
+		calls int32
+	}{
+		{
+			name: "repeat is replayed",
+			requests: []idempotentRequest{
+				{key: "k", body: "a", status: 201},
+				{key: "k", body: "a", status: 201, replayed: true},
+			},
+			calls: 1,
+		},
+		{
+			name: "no key",
+			requests: []idempotentRequest{
+				{body: "a", status: 201},
+				{body: "a", status: 201},
+			},
+			calls: 2,
+		},
+		{
+			name: "different body",
+			requests: []idempotentRequest{
+				{key: "k", body: "a", status: 201},
+				{key: "k", body: "b", status: 422, wantError: "idempotency_key_reused"},
+			},
+			calls: 1,
+		},
+		{
+			name: "different query",
+			requests: []idempotentRequest{
+				{key: "k", body: "a", status: 201},
+				{key: "k", body: "a", query: "async=true", status: 422, wantError: "idempotency_key_reused"},
+			},
+			calls: 1,
+		},
+		{
+			name: "keys are per owner",
+			requests: []idempotentRequest{
+				{owner: "alice", key: "k", body: "a", status: 201},
+				{owner: "bob", key: "k", body: "b", status: 201},
+				{key: "k", body: "c", status: 201},
+				{owner: "alice", key: "k", body: "a", status: 201, replayed: true},
+			},
+			calls: 3,
+		},
+		{
+			name:    "server errors are not stored",
+			failing: true,
+			requests: []idempotentRequest{
+				{key: "k", body: "a", status: 500},
+				{key: "k", body: "a", status: 500},
+			},
+			calls: 2,
+		},
+		{
+			name: "key too long",
+			requests: []idempotentRequest{
+				{key: strings.Repeat("k", 256), body: "a", status: 400, wantError: "idempotency_key_invalid"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := calls.Add(1)
+				if tt.failing {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("Location", fmt.Sprintf("/links/%d", n))
+				w.Header().Set("X-Not-Replayed", "1")
+				w.WriteHeader(http.StatusCreated)
+				fmt.Fprintf(w, `{"n":%d}`, n)
+			})
+			h := Idempotency(idempotency.NewStore(time.Hour), apierror.New())(handler)
+
+			var first *httptest.ResponseRecorder
+			for i, req := range tt.requests {
+				r := httptest.NewRequest(http.MethodPost, "/api/shorten?"+req.query, strings.NewReader(req.body))
+				if req.key != "" {
+					r.Header.Set("Idempotency-Key", req.key)
+				}
+				if req.owner != "" {
+					r = r.WithContext(auth.WithPrincipal(context.Background(), auth.Principal{ID: req.owner}))
+				}
+				rec := httptest.NewRecorder()
+				h.ServeHTTP(rec, r)
+
+				if rec.Code != req.status {
+					t.Fatalf("request %d: status = %d, want %d: %s", i, rec.Code, req.status, rec.Body)
+				}
+				if req.wantError != "" && !strings.Contains(rec.Body.String(), `"`+req.wantError+`"`) {
+					t.Errorf("request %d: body = %s, want %s", i, rec.Body, req.wantError)
+				}
+				if got := rec.Header().Get("Idempotent-Replayed") == "true"; got != req.replayed {
+					t.Errorf("request %d: replayed = %v, want %v", i, got, req.replayed)
+				}
+				if req.replayed {
+					if rec.Body.String() != first.Body.String() || rec.Header().Get("Location") != first.Header().Get("Location") {
+						t.Errorf("request %d: replay %s %s differs from %s %s", i,
+							rec.Header().Get("Location"), rec.Body, first.Header().Get("Location"), first.Body)
+					}
+					if rec.Header().Get("X-Not-Replayed") != "" {
+						t.Errorf("request %d: header outside the allow list was replayed", i)
+					}
+				}
+				if i == 0 {
+					first = rec
+				}
+			}
+			if got := calls.Load(); got != tt.calls {
+				t.Errorf("handler ran %d times, want %d", got, tt.calls)
+			}
+		})
+	}
+}
+
+func TestIdempotencyReleasesKeyOnPanic(t *testing.T) {
+	store := idempotency.NewStore(time.Hour)
+	var calls atomic.Int32
+	h := Idempotency(store, apierror.New())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			panic("boom")
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	send := func() *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader("a"))
+		r.Header.Set("Idempotency-Key", "k")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("panic was swallowed")
+			}
+		}()
+		send()
+	}()
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- send() }()
+	select {
+	case rec := <-done:
+		if rec.Code != http.StatusCreated {
+			t.Errorf("retry status = %d", rec.Code)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("retry after panic is stuck on the reserved key")
+	}
+}
@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"shorted/pkg/tracing"
+	"time"
+)
+
+func Logging(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+
+		next.ServeHTTP(rec, r)
+
+		log.Printf("%s %s %d %s trace_id=%s",
+			r.Method, r.URL.Path, rec.Status(), time.Since(start), tracing.TraceIDFromContext(r.Context()))
+	})
+}
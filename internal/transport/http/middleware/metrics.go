@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+	"shorted/pkg/metrics"
+	"strconv"
+	"time"
+)
+
+func Metrics(registry *metrics.Registry) func(http.Handler) http.Handler {
+	requests := registry.NewCounterVec("http_requests_total", "Number of HTTP requests by route and status.", "method", "route", "status")
+	duration := registry.NewHistogramVec("http_request_duration_seconds", "HTTP request latency by route and status.", nil, "method", "route", "status")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := &statusRecorder{ResponseWriter: w}
+
+			next.ServeHTTP(rec, r)
+
+			// ServeMux записывает совпавший шаблон в r.Pattern
+			route := r.Pattern
+			if route == "" {
+				route = "unmatched"
+			}
+			status := strconv.Itoa(rec.Status())
+
+			requests.WithLabelValues(r.Method, route, status).Inc()
+			duration.WithLabelValues(r.Method, route, status).ObserveSince(start)
+		})
+	}
+}
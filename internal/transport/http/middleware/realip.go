@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// RealIP подставляет в RemoteAddr адрес клиента из X-Forwarded-For, если запрос
+// пришёл от доверенного прокси. Адреса разбираются справа налево: первый адрес не
+// из trusted и есть клиент, всё левее него клиент мог дописать сам. Без trusted
+// заголовок не читается, и тогда сервис должен принимать соединения напрямую,
+// иначе все клиенты получат адрес балансировщика.
+//
+// X-Forwarded-Proto остаётся в запросе, только если его прислал доверенный
+// прокси и в нём http или https: по нему строятся короткие ссылки в ответах.
+func RealIP(trusted []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host, port, err := net.SplitHostPort(r.RemoteAddr)
+			peer, perr := netip.ParseAddr(host)
+			if err != nil || perr != nil || !isTrusted(trusted, peer) {
+				if r.Header.Get("X-Forwarded-Proto") != "" {
+					r = r.Clone(r.Context())
+					r.Header.Del("X-Forwarded-Proto")
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			r = r.Clone(r.Context())
+			if proto := strings.ToLower(r.Header.Get("X-Forwarded-Proto")); proto == "http" || proto == "https" {
+				r.Header.Set("X-Forwarded-Proto", proto)
+			} else {
+				r.Header.Del("X-Forwarded-Proto")
+			}
+			if client, ok := forwardedFor(r.Header.Values("X-Forwarded-For"), trusted); ok {
+				r.RemoteAddr = net.JoinHostPort(client.String(), port)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// forwardedFor возвращает самый правый адрес цепочки, не принадлежащий прокси.
+// Если недоверенных адресов нет, клиентом считается самый левый.
+func forwardedFor(headers []string, trusted []netip.Prefix) (netip.Addr, bool) {
+	var hops []string
+	for _, h := range headers {
+		hops = append(hops, strings.Split(h, ",")...)
+	}
+
+	var client netip.Addr
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			// мусор в цепочке: дальше доверять ей нельзя
+			break
+		}
+		client = addr.Unmap()
+		if !isTrusted(trusted, client) {
+			break
+		}
+	}
+	return client, client.IsValid()
+}
+
+func isTrusted(trusted []netip.Prefix, addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, p := range trusted {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseTrustedProxies разбирает сети доверенных прокси; одиночный адрес
+// считается сетью из одного адреса.
+func ParseTrustedProxies(list []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(list))
+	for _, s := range list {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if !strings.Contains(s, "/") {
+			addr, err := netip.ParseAddr(s)
+			if err != nil {
+				return nil, err
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, p.Masked())
+	}
+	return prefixes, nil
+}
@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestRealIP(t *testing.T) {
+	trusted, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.0.2.1", "2001:db8::/32"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		trusted    []netip.Prefix
+		remoteAddr string
+		forwarded  []string
+		want       string
+	}{
+		{name: "no proxies configured", remoteAddr: "10.0.0.5:1234", forwarded: []string{"203.0.113.7"}, want: "10.0.0.5:1234"},
+		{name: "untrusted peer", trusted: trusted, remoteAddr: "198.51.100.1:1234", forwarded: []string{"203.0.113.7"}, want: "198.51.100.1:1234"},
+		{name: "trusted peer", trusted: trusted, remoteAddr: "10.0.0.5:1234", forwarded: []string{"203.0.113.7"}, want: "203.0.113.7:1234"},
+		{name: "single trusted address", trusted: trusted, remoteAddr: "192.0.2.1:80", forwarded: []string{"203.0.113.7"}, want: "203.0.113.7:80"},
+		{name: "no header", trusted: trusted, remoteAddr: "10.0.0.5:1234", want: "10.0.0.5:1234"},
+		// клиент подделал начало цепочки: берём последний недоверенный адрес
+		{name: "spoofed prefix", trusted: trusted, remoteAddr: "10.0.0.5:1234", forwarded: []string{"1.1.1.1, 203.0.113.7, 10.0.0.9"}, want: "203.0.113.7:1234"},
+		{name: "several headers", trusted: trusted, remoteAddr: "10.0.0.5:1234", forwarded: []string{"1.1.1.1", "203.0.113.7, 10.1.1.1"}, want: "203.0.113.7:1234"},
+		{name: "only proxies", trusted: trusted, remoteAddr: "10.0.0.5:1234", forwarded: []string{"10.0.0.7, 10.0.0.8"}, want: "10.0.0.7:1234"},
+		{name: "garbage stops the chain", trusted: trusted, remoteAddr: "10.0.0.5:1234", forwarded: []string{"203.0.113.7, unknown"}, want: "10.0.0.5:1234"},
+		{name: "ipv6", trusted: trusted, remoteAddr: "[2001:db8::1]:443", forwarded: []string{"2001:db8:ffff::1, 2a00:1450::1"}, want: "[2a00:1450::1]:443"},
+		{name: "mapped ipv4 peer", trusted: trusted, remoteAddr: "[::ffff:10.0.0.5]:1234", forwarded: []string{"203.0.113.7"}, want: "203.0.113.7:1234"},
+		{name: "mapped ipv4 client", trusted: trusted, remoteAddr: "10.0.0.5:1234", forwarded: []string{"::ffff:203.0.113.7"}, want: "203.0.113.7:1234"},
+		{name: "remote addr without port", trusted: trusted, remoteAddr: "10.0.0.5", forwarded: []string{"203.0.113.7"}, want: "10.0.0.5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			h := RealIP(tt.trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.RemoteAddr
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, v := range tt.forwarded {
+				req.Header.Add("X-Forwarded-For", v)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			if got != tt.want {
+				t.Errorf("RemoteAddr = %q, want %q", got, tt.want)
+			}
+			if req.RemoteAddr != tt.remoteAddr {
+				t.Errorf("original request was modified: %q", req.RemoteAddr)
+			}
+		})
+	}
+}
+
+func TestRealIPForwardedProto(t *testing.T) {
+	trusted, err := ParseTrustedProxies([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		trusted    []netip.Prefix
+		remoteAddr string
+		proto      string
+		want       string
+	}{
+		{name: "no proxies configured", remoteAddr: "10.0.0.5:1234", proto: "https"},
+		{name: "untrusted peer", trusted: trusted, remoteAddr: "198.51.100.1:1234", proto: "https"},
+		{name: "trusted https", trusted: trusted, remoteAddr: "10.0.0.5:1234", proto: "https", want: "https"},
+		{name: "trusted upper case", trusted: trusted, remoteAddr: "10.0.0.5:1234", proto: "HTTP", want: "http"},
+		{name: "trusted unknown scheme", trusted: trusted, remoteAddr: "10.0.0.5:1234", proto: "javascript"},
+		{name: "trusted list", trusted: trusted, remoteAddr: "10.0.0.5:1234", proto: "https, http"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			h := RealIP(tt.trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.Header.Get("X-Forwarded-Proto")
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set("X-Forwarded-Proto", tt.proto)
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			if got != tt.want {
+				t.Errorf("X-Forwarded-Proto = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	tests := []struct {
+		in      []string
+		want    []string
+		wantErr bool
+	}{
+		{in: nil, want: []string{}},
+		{in: []string{" 10.1.2.3/8 ", "", "192.0.2.1"}, want: []string{"10.0.0.0/8", "192.0.2.1/32"}},
+		{in: []string{"::1", "::ffff:10.0.0.1"}, want: []string{"::1/128", "10.0.0.1/32"}},
+		{in: []string{"10.0.0.0/33"}, wantErr: true},
+		{in: []string{"proxy.local"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseTrustedProxies(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseTrustedProxies(%q) error = %v", tt.in, err)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("ParseTrustedProxies(%q) = %v, want %v", tt.in, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i].String() != tt.want[i] {
+				t.Errorf("ParseTrustedProxies(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net/http"
+	"shorted/pkg/tracing"
+)
+
+func Tracing(tracer *tracing.Tracer) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			if sc, err := tracing.ParseTraceparent(r.Header.Get("traceparent")); err == nil {
+				sc.TraceState = r.Header.Get("tracestate")
+				ctx = tracing.ContextWithRemoteSpanContext(ctx, sc)
+			}
+
+			ctx, span := tracer.Start(ctx, r.Method, tracing.SpanKindServer)
+			defer span.End()
+
+			span.SetAttribute("http.request.method", r.Method)
+			span.SetAttribute("url.path", r.URL.Path)
+			w.Header().Set("traceresponse", span.SpanContext().Traceparent())
+
+			rec := &statusRecorder{ResponseWriter: w}
+			r = r.WithContext(ctx)
+			next.ServeHTTP(rec, r)
+
+			if r.Pattern != "" {
+				span.SetName(r.Pattern)
+				span.SetAttribute("http.route", r.Pattern)
+			}
+			status := rec.Status()
+			span.SetAttribute("http.response.status_code", status)
+			if status >= 500 {
+				span.SetStatus(tracing.StatusError, http.StatusText(status))
+			}
+		})
+	}
+}
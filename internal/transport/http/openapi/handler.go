@@ -0,0 +1,43 @@
+package openapi
+
+import (
+	"embed"
+	"net/http"
+)
+
+// redocCDN — тот же бандл, что скачивает go generate; версии должны совпадать.
+const redocCDN = "https://cdn.redoc.ly/redoc/v2.1.5/bundles/redoc.standalone.js"
+
+//go:generate curl -sSfL -o assets/redoc.standalone.js https://cdn.redoc.ly/redoc/v2.1.5/bundles/redoc.standalone.js
+
+//go:embed docs.html
+var docsPage []byte
+
+// assets хранит скрипт Redoc, чтобы документация работала без доступа к CDN.
+//
+//go:embed assets
+var assets embed.FS
+
+func (s *Spec) ServeSpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(s.raw)
+}
+
+func ServeDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(docsPage)
+}
+
+// ServeDocsScript отдаёт встроенный redoc.standalone.js. Если бандл не положили в
+// assets (go generate ./internal/transport/http/openapi), перенаправляет на CDN,
+// чтобы /api/docs не оставался пустым.
+func ServeDocsScript(w http.ResponseWriter, r *http.Request) {
+	script, err := assets.ReadFile("assets/redoc.standalone.js")
+	if err != nil {
+		http.Redirect(w, r, redocCDN, http.StatusFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Write(script)
+}
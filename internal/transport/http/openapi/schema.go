@@ -0,0 +1,185 @@
+package openapi
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"reflect"
+	"shorted/internal/domain/domainerr"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+// validate проверяет значение, полученное из JSON (map/slice/float64/string/bool/nil),
+// против подмножества JSON Schema, используемого в документе.
+func (s *Spec) validate(schema *Schema, value any, field string, errs *[]domainerr.FieldError) {
+	schema = s.resolve(schema)
+	if schema == nil {
+		return
+	}
+
+	fail := func(code, format string, args ...any) {
+		*errs = append(*errs, domainerr.FieldError{Field: field, Code: code, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, sub := range schema.AllOf {
+		s.validate(sub, value, field, errs)
+	}
+	if len(schema.AnyOf) > 0 && s.matches(schema.AnyOf, value) == 0 {
+		fail("any_of", "value does not match any allowed schema")
+	}
+	if len(schema.OneOf) > 0 && s.matches(schema.OneOf, value) != 1 {
+		fail("one_of", "value must match exactly one schema")
+	}
+
+	if len(schema.Type) > 0 && !typeMatches(schema.Type, value) {
+		fail("type", "must be of type %v", []string(schema.Type))
+		return
+	}
+
+	if len(schema.Enum) > 0 {
+		found := false
+		for _, allowed := range schema.Enum {
+			if reflect.DeepEqual(allowed, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("enum", "must be one of %v", schema.Enum)
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		n := utf8.RuneCountInString(v)
+		if schema.MinLength != nil && n < *schema.MinLength {
+			fail("min_length", "must be at least %d characters", *schema.MinLength)
+		}
+		if schema.MaxLength != nil && n > *schema.MaxLength {
+			fail("max_length", "must be at most %d characters", *schema.MaxLength)
+		}
+		if schema.pattern != nil && !schema.pattern.MatchString(v) {
+			fail("pattern", "must match %s", schema.Pattern)
+		}
+		if schema.Format == "uri" {
+			if u, err := url.Parse(v); err != nil || !u.IsAbs() {
+				fail("format", "must be an absolute URI")
+			}
+		}
+	case float64:
+		if schema.Minimum != nil && v < *schema.Minimum {
+			fail("minimum", "must be >= %v", *schema.Minimum)
+		}
+		if schema.Maximum != nil && v > *schema.Maximum {
+			fail("maximum", "must be <= %v", *schema.Maximum)
+		}
+	case []any:
+		if schema.MinItems != nil && len(v) < *schema.MinItems {
+			fail("min_items", "must contain at least %d items", *schema.MinItems)
+		}
+		if schema.MaxItems != nil && len(v) > *schema.MaxItems {
+			fail("max_items", "must contain at most %d items", *schema.MaxItems)
+		}
+		for i, item := range v {
+			s.validate(schema.Items, item, field+"["+strconv.Itoa(i)+"]", errs)
+		}
+	case map[string]any:
+		for _, name := range schema.Required {
+			if _, ok := v[name]; !ok {
+				*errs = append(*errs, domainerr.FieldError{Field: join(field, name), Code: "required", Message: "is required"})
+			}
+		}
+
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			if prop, ok := schema.Properties[k]; ok {
+				s.validate(prop, v[k], join(field, k), errs)
+				continue
+			}
+			if schema.NoAdditional {
+				*errs = append(*errs, domainerr.FieldError{Field: join(field, k), Code: "unknown_field", Message: "is not allowed"})
+				continue
+			}
+			s.validate(schema.AdditionalProperties, v[k], join(field, k), errs)
+		}
+	}
+}
+
+func (s *Spec) matches(schemas []*Schema, value any) int {
+	n := 0
+	for _, sub := range schemas {
+		var errs []domainerr.FieldError
+		s.validate(sub, value, "", &errs)
+		if len(errs) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func typeMatches(types typeList, value any) bool {
+	for _, t := range types {
+		switch v := value.(type) {
+		case nil:
+			if t == "null" {
+				return true
+			}
+		case bool:
+			if t == "boolean" {
+				return true
+			}
+		case string:
+			if t == "string" {
+				return true
+			}
+		case float64:
+			if t == "number" || t == "integer" && v == math.Trunc(v) {
+				return true
+			}
+		case []any:
+			if t == "array" {
+				return true
+			}
+		case map[string]any:
+			if t == "object" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// coerce приводит строковое значение параметра пути или запроса к типу схемы.
+func (s *Spec) coerce(schema *Schema, raw string) any {
+	schema = s.resolve(schema)
+	if schema == nil {
+		return raw
+	}
+	for _, t := range schema.Type {
+		switch t {
+		case "integer", "number":
+			if f, err := strconv.ParseFloat(raw, 64); err == nil {
+				return f
+			}
+		case "boolean":
+			if b, err := strconv.ParseBool(raw); err == nil {
+				return b
+			}
+		}
+	}
+	return raw
+}
+
+func join(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
@@ -0,0 +1,232 @@
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+//go:embed openapi.json
+var document []byte
+
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 typeList           `json:"type"`
+	Format               string             `json:"format"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *Schema            `json:"-"`
+	NoAdditional         bool               `json:"-"`
+	Items                *Schema            `json:"items"`
+	Enum                 []any              `json:"enum"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	Pattern              string             `json:"pattern"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	MinItems             *int               `json:"minItems"`
+	MaxItems             *int               `json:"maxItems"`
+	OneOf                []*Schema          `json:"oneOf"`
+	AnyOf                []*Schema          `json:"anyOf"`
+	AllOf                []*Schema          `json:"allOf"`
+
+	pattern *regexp.Regexp
+}
+
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	type plain Schema
+	var aux struct {
+		plain
+		AdditionalProperties json.RawMessage `json:"additionalProperties"`
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	*s = Schema(aux.plain)
+
+	switch raw := strings.TrimSpace(string(aux.AdditionalProperties)); raw {
+	case "", "true":
+	case "false":
+		s.NoAdditional = true
+	default:
+		s.AdditionalProperties = &Schema{}
+		if err := json.Unmarshal(aux.AdditionalProperties, s.AdditionalProperties); err != nil {
+			return err
+		}
+	}
+
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("openapi: invalid pattern %q: %w", s.Pattern, err)
+		}
+		s.pattern = re
+	}
+	return nil
+}
+
+// typeList принимает и "string", и ["string", "null"] из JSON Schema 2020-12.
+type typeList []string
+
+func (t *typeList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = typeList{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*t = list
+	return nil
+}
+
+type Parameter struct {
+	Ref      string  `json:"$ref"`
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Ref      string               `json:"$ref"`
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Ref         string               `json:"$ref"`
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content"`
+}
+
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Parameters  []*Parameter         `json:"parameters"`
+	RequestBody *RequestBody         `json:"requestBody"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+type Spec struct {
+	raw        []byte
+	operations map[string]*Operation
+	schemas    map[string]*Schema
+}
+
+var methods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// Load разбирает встроенный документ openapi.json.
+func Load() (*Spec, error) {
+	return Parse(document)
+}
+
+// Parse разбирает OpenAPI-документ и разрешает ссылки на параметры, тела и ответы.
+func Parse(document []byte) (*Spec, error) {
+	var doc struct {
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas       map[string]*Schema      `json:"schemas"`
+			Parameters    map[string]*Parameter   `json:"parameters"`
+			RequestBodies map[string]*RequestBody `json:"requestBodies"`
+			Responses     map[string]*Response    `json:"responses"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(document, &doc); err != nil {
+		return nil, fmt.Errorf("openapi: parse document: %w", err)
+	}
+
+	spec := &Spec{
+		raw:        document,
+		operations: make(map[string]*Operation),
+		schemas:    doc.Components.Schemas,
+	}
+
+	for path, item := range doc.Paths {
+		for _, method := range methods {
+			raw, ok := item[method]
+			if !ok {
+				continue
+			}
+
+			var op Operation
+			if err := json.Unmarshal(raw, &op); err != nil {
+				return nil, fmt.Errorf("openapi: %s %s: %w", method, path, err)
+			}
+
+			for i, p := range op.Parameters {
+				if p.Ref == "" {
+					continue
+				}
+				resolved, ok := doc.Components.Parameters[refName(p.Ref)]
+				if !ok {
+					return nil, fmt.Errorf("openapi: unresolved %s", p.Ref)
+				}
+				op.Parameters[i] = resolved
+			}
+			if op.RequestBody != nil && op.RequestBody.Ref != "" {
+				resolved, ok := doc.Components.RequestBodies[refName(op.RequestBody.Ref)]
+				if !ok {
+					return nil, fmt.Errorf("openapi: unresolved %s", op.RequestBody.Ref)
+				}
+				op.RequestBody = resolved
+			}
+			for status, resp := range op.Responses {
+				if resp.Ref == "" {
+					continue
+				}
+				resolved, ok := doc.Components.Responses[refName(resp.Ref)]
+				if !ok {
+					return nil, fmt.Errorf("openapi: unresolved %s", resp.Ref)
+				}
+				op.Responses[status] = resolved
+			}
+
+			spec.operations[operationKey(method, path)] = &op
+		}
+	}
+
+	return spec, nil
+}
+
+// Routes возвращает все операции документа как шаблоны ServeMux без
+// многосегментных параметров: "GET /api/links/{code}/qr".
+func (s *Spec) Routes() []string {
+	routes := make([]string, 0, len(s.operations))
+	for key := range s.operations {
+		method, path, _ := strings.Cut(key, " ")
+		routes = append(routes, strings.ToUpper(method)+" "+path)
+	}
+	slices.Sort(routes)
+	return routes
+}
+
+// Operation ищет операцию по методу и пути в синтаксисе ServeMux ("/{code}").
+func (s *Spec) Operation(method, path string) *Operation {
+	return s.operations[operationKey(method, path)]
+}
+
+func (s *Spec) resolve(schema *Schema) *Schema {
+	for schema != nil && schema.Ref != "" {
+		schema = s.schemas[refName(schema.Ref)]
+	}
+	return schema
+}
+
+// operationKey приводит путь ServeMux к записи OpenAPI: "{rest...}" в спецификации
+// записывается как "{rest}".
+func operationKey(method, path string) string {
+	return strings.ToLower(method) + " " + strings.ReplaceAll(path, "...}", "}")
+}
+
+func refName(ref string) string {
+	return ref[strings.LastIndexByte(ref, '/')+1:]
+}
@@ -0,0 +1,226 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"shorted/internal/contract"
+	"shorted/internal/domain/domainerr"
+	"shorted/pkg/codec"
+	"strconv"
+	"strings"
+)
+
+const maxValidatedBody = 1 << 20
+
+type Validation struct {
+	Requests  bool
+	Responses bool
+	// ResponseMismatch получает каждое расхождение ответа с документом. По
+	// умолчанию расхождения пишутся в лог; тесты передают сюда t.Errorf, чтобы
+	// ответ не по спецификации ронял тест.
+	ResponseMismatch func(r *http.Request, problem string)
+}
+
+type Validator struct {
+	spec   *Spec
+	mode   Validation
+	codecs *codec.Registry
+	errors contract.ErrorWriter
+}
+
+func NewValidator(spec *Spec, mode Validation, codecs *codec.Registry, errs contract.ErrorWriter) *Validator {
+	return &Validator{spec: spec, mode: mode, codecs: codecs, errors: errs}
+}
+
+// Wrap добавляет проверку запроса и ответа операции op; при выключенной валидации возвращает h как есть.
+func (v *Validator) Wrap(op *Operation, h http.Handler) http.Handler {
+	if !v.mode.Requests && !v.mode.Responses {
+		return h
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if v.mode.Requests {
+			if fields, ok := v.validateRequest(op, r); !ok {
+				v.errors.WriteErr(w, r, domainerr.Validation(fields...))
+				return
+			}
+		}
+
+		if !v.mode.Responses {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: w}
+		h.ServeHTTP(rec, r)
+		v.validateResponse(op, r, rec)
+	})
+}
+
+func (v *Validator) validateRequest(op *Operation, r *http.Request) ([]domainerr.FieldError, bool) {
+	var fields []domainerr.FieldError
+
+	for _, p := range op.Parameters {
+		var raw string
+		var present bool
+		switch p.In {
+		case "path":
+			raw = r.PathValue(p.Name)
+			present = raw != ""
+		case "query":
+			present = r.URL.Query().Has(p.Name)
+			raw = r.URL.Query().Get(p.Name)
+		case "header":
+			raw = r.Header.Get(p.Name)
+			present = raw != ""
+		default:
+			continue
+		}
+
+		if !present {
+			if p.Required {
+				fields = append(fields, domainerr.FieldError{Field: p.Name, Code: "required", Message: "is required"})
+			}
+			continue
+		}
+		v.spec.validate(p.Schema, v.spec.coerce(p.Schema, raw), p.Name, &fields)
+	}
+
+	if op.RequestBody != nil {
+		fields = append(fields, v.validateBody(op.RequestBody, r)...)
+	}
+
+	return fields, len(fields) == 0
+}
+
+func (v *Validator) validateBody(body *RequestBody, r *http.Request) []domainerr.FieldError {
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxValidatedBody+1))
+	if err != nil {
+		return []domainerr.FieldError{{Field: "body", Code: "unreadable", Message: err.Error()}}
+	}
+	r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(data), r.Body))
+
+	// большие тела (пачки ссылок) не буферизуем: их размер и формат проверяет обработчик
+	if len(data) > maxValidatedBody {
+		return nil
+	}
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		if body.Required {
+			return []domainerr.FieldError{{Field: "body", Code: "required", Message: "request body is required"}}
+		}
+		return nil
+	}
+
+	// неизвестный Content-Type и битое тело оставляем обработчику: он ответит 415/400 из каталога
+	c, err := v.codecs.ForContentType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return nil
+	}
+	var value any
+	if err := c.Decode(bytes.NewReader(data), &value); err != nil {
+		return nil
+	}
+
+	var fields []domainerr.FieldError
+	v.spec.validate(schemaFor(body.Content, c.ContentType()), value, "", &fields)
+	return fields
+}
+
+func (v *Validator) validateResponse(op *Operation, r *http.Request, rec *responseRecorder) {
+	status := rec.Status()
+	resp, ok := op.Responses[strconv.Itoa(status)]
+	if !ok {
+		resp, ok = op.Responses[strconv.Itoa(status/100)+"XX"]
+	}
+	if !ok {
+		resp, ok = op.Responses["default"]
+	}
+	if !ok {
+		v.mismatch(r, fmt.Sprintf("responded with undocumented status %d", status))
+		return
+	}
+	if len(resp.Content) == 0 || rec.body.Len() == 0 {
+		return
+	}
+
+	contentType, _, _ := mime.ParseMediaType(rec.Header().Get("Content-Type"))
+	if contentType != "application/json" && !strings.HasSuffix(contentType, "+json") {
+		return
+	}
+	schema := schemaFor(resp.Content, contentType)
+	if schema == nil {
+		v.mismatch(r, "responded with undocumented content type "+contentType)
+		return
+	}
+
+	var value any
+	if err := json.Unmarshal(rec.body.Bytes(), &value); err != nil {
+		v.mismatch(r, fmt.Sprintf("responded with invalid JSON: %v", err))
+		return
+	}
+
+	var fields []domainerr.FieldError
+	v.spec.validate(schema, value, "", &fields)
+	for _, f := range fields {
+		v.mismatch(r, fmt.Sprintf("response %d: %s %s", status, f.Field, f.Message))
+	}
+}
+
+func (v *Validator) mismatch(r *http.Request, problem string) {
+	if v.mode.ResponseMismatch != nil {
+		v.mode.ResponseMismatch(r, problem)
+		return
+	}
+	log.Printf("openapi: %s %s %s", r.Method, r.Pattern, problem)
+}
+
+// schemaFor берёт схему точного media type, а для бинарных форматов — схему JSON-представления.
+func schemaFor(content map[string]MediaType, contentType string) *Schema {
+	if mt, ok := content[contentType]; ok {
+		return mt.Schema
+	}
+	if mt, ok := content["application/json"]; ok {
+		return mt.Schema
+	}
+	return nil
+}
+
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	if r.body.Len() < maxValidatedBody {
+		r.body.Write(b)
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (r *responseRecorder) Status() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
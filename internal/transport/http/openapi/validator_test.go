@@ -0,0 +1,93 @@
+package openapi
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"shorted/pkg/apierror"
+	"shorted/pkg/codec"
+	"strings"
+	"testing"
+)
+
+func TestResponseValidationToggle(t *testing.T) {
+	spec, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	op := spec.Operation(http.MethodGet, "/healthz")
+	// status должен быть строкой из перечисления, а не числом
+	invalid := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":42}`))
+	})
+
+	tests := []struct {
+		name    string
+		mode    Validation
+		wantLog bool
+	}{
+		{"enabled", Validation{Responses: true}, true},
+		{"disabled", Validation{}, false},
+		{"requests only", Validation{Requests: true}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logged bytes.Buffer
+			defer log.SetOutput(log.Writer())
+			log.SetOutput(&logged)
+
+			codecs := codec.Default()
+			h := NewValidator(spec, tt.mode, codecs, apierror.New(apierror.WithCodecs(codecs))).Wrap(op, invalid)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+			// проверка ответа только пишет в лог и не меняет ответ клиенту
+			if rec.Code != http.StatusOK || rec.Body.String() != `{"status":42}` {
+				t.Errorf("response changed: %d %s", rec.Code, rec.Body)
+			}
+			if got := strings.Contains(logged.String(), "response 200: status"); got != tt.wantLog {
+				t.Errorf("logged = %q, want log %v", logged.String(), tt.wantLog)
+			}
+		})
+	}
+}
+
+func TestResponseMismatchHook(t *testing.T) {
+	spec, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	op := spec.Operation(http.MethodGet, "/healthz")
+	teapot := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	var problems []string
+	mode := Validation{Responses: true, ResponseMismatch: func(r *http.Request, problem string) {
+		problems = append(problems, problem)
+	}}
+	codecs := codec.Default()
+	h := NewValidator(spec, mode, codecs, apierror.New(apierror.WithCodecs(codecs))).Wrap(op, teapot)
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	if len(problems) != 1 || problems[0] != "responded with undocumented status 418" {
+		t.Errorf("problems = %q", problems)
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	spec, err := Parse([]byte(`{"paths": {
+		"/{code}/{rest}": {"get": {}},
+		"/api/links": {"get": {}, "post": {}}
+	}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(spec.Routes(), ", ")
+	if want := "GET /api/links, GET /{code}/{rest}, POST /api/links"; got != want {
+		t.Errorf("Routes = %s, want %s", got, want)
+	}
+}
@@ -1,28 +1,229 @@
 package http
 
 import (
+	"fmt"
+	"image"
 	"net/http"
+	"net/netip"
+	"shorted/internal/auth"
+	"shorted/internal/contract"
+	"shorted/internal/service/moderation"
 	"shorted/internal/service/shortener"
+	"shorted/internal/transport/graphql"
 	"shorted/internal/transport/http/handlers"
+	"shorted/internal/transport/http/middleware"
+	"shorted/internal/transport/http/openapi"
+	"shorted/pkg/apirequest"
+	"shorted/pkg/apiresponse"
+	"shorted/pkg/codec"
+	"shorted/pkg/health"
+	"shorted/pkg/idempotency"
+	"shorted/pkg/metrics"
+	"shorted/pkg/qr"
+	"shorted/pkg/tracing"
+	"shorted/pkg/urlpolicy"
+	"strings"
+	"time"
 )
 
+// idempotencyTTL — сколько хранится ответ для Idempotency-Key.
+const idempotencyTTL = 24 * time.Hour
+
 type Router struct {
-	mux *http.ServeMux
+	mux         *http.ServeMux
+	handler     http.Handler
+	spec        *openapi.Spec
+	validator   *openapi.Validator
+	idempotency func(http.Handler) http.Handler
+	auth        func(http.Handler) http.Handler
+	// err — первый маршрут, не описанный в OpenAPI-документе
+	err error
+}
+
+type Deps struct {
+	Shortener  *shortener.Service
+	Moderation *moderation.Service
+	Auth       auth.Authenticator
+	Health     *health.Checker
+	Metrics    *metrics.Registry
+	Tracer     *tracing.Tracer
+	Errors     contract.ErrorWriter
+	Codecs     *codec.Registry
+	Validation openapi.Validation
+	Policy     *urlpolicy.Engine
+	// Admins — владельцы API-ключей с доступом к /api/admin.
+	Admins []string
+	// QRCache хранит готовые QR-коды, QRLogo — логотип для их центра (может быть nil).
+	QRCache *qr.Cache
+	QRLogo  image.Image
+	// Spec — OpenAPI-документ маршрутов; nil — встроенный openapi.json.
+	Spec *openapi.Spec
+	// TrustedProxies — сети прокси, которым можно верить в X-Forwarded-For; без
+	// них адресом клиента считается адрес соединения.
+	TrustedProxies []netip.Prefix
 }
 
-func NewRouter(shortenerService *shortener.Service) *Router {
-	r := &Router{mux: http.NewServeMux()}
-	shortHandler := handlers.NewShortenerHandler(shortenerService)
+// NewRouter собирает маршруты API. Ошибка возвращается, если документ не
+// разбирается или какой-то маршрут в нём не описан.
+func NewRouter(deps Deps) (*Router, error) {
+	spec := deps.Spec
+	if spec == nil {
+		var err error
+		if spec, err = openapi.Load(); err != nil {
+			return nil, err
+		}
+	}
+
+	r := &Router{
+		mux:         http.NewServeMux(),
+		spec:        spec,
+		validator:   openapi.NewValidator(spec, deps.Validation, deps.Codecs, deps.Errors),
+		idempotency: middleware.Idempotency(idempotency.NewStore(idempotencyTTL), deps.Errors),
+		auth:        middleware.Auth(deps.Auth, deps.Errors),
+	}
+	request := apirequest.New(deps.Codecs)
+	response := apiresponse.New(deps.Codecs, deps.Errors)
+
+	// служебные маршруты регистрируются явно, поэтому имеют приоритет над GET /{code}
+	healthHandler := handlers.NewHealthHandler(deps.Health, response)
+	r.registerHealthRoutes(healthHandler)
+	r.handle("GET /metrics", deps.Metrics.Handler())
+	r.registerDocsRoutes()
+
+	shortHandler := handlers.NewShortenerHandler(deps.Shortener, request, response, deps.Errors, deps.Metrics)
 	r.registerShortenerRoutes(shortHandler)
 
-	return r
+	batchRequest := apirequest.New(deps.Codecs, apirequest.WithMaxBodySize(handlers.MaxBatchBodySize))
+	batchHandler := handlers.NewBatchHandler(deps.Shortener, batchRequest, response, deps.Errors, deps.Metrics)
+	r.registerBatchRoutes(batchHandler)
+
+	qrHandler := handlers.NewQRHandler(deps.Shortener, deps.QRCache, deps.QRLogo, deps.Errors, deps.Metrics)
+	r.handleFunc("GET /api/links/{code}/qr", qrHandler.QR)
+
+	campaignHandler := handlers.NewCampaignHandler(deps.Shortener, request, response, deps.Errors)
+	r.registerCampaignRoutes(campaignHandler)
+
+	r.handle("POST /api/graphql", graphql.NewHandler(deps.Shortener, request, response, deps.Errors))
+
+	admin := middleware.Admin(deps.Admins, deps.Errors)
+	if deps.Policy != nil {
+		policyHandler := handlers.NewPolicyHandler(deps.Policy, deps.Shortener, request, response, deps.Errors)
+		r.registerPolicyRoutes(policyHandler, admin)
+	}
+	if deps.Moderation != nil {
+		moderationHandler := handlers.NewModerationHandler(deps.Moderation, request, response, deps.Errors, deps.Metrics)
+		r.registerModerationRoutes(moderationHandler, admin)
+	}
+
+	handler := middleware.Tracing(deps.Tracer)(middleware.Logging(middleware.Metrics(deps.Metrics)(r.mux)))
+	r.handler = middleware.RealIP(deps.TrustedProxies)(handler)
+
+	if r.err != nil {
+		return nil, r.err
+	}
+	return r, nil
+}
+
+// handle регистрирует маршрут только если он описан в OpenAPI-документе,
+// поэтому незадокументированный маршрут не даст серверу стартовать.
+//
+// Auth подключается к каждому маршруту, а не снаружи ServeMux: r.WithContext
+// копирует запрос, и r.Pattern, который ServeMux записывает в свою копию, иначе
+// не увидели бы Metrics и Tracing.
+func (r *Router) handle(pattern string, h http.Handler) {
+	r.mux.Handle(pattern, r.auth(r.operation(pattern, h)))
+}
+
+// operation оборачивает h проверками операции pattern из OpenAPI-документа.
+// Для неописанного маршрута запоминает ошибку, которую вернёт NewRouter.
+func (r *Router) operation(pattern string, h http.Handler) http.Handler {
+	method, path, _ := strings.Cut(pattern, " ")
+	op := r.spec.Operation(method, path)
+	if op == nil {
+		if r.err == nil {
+			r.err = fmt.Errorf("openapi: route %q is not described in the OpenAPI document", pattern)
+		}
+		return h
+	}
+	return r.validator.Wrap(op, h)
+}
+
+func (r *Router) handleFunc(pattern string, h http.HandlerFunc) {
+	r.handle(pattern, h)
+}
+
+func (r *Router) registerHealthRoutes(h *handlers.HealthHandler) {
+	r.handleFunc("GET /healthz", h.Liveness)
+	r.handleFunc("GET /readyz", h.Readiness)
+}
+
+func (r *Router) registerDocsRoutes() {
+	r.handleFunc("GET /api/openapi.json", r.spec.ServeSpec)
+	r.handleFunc("GET /api/docs", openapi.ServeDocs)
+	r.handleFunc("GET /api/docs/redoc.standalone.js", openapi.ServeDocsScript)
 }
 
 func (r *Router) registerShortenerRoutes(h *handlers.ShortenerHandler) {
-	r.mux.HandleFunc("POST /api/shorten", h.CreateShortURL)
-	r.mux.HandleFunc("GET /{code}", h.Redirect)
+	r.handle("POST /api/shorten", r.idempotency(http.HandlerFunc(h.CreateShortURL)))
+	r.handleFunc("PUT /api/links/{code}/routing", h.SetRouting)
+	r.handleFunc("PATCH /api/links/{code}/split", h.UpdateSplit)
+	r.handleFunc("PUT /api/links/{code}/forwarding", h.SetForwarding)
+	r.handleFunc("POST /api/conversions", h.RecordConversion)
+	r.handleFunc("GET /api/links", h.ListLinks)
+	r.handleFunc("GET /api/links/search", h.SearchLinks)
+	r.handleFunc("POST /api/links/tags", h.EditTags)
+	r.handleFunc("GET /api/tags", h.Tags)
+	r.handleFunc("PUT /api/links/{code}/campaigns", h.SetLinkCampaigns)
+	preview := r.operation("GET /{code}/preview", http.HandlerFunc(h.Preview))
+	r.mux.Handle("GET /{code}/preview", r.auth(preview))
+
+	// ServeMux не умеет шаблоны вида /{code}+, поэтому суффикс разбирается здесь:
+	// /abc+ уходит в предпросмотр, а проверка кода по OpenAPI видит уже abc
+	redirect := r.operation("GET /{code}", http.HandlerFunc(h.Redirect))
+	r.mux.Handle("GET /{code}", r.auth(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if code, ok := strings.CutSuffix(req.PathValue("code"), "+"); ok {
+			req.SetPathValue("code", code)
+			preview.ServeHTTP(w, req)
+			return
+		}
+		redirect.ServeHTTP(w, req)
+	})))
+	// путь после кода для ссылок с forward_path; более конкретные маршруты вроде
+	// /{code}/preview и /api/... ServeMux выбирает раньше
+	r.handleFunc("GET /{code}/{rest...}", h.Redirect)
+}
+
+func (r *Router) registerCampaignRoutes(h *handlers.CampaignHandler) {
+	r.handleFunc("POST /api/campaigns", h.Create)
+	r.handleFunc("GET /api/campaigns", h.List)
+	r.handleFunc("GET /api/campaigns/{id}", h.Get)
+	r.handleFunc("PATCH /api/campaigns/{id}", h.Update)
+	r.handleFunc("DELETE /api/campaigns/{id}", h.Delete)
+	r.handleFunc("GET /api/campaigns/{id}/stats", h.Stats)
+}
+
+func (r *Router) registerBatchRoutes(h *handlers.BatchHandler) {
+	r.handle("POST /api/links/batch", r.idempotency(http.HandlerFunc(h.CreateBatch)))
+	r.handleFunc("GET /api/jobs/{id}", h.Job)
+}
+
+func (r *Router) registerPolicyRoutes(h *handlers.PolicyHandler, admin func(http.Handler) http.Handler) {
+	r.handle("GET /api/admin/policy/entries", admin(http.HandlerFunc(h.Entries)))
+	r.handle("POST /api/admin/policy/entries", admin(http.HandlerFunc(h.AddEntry)))
+	r.handle("DELETE /api/admin/policy/entries/{id}", admin(http.HandlerFunc(h.DeleteEntry)))
+	r.handle("POST /api/admin/policy/check", admin(http.HandlerFunc(h.Check)))
+	r.handle("POST /api/admin/policy/reload", admin(http.HandlerFunc(h.Reload)))
+}
+
+func (r *Router) registerModerationRoutes(h *handlers.ModerationHandler, admin func(http.Handler) http.Handler) {
+	r.handleFunc("GET /{code}/report", h.ReportForm)
+	r.handleFunc("POST /{code}/report", h.Report)
+	r.handle("GET /api/admin/reports", admin(http.HandlerFunc(h.Reports)))
+	r.handle("PATCH /api/admin/reports/{id}", admin(http.HandlerFunc(h.UpdateReport)))
+	r.handle("POST /api/admin/links/{code}/disable", admin(http.HandlerFunc(h.DisableLink)))
+	r.handle("POST /api/admin/links/{code}/enable", admin(http.HandlerFunc(h.EnableLink)))
 }
 
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	r.mux.ServeHTTP(w, req)
+	r.handler.ServeHTTP(w, req)
 }
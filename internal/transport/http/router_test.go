@@ -0,0 +1,414 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"regexp"
+	"shorted/internal/auth"
+	"shorted/internal/repository/memory"
+	"shorted/internal/service/moderation"
+	"shorted/internal/service/shortener"
+	"shorted/internal/transport/http/openapi"
+	"shorted/pkg/apierror"
+	"shorted/pkg/codec"
+	"shorted/pkg/health"
+	"shorted/pkg/metrics"
+	"shorted/pkg/qr"
+	"shorted/pkg/tracing"
+	"shorted/pkg/urlpolicy"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func testDeps(t *testing.T) Deps {
+	t.Helper()
+	tracer := tracing.NewTracer(tracing.NeverSample(), nil)
+	links := memory.NewLinkRepo()
+	policy, err := urlpolicy.New(urlpolicy.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	codecs := codec.Default()
+	return Deps{
+		Shortener:  shortener.NewService(links, memory.NewStatsRepo(), tracer),
+		Moderation: moderation.NewService(links, memory.NewReportRepo(), tracer),
+		Health:     health.NewChecker(),
+		Metrics:    metrics.NewRegistry(),
+		Tracer:     tracer,
+		Errors:     apierror.New(apierror.WithCodecs(codecs)),
+		Codecs:     codecs,
+		Policy:     policy,
+		QRCache:    qr.NewCache(8),
+	}
+}
+
+// pathParam — параметр маршрута вида {code} или {rest...}.
+var pathParam = regexp.MustCompile(`\{[^}]+\}`)
+
+// TestEveryRouteIsDocumented проверяет соответствие в обе стороны: NewRouter
+// отказывается стартовать с неописанным маршрутом, а каждая операция документа
+// обслуживается своим маршрутом, а не соседним шаблоном вроде GET /{code}.
+func TestEveryRouteIsDocumented(t *testing.T) {
+	spec, err := openapi.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewRouter(testDeps(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, route := range spec.Routes() {
+		method, path, _ := strings.Cut(route, " ")
+		req := httptest.NewRequest(method, pathParam.ReplaceAllString(path, "x"), nil)
+		_, pattern := r.mux.Handler(req)
+		if got := strings.ReplaceAll(pattern, "...}", "}"); got != route {
+			t.Errorf("%s is served by %q", route, pattern)
+		}
+	}
+}
+
+func TestNewRouterRejectsUndocumentedRoute(t *testing.T) {
+	var doc map[string]any
+	if err := json.NewDecoder(bytes.NewReader(specDocument(t))).Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+	delete(doc["paths"].(map[string]any), "/api/tags")
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, err := openapi.Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deps := testDeps(t)
+	deps.Spec = spec
+	r, err := NewRouter(deps)
+	if err == nil || r != nil {
+		t.Fatal("NewRouter accepted a route missing from the document")
+	}
+	if !strings.Contains(err.Error(), `"GET /api/tags"`) {
+		t.Errorf("error does not name the route: %v", err)
+	}
+}
+
+func TestRequestValidationToggle(t *testing.T) {
+	tests := []struct {
+		name       string
+		validation openapi.Validation
+		wantStatus int
+		wantCode   string
+	}{
+		// код с недопустимым символом отклоняет валидатор по pattern из документа
+		{"enabled", openapi.Validation{Requests: true}, http.StatusBadRequest, "validation_failed"},
+		// без валидации запрос доходит до обработчика, и ссылка просто не находится
+		{"disabled", openapi.Validation{}, http.StatusNotFound, "link_not_found"},
+		{"responses only", openapi.Validation{Responses: true}, http.StatusNotFound, "link_not_found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps := testDeps(t)
+			deps.Validation = tt.validation
+			r, err := NewRouter(deps)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/bad@code", nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			var body struct {
+				Error string `json:"error"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Error != tt.wantCode {
+				t.Errorf("error = %s, want %s", body.Error, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestPreviewRoutes(t *testing.T) {
+	deps := testDeps(t)
+	link, _, err := deps.Shortener.CreateShortURL(context.Background(), "https://example.com/", shortener.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewRouter(deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path       string
+		wantStatus int
+	}{
+		{path: "/" + link.ShortCode, wantStatus: http.StatusFound},
+		{path: "/" + link.ShortCode + "+", wantStatus: http.StatusOK},
+		{path: "/" + link.ShortCode + "/preview", wantStatus: http.StatusOK},
+		{path: "/missing+", wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus == http.StatusOK && !strings.Contains(rec.Body.String(), `"short_code":"`+link.ShortCode+`"`) {
+				t.Errorf("body = %s", rec.Body)
+			}
+		})
+	}
+}
+
+func TestDocsAreServedLocally(t *testing.T) {
+	r, err := NewRouter(testDeps(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/docs", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	// страница документации не должна тянуть скрипты со сторонних доменов
+	for _, src := range regexp.MustCompile(`src="([^"]+)"`).FindAllStringSubmatch(rec.Body.String(), -1) {
+		if !strings.HasPrefix(src[1], "/") || strings.HasPrefix(src[1], "//") {
+			t.Errorf("docs page loads external script %s", src[1])
+		}
+		_, pattern := r.mux.Handler(httptest.NewRequest(http.MethodGet, src[1], nil))
+		if pattern != "GET "+src[1] {
+			t.Errorf("script %s is served by %q", src[1], pattern)
+		}
+
+		// без встроенного бандла скрипт берётся из CDN, а не отвечает 404
+		script := httptest.NewRecorder()
+		r.ServeHTTP(script, httptest.NewRequest(http.MethodGet, src[1], nil))
+		if script.Code != http.StatusOK && script.Code != http.StatusFound {
+			t.Errorf("script %s: status = %d", src[1], script.Code)
+		}
+	}
+}
+
+type recordingExporter struct {
+	mu    sync.Mutex
+	spans []tracing.SpanData
+}
+
+func (e *recordingExporter) Export(ctx context.Context, spans []tracing.SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+// TestRouteReachesOuterMiddleware проверяет, что шаблон маршрута виден метрикам и
+// трассировке и для запросов с API-ключом.
+func TestRouteReachesOuterMiddleware(t *testing.T) {
+	tests := []struct {
+		name      string
+		header    string
+		wantRoute string
+		status    string
+	}{
+		{"authenticated", "Bearer alice-key", "POST /api/shorten", "201"},
+		{"anonymous", "", "POST /api/shorten", "201"},
+		{"invalid key", "Bearer wrong", "POST /api/shorten", "401"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := &recordingExporter{}
+			deps := testDeps(t)
+			deps.Auth = auth.ParseStaticKeys("alice-key:alice")
+			deps.Tracer = tracing.NewTracer(tracing.AlwaysSample(), exporter)
+			r, err := NewRouter(deps)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(`{"url":"https://example.com/"}`))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+			if got := strconv.Itoa(rec.Code); got != tt.status {
+				t.Fatalf("status = %s, want %s: %s", got, tt.status, rec.Body)
+			}
+
+			var out bytes.Buffer
+			deps.Metrics.Write(&out)
+			want := `http_requests_total{method="POST",route="` + tt.wantRoute + `",status="` + tt.status + `"} 1`
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("metrics do not contain %s:\n%s", want, out.String())
+			}
+
+			if err := deps.Tracer.Shutdown(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, span := range exporter.spans {
+				if span.Kind == tracing.SpanKindServer {
+					names = append(names, span.Name)
+				}
+			}
+			if len(names) != 1 || names[0] != tt.wantRoute {
+				t.Errorf("server spans = %v, want [%s]", names, tt.wantRoute)
+			}
+		})
+	}
+}
+
+func specDocument(t *testing.T) []byte {
+	t.Helper()
+	r, err := NewRouter(testDeps(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	return rec.Body.Bytes()
+}
+
+func TestShortURLScheme(t *testing.T) {
+	tests := []struct {
+		name       string
+		remoteAddr string
+		proto      string
+		want       string
+	}{
+		{name: "direct client", remoteAddr: "198.51.100.1:1234", proto: "https", want: "http://"},
+		{name: "direct client, unsafe scheme", remoteAddr: "198.51.100.1:1234", proto: "javascript", want: "http://"},
+		{name: "trusted proxy", remoteAddr: "10.0.0.5:1234", proto: "https", want: "https://"},
+		{name: "trusted proxy, unsafe scheme", remoteAddr: "10.0.0.5:1234", proto: "javascript", want: "http://"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps := testDeps(t)
+			deps.TrustedProxies = []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+			r, err := NewRouter(deps)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(`{"url":"https://example.com/"}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-Forwarded-Proto", tt.proto)
+			req.RemoteAddr = tt.remoteAddr
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+			}
+			var body struct {
+				ShortURL string `json:"short_url"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(body.ShortURL, tt.want) {
+				t.Errorf("short_url = %q, want the %s scheme", body.ShortURL, tt.want)
+			}
+		})
+	}
+}
+
+// TestResponsesMatchSpec прогоняет настоящие ответы маршрутов через проверку
+// ответов: любое расхождение с документом роняет тест.
+func TestResponsesMatchSpec(t *testing.T) {
+	deps := testDeps(t)
+	links := memory.NewLinkRepo()
+	deps.Shortener = shortener.NewService(links, memory.NewStatsRepo(), deps.Tracer,
+		shortener.WithCampaigns(memory.NewCampaignRepo()))
+	deps.Moderation = moderation.NewService(links, memory.NewReportRepo(), deps.Tracer)
+	deps.Auth = auth.ParseStaticKeys("alice-key:alice,root-key:root")
+	deps.Admins = []string{"root"}
+	deps.Validation = openapi.Validation{
+		Requests:  true,
+		Responses: true,
+		ResponseMismatch: func(r *http.Request, problem string) {
+			t.Errorf("%s: %s", r.Pattern, problem)
+		},
+	}
+	r, err := NewRouter(deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// capture запоминает поле ответа, чтобы подставить его в следующие запросы
+	vars := map[string]string{}
+	steps := []struct {
+		method, path, key, body string
+		wantStatus              int
+		capture                 string
+	}{
+		{http.MethodPost, "/api/shorten", "alice-key", `{"url":"https://example.com/"}`, http.StatusCreated, "short_code"},
+		{http.MethodGet, "/{short_code}", "", "", http.StatusFound, ""},
+		{http.MethodGet, "/{short_code}+", "", "", http.StatusOK, ""},
+		{http.MethodGet, "/{short_code}/preview", "", "", http.StatusOK, ""},
+		{http.MethodGet, "/missing", "", "", http.StatusNotFound, ""},
+		{http.MethodGet, "/api/links", "alice-key", "", http.StatusOK, ""},
+		{http.MethodGet, "/api/links", "wrong-key", "", http.StatusUnauthorized, ""},
+		{http.MethodGet, "/api/links/search?q=example", "alice-key", "", http.StatusOK, ""},
+		{http.MethodPost, "/api/links/tags", "alice-key", `{"short_codes":["{short_code}"],"add":["promo"]}`, http.StatusNoContent, ""},
+		{http.MethodGet, "/api/tags", "alice-key", "", http.StatusOK, ""},
+		{http.MethodPost, "/api/campaigns", "alice-key", `{"name":"spring"}`, http.StatusCreated, "id"},
+		{http.MethodGet, "/api/campaigns", "alice-key", "", http.StatusOK, ""},
+		{http.MethodPut, "/api/links/{short_code}/campaigns", "alice-key", `{"campaigns":["{id}"]}`, http.StatusOK, ""},
+		{http.MethodGet, "/api/campaigns/{id}/stats", "alice-key", "", http.StatusOK, ""},
+		{http.MethodPut, "/api/links/{short_code}/forwarding", "alice-key", `{"params":{"utm_source":"{code}"}}`, http.StatusOK, ""},
+		{http.MethodPut, "/api/links/{short_code}/routing", "alice-key", `{"geo":[{"countries":["de"],"url":"https://example.de/"}]}`, http.StatusOK, ""},
+		{http.MethodPost, "/api/links/batch", "alice-key", `[{"url":"https://example.org/","tags":["promo"]},{"url":"not a url"}]`, http.StatusOK, ""},
+		{http.MethodPost, "/api/graphql", "alice-key", `{"query":"{ links { shortCode } }"}`, http.StatusOK, ""},
+		{http.MethodPost, "/{short_code}/report", "", `{"reason":"spam"}`, http.StatusCreated, ""},
+		{http.MethodGet, "/api/admin/reports", "root-key", "", http.StatusOK, ""},
+		{http.MethodGet, "/api/admin/reports", "alice-key", "", http.StatusForbidden, ""},
+		{http.MethodPost, "/api/admin/policy/check", "root-key", `{"url":"https://example.com/"}`, http.StatusOK, ""},
+		{http.MethodGet, "/healthz", "", "", http.StatusOK, ""},
+	}
+
+	for _, step := range steps {
+		path, body := step.path, step.body
+		for name, value := range vars {
+			path = strings.ReplaceAll(path, "{"+name+"}", value)
+			body = strings.ReplaceAll(body, "{"+name+"}", value)
+		}
+		req := httptest.NewRequest(step.method, path, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if step.key != "" {
+			req.Header.Set("Authorization", "Bearer "+step.key)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if rec.Code != step.wantStatus {
+			t.Fatalf("%s %s: status = %d, want %d: %s", step.method, path, rec.Code, step.wantStatus, rec.Body)
+		}
+		if step.capture != "" {
+			var out map[string]any
+			if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+				t.Fatal(err)
+			}
+			vars[step.capture], _ = out[step.capture].(string)
+		}
+	}
+}
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS links (
+    short_code   VARCHAR(64) PRIMARY KEY,
+    original_url TEXT        NOT NULL,
+    created_at   BIGINT      NOT NULL
+);
@@ -0,0 +1,9 @@
+ALTER TABLE links
+    ADD COLUMN IF NOT EXISTS owner_id   TEXT   NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS updated_at BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS link_stats (
+    short_code    VARCHAR(64) PRIMARY KEY REFERENCES links (short_code) ON DELETE CASCADE,
+    clicks        BIGINT      NOT NULL DEFAULT 0,
+    last_click_at BIGINT      NOT NULL DEFAULT 0
+);
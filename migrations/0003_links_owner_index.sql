@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS links_owner_id_short_code_idx ON links (owner_id, short_code);
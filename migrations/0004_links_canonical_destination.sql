@@ -0,0 +1,8 @@
+ALTER TABLE links
+    ADD COLUMN IF NOT EXISTS canonical BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- у владельца не больше одной канонической ссылки на адрес; md5, потому что
+-- длинные URL не помещаются в btree-индекс
+CREATE UNIQUE INDEX IF NOT EXISTS links_owner_destination_idx
+    ON links (owner_id, md5(original_url))
+    WHERE canonical;
@@ -0,0 +1,20 @@
+ALTER TABLE links
+    ADD COLUMN IF NOT EXISTS disabled_at     BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS disabled_reason TEXT   NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS link_reports (
+    id         TEXT        PRIMARY KEY,
+    short_code VARCHAR(64) NOT NULL,
+    reason     TEXT        NOT NULL,
+    comment    TEXT        NOT NULL DEFAULT '',
+    reporter   TEXT        NOT NULL,
+    state      TEXT        NOT NULL DEFAULT 'open',
+    note       TEXT        NOT NULL DEFAULT '',
+    created_at BIGINT      NOT NULL,
+    updated_at BIGINT      NOT NULL DEFAULT 0
+);
+
+-- жалобы переживают удаление ссылки, чтобы у модераторов оставалась история;
+-- очередь фильтруется по состоянию, порог автоотключения считается по ссылке
+CREATE INDEX IF NOT EXISTS link_reports_state_idx ON link_reports (state, id);
+CREATE INDEX IF NOT EXISTS link_reports_code_created_idx ON link_reports (short_code, created_at);
@@ -0,0 +1,3 @@
+-- заголовок, описание и картинки страницы назначения; NULL, пока не загружены
+ALTER TABLE links
+    ADD COLUMN IF NOT EXISTS metadata JSONB;
@@ -0,0 +1,3 @@
+-- правила выбора адреса по устройству посетителя; NULL — всегда original_url
+ALTER TABLE links
+    ADD COLUMN IF NOT EXISTS routing JSONB;
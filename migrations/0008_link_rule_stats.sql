@@ -0,0 +1,7 @@
+-- переходы по правилам маршрутизации ссылки; правило задаётся строкой вида geo:<имя>
+CREATE TABLE IF NOT EXISTS link_rule_stats (
+    short_code VARCHAR(64) NOT NULL REFERENCES links (short_code) ON DELETE CASCADE,
+    rule       TEXT        NOT NULL,
+    clicks     BIGINT      NOT NULL DEFAULT 0,
+    PRIMARY KEY (short_code, rule)
+);
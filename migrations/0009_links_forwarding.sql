@@ -0,0 +1,3 @@
+-- параметры и путь, добавляемые к адресу назначения при переходе
+ALTER TABLE links
+    ADD COLUMN IF NOT EXISTS forwarding JSONB;
@@ -0,0 +1,18 @@
+-- переходы с выданным идентификатором клика и конверсии по ним
+CREATE TABLE IF NOT EXISTS tracked_clicks (
+    id         TEXT        PRIMARY KEY,
+    short_code VARCHAR(64) NOT NULL REFERENCES links (short_code) ON DELETE CASCADE,
+    variant    TEXT        NOT NULL DEFAULT '',
+    campaign   TEXT        NOT NULL DEFAULT '',
+    at         BIGINT      NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS tracked_clicks_short_code_idx ON tracked_clicks (short_code);
+
+CREATE TABLE IF NOT EXISTS conversions (
+    click_id TEXT           NOT NULL REFERENCES tracked_clicks (id) ON DELETE CASCADE,
+    event    TEXT           NOT NULL,
+    value    NUMERIC(20, 4) NOT NULL DEFAULT 0,
+    at       BIGINT         NOT NULL,
+    PRIMARY KEY (click_id, event)
+);
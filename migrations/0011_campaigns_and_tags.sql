@@ -0,0 +1,19 @@
+-- кампании владельцев; ссылки хранят метки и идентификаторы кампаний массивами
+CREATE TABLE IF NOT EXISTS campaigns (
+    id          TEXT   PRIMARY KEY,
+    owner_id    TEXT   NOT NULL DEFAULT '',
+    name        TEXT   NOT NULL,
+    description TEXT   NOT NULL DEFAULT '',
+    created_at  BIGINT NOT NULL,
+    updated_at  BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS campaigns_owner_idx ON campaigns (owner_id, id);
+
+ALTER TABLE links
+    ADD COLUMN IF NOT EXISTS tags      TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS campaigns TEXT[] NOT NULL DEFAULT '{}';
+
+-- фильтры списка ссылок (tags @> ARRAY[...]) и удаление кампании
+CREATE INDEX IF NOT EXISTS links_tags_idx ON links USING GIN (tags);
+CREATE INDEX IF NOT EXISTS links_campaigns_idx ON links USING GIN (campaigns);
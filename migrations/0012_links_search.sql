@@ -0,0 +1,45 @@
+-- полнотекстовый поиск по ссылкам: search_vector — слова с весами для поиска
+-- по словам и префиксам, search_text — те же слова строкой для поиска с
+-- опечатками по триграммам. Обе колонки заполняет триггер.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE links
+    ADD COLUMN IF NOT EXISTS search_vector TSVECTOR NOT NULL DEFAULT ''::tsvector,
+    ADD COLUMN IF NOT EXISTS search_text   TEXT     NOT NULL DEFAULT '';
+
+-- слова в нижнем регистре через пробел, как их делит textsearch.Terms
+CREATE OR REPLACE FUNCTION links_search_words(value TEXT) RETURNS TEXT
+    LANGUAGE SQL IMMUTABLE AS
+$$ SELECT trim(regexp_replace(lower(coalesce(value, '')), '[^[:alnum:]]+', ' ', 'g')) $$;
+
+CREATE OR REPLACE FUNCTION links_search_update() RETURNS TRIGGER
+    LANGUAGE plpgsql AS
+$$
+DECLARE
+    code        TEXT := links_search_words(NEW.short_code);
+    tags        TEXT := links_search_words(array_to_string(NEW.tags, ' '));
+    title       TEXT := links_search_words(NEW.metadata ->> 'title');
+    -- без схемы, чтобы "https" не находил все ссылки подряд
+    url         TEXT := links_search_words(regexp_replace(NEW.original_url, '^[a-z][a-z0-9+.-]*://', '', 'i'));
+    description TEXT := links_search_words(NEW.metadata ->> 'description');
+BEGIN
+    NEW.search_vector :=
+        setweight(to_tsvector('simple', code || ' ' || tags), 'A') ||
+        setweight(to_tsvector('simple', title), 'B') ||
+        setweight(to_tsvector('simple', url), 'C') ||
+        setweight(to_tsvector('simple', description), 'D');
+    NEW.search_text := concat_ws(' ', code, tags, title, url, description);
+    RETURN NEW;
+END
+$$;
+
+DROP TRIGGER IF EXISTS links_search_update ON links;
+CREATE TRIGGER links_search_update
+    BEFORE INSERT OR UPDATE OF short_code, original_url, metadata, tags ON links
+    FOR EACH ROW EXECUTE FUNCTION links_search_update();
+
+-- заполняет колонки у существующих ссылок
+UPDATE links SET tags = tags;
+
+CREATE INDEX IF NOT EXISTS links_search_vector_idx ON links USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS links_search_text_idx ON links USING GIN (search_text gin_trgm_ops);
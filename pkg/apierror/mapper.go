@@ -0,0 +1,50 @@
+package apierror
+
+import (
+	"net/http"
+	"shorted/internal/domain/domainerr"
+)
+
+var kindStatus = map[domainerr.Kind]int{
+	domainerr.KindInvalid:         http.StatusBadRequest,
+	domainerr.KindUnauthenticated: http.StatusUnauthorized,
+	domainerr.KindForbidden:       http.StatusForbidden,
+	domainerr.KindNotFound:        http.StatusNotFound,
+	domainerr.KindNotAcceptable:   http.StatusNotAcceptable,
+	domainerr.KindConflict:        http.StatusConflict,
+	domainerr.KindGone:            http.StatusGone,
+	domainerr.KindUnsupportedType: http.StatusUnsupportedMediaType,
+	domainerr.KindTooLarge:        http.StatusRequestEntityTooLarge,
+	domainerr.KindUnprocessable:   http.StatusUnprocessableEntity,
+	domainerr.KindRateLimited:     http.StatusTooManyRequests,
+	domainerr.KindUnavailable:     http.StatusServiceUnavailable,
+	domainerr.KindInternal:        http.StatusInternalServerError,
+}
+
+// Map переводит ошибку сервиса или репозитория в доменную ошибку и HTTP-статус.
+// Неизвестные ошибки превращаются в ErrInternal, исходный текст наружу не попадает.
+func Map(err error) (*domainerr.Error, int) {
+	de := domainerr.From(err)
+
+	status, ok := kindStatus[de.Kind]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+
+	return de, status
+}
+
+func (w *writer) WriteErr(rw http.ResponseWriter, r *http.Request, err error) {
+	de, status := Map(err)
+	if status >= 500 {
+		// в лог уходит полная цепочка, клиенту — только текст из каталога
+		logInternal(r, err.Error())
+	}
+
+	var details interface{}
+	if len(de.Fields) > 0 {
+		details = de.Fields
+	}
+
+	w.write(rw, r, status, de.Code, de.Message, details)
+}
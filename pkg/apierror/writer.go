@@ -1,40 +1,134 @@
 package apierror
 
 import (
-	"encoding/json"
+	"bytes"
 	"log"
 	"net/http"
 	"shorted/internal/contract"
+	"shorted/pkg/codec"
+	"shorted/pkg/tracing"
+	"strings"
 )
 
-type writer struct{}
+type writer struct {
+	problem  bool
+	typeBase string
+	codecs   *codec.Registry
+}
+
+type Option func(*writer)
 
-func New() contract.ErrorWriter {
-	return &writer{}
+// WithProblemDetails включает формат RFC 7807; typeBase — префикс URI для поля type.
+func WithProblemDetails(typeBase string) Option {
+	return func(w *writer) {
+		w.problem = true
+		w.typeBase = typeBase
+	}
 }
 
-func (w *writer) WriteError(rw http.ResponseWriter, status int, message string) {
-	w.WriteWithCode(rw, status, http.StatusText(status), message, nil)
+// WithCodecs задаёт реестр форматов, из которого выбирается кодек по Accept.
+func WithCodecs(codecs *codec.Registry) Option {
+	return func(w *writer) {
+		w.codecs = codecs
+	}
 }
 
-func (w *writer) WriteWithCode(rw http.ResponseWriter, status int, errorCode, message string, details interface{}) {
+func New(opts ...Option) contract.ErrorWriter {
+	w := &writer{}
+	for _, opt := range opts {
+		opt(w)
+	}
+	if w.codecs == nil {
+		w.codecs = codec.Default()
+	}
+	return w
+}
+
+func (w *writer) WriteError(rw http.ResponseWriter, r *http.Request, status int, message string) {
+	w.WriteWithCode(rw, r, status, http.StatusText(status), message, nil)
+}
+
+func (w *writer) WriteWithCode(rw http.ResponseWriter, r *http.Request, status int, errorCode, message string, details interface{}) {
 	if status >= 500 {
-		log.Printf("internal error: %s", message)
+		logInternal(r, message)
 	}
+	w.write(rw, r, status, errorCode, message, details)
+}
 
-	rw.Header().Set("Content-Type", "application/json")
-	rw.WriteHeader(status)
+type errorBody struct {
+	Code    int         `json:"code"`
+	Error   string      `json:"error"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"`
+	TraceID string      `json:"trace_id,omitempty"`
+}
 
-	json.NewEncoder(rw).Encode(struct {
-		Code    int         `json:"code"`
-		Error   string      `json:"error"`
-		Message string      `json:"message"`
-		Details interface{} `json:"details,omitempty"`
-	}{
-		Code:    status,
-		Error:   errorCode,
-		Message: message,
-		Details: details,
-	})
+func (errorBody) XMLRoot() (string, string) { return "error", "" }
+
+type problem struct {
+	Type     string      `json:"type"`
+	Title    string      `json:"title"`
+	Status   int         `json:"status"`
+	Detail   string      `json:"detail,omitempty"`
+	Instance string      `json:"instance,omitempty"`
+	Code     string      `json:"code,omitempty"`
+	Details  interface{} `json:"details,omitempty"`
+	TraceID  string      `json:"trace_id,omitempty"`
+}
+
+func (problem) XMLRoot() (string, string) { return "problem", "urn:ietf:rfc:7807" }
+
+func (w *writer) write(rw http.ResponseWriter, r *http.Request, status int, errorCode, message string, details interface{}) {
+	// об ошибке нужно сообщить в любом случае, поэтому без подходящего формата отвечаем JSON
+	c, ok := w.codecs.Negotiate(r.Header.Get("Accept"))
+	if !ok {
+		c = codec.JSON{}
+	}
+
+	traceID := tracing.TraceIDFromContext(r.Context())
+	contentType := c.ContentType()
+
+	var body interface{}
+	if w.problem {
+		problemType := "about:blank"
+		if errorCode != "" && errorCode != http.StatusText(status) {
+			problemType = w.typeBase + strings.ReplaceAll(errorCode, "_", "-")
+		}
+		body = problem{
+			Type:     problemType,
+			Title:    http.StatusText(status),
+			Status:   status,
+			Detail:   message,
+			Instance: r.URL.RequestURI(),
+			Code:     errorCode,
+			Details:  details,
+			TraceID:  traceID,
+		}
+		// RFC 7807 определяет problem+json и problem+xml
+		if typ, subtype, _ := strings.Cut(contentType, "/"); subtype == "json" || subtype == "xml" {
+			contentType = typ + "/problem+" + subtype
+		}
+	} else {
+		body = errorBody{
+			Code:    status,
+			Error:   errorCode,
+			Message: message,
+			Details: details,
+			TraceID: traceID,
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := c.Encode(&buf, body); err != nil {
+		log.Printf("encode %s error response: %v", c.ContentType(), err)
+	}
+
+	rw.Header().Set("Content-Type", contentType)
+	rw.Header().Add("Vary", "Accept")
+	rw.WriteHeader(status)
+	rw.Write(buf.Bytes())
+}
 
+func logInternal(r *http.Request, message string) {
+	log.Printf("internal error: %s trace_id=%s", message, tracing.TraceIDFromContext(r.Context()))
 }
@@ -0,0 +1,158 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"shorted/internal/domain/domainerr"
+	"shorted/internal/domain/repositories"
+	"strings"
+	"testing"
+)
+
+func TestMap(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{"validation", domainerr.Validation(domainerr.FieldError{Field: "url"}), http.StatusBadRequest, "validation_failed"},
+		{"wrapped domain error", fmt.Errorf("create: %w", domainerr.ErrForbidden), http.StatusForbidden, "forbidden"},
+		{"repository not found", fmt.Errorf("find: %w", repositories.ErrNotFound), http.StatusNotFound, "link_not_found"},
+		{"repository conflict", repositories.ErrAlreadyExists, http.StatusConflict, "link_already_exists"},
+		{"disabled link", domainerr.ErrLinkDisabled, http.StatusGone, "link_disabled"},
+		{"body too large", domainerr.ErrBodyTooLarge.WithCause(errors.New("http: request body too large")), http.StatusRequestEntityTooLarge, "body_too_large"},
+		{"unknown error", errors.New("pq: connection reset"), http.StatusInternalServerError, "internal"},
+		{"unknown kind", domainerr.New("teapot", "teapot", "short and stout"), http.StatusInternalServerError, "teapot"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			de, status := Map(tt.err)
+			if status != tt.wantStatus || de.Code != tt.wantCode {
+				t.Errorf("Map = %d %s, want %d %s", status, de.Code, tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestWriteErr(t *testing.T) {
+	validation := domainerr.Validation(domainerr.FieldError{Field: "url", Code: "invalid_url", Message: "bad"})
+
+	tests := []struct {
+		name        string
+		opts        []Option
+		accept      string
+		err         error
+		wantType    string
+		wantContent string
+		wantBody    map[string]any
+	}{
+		{
+			name:        "legacy body",
+			err:         validation,
+			wantContent: "application/json",
+			wantBody: map[string]any{
+				"code": 400.0, "error": "validation_failed", "message": "request validation failed",
+				"details": []any{map[string]any{"field": "url", "code": "invalid_url", "message": "bad"}},
+			},
+		},
+		{
+			name:        "problem details",
+			opts:        []Option{WithProblemDetails("https://errors.example.com/")},
+			err:         validation,
+			wantContent: "application/problem+json",
+			wantBody: map[string]any{
+				"type": "https://errors.example.com/validation-failed", "title": "Bad Request", "status": 400.0,
+				"detail": "request validation failed", "instance": "/api/shorten?x=1", "code": "validation_failed",
+				"details": []any{map[string]any{"field": "url", "code": "invalid_url", "message": "bad"}},
+			},
+		},
+		{
+			name:        "internal error hides the cause",
+			opts:        []Option{WithProblemDetails("https://errors.example.com/")},
+			err:         errors.New("pq: password authentication failed"),
+			wantContent: "application/problem+json",
+			wantBody: map[string]any{
+				"type": "https://errors.example.com/internal", "title": "Internal Server Error", "status": 500.0,
+				"detail": "internal server error", "instance": "/api/shorten?x=1", "code": "internal",
+			},
+		},
+		{
+			name:        "problem xml",
+			opts:        []Option{WithProblemDetails("https://errors.example.com/")},
+			accept:      "application/xml",
+			err:         domainerr.ErrLinkNotFound,
+			wantContent: "application/problem+xml",
+		},
+		{
+			name:        "unacceptable Accept falls back to JSON",
+			accept:      "image/png",
+			err:         domainerr.ErrLinkNotFound,
+			wantContent: "application/json",
+			wantBody:    map[string]any{"code": 404.0, "error": "link_not_found", "message": "link not found"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/shorten?x=1", nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			New(tt.opts...).WriteErr(rec, r, tt.err)
+
+			de, status := Map(tt.err)
+			if rec.Code != status {
+				t.Errorf("status = %d, want %d", rec.Code, status)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != tt.wantContent {
+				t.Errorf("Content-Type = %s, want %s", ct, tt.wantContent)
+			}
+			if rec.Header().Get("Vary") != "Accept" {
+				t.Error("missing Vary: Accept")
+			}
+			if tt.wantBody == nil {
+				if !strings.Contains(rec.Body.String(), de.Code) {
+					t.Errorf("body does not mention %s: %s", de.Code, rec.Body)
+				}
+				return
+			}
+			var body map[string]any
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if got, want := mustJSON(t, body), mustJSON(t, tt.wantBody); got != want {
+				t.Errorf("body:\n got %s\nwant %s", got, want)
+			}
+		})
+	}
+}
+
+func TestWriteErrorUsesStatusText(t *testing.T) {
+	rec := httptest.NewRecorder()
+	New(WithProblemDetails("https://errors.example.com/")).
+		WriteError(rec, httptest.NewRequest(http.MethodGet, "/x", nil), http.StatusTooManyRequests, "slow down")
+
+	var body problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	// код совпадает с текстом статуса, поэтому type — about:blank
+	if body.Type != "about:blank" || body.Title != "Too Many Requests" || body.Detail != "slow down" {
+		t.Errorf("problem = %+v", body)
+	}
+}
+
+func mustJSON(t *testing.T, v any) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
@@ -0,0 +1,57 @@
+package apirequest
+
+import (
+	"errors"
+	"net/http"
+	"shorted/internal/contract"
+	"shorted/internal/domain/domainerr"
+	"shorted/pkg/codec"
+)
+
+const defaultMaxBodySize = 1 << 20
+
+type decoder struct {
+	codecs      *codec.Registry
+	maxBodySize int64
+}
+
+type Option func(*decoder)
+
+// WithMaxBodySize меняет предел размера тела (по умолчанию 1 МБ).
+func WithMaxBodySize(n int64) Option {
+	return func(d *decoder) {
+		d.maxBodySize = n
+	}
+}
+
+func New(codecs *codec.Registry, opts ...Option) contract.RequestDecoder {
+	d := &decoder{codecs: codecs, maxBodySize: defaultMaxBodySize}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Decode читает тело в формате из Content-Type и возвращает ошибки из каталога domainerr.
+func (d *decoder) Decode(r *http.Request, v interface{}) error {
+	c, err := d.codecs.ForContentType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return domainerr.ErrUnsupportedType.WithCause(err)
+	}
+
+	body := http.MaxBytesReader(nil, r.Body, d.maxBodySize)
+	if err := c.Decode(body, v); err != nil {
+		return BodyError(err)
+	}
+
+	return nil
+}
+
+// BodyError переводит ошибку чтения тела в ErrBodyTooLarge, если сработал
+// http.MaxBytesReader, и в ErrInvalidBody в остальных случаях.
+func BodyError(err error) *domainerr.Error {
+	if errors.As(err, new(*http.MaxBytesError)) {
+		return domainerr.ErrBodyTooLarge.WithCause(err)
+	}
+	return domainerr.ErrInvalidBody.WithCause(err)
+}
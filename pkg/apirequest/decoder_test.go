@@ -0,0 +1,60 @@
+package apirequest
+
+import (
+	"errors"
+	"net/http/httptest"
+	"shorted/internal/domain/domainerr"
+	"shorted/pkg/codec"
+	"strings"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		maxBody     int64
+		wantErr     *domainerr.Error
+		want        string
+	}{
+		{name: "json", contentType: "application/json", body: `{"url":"https://example.com/"}`, want: "https://example.com/"},
+		{name: "json with charset", contentType: "application/json; charset=utf-8", body: `{"url":"x"}`, want: "x"},
+		{name: "xml", contentType: "application/xml", body: `<request><url>x</url></request>`, want: "x"},
+		{name: "unsupported type", contentType: "text/plain", body: "x", wantErr: domainerr.ErrUnsupportedType},
+		{name: "malformed body", contentType: "application/json", body: `{"url":`, wantErr: domainerr.ErrInvalidBody},
+		{name: "body too large", contentType: "application/json", body: `{"url":"https://example.com/"}`, maxBody: 10, wantErr: domainerr.ErrBodyTooLarge},
+		{name: "xml body too large", contentType: "application/xml", body: `<request><url>https://example.com/</url></request>`, maxBody: 10, wantErr: domainerr.ErrBodyTooLarge},
+		{name: "msgpack body too large", contentType: "application/msgpack", body: "\x81\xa3url\xb4https://example.com/", maxBody: 10, wantErr: domainerr.ErrBodyTooLarge},
+		{name: "cbor body too large", contentType: "application/cbor", body: "\xa1curlthttps://example.com/", maxBody: 10, wantErr: domainerr.ErrBodyTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []Option
+			if tt.maxBody > 0 {
+				opts = append(opts, WithMaxBodySize(tt.maxBody))
+			}
+			d := New(codec.Default(), opts...)
+
+			r := httptest.NewRequest("POST", "/", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", tt.contentType)
+			var v struct {
+				URL string `json:"url" xml:"url" msgpack:"url" cbor:"url"`
+			}
+			err := d.Decode(r, &v)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %s", err, tt.wantErr.Code)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v.URL != tt.want {
+				t.Errorf("URL = %q, want %q", v.URL, tt.want)
+			}
+		})
+	}
+}
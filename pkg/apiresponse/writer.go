@@ -1,19 +1,42 @@
 package apiresponse
 
 import (
-	"encoding/json"
+	"bytes"
+	"log"
 	"net/http"
 	"shorted/internal/contract"
+	"shorted/internal/domain/domainerr"
+	"shorted/pkg/codec"
+	"strings"
 )
 
-type writer struct{}
+type writer struct {
+	codecs *codec.Registry
+	errors contract.ErrorWriter
+}
 
-func New() contract.ResponseWriter {
-	return &writer{}
+func New(codecs *codec.Registry, errors contract.ErrorWriter) contract.ResponseWriter {
+	return &writer{codecs: codecs, errors: errors}
 }
 
-func (w *writer) Write(rw http.ResponseWriter, status int, data interface{}) {
-	rw.Header().Set("Content-Type", "application/json")
+func (w *writer) Write(rw http.ResponseWriter, r *http.Request, status int, data interface{}) {
+	c, ok := w.codecs.Negotiate(r.Header.Get("Accept"))
+	if !ok {
+		w.errors.WriteErr(rw, r, domainerr.ErrNotAcceptable.WithMessage(
+			"supported types: "+strings.Join(w.codecs.ContentTypes(), ", ")))
+		return
+	}
+
+	// кодируем в буфер, чтобы ошибка сериализации не оставила клиенту половину тела с 200
+	var buf bytes.Buffer
+	if err := c.Encode(&buf, data); err != nil {
+		log.Printf("encode %s response: %v", c.ContentType(), err)
+		w.errors.WriteErr(rw, r, err)
+		return
+	}
+
+	rw.Header().Set("Content-Type", c.ContentType())
+	rw.Header().Add("Vary", "Accept")
 	rw.WriteHeader(status)
-	json.NewEncoder(rw).Encode(data)
+	rw.Write(buf.Bytes())
 }
@@ -0,0 +1,61 @@
+package apiresponse
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"shorted/pkg/apierror"
+	"shorted/pkg/codec"
+	"strings"
+	"testing"
+)
+
+type payload struct {
+	Code string `json:"code"`
+}
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		name        string
+		accept      string
+		data        any
+		wantStatus  int
+		wantContent string
+		wantBody    string
+	}{
+		{"default codec", "", payload{Code: "abc"}, http.StatusCreated, "application/json", `{"code":"abc"}` + "\n"},
+		{"negotiated codec", "application/x-msgpack", payload{Code: "abc"}, http.StatusCreated, "application/msgpack", "\x81\xa4code\xa3abc"},
+		{"not acceptable", "image/png", payload{Code: "abc"}, http.StatusNotAcceptable, "application/json", "supported types: application/json"},
+		// тело не начато, поэтому клиент получает ошибку, а не 201 с обрывком
+		{"encode failure", "", map[string]any{"ch": make(chan int)}, http.StatusInternalServerError, "application/json", `"internal"`},
+	}
+
+	codecs := codec.Default()
+	w := New(codecs, apierror.New(apierror.WithCodecs(codecs)))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/shorten", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			w.Write(rec, req, http.StatusCreated, tt.data)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != tt.wantContent {
+				t.Errorf("Content-Type = %q, want %q", ct, tt.wantContent)
+			}
+			if tt.wantStatus == http.StatusCreated {
+				if rec.Body.String() != tt.wantBody {
+					t.Errorf("body = %q, want %q", rec.Body, tt.wantBody)
+				}
+				if vary := rec.Header().Get("Vary"); vary != "Accept" {
+					t.Errorf("Vary = %q", vary)
+				}
+			} else if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("body = %s, want it to contain %s", rec.Body, tt.wantBody)
+			}
+		})
+	}
+}
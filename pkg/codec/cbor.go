@@ -0,0 +1,50 @@
+package codec
+
+import (
+	"io"
+	"reflect"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// CBOR реализует RFC 8949 для деревьев map/slice/scalar.
+type CBOR struct{}
+
+func (CBOR) ContentType() string { return "application/cbor" }
+
+var (
+	cborEncMode = mustCBOR(cbor.EncOptions{Sort: cbor.SortBytewiseLexical}.EncMode())
+	cborDecMode = mustCBOR(cbor.DecOptions{
+		MaxNestedLevels:  maxDecodeDepth,
+		MaxArrayElements: maxDecodeLen,
+		MaxMapPairs:      maxDecodeLen,
+		IndefLength:      cbor.IndefLengthAllowed,
+		DefaultMapType:   reflect.TypeOf(map[string]any(nil)),
+		// теги не интерпретируем, берём вложенное значение как есть
+		UnrecognizedTagToAny: cbor.UnrecognizedTagContentToAny,
+		TimeTagToAny:         cbor.TimeTagToRFC3339Nano,
+	}.DecMode())
+)
+
+func (CBOR) Encode(w io.Writer, v any) error {
+	generic, err := toGeneric(v)
+	if err != nil {
+		return err
+	}
+	return cborEncMode.NewEncoder(w).Encode(plainNumbers(generic))
+}
+
+func (CBOR) Decode(r io.Reader, v any) error {
+	var generic any
+	if err := cborDecMode.NewDecoder(r).Decode(&generic); err != nil {
+		return err
+	}
+	return fromGeneric(generic, v)
+}
+
+func mustCBOR[T any](mode T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return mode
+}
@@ -0,0 +1,98 @@
+package codec
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"strings"
+	"sync"
+)
+
+type Codec interface {
+	ContentType() string
+	Encode(w io.Writer, v any) error
+	Decode(r io.Reader, v any) error
+}
+
+var ErrUnsupported = errors.New("unsupported media type")
+
+// Registry хранит кодеки по media type; первый зарегистрированный используется по умолчанию.
+type Registry struct {
+	mu     sync.RWMutex
+	codecs []Codec
+	byType map[string]Codec
+}
+
+func NewRegistry() *Registry {
+	return &Registry{byType: make(map[string]Codec)}
+}
+
+// Default возвращает реестр с JSON (по умолчанию), MessagePack, CBOR и XML.
+func Default() *Registry {
+	r := NewRegistry()
+	r.Register(JSON{}, "text/json")
+	r.Register(MessagePack{}, "application/x-msgpack", "application/vnd.msgpack")
+	r.Register(CBOR{})
+	r.Register(XML{}, "text/xml")
+	return r
+}
+
+// Register добавляет кодек; aliases — дополнительные media type, которые он обслуживает.
+func (r *Registry) Register(c Codec, aliases ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.codecs = append(r.codecs, c)
+	r.byType[c.ContentType()] = c
+	for _, alias := range aliases {
+		r.byType[alias] = c
+	}
+}
+
+func (r *Registry) Default() Codec {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.codecs[0]
+}
+
+func (r *Registry) ContentTypes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	types := make([]string, 0, len(r.codecs))
+	for _, c := range r.codecs {
+		types = append(types, c.ContentType())
+	}
+	return types
+}
+
+// ForContentType подбирает кодек для тела запроса. Пустой Content-Type означает кодек по умолчанию.
+func (r *Registry) ForContentType(contentType string) (Codec, error) {
+	if strings.TrimSpace(contentType) == "" {
+		return r.Default(), nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, ErrUnsupported
+	}
+	if c, ok := r.lookup(mediaType); ok {
+		return c, nil
+	}
+	return nil, ErrUnsupported
+}
+
+func (r *Registry) lookup(mediaType string) (Codec, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if c, ok := r.byType[mediaType]; ok {
+		return c, true
+	}
+	// structured syntax suffix: application/problem+json -> application/json
+	if i := strings.LastIndexByte(mediaType, '+'); i >= 0 {
+		c, ok := r.byType["application/"+mediaType[i+1:]]
+		return c, ok
+	}
+	return nil, false
+}
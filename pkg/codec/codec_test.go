@@ -0,0 +1,220 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type sample struct {
+	Name    string            `json:"name"`
+	Count   int64             `json:"count"`
+	Big     uint64            `json:"big"`
+	Ratio   float64           `json:"ratio"`
+	OK      bool              `json:"ok"`
+	Tags    []string          `json:"tags,omitempty"`
+	Meta    map[string]string `json:"meta,omitempty"`
+	Nested  *sample           `json:"nested,omitempty"`
+	Skipped string            `json:"-"`
+}
+
+func (sample) XMLRoot() (string, string) { return "sample", "" }
+
+var codecs = []Codec{JSON{}, MessagePack{}, CBOR{}, XML{}}
+
+func TestRoundTrip(t *testing.T) {
+	values := map[string]sample{
+		"zero": {},
+		"full": {
+			Name:  "ссылка <&> \"quoted\"",
+			Count: -42,
+			Big:   math.MaxUint64,
+			Ratio: 0.25,
+			OK:    true,
+			Tags:  []string{"a", "b"},
+			Meta:  map[string]string{"k": "v", "empty": "x"},
+			Nested: &sample{
+				Name: "inner",
+				Tags: []string{"single"},
+			},
+		},
+		"large ints": {Count: math.MinInt64, Big: 1<<53 + 1},
+	}
+
+	for _, c := range codecs {
+		for name, in := range values {
+			t.Run(c.ContentType()+"/"+name, func(t *testing.T) {
+				var buf bytes.Buffer
+				if err := c.Encode(&buf, in); err != nil {
+					t.Fatal(err)
+				}
+				var out sample
+				if err := c.Decode(&buf, &out); err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(in, out) {
+					t.Errorf("round trip:\n got %+v\nwant %+v", out, in)
+				}
+			})
+		}
+	}
+}
+
+func TestSkippedFieldsAreNotEncoded(t *testing.T) {
+	for _, c := range codecs {
+		var buf bytes.Buffer
+		if err := c.Encode(&buf, sample{Skipped: "secret"}); err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(buf.Bytes(), []byte("secret")) {
+			t.Errorf("%s encoded a json:\"-\" field", c.ContentType())
+		}
+	}
+}
+
+func TestBinaryEncoding(t *testing.T) {
+	// ключи сортируются, целые кодируются самым коротким видом
+	in := map[string]any{"b": 1, "a": -1, "c": 1.5}
+	tests := []struct {
+		codec Codec
+		want  string
+	}{
+		{MessagePack{}, "83" + "a161ff" + "a16201" + "a163cb3ff8000000000000"},
+		{CBOR{}, "a3" + "616120" + "616201" + "6163fb3ff8000000000000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.codec.ContentType(), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.codec.Encode(&buf, in); err != nil {
+				t.Fatal(err)
+			}
+			if got := hex.EncodeToString(buf.Bytes()); got != tt.want {
+				t.Errorf("encoded %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		codec Codec
+		input string
+	}{
+		// длина строки 4 ГБ при трёх байтах данных: ошибка без выделения памяти под длину
+		{"msgpack oversized str32", MessagePack{}, "\xdb\xff\xff\xff\xffabc"},
+		{"msgpack oversized array32", MessagePack{}, "\xdd\x7f\xff\xff\xff"},
+		{"msgpack oversized map32", MessagePack{}, "\xdf\x7f\xff\xff\xff"},
+		{"msgpack deep nesting", MessagePack{}, strings.Repeat("\x91", 1000) + "\xc0"},
+		{"msgpack deep map key", MessagePack{}, "\x81" + strings.Repeat("\x91", 1000) + "\xc0\xc0"},
+		{"msgpack unknown type byte", MessagePack{}, "\xc1"},
+		{"msgpack empty", MessagePack{}, ""},
+		{"cbor oversized text", CBOR{}, "\x7b\xff\xff\xff\xff\xff\xff\xff\xffabc"},
+		{"cbor oversized array", CBOR{}, "\x9b\x00\x00\x00\x00\x7f\xff\xff\xff"},
+		{"cbor oversized map", CBOR{}, "\xbb\x00\x00\x00\x00\x7f\xff\xff\xff"},
+		{"cbor deep nesting", CBOR{}, strings.Repeat("\x81", 1000) + "\xf6"},
+		{"cbor deep indefinite nesting", CBOR{}, strings.Repeat("\x9f", 1000)},
+		{"cbor invalid additional info", CBOR{}, "\x1c"},
+		{"cbor stray break", CBOR{}, "\xff"},
+		{"cbor empty", CBOR{}, ""},
+		{"xml deep nesting", XML{}, strings.Repeat("<a>", 1000) + strings.Repeat("</a>", 1000)},
+		{"xml unclosed", XML{}, "<sample><name>x</name>"},
+		{"xml mismatched", XML{}, "<sample><name>x</nam></sample>"},
+		{"json truncated", JSON{}, `{"name":`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out map[string]any
+			if err := tt.codec.Decode(strings.NewReader(tt.input), &out); err == nil {
+				t.Errorf("decoded %v, want error", out)
+			}
+		})
+	}
+}
+
+func TestNestingLimit(t *testing.T) {
+	nested := func(depth int) any {
+		var v any = "leaf"
+		for range depth {
+			v = []any{v}
+		}
+		return v
+	}
+
+	for _, c := range []Codec{MessagePack{}, CBOR{}} {
+		t.Run(c.ContentType(), func(t *testing.T) {
+			for _, tt := range []struct {
+				depth int
+				ok    bool
+			}{{10, true}, {maxDecodeDepth - 1, true}, {maxDecodeDepth + 1, false}} {
+				var buf bytes.Buffer
+				if err := c.Encode(&buf, nested(tt.depth)); err != nil {
+					t.Fatal(err)
+				}
+				var out any
+				err := c.Decode(&buf, &out)
+				if (err == nil) != tt.ok {
+					t.Errorf("depth %d: err = %v, want ok=%v", tt.depth, err, tt.ok)
+				}
+			}
+		})
+	}
+}
+
+func TestTruncatedInput(t *testing.T) {
+	in := sample{Name: "name", Count: 300, Big: 1 << 40, Ratio: 1.5, Tags: []string{"x", "y"}, Meta: map[string]string{"k": "v"}}
+
+	for _, c := range codecs {
+		t.Run(c.ContentType(), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := c.Encode(&buf, in); err != nil {
+				t.Fatal(err)
+			}
+			full := bytes.TrimSpace(buf.Bytes())
+			for n := range len(full) {
+				var out sample
+				if err := c.Decode(bytes.NewReader(full[:n]), &out); err == nil {
+					t.Fatalf("decoded %d of %d bytes without error: %+v", n, len(full), out)
+				}
+			}
+		})
+	}
+}
+
+func TestXMLDecodeCoercion(t *testing.T) {
+	const doc = `<?xml version="1.0"?>
+<request>
+  <name> spaced </name>
+  <count>7</count>
+  <ok>true</ok>
+  <tags><item>a</item><item>b</item></tags>
+  <nested><name>inner</name><tags><item>only</item></tags></nested>
+</request>`
+
+	var out sample
+	if err := (XML{}).Decode(strings.NewReader(doc), &out); err != nil {
+		t.Fatal(err)
+	}
+	want := sample{Name: "spaced", Count: 7, OK: true, Tags: []string{"a", "b"}, Nested: &sample{Name: "inner", Tags: []string{"only"}}}
+	if !reflect.DeepEqual(out, want) {
+		t.Errorf("got %+v\nwant %+v", out, want)
+	}
+}
+
+func TestXMLEncode(t *testing.T) {
+	var buf bytes.Buffer
+	err := (XML{}).Encode(&buf, map[string]any{"short code": "a<b", "items": []string{"x"}, "1st": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+		"<response><_st>true</_st><items><item>x</item></items><short_code>a&lt;b</short_code></response>\n"
+	if buf.String() != want {
+		t.Errorf("got  %s\nwant %s", buf.String(), want)
+	}
+}
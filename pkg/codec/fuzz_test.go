@@ -0,0 +1,60 @@
+package codec
+
+import (
+	"bytes"
+	"testing"
+)
+
+// fuzzSeeds — закодированные примеры значений, от которых отталкивается фаззер.
+func fuzzSeeds(f *testing.F, c Codec) {
+	for _, v := range []any{
+		sample{Name: "x", Count: -1, Big: 1 << 63, Ratio: 0.5, OK: true, Tags: []string{"a"}, Meta: map[string]string{"k": "v"}},
+		map[string]any{"url": "https://example.com", "nested": []any{1, "two", nil, map[string]any{}}},
+		[]any{},
+	} {
+		var buf bytes.Buffer
+		if err := c.Encode(&buf, v); err != nil {
+			f.Fatal(err)
+		}
+		f.Add(buf.Bytes())
+	}
+}
+
+// fuzzDecode проверяет, что произвольный вход не роняет декодер, а то, что
+// удалось разобрать, снова кодируется и разбирается в то же дерево.
+func fuzzDecode(t *testing.T, c Codec, data []byte) {
+	var s sample
+	_ = c.Decode(bytes.NewReader(data), &s)
+
+	var v map[string]any
+	if err := c.Decode(bytes.NewReader(data), &v); err != nil {
+		return
+	}
+	var buf bytes.Buffer
+	if err := c.Encode(&buf, v); err != nil {
+		// например, NaN: JSON-мост его не принимает
+		return
+	}
+	var again map[string]any
+	if err := c.Decode(&buf, &again); err != nil {
+		t.Fatalf("re-encoded value does not decode: %v", err)
+	}
+}
+
+func FuzzMessagePackDecode(f *testing.F) {
+	fuzzSeeds(f, MessagePack{})
+	f.Add([]byte("\xdb\xff\xff\xff\xff"))
+	f.Fuzz(func(t *testing.T, data []byte) { fuzzDecode(t, MessagePack{}, data) })
+}
+
+func FuzzCBORDecode(f *testing.F) {
+	fuzzSeeds(f, CBOR{})
+	f.Add([]byte("\x9f\x9f\xff\xff"))
+	f.Fuzz(func(t *testing.T, data []byte) { fuzzDecode(t, CBOR{}, data) })
+}
+
+func FuzzXMLDecode(f *testing.F) {
+	fuzzSeeds(f, XML{})
+	f.Add([]byte("<a><item>1</item><item><b/></item></a>"))
+	f.Fuzz(func(t *testing.T, data []byte) { fuzzDecode(t, XML{}, data) })
+}
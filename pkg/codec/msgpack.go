@@ -0,0 +1,128 @@
+package codec
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/vmihailenco/msgpack/v5/msgpcode"
+)
+
+const (
+	// maxDecodeLen ограничивает длины коллекций во входящих бинарных телах.
+	maxDecodeLen = 16 << 20
+	// maxDecodeDepth ограничивает вложенность входящих тел во всех форматах.
+	maxDecodeDepth = 64
+)
+
+var (
+	errTooDeep = errors.New("codec: nesting exceeds limit")
+	errTooLong = errors.New("codec: length exceeds limit")
+	errMapKey  = errors.New("msgpack: map keys must be strings or numbers")
+)
+
+type MessagePack struct{}
+
+func (MessagePack) ContentType() string { return "application/msgpack" }
+
+func (MessagePack) Encode(w io.Writer, v any) error {
+	generic, err := toGeneric(v)
+	if err != nil {
+		return err
+	}
+
+	enc := msgpack.NewEncoder(w)
+	enc.SetSortMapKeys(true)
+	enc.UseCompactInts(true)
+	return enc.Encode(plainNumbers(generic))
+}
+
+func (MessagePack) Decode(r io.Reader, v any) error {
+	generic, err := decodeMsgpack(msgpack.NewDecoder(r), 0)
+	if err != nil {
+		return err
+	}
+	return fromGeneric(generic, v)
+}
+
+// decodeMsgpack собирает дерево map/slice/scalar. Скаляры разбирает библиотека,
+// коллекции — мы: у библиотеки нет предела вложенности.
+func decodeMsgpack(dec *msgpack.Decoder, depth int) (any, error) {
+	code, err := dec.PeekCode()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case isMsgpackArray(code):
+		if depth >= maxDecodeDepth {
+			return nil, errTooDeep
+		}
+		n, err := dec.DecodeArrayLen()
+		if err != nil {
+			return nil, err
+		}
+		if n > maxDecodeLen {
+			return nil, errTooLong
+		}
+		out := make([]any, 0, min(n, 1024))
+		for range n {
+			item, err := decodeMsgpack(dec, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, item)
+		}
+		return out, nil
+	case isMsgpackMap(code):
+		if depth >= maxDecodeDepth {
+			return nil, errTooDeep
+		}
+		n, err := dec.DecodeMapLen()
+		if err != nil {
+			return nil, err
+		}
+		if n > maxDecodeLen {
+			return nil, errTooLong
+		}
+		out := make(map[string]any, min(n, 1024))
+		for range n {
+			// ключ-коллекцию библиотека разобрала бы без предела вложенности
+			if code, err := dec.PeekCode(); err != nil {
+				return nil, err
+			} else if isMsgpackCollection(code) {
+				return nil, errMapKey
+			}
+			key, err := dec.DecodeInterfaceLoose()
+			if err != nil {
+				return nil, err
+			}
+			switch key.(type) {
+			case string, int64, uint64, float64:
+			default:
+				return nil, errMapKey
+			}
+			value, err := decodeMsgpack(dec, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			out[fmt.Sprint(key)] = value
+		}
+		return out, nil
+	default:
+		return dec.DecodeInterfaceLoose()
+	}
+}
+
+func isMsgpackArray(code byte) bool {
+	return msgpcode.IsFixedArray(code) || code == msgpcode.Array16 || code == msgpcode.Array32
+}
+
+func isMsgpackMap(code byte) bool {
+	return msgpcode.IsFixedMap(code) || code == msgpcode.Map16 || code == msgpcode.Map32
+}
+
+func isMsgpackCollection(code byte) bool {
+	return isMsgpackArray(code) || isMsgpackMap(code)
+}
@@ -0,0 +1,107 @@
+package codec
+
+import (
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type mediaRange struct {
+	typ, subtype string
+	q            float64
+}
+
+func (m mediaRange) specificity() int {
+	switch {
+	case m.typ == "*":
+		return 0
+	case m.subtype == "*":
+		return 1
+	default:
+		return 2
+	}
+}
+
+// Negotiate выбирает кодек по заголовку Accept с учётом q-значений и wildcard.
+// ok == false означает, что ни один зарегистрированный формат не подходит (406).
+func (r *Registry) Negotiate(accept string) (Codec, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return r.Default(), true
+	}
+
+	ranges := parseAccept(accept)
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].q != ranges[j].q {
+			return ranges[i].q > ranges[j].q
+		}
+		return ranges[i].specificity() > ranges[j].specificity()
+	})
+
+	for _, mr := range ranges {
+		if mr.q <= 0 {
+			continue
+		}
+		if c, ok := r.match(mr, ranges); ok {
+			return c, true
+		}
+	}
+
+	return nil, false
+}
+
+func (r *Registry) match(mr mediaRange, all []mediaRange) (Codec, bool) {
+	if mr.typ != "*" && mr.subtype != "*" {
+		return r.lookup(mr.typ + "/" + mr.subtype)
+	}
+
+	r.mu.RLock()
+	codecs := append([]Codec(nil), r.codecs...)
+	r.mu.RUnlock()
+
+	for _, c := range codecs {
+		typ, subtype, _ := strings.Cut(c.ContentType(), "/")
+		if mr.typ != "*" && mr.typ != typ {
+			continue
+		}
+		if excluded(typ, subtype, all) {
+			continue
+		}
+		return c, true
+	}
+	return nil, false
+}
+
+// excluded учитывает явный отказ вида "application/xml;q=0" при выборе по wildcard.
+func excluded(typ, subtype string, all []mediaRange) bool {
+	for _, mr := range all {
+		if mr.typ == typ && mr.subtype == subtype && mr.q <= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		typ, subtype, ok := strings.Cut(mediaType, "/")
+		if !ok {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+
+		ranges = append(ranges, mediaRange{typ: typ, subtype: subtype, q: q})
+	}
+	return ranges
+}
@@ -0,0 +1,85 @@
+package codec
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	r := Default()
+
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", "application/json"},
+		{"*/*", "application/json"},
+		{"application/msgpack", "application/msgpack"},
+		{"application/vnd.msgpack", "application/msgpack"},
+		{"application/cbor, application/json;q=0.5", "application/cbor"},
+		{"application/cbor;q=0.1, application/xml", "application/xml"},
+		// у кодеков нет основного типа text/*, только псевдонимы
+		{"text/*", ""},
+		{"text/xml", "application/xml"},
+		{"application/problem+json", "application/json"},
+		{"application/json;q=0, */*", "application/msgpack"},
+		{"application/*;q=0.5, application/cbor", "application/cbor"},
+		{"text/html, */*;q=0.1", "application/json"},
+		{"image/png", ""},
+		{"application/json;q=0", ""},
+		{"garbage;;;", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			c, ok := r.Negotiate(tt.accept)
+			if tt.want == "" {
+				if ok {
+					t.Errorf("Negotiate = %s, want none", c.ContentType())
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("Negotiate found nothing, want %s", tt.want)
+			}
+			if c.ContentType() != tt.want {
+				t.Errorf("Negotiate = %s, want %s", c.ContentType(), tt.want)
+			}
+		})
+	}
+}
+
+func TestForContentType(t *testing.T) {
+	r := Default()
+
+	tests := []struct {
+		contentType string
+		want        string
+	}{
+		{"", "application/json"},
+		{"application/json; charset=utf-8", "application/json"},
+		{"application/x-msgpack", "application/msgpack"},
+		{"application/cbor", "application/cbor"},
+		{"application/merge-patch+json", "application/json"},
+		{"text/plain", ""},
+		{"not a media type", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			c, err := r.ForContentType(tt.contentType)
+			if tt.want == "" {
+				if !errors.Is(err, ErrUnsupported) {
+					t.Errorf("err = %v, want ErrUnsupported", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.ContentType() != tt.want {
+				t.Errorf("got %s, want %s", c.ContentType(), tt.want)
+			}
+		})
+	}
+}
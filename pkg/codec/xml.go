@@ -0,0 +1,258 @@
+package codec
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// XMLRooter позволяет значению задать имя и namespace корневого элемента.
+type XMLRooter interface {
+	XMLRoot() (name, namespace string)
+}
+
+type XML struct{}
+
+func (XML) ContentType() string { return "application/xml" }
+
+func (XML) Encode(w io.Writer, v any) error {
+	root, namespace := "response", ""
+	if rooter, ok := v.(XMLRooter); ok {
+		root, namespace = rooter.XMLRoot()
+	}
+
+	generic, err := toGeneric(v)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString(xml.Header)
+	bw.WriteString("<" + root)
+	if namespace != "" {
+		bw.WriteString(` xmlns="`)
+		xml.EscapeText(bw, []byte(namespace))
+		bw.WriteString(`"`)
+	}
+	bw.WriteString(">")
+	if err := writeXMLValue(bw, generic); err != nil {
+		return err
+	}
+	bw.WriteString("</" + root + ">\n")
+	return bw.Flush()
+}
+
+func writeXMLValue(w *bufio.Writer, v any) error {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case map[string]any:
+		for _, key := range sortedKeys(v) {
+			name := xmlName(key)
+			w.WriteString("<" + name + ">")
+			if err := writeXMLValue(w, v[key]); err != nil {
+				return err
+			}
+			w.WriteString("</" + name + ">")
+		}
+		return nil
+	case []any:
+		for _, item := range v {
+			w.WriteString("<item>")
+			if err := writeXMLValue(w, item); err != nil {
+				return err
+			}
+			w.WriteString("</item>")
+		}
+		return nil
+	case string:
+		return xml.EscapeText(w, []byte(v))
+	case json.Number:
+		_, err := w.WriteString(string(v))
+		return err
+	case bool:
+		_, err := fmt.Fprint(w, v)
+		return err
+	default:
+		return fmt.Errorf("xml: unsupported type %T", v)
+	}
+}
+
+// xmlName заменяет недопустимые в имени элемента символы на "_".
+func xmlName(key string) string {
+	var b strings.Builder
+	for i, r := range key {
+		valid := r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' ||
+			i > 0 && (r == '-' || r == '.' || r >= '0' && r <= '9')
+		if valid {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	if b.Len() == 0 {
+		return "_"
+	}
+	return b.String()
+}
+
+// Decode разбирает документ в дерево: элементы с детьми становятся объектами,
+// повторяющиеся элементы — массивами, листья — строками.
+func (XML) Decode(r io.Reader, v any) error {
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if _, ok := tok.(xml.StartElement); ok {
+			generic, err := readXMLElement(dec, 0)
+			if err != nil {
+				return err
+			}
+			return fromGeneric(coerceXML(reflect.TypeOf(v), generic), v)
+		}
+	}
+}
+
+func readXMLElement(dec *xml.Decoder, depth int) (any, error) {
+	if depth >= maxDecodeDepth {
+		return nil, errTooDeep
+	}
+	var (
+		text     strings.Builder
+		children map[string]any
+	)
+
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			child, err := readXMLElement(dec, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			if children == nil {
+				children = make(map[string]any)
+			}
+			name := t.Name.Local
+			switch existing := children[name].(type) {
+			case nil:
+				children[name] = child
+			case []any:
+				children[name] = append(existing, child)
+			default:
+				children[name] = []any{existing, child}
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			if children != nil {
+				if items, ok := children["item"]; ok && len(children) == 1 {
+					if list, ok := items.([]any); ok {
+						return list, nil
+					}
+					return []any{items}, nil
+				}
+				return children, nil
+			}
+			return strings.TrimSpace(text.String()), nil
+		}
+	}
+}
+
+// coerceXML приводит строковые листья к типам целевой структуры: в XML нет
+// различия между строкой и числом, а JSON-мост его требует.
+func coerceXML(t reflect.Type, v any) any {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil {
+		return v
+	}
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if s, ok := v.(string); ok {
+			if s == "" {
+				return nil
+			}
+			return json.Number(s)
+		}
+	case reflect.Bool:
+		if s, ok := v.(string); ok {
+			if b, err := strconv.ParseBool(s); err == nil {
+				return b
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if v == "" {
+			return nil
+		}
+		items, ok := v.([]any)
+		if !ok {
+			items = []any{v}
+		}
+		for i := range items {
+			items[i] = coerceXML(t.Elem(), items[i])
+		}
+		return items
+	case reflect.Map:
+		if v == "" {
+			return nil
+		}
+		if m, ok := v.(map[string]any); ok {
+			for k := range m {
+				m[k] = coerceXML(t.Elem(), m[k])
+			}
+		}
+	case reflect.Struct:
+		if v == "" {
+			return nil
+		}
+		if m, ok := v.(map[string]any); ok {
+			for i := 0; i < t.NumField(); i++ {
+				f := t.Field(i)
+				name := strings.Split(f.Tag.Get("json"), ",")[0]
+				if name == "-" || !f.IsExported() {
+					continue
+				}
+				if name == "" {
+					name = f.Name
+				}
+				for k := range m {
+					if strings.EqualFold(k, name) {
+						m[k] = coerceXML(f.Type, m[k])
+					}
+				}
+			}
+		}
+	}
+
+	return v
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,142 @@
+package dataloader
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder — BatchFunc, запоминающая пачки ключей; значение ключа — его квадрат,
+// ключа 0 в ответе нет.
+type recorder struct {
+	mu      sync.Mutex
+	batches [][]int
+	err     error
+}
+
+func (r *recorder) fetch(ctx context.Context, keys []int) (map[int]int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.batches = append(r.batches, slices.Sorted(slices.Values(keys)))
+	if r.err != nil {
+		return nil, r.err
+	}
+	values := make(map[int]int, len(keys))
+	for _, k := range keys {
+		if k != 0 {
+			values[k] = k * k
+		}
+	}
+	return values, nil
+}
+
+// loadAll запрашивает ключи одновременно и возвращает значения в том же порядке.
+func loadAll(t *testing.T, l *Loader[int, int], keys []int) ([]int, []error) {
+	t.Helper()
+	values := make([]int, len(keys))
+	errs := make([]error, len(keys))
+	var wg sync.WaitGroup
+	for i, key := range keys {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			values[i], errs[i] = l.Load(context.Background(), key)
+		}()
+	}
+	wg.Wait()
+	return values, errs
+}
+
+func TestLoader(t *testing.T) {
+	tests := []struct {
+		name     string
+		keys     []int
+		maxBatch int
+		// wantBatches — размеры пачек по возрастанию
+		wantBatches []int
+	}{
+		{name: "one key", keys: []int{3}, maxBatch: 10, wantBatches: []int{1}},
+		{name: "one batch", keys: []int{1, 2, 3, 4}, maxBatch: 10, wantBatches: []int{4}},
+		{name: "repeated keys", keys: []int{1, 2, 1, 2, 1}, maxBatch: 10, wantBatches: []int{2}},
+		{name: "split by max batch", keys: []int{1, 2, 3, 4, 5}, maxBatch: 2, wantBatches: []int{1, 2, 2}},
+		{name: "missing key", keys: []int{0, 5}, maxBatch: 10, wantBatches: []int{2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &recorder{}
+			// ожидание с запасом, чтобы все горутины успели попасть в пачку
+			l := New(r.fetch, WithWait(50*time.Millisecond), WithMaxBatch(tt.maxBatch))
+
+			values, errs := loadAll(t, l, tt.keys)
+			for i, key := range tt.keys {
+				if errs[i] != nil || values[i] != key*key {
+					t.Errorf("Load(%d) = %d, %v; want %d", key, values[i], errs[i], key*key)
+				}
+			}
+
+			var sizes []int
+			for _, b := range r.batches {
+				sizes = append(sizes, len(b))
+			}
+			slices.Sort(sizes)
+			if !slices.Equal(sizes, tt.wantBatches) {
+				t.Errorf("batch sizes = %v (%v), want %v", sizes, r.batches, tt.wantBatches)
+			}
+		})
+	}
+}
+
+func TestLoaderCache(t *testing.T) {
+	r := &recorder{}
+	l := New(r.fetch)
+	ctx := context.Background()
+	for range 3 {
+		if v, err := l.Load(ctx, 4); err != nil || v != 16 {
+			t.Fatalf("Load(4) = %d, %v", v, err)
+		}
+	}
+	if _, err := l.Load(ctx, 5); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.batches) != 2 {
+		t.Errorf("batches = %v, want the cached key loaded once", r.batches)
+	}
+}
+
+func TestLoaderError(t *testing.T) {
+	r := &recorder{err: errors.New("db down")}
+	l := New(r.fetch, WithWait(50*time.Millisecond))
+
+	_, errs := loadAll(t, l, []int{1, 2, 3})
+	for i, err := range errs {
+		if !errors.Is(err, r.err) {
+			t.Errorf("key %d: error = %v, want the batch error", i, err)
+		}
+	}
+	// ошибка тоже кешируется: Loader живёт один запрос
+	if _, err := l.Load(context.Background(), 1); !errors.Is(err, r.err) {
+		t.Errorf("repeated load: error = %v", err)
+	}
+	if len(r.batches) != 1 {
+		t.Errorf("batches = %v", r.batches)
+	}
+}
+
+func TestLoaderContext(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	l := New(func(ctx context.Context, keys []int) (map[int]int, error) {
+		<-release
+		return nil, nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := l.Load(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error = %v, want the context error", err)
+	}
+}
@@ -0,0 +1,59 @@
+// Package geoip определяет страну и регион по IP-адресу из локальной базы в
+// формате MaxMind DB (GeoLite2/GeoIP2 Country или City, DB-IP и совместимые).
+package geoip
+
+import (
+	"net"
+	"net/netip"
+	"strings"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// Location — коды ISO 3166: Country — alpha-2 (DE), Region — код субъекта
+// ISO 3166-2 (DE-BY). Region пуст, если в базе нет субъектов (Country-базы).
+type Location struct {
+	Country string
+	Region  string
+}
+
+type DB struct {
+	reader *maxminddb.Reader
+}
+
+// record — общая часть записей Country и City.
+type record struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	Subdivisions []struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"subdivisions"`
+}
+
+// Open загружает базу из файла целиком в память.
+func Open(path string) (*DB, error) {
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &DB{reader: reader}, nil
+}
+
+// Lookup возвращает пустой Location для адресов, которых нет в базе.
+func (db *DB) Lookup(addr netip.Addr) (Location, error) {
+	var rec record
+	if err := db.reader.Lookup(net.IP(addr.Unmap().AsSlice()), &rec); err != nil {
+		return Location{}, err
+	}
+
+	loc := Location{Country: strings.ToUpper(rec.Country.ISOCode)}
+	if loc.Country != "" && len(rec.Subdivisions) > 0 && rec.Subdivisions[0].ISOCode != "" {
+		loc.Region = loc.Country + "-" + strings.ToUpper(rec.Subdivisions[0].ISOCode)
+	}
+	return loc, nil
+}
+
+func (db *DB) Close() error {
+	return db.reader.Close()
+}
@@ -0,0 +1,95 @@
+package geoip
+
+import (
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/maxmind/mmdbwriter"
+	"github.com/maxmind/mmdbwriter/mmdbtype"
+)
+
+// writeDB собирает базу в формате GeoIP2 City: страна и, если задан, первый субъект.
+func writeDB(t *testing.T, records map[string][2]string) string {
+	t.Helper()
+	tree, err := mmdbwriter.New(mmdbwriter.Options{DatabaseType: "Test-City", RecordSize: 24})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for cidr, codes := range records {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := mmdbtype.Map{"country": mmdbtype.Map{"iso_code": mmdbtype.String(codes[0])}}
+		if codes[1] != "" {
+			rec["subdivisions"] = mmdbtype.Slice{mmdbtype.Map{"iso_code": mmdbtype.String(codes[1])}}
+		}
+		if err := tree.Insert(network, rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "test.mmdb")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := tree.WriteTo(f); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLookup(t *testing.T) {
+	db, err := Open(writeDB(t, map[string][2]string{
+		"81.2.69.0/24":    {"GB", "ENG"},
+		"89.160.20.0/24":  {"se", "ab"},
+		"2.125.160.0/24":  {"DE", ""},
+		"2001:218::/32":   {"JP", "13"},
+		"175.16.199.0/24": {"", "22"},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	tests := []struct {
+		addr string
+		want Location
+	}{
+		{"81.2.69.160", Location{Country: "GB", Region: "GB-ENG"}},
+		{"89.160.20.112", Location{Country: "SE", Region: "SE-AB"}},
+		{"2.125.160.216", Location{Country: "DE"}},
+		{"2001:218::1", Location{Country: "JP", Region: "JP-13"}},
+		// IPv4 в виде IPv6 (::ffff:a.b.c.d) ищется как IPv4
+		{"::ffff:81.2.69.160", Location{Country: "GB", Region: "GB-ENG"}},
+		// без страны регион не собрать
+		{"175.16.199.1", Location{}},
+		{"8.8.8.8", Location{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			got, err := db.Lookup(netip.MustParseAddr(tt.addr))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Lookup(%s) = %+v, want %+v", tt.addr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "broken.mmdb")
+	if err := os.WriteFile(path, []byte("not a database"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(path); err == nil {
+		t.Error("Open accepted a file without MaxMind DB metadata")
+	}
+}
@@ -0,0 +1,106 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCheckerReady(t *testing.T) {
+	up := func(context.Context) error { return nil }
+	failing := func(context.Context) error { return errors.New("connection refused") }
+	hanging := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	tests := []struct {
+		name         string
+		checks       map[string]CheckFunc
+		shuttingDown bool
+		want         Status
+		wantChecks   map[string]Status
+	}{
+		{
+			name: "no checks",
+			want: StatusUp,
+		},
+		{
+			name:       "all up",
+			checks:     map[string]CheckFunc{"db": up, "cache": up},
+			want:       StatusUp,
+			wantChecks: map[string]Status{"db": StatusUp, "cache": StatusUp},
+		},
+		{
+			name:       "one failing",
+			checks:     map[string]CheckFunc{"db": failing, "cache": up},
+			want:       StatusDown,
+			wantChecks: map[string]Status{"db": StatusDown, "cache": StatusUp},
+		},
+		{
+			name:       "timeout",
+			checks:     map[string]CheckFunc{"db": hanging},
+			want:       StatusDown,
+			wantChecks: map[string]Status{"db": StatusDown},
+		},
+		{
+			name:         "shutting down",
+			checks:       map[string]CheckFunc{"db": up},
+			shuttingDown: true,
+			want:         StatusDown,
+			wantChecks:   map[string]Status{"db": StatusUp},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewChecker()
+			for name, fn := range tt.checks {
+				c.Register(name, 20*time.Millisecond, fn)
+			}
+			if tt.shuttingDown {
+				c.SetShuttingDown()
+			}
+
+			report := c.Ready(context.Background())
+			if report.Status != tt.want {
+				t.Errorf("status = %s, want %s", report.Status, tt.want)
+			}
+			if report.ShuttingDown != tt.shuttingDown {
+				t.Errorf("shutting_down = %v, want %v", report.ShuttingDown, tt.shuttingDown)
+			}
+			if len(report.Checks) != len(tt.wantChecks) {
+				t.Fatalf("checks = %v, want %v", report.Checks, tt.wantChecks)
+			}
+			for name, want := range tt.wantChecks {
+				got := report.Checks[name]
+				if got.Status != want {
+					t.Errorf("check %s = %s, want %s", name, got.Status, want)
+				}
+				if (got.Error != "") != (want == StatusDown) {
+					t.Errorf("check %s error = %q", name, got.Error)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckerRunsChecksConcurrently(t *testing.T) {
+	c := NewChecker()
+	slow := func(context.Context) error {
+		time.Sleep(50 * time.Millisecond)
+		return nil
+	}
+	for _, name := range []string{"a", "b", "c", "d"} {
+		c.Register(name, time.Second, slow)
+	}
+
+	start := time.Now()
+	if report := c.Ready(context.Background()); report.Status != StatusUp {
+		t.Fatalf("status = %s", report.Status)
+	}
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("Ready took %s, checks are not run concurrently", elapsed)
+	}
+}
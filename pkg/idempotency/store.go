@@ -0,0 +1,114 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const sweepInterval = time.Minute
+
+// ErrMismatch — ключ уже использован для запроса с другим содержимым.
+var ErrMismatch = errors.New("idempotency key reused with a different request")
+
+// Response — сохранённый ответ, который повторяется для запросов с тем же ключом.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Store хранит ответы по ключам в памяти процесса. Запросы с одним ключом
+// выполняются по очереди: второй ждёт, пока первый не сохранит ответ.
+type Store struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	entries   map[string]*entry
+	lastSweep time.Time
+}
+
+type entry struct {
+	fingerprint string
+	done        chan struct{}
+	resp        *Response
+	expires     time.Time
+}
+
+func NewStore(ttl time.Duration) *Store {
+	return &Store{
+		ttl:       ttl,
+		entries:   make(map[string]*entry),
+		lastSweep: time.Now(),
+	}
+}
+
+// Acquire возвращает сохранённый ответ для key или, если ответа ещё нет, резервирует
+// ключ и отдаёт commit. commit(nil) снимает резерв без сохранения, например после 5xx,
+// чтобы клиент мог повторить запрос.
+func (s *Store) Acquire(ctx context.Context, key, fingerprint string) (*Response, func(*Response), error) {
+	for {
+		s.mu.Lock()
+		s.sweep()
+
+		e, ok := s.entries[key]
+		if ok && e.resp != nil && time.Now().After(e.expires) {
+			delete(s.entries, key)
+			ok = false
+		}
+		if !ok {
+			e = &entry{fingerprint: fingerprint, done: make(chan struct{})}
+			s.entries[key] = e
+			s.mu.Unlock()
+			return nil, s.commit(key, e), nil
+		}
+		if e.fingerprint != fingerprint {
+			s.mu.Unlock()
+			return nil, nil, ErrMismatch
+		}
+		if e.resp != nil {
+			resp := e.resp
+			s.mu.Unlock()
+			return resp, nil, nil
+		}
+		s.mu.Unlock()
+
+		select {
+		case <-e.done:
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	}
+}
+
+func (s *Store) commit(key string, e *entry) func(*Response) {
+	return func(resp *Response) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if resp == nil {
+			delete(s.entries, key)
+		} else {
+			e.resp = resp
+			e.expires = time.Now().Add(s.ttl)
+		}
+		close(e.done)
+	}
+}
+
+// sweep раз в минуту удаляет просроченные ответы; вызывается под s.mu.
+func (s *Store) sweep() {
+	now := time.Now()
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, e := range s.entries {
+		if e.resp != nil && now.After(e.expires) {
+			delete(s.entries, key)
+		}
+	}
+}
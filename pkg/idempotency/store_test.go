@@ -0,0 +1,134 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStoreAcquire(t *testing.T) {
+	s := NewStore(time.Hour)
+	ctx := context.Background()
+
+	replay, commit, err := s.Acquire(ctx, "k", "fp")
+	if err != nil || replay != nil || commit == nil {
+		t.Fatalf("first Acquire = %v, %v", replay, err)
+	}
+	commit(&Response{Status: 201, Body: []byte("created")})
+
+	replay, commit, err = s.Acquire(ctx, "k", "fp")
+	if err != nil || commit != nil || replay == nil || replay.Status != 201 || string(replay.Body) != "created" {
+		t.Fatalf("replay = %+v, %v", replay, err)
+	}
+
+	if _, _, err := s.Acquire(ctx, "k", "other"); !errors.Is(err, ErrMismatch) {
+		t.Errorf("different fingerprint: %v", err)
+	}
+	if _, commit, err := s.Acquire(ctx, "other-key", "fp"); err != nil || commit == nil {
+		t.Errorf("other key: %v", err)
+	}
+}
+
+func TestStoreCommitNilReleasesKey(t *testing.T) {
+	s := NewStore(time.Hour)
+	_, commit, _ := s.Acquire(context.Background(), "k", "fp")
+	commit(nil)
+
+	// после снятия резерва ключ можно использовать даже с другим телом
+	replay, commit, err := s.Acquire(context.Background(), "k", "changed")
+	if err != nil || replay != nil || commit == nil {
+		t.Errorf("Acquire after release = %v, %v", replay, err)
+	}
+}
+
+func TestStoreWaitsForInFlightRequest(t *testing.T) {
+	s := NewStore(time.Hour)
+	_, commit, _ := s.Acquire(context.Background(), "k", "fp")
+
+	var wg sync.WaitGroup
+	replays := make([]*Response, 5)
+	for i := range replays {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			replays[i], _, _ = s.Acquire(context.Background(), "k", "fp")
+		}()
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	commit(&Response{Status: 200})
+	wg.Wait()
+	for i, r := range replays {
+		if r == nil || r.Status != 200 {
+			t.Errorf("waiter %d got %+v", i, r)
+		}
+	}
+}
+
+func TestStoreWaiterTakesOverAfterRelease(t *testing.T) {
+	s := NewStore(time.Hour)
+	_, commit, _ := s.Acquire(context.Background(), "k", "fp")
+
+	result := make(chan func(*Response))
+	go func() {
+		_, next, _ := s.Acquire(context.Background(), "k", "fp")
+		result <- next
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	commit(nil)
+	select {
+	case next := <-result:
+		if next == nil {
+			t.Error("waiter did not get the reservation")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("waiter is stuck")
+	}
+}
+
+func TestStoreWaitRespectsContext(t *testing.T) {
+	s := NewStore(time.Hour)
+	s.Acquire(context.Background(), "k", "fp")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, _, err := s.Acquire(ctx, "k", "fp"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestStoreExpiry(t *testing.T) {
+	s := NewStore(10 * time.Millisecond)
+	_, commit, _ := s.Acquire(context.Background(), "k", "fp")
+	commit(&Response{Status: 200})
+
+	time.Sleep(20 * time.Millisecond)
+	replay, commit, err := s.Acquire(context.Background(), "k", "other")
+	if err != nil || replay != nil || commit == nil {
+		t.Errorf("expired key: replay = %v, err = %v", replay, err)
+	}
+}
+
+func TestStoreSweep(t *testing.T) {
+	s := NewStore(time.Millisecond)
+	_, commit, _ := s.Acquire(context.Background(), "old", "fp")
+	commit(&Response{Status: 200})
+	s.Acquire(context.Background(), "pending", "fp")
+
+	time.Sleep(5 * time.Millisecond)
+	s.lastSweep = time.Now().Add(-sweepInterval)
+	s.Acquire(context.Background(), "new", "fp")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.entries["old"]; ok {
+		t.Error("expired entry survived the sweep")
+	}
+	// незавершённые запросы не удаляются, сколько бы они ни шли
+	if _, ok := s.entries["pending"]; !ok {
+		t.Error("sweep removed an in-flight entry")
+	}
+}
@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sync"
+	"sync/atomic"
+)
+
+type Counter struct {
+	values []string
+	bits   atomic.Uint64
+}
+
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+func (c *Counter) Add(delta float64) {
+	if delta < 0 {
+		panic("metrics: counter cannot decrease")
+	}
+	for {
+		old := c.bits.Load()
+		next := math.Float64bits(math.Float64frombits(old) + delta)
+		if c.bits.CompareAndSwap(old, next) {
+			return
+		}
+	}
+}
+
+func (c *Counter) Value() float64 {
+	return math.Float64frombits(c.bits.Load())
+}
+
+type CounterVec struct {
+	desc
+	mu     sync.RWMutex
+	series map[string]*Counter
+}
+
+func (v *CounterVec) WithLabelValues(values ...string) *Counter {
+	key := v.key(values)
+
+	v.mu.RLock()
+	c, ok := v.series[key]
+	v.mu.RUnlock()
+	if ok {
+		return c
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if c, ok = v.series[key]; !ok {
+		c = &Counter{values: append([]string(nil), values...)}
+		v.series[key] = c
+	}
+
+	return c
+}
+
+func (v *CounterVec) writeTo(w io.Writer) {
+	v.writeHeader(w, "counter")
+
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	for _, key := range sortedKeys(v.series) {
+		c := v.series[key]
+		fmt.Fprintf(w, "%s%s %s\n", v.name, formatLabels(v.labels, c.values), formatFloat(c.Value()))
+	}
+}
@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type Histogram struct {
+	values  []string
+	buckets []float64
+	counts  []atomic.Uint64
+	count   atomic.Uint64
+	sumBits atomic.Uint64
+}
+
+func (h *Histogram) Observe(v float64) {
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		h.counts[i].Add(1)
+	}
+	h.count.Add(1)
+	for {
+		old := h.sumBits.Load()
+		next := math.Float64bits(math.Float64frombits(old) + v)
+		if h.sumBits.CompareAndSwap(old, next) {
+			return
+		}
+	}
+}
+
+func (h *Histogram) ObserveSince(start time.Time) {
+	h.Observe(time.Since(start).Seconds())
+}
+
+type HistogramVec struct {
+	desc
+	buckets []float64
+	mu      sync.RWMutex
+	series  map[string]*Histogram
+}
+
+func (v *HistogramVec) WithLabelValues(values ...string) *Histogram {
+	key := v.key(values)
+
+	v.mu.RLock()
+	h, ok := v.series[key]
+	v.mu.RUnlock()
+	if ok {
+		return h
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if h, ok = v.series[key]; !ok {
+		h = &Histogram{
+			values:  append([]string(nil), values...),
+			buckets: v.buckets,
+			counts:  make([]atomic.Uint64, len(v.buckets)),
+		}
+		v.series[key] = h
+	}
+
+	return h
+}
+
+func (v *HistogramVec) writeTo(w io.Writer) {
+	v.writeHeader(w, "histogram")
+
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	for _, key := range sortedKeys(v.series) {
+		h := v.series[key]
+
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += h.counts[i].Load()
+			fmt.Fprintf(w, "%s_bucket%s %d\n", v.name, formatLabels(v.labels, h.values, "le", formatFloat(upper)), cumulative)
+		}
+		count := h.count.Load()
+		fmt.Fprintf(w, "%s_bucket%s %d\n", v.name, formatLabels(v.labels, h.values, "le", "+Inf"), count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", v.name, formatLabels(v.labels, h.values), formatFloat(math.Float64frombits(h.sumBits.Load())))
+		fmt.Fprintf(w, "%s_count%s %d\n", v.name, formatLabels(v.labels, h.values), count)
+	}
+}
@@ -0,0 +1,164 @@
+package metrics
+
+import (
+	"bytes"
+	"flag"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
+func TestExpositionGolden(t *testing.T) {
+	r := NewRegistry()
+
+	requests := r.NewCounterVec("http_requests_total", "Total HTTP requests.", "method", "route")
+	requests.WithLabelValues("GET", "/{code}").Add(3)
+	requests.WithLabelValues("POST", "/api/shorten").Inc()
+	// экранирование: обратная косая, кавычка и перевод строки
+	requests.WithLabelValues("GET", "C:\\path \"quoted\"\nnext").Inc()
+
+	r.NewCounter("jobs_total", "Help with \\ backslash\nand newline.").Add(1.5)
+
+	duration := r.NewHistogramVec("request_duration_seconds", "Request latency.", []float64{0.1, 0.5, 1}, "route")
+	for _, v := range []float64{0.05, 0.1, 0.3, 0.7, 2} {
+		duration.WithLabelValues("/a").Observe(v)
+	}
+
+	r.NewHistogramVec("empty_seconds", "Histogram without series.", []float64{1})
+	r.NewGaugeFunc("queue_length", "Items in queue.", func() float64 { return 7 })
+	r.NewCounterFunc("evictions_total", "Evicted entries.", func() float64 { return 0.25 })
+
+	var buf bytes.Buffer
+	r.Write(&buf)
+	assertGolden(t, "exposition.golden", buf.Bytes())
+}
+
+func TestHistogramBuckets(t *testing.T) {
+	tests := []struct {
+		name     string
+		observed []float64
+		// накопленные значения для le=1, le=2, +Inf
+		want []uint64
+		sum  float64
+	}{
+		{name: "empty", want: []uint64{0, 0, 0}},
+		{name: "on bound", observed: []float64{1, 2}, want: []uint64{1, 2, 2}, sum: 3},
+		{name: "above last bucket", observed: []float64{5}, want: []uint64{0, 0, 1}, sum: 5},
+		{name: "below first bucket", observed: []float64{-1, 0}, want: []uint64{2, 2, 2}, sum: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRegistry()
+			h := r.NewHistogramVec("h", "h", []float64{1, 2}).WithLabelValues()
+			for _, v := range tt.observed {
+				h.Observe(v)
+			}
+
+			var buf bytes.Buffer
+			r.Write(&buf)
+			out := buf.String()
+			for i, le := range []string{"1", "2", "+Inf"} {
+				line := "h_bucket{le=\"" + le + "\"} " + formatUint(tt.want[i]) + "\n"
+				if !strings.Contains(out, line) {
+					t.Errorf("missing %q in\n%s", line, out)
+				}
+			}
+			if line := "h_sum " + formatFloat(tt.sum) + "\n"; !strings.Contains(out, line) {
+				t.Errorf("missing %q in\n%s", line, out)
+			}
+			if line := "h_count " + formatUint(uint64(len(tt.observed))) + "\n"; !strings.Contains(out, line) {
+				t.Errorf("missing %q in\n%s", line, out)
+			}
+		})
+	}
+}
+
+func TestRegistryPanics(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(r *Registry)
+	}{
+		{"duplicate name", func(r *Registry) {
+			r.NewCounter("x_total", "x")
+			r.NewGaugeFunc("x_total", "x", func() float64 { return 0 })
+		}},
+		{"wrong label count", func(r *Registry) {
+			r.NewCounterVec("x_total", "x", "a", "b").WithLabelValues("only one")
+		}},
+		{"negative counter", func(r *Registry) {
+			r.NewCounter("x_total", "x").Add(-1)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected panic")
+				}
+			}()
+			tt.fn(NewRegistry())
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("hits_total", "Hits.").Inc()
+	r.RegisterGoCollector()
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if ct := rec.Header().Get("Content-Type"); ct != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE hits_total counter\nhits_total 1\n",
+		"# TYPE go_goroutines gauge\ngo_goroutines ",
+		"# TYPE go_memstats_alloc_bytes_total counter\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body does not contain %q", want)
+		}
+	}
+	// каждая метрика описана HELP и TYPE ровно один раз
+	seen := make(map[string]int)
+	for _, line := range strings.Split(body, "\n") {
+		if name, ok := strings.CutPrefix(line, "# TYPE "); ok {
+			seen[strings.Fields(name)[0]]++
+		}
+	}
+	for name, n := range seen {
+		if n != 1 {
+			t.Errorf("TYPE for %s written %d times", name, n)
+		}
+	}
+}
+
+func formatUint(v uint64) string {
+	return formatFloat(float64(v))
+}
+
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s (run with -update to rewrite)\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
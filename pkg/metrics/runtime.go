@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+)
+
+type goCollector struct{}
+
+// RegisterGoCollector добавляет базовую статистику рантайма Go (go_*).
+func (r *Registry) RegisterGoCollector() {
+	r.register("go_", goCollector{})
+}
+
+func (goCollector) writeTo(w io.Writer) {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	gauges := []struct {
+		name, help, typ string
+		value           float64
+	}{
+		{"go_goroutines", "Number of goroutines that currently exist.", "gauge", float64(runtime.NumGoroutine())},
+		{"go_threads", "Number of OS threads created.", "gauge", threadCount()},
+		{"go_memstats_alloc_bytes", "Number of bytes allocated and still in use.", "gauge", float64(ms.Alloc)},
+		{"go_memstats_alloc_bytes_total", "Total number of bytes allocated, even if freed.", "counter", float64(ms.TotalAlloc)},
+		{"go_memstats_sys_bytes", "Number of bytes obtained from system.", "gauge", float64(ms.Sys)},
+		{"go_memstats_heap_inuse_bytes", "Number of heap bytes that are in use.", "gauge", float64(ms.HeapInuse)},
+		{"go_memstats_heap_objects", "Number of allocated objects.", "gauge", float64(ms.HeapObjects)},
+		{"go_memstats_mallocs_total", "Total number of mallocs.", "counter", float64(ms.Mallocs)},
+		{"go_memstats_frees_total", "Total number of frees.", "counter", float64(ms.Frees)},
+		{"go_memstats_last_gc_time_seconds", "Number of seconds since 1970 of last garbage collection.", "gauge", float64(ms.LastGC) / 1e9},
+		{"go_gc_cycles_total", "Number of completed GC cycles.", "counter", float64(ms.NumGC)},
+		{"go_gc_pause_seconds_total", "Total GC stop-the-world pause time.", "counter", float64(ms.PauseTotalNs) / 1e9},
+	}
+
+	for _, g := range gauges {
+		desc{name: g.name, help: g.help}.writeHeader(w, g.typ)
+		fmt.Fprintf(w, "%s %s\n", g.name, formatFloat(g.value))
+	}
+}
+
+func threadCount() float64 {
+	n, _ := runtime.ThreadCreateProfile(nil)
+	return float64(n)
+}
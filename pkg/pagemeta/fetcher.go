@@ -0,0 +1,151 @@
+package pagemeta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"shorted/pkg/urlvalidate"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	defaultTimeout      = 5 * time.Second
+	defaultMaxBodySize  = 1 << 20
+	defaultMaxRedirects = 3
+	defaultUserAgent    = "shorted-preview/1.0 (+link metadata)"
+)
+
+var (
+	ErrForbiddenAddress = errors.New("pagemeta: destination resolves to a non-public address")
+	ErrNotHTML          = errors.New("pagemeta: destination is not an HTML page")
+	ErrTooManyRedirects = errors.New("pagemeta: too many redirects")
+)
+
+type Config struct {
+	Timeout time.Duration
+	// MaxBodySize — сколько байт страницы читаем; метаданные почти всегда в начале.
+	MaxBodySize  int64
+	MaxRedirects int
+	UserAgent    string
+	// AllowPrivate разрешает запросы во внутреннюю сеть; нужен только тестам на httptest.
+	AllowPrivate bool
+}
+
+// Metadata — то, что удалось достать из <head> страницы. Ссылки на картинки
+// абсолютные.
+type Metadata struct {
+	Title       string
+	Description string
+	Image       string
+	Favicon     string
+	SiteName    string
+}
+
+// Fetcher загружает страницу назначения и разбирает её метаданные. Адрес
+// проверяется при каждом соединении, поэтому ни редирект, ни DNS-ответ, сменившийся
+// после проверки URL, не приведут запрос во внутреннюю сеть.
+type Fetcher struct {
+	cfg    Config
+	client *http.Client
+}
+
+func New(cfg Config) *Fetcher {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.MaxBodySize <= 0 {
+		cfg.MaxBodySize = defaultMaxBodySize
+	}
+	if cfg.MaxRedirects <= 0 {
+		cfg.MaxRedirects = defaultMaxRedirects
+	}
+	if cfg.UserAgent == "" {
+		cfg.UserAgent = defaultUserAgent
+	}
+
+	f := &Fetcher{cfg: cfg}
+	dialer := &net.Dialer{Timeout: cfg.Timeout, Control: f.checkAddress}
+	f.client = &http.Client{
+		Timeout: cfg.Timeout,
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   cfg.Timeout,
+			ResponseHeaderTimeout: cfg.Timeout,
+			MaxIdleConns:          16,
+			IdleConnTimeout:       30 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > cfg.MaxRedirects {
+				return ErrTooManyRedirects
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("pagemeta: redirect to %s scheme", req.URL.Scheme)
+			}
+			return nil
+		},
+	}
+	return f
+}
+
+// checkAddress вызывается для уже разрезолвленного адреса перед соединением.
+func (f *Fetcher) checkAddress(network, address string, _ syscall.RawConn) error {
+	if f.cfg.AllowPrivate {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !urlvalidate.IsPublic(addr) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*Metadata, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("pagemeta: unsupported scheme %q", u.Scheme)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", f.cfg.UserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("pagemeta: destination answered %d", resp.StatusCode)
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, ErrNotHTML
+	}
+
+	body, err := charset.NewReader(io.LimitReader(resp.Body, f.cfg.MaxBodySize), contentType)
+	if err != nil {
+		return nil, err
+	}
+	// относительные ссылки разрешаются от адреса после редиректов
+	return parse(body, resp.Request.URL), nil
+}
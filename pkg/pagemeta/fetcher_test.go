@@ -0,0 +1,248 @@
+package pagemeta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testPage = `<!DOCTYPE html><html><head><title>Hello</title></head><body></body></html>`
+
+func TestFetchRefusesPrivateAddresses(t *testing.T) {
+	var hits int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(testPage))
+	}))
+	defer srv.Close()
+
+	// httptest слушает 127.0.0.1: без AllowPrivate dialer отказывает ещё до соединения
+	_, err := New(Config{}).Fetch(context.Background(), srv.URL)
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Fatalf("Fetch() error = %v, want ErrForbiddenAddress", err)
+	}
+	// localhost резолвится в тот же адрес и тоже отклоняется
+	_, err = New(Config{}).Fetch(context.Background(), strings.Replace(srv.URL, "127.0.0.1", "localhost", 1))
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Fatalf("Fetch(localhost) error = %v, want ErrForbiddenAddress", err)
+	}
+	if hits != 0 {
+		t.Errorf("server got %d requests", hits)
+	}
+
+	meta, err := New(Config{AllowPrivate: true}).Fetch(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Title != "Hello" {
+		t.Errorf("Title = %q", meta.Title)
+	}
+}
+
+func TestCheckAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		wantErr bool
+	}{
+		{address: "93.184.216.34:443"},
+		{address: "[2606:2800:220:1:248:1893:25c8:1946]:80"},
+		{address: "127.0.0.1:80", wantErr: true},
+		{address: "10.0.0.1:80", wantErr: true},
+		{address: "169.254.169.254:80", wantErr: true},
+		{address: "[::1]:80", wantErr: true},
+		{address: "[::ffff:192.168.0.1]:80", wantErr: true},
+		{address: "[fc00::1]:80", wantErr: true},
+		{address: "0.0.0.0:80", wantErr: true},
+		{address: "example.com:80", wantErr: true},
+	}
+	f := New(Config{})
+	for _, tt := range tests {
+		err := f.checkAddress("tcp", tt.address, nil)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checkAddress(%s) = %v, wantErr %v", tt.address, err, tt.wantErr)
+		}
+	}
+}
+
+func TestFetchRedirectLimit(t *testing.T) {
+	// /hop/N перенаправляет N раз, затем отдаёт страницу
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n int
+		fmt.Sscanf(r.URL.Path, "/hop/%d", &n)
+		switch {
+		case r.URL.Path == "/scheme":
+			http.Redirect(w, r, "ftp://example.com/", http.StatusFound)
+		case n > 0:
+			http.Redirect(w, r, fmt.Sprintf("/hop/%d", n-1), http.StatusFound)
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprintf(w, `<html><head><title>Final</title><link rel="icon" href="icon.png"></head></html>`)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr error
+	}{
+		{name: "no redirects", path: "/hop/0"},
+		{name: "at the limit", path: "/hop/2"},
+		{name: "over the limit", path: "/hop/3", wantErr: ErrTooManyRedirects},
+		{name: "far over the limit", path: "/hop/10", wantErr: ErrTooManyRedirects},
+	}
+
+	f := New(Config{AllowPrivate: true, MaxRedirects: 2})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta, err := f.Fetch(context.Background(), srv.URL+tt.path)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			// иконка разрешается от адреса после редиректов
+			if meta.Title != "Final" || meta.Favicon != srv.URL+"/hop/icon.png" {
+				t.Errorf("meta = %+v", meta)
+			}
+		})
+	}
+
+	if _, err := f.Fetch(context.Background(), srv.URL+"/scheme"); err == nil || !strings.Contains(err.Error(), "redirect to ftp scheme") {
+		t.Errorf("redirect to ftp: error = %v", err)
+	}
+}
+
+func TestFetchBodyLimit(t *testing.T) {
+	padding := strings.Repeat("<!-- padding -->", 100)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, `<html><head><meta name="description" content="early">%s<title>Late</title></head></html>`, padding)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name      string
+		limit     int64
+		wantTitle string
+	}{
+		{name: "whole page", limit: 1 << 20, wantTitle: "Late"},
+		{name: "cut before the title", limit: 512},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta, err := New(Config{AllowPrivate: true, MaxBodySize: tt.limit}).Fetch(context.Background(), srv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if meta.Title != tt.wantTitle || meta.Description != "early" {
+				t.Errorf("meta = %+v", meta)
+			}
+		})
+	}
+}
+
+func TestFetchTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{name: "slow headers", handler: func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+		}},
+		// таймаут клиента распространяется и на чтение тела, а не только на заголовки
+		{name: "slow body", handler: func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html><head><title>Partial</title>"))
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(tt.handler)
+			defer srv.Close()
+
+			start := time.Now()
+			_, err := New(Config{AllowPrivate: true, Timeout: 100 * time.Millisecond}).Fetch(context.Background(), srv.URL)
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("Fetch() took %s", elapsed)
+			}
+			if err == nil {
+				t.Error("Fetch() succeeded, want a timeout")
+			}
+		})
+	}
+}
+
+func TestFetchResponses(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		contentType string
+		body        string
+		wantErr     error
+		wantTitle   string
+	}{
+		{name: "html", contentType: "text/html; charset=utf-8", body: testPage, wantTitle: "Hello"},
+		{name: "xhtml", contentType: "application/xhtml+xml", body: testPage, wantTitle: "Hello"},
+		{name: "upper-case type", contentType: "TEXT/HTML", body: testPage, wantTitle: "Hello"},
+		{name: "windows-1251", contentType: "text/html; charset=windows-1251", body: "<title>\xcf\xf0\xe8\xe2\xe5\xf2</title>", wantTitle: "Привет"},
+		{name: "charset from meta", contentType: "text/html", body: `<meta charset="koi8-r"><title>` + "\xf0\xd2\xc9\xd7\xc5\xd4" + `</title>`, wantTitle: "Привет"},
+		{name: "json", contentType: "application/json", body: `{}`, wantErr: ErrNotHTML},
+		{name: "image", contentType: "image/png", body: "\x89PNG", wantErr: ErrNotHTML},
+		{name: "no content type", body: "", wantErr: ErrNotHTML},
+		{name: "not found", status: http.StatusNotFound, contentType: "text/html", body: testPage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("User-Agent") != defaultUserAgent {
+					t.Errorf("User-Agent = %q", r.Header.Get("User-Agent"))
+				}
+				w.Header()["Content-Type"] = []string{tt.contentType}
+				if tt.status != 0 {
+					w.WriteHeader(tt.status)
+				}
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			meta, err := New(Config{AllowPrivate: true}).Fetch(context.Background(), srv.URL)
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.wantTitle == "":
+				if err == nil {
+					t.Fatalf("Fetch() = %+v, want an error", meta)
+				}
+			case err != nil:
+				t.Fatal(err)
+			case meta.Title != tt.wantTitle:
+				t.Errorf("Title = %q, want %q", meta.Title, tt.wantTitle)
+			}
+		})
+	}
+}
+
+func TestFetchRejectsSchemes(t *testing.T) {
+	for _, raw := range []string{"ftp://example.com/", "file:///etc/passwd", "javascript:alert(1)", "://bad"} {
+		if _, err := New(Config{AllowPrivate: true}).Fetch(context.Background(), raw); err == nil {
+			t.Errorf("Fetch(%q) succeeded", raw)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package pagemeta
+
+import (
+	"io"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	maxTitleLength       = 300
+	maxDescriptionLength = 1000
+)
+
+// parse читает только <head>: разбор останавливается на <body> или </head>.
+func parse(r io.Reader, base *url.URL) *Metadata {
+	var (
+		meta              Metadata
+		title             strings.Builder
+		inTitle           bool
+		ogTitle, ogDesc   string
+		favicon, fallback string
+	)
+
+	z := html.NewTokenizer(r)
+loop:
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			break loop
+
+		case html.TextToken:
+			if inTitle {
+				title.Write(z.Text())
+			}
+
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch atom.Lookup(name) {
+			case atom.Title:
+				inTitle = false
+			case atom.Head:
+				break loop
+			}
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			tag := atom.Lookup(name)
+			if tag == atom.Body {
+				break loop
+			}
+			if tag == atom.Title && meta.Title == "" && title.Len() == 0 {
+				inTitle = tt == html.StartTagToken
+				continue
+			}
+
+			attrs := map[string]string{}
+			for hasAttr {
+				var key, val []byte
+				key, val, hasAttr = z.TagAttr()
+				attrs[string(key)] = string(val)
+			}
+
+			switch tag {
+			case atom.Meta:
+				content := attrs["content"]
+				switch strings.ToLower(attrs["property"] + attrs["name"]) {
+				case "og:title":
+					ogTitle = content
+				case "og:description":
+					ogDesc = content
+				case "description":
+					meta.Description = content
+				case "og:image", "og:image:url", "og:image:secure_url":
+					if meta.Image == "" {
+						meta.Image = resolve(base, content)
+					}
+				case "og:site_name":
+					meta.SiteName = content
+				}
+			case atom.Link:
+				for _, rel := range strings.Fields(strings.ToLower(attrs["rel"])) {
+					switch rel {
+					case "icon":
+						if favicon == "" {
+							favicon = resolve(base, attrs["href"])
+						}
+					case "apple-touch-icon":
+						if fallback == "" {
+							fallback = resolve(base, attrs["href"])
+						}
+					}
+				}
+			}
+		}
+	}
+
+	meta.Title = clean(title.String(), maxTitleLength)
+	if meta.Title == "" {
+		meta.Title = clean(ogTitle, maxTitleLength)
+	}
+	if meta.Description == "" {
+		meta.Description = ogDesc
+	}
+	meta.Description = clean(meta.Description, maxDescriptionLength)
+	meta.SiteName = clean(meta.SiteName, maxTitleLength)
+
+	meta.Favicon = favicon
+	if meta.Favicon == "" {
+		meta.Favicon = fallback
+	}
+	if meta.Favicon == "" {
+		meta.Favicon = resolve(base, "/favicon.ico")
+	}
+	return &meta
+}
+
+// resolve делает ссылку абсолютной и отбрасывает всё, кроме http(s): data: и
+// javascript: в метаданных нам не нужны.
+func resolve(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return ""
+	}
+	u, err := base.Parse(ref)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	return u.String()
+}
+
+// clean схлопывает пробелы и обрезает текст до limit символов.
+func clean(s string, limit int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+	return string([]rune(s)[:limit-1]) + "…"
+}
@@ -0,0 +1,98 @@
+package pagemeta
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	base, _ := url.Parse("https://example.com/blog/post")
+
+	tests := []struct {
+		name string
+		html string
+		want Metadata
+	}{
+		{
+			name: "title and description",
+			html: `<html><head><title>  Hello
+				world </title><meta name="description" content="About things"></head></html>`,
+			want: Metadata{Title: "Hello world", Description: "About things", Favicon: "https://example.com/favicon.ico"},
+		},
+		{
+			name: "open graph",
+			html: `<head><meta property="og:title" content="OG title"><meta property="og:description" content="OG desc">
+				<meta property="og:image" content="/img/a.png"><meta property="og:image" content="/img/b.png">
+				<meta property="og:site_name" content="Example"></head>`,
+			want: Metadata{Title: "OG title", Description: "OG desc", Image: "https://example.com/img/a.png", SiteName: "Example", Favicon: "https://example.com/favicon.ico"},
+		},
+		{
+			name: "title wins over og:title",
+			html: `<head><meta property="og:title" content="OG"><title>Title</title><meta name="description" content="Desc"><meta property="og:description" content="OG desc"></head>`,
+			want: Metadata{Title: "Title", Description: "Desc", Favicon: "https://example.com/favicon.ico"},
+		},
+		{
+			name: "icons",
+			html: `<head><link rel="apple-touch-icon" href="touch.png"><link rel="shortcut icon" href="fav.ico"><link rel="icon" href="second.ico"></head>`,
+			want: Metadata{Favicon: "https://example.com/blog/fav.ico"},
+		},
+		{
+			name: "apple-touch-icon fallback",
+			html: `<head><link rel="apple-touch-icon" href="//cdn.example/touch.png"></head>`,
+			want: Metadata{Favicon: "https://cdn.example/touch.png"},
+		},
+		{
+			name: "unsafe schemes are dropped",
+			html: `<head><meta property="og:image" content="javascript:alert(1)"><link rel="icon" href="data:image/png;base64,AAAA"></head>`,
+			want: Metadata{Favicon: "https://example.com/favicon.ico"},
+		},
+		{
+			name: "stops at body",
+			html: `<head><title>Head</title></head><body><title>Body</title><meta name="description" content="late"></body>`,
+			want: Metadata{Title: "Head", Favicon: "https://example.com/favicon.ico"},
+		},
+		{
+			name: "only the first title",
+			html: `<title>First</title><title>Second</title>`,
+			want: Metadata{Title: "First", Favicon: "https://example.com/favicon.ico"},
+		},
+		{
+			name: "entities",
+			html: `<title>Fish &amp; chips</title><meta name="description" content="&lt;b&gt;">`,
+			want: Metadata{Title: "Fish & chips", Description: "<b>", Favicon: "https://example.com/favicon.ico"},
+		},
+		{
+			name: "empty page",
+			want: Metadata{Favicon: "https://example.com/favicon.ico"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parse(strings.NewReader(tt.html), base)
+			if *got != tt.want {
+				t.Errorf("parse() = %+v\nwant      %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClean(t *testing.T) {
+	tests := []struct {
+		in    string
+		limit int
+		want  string
+	}{
+		{in: "  a \n\t b  ", limit: 10, want: "a b"},
+		{in: "abcdef", limit: 6, want: "abcdef"},
+		{in: "abcdefg", limit: 6, want: "abcde…"},
+		{in: "приветмир", limit: 7, want: "привет…"},
+		{in: "", limit: 5, want: ""},
+	}
+	for _, tt := range tests {
+		if got := clean(tt.in, tt.limit); got != tt.want {
+			t.Errorf("clean(%q, %d) = %q, want %q", tt.in, tt.limit, got, tt.want)
+		}
+	}
+}
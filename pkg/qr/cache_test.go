@@ -0,0 +1,39 @@
+package qr
+
+import "testing"
+
+func TestCache(t *testing.T) {
+	c := NewCache(2)
+	c.Add("a", []byte("1"))
+	c.Add("b", []byte("2"))
+	// обращение к a делает самой давней запись b
+	if v, ok := c.Get("a"); !ok || string(v) != "1" {
+		t.Fatalf("Get(a) = %q, %v", v, ok)
+	}
+	c.Add("c", []byte("3"))
+	if _, ok := c.Get("b"); ok {
+		t.Error("b was not evicted")
+	}
+
+	// повторное добавление заменяет значение и не вытесняет соседей
+	c.Add("a", []byte("1'"))
+	if v, _ := c.Get("a"); string(v) != "1'" {
+		t.Errorf("Get(a) = %q after update", v)
+	}
+	if _, ok := c.Get("c"); !ok {
+		t.Error("c was evicted by an update")
+	}
+	if c.order.Len() != 2 || len(c.entries) != 2 {
+		t.Errorf("cache holds %d/%d entries", c.order.Len(), len(c.entries))
+	}
+}
+
+func TestCacheDisabled(t *testing.T) {
+	for _, size := range []int{0, -1} {
+		c := NewCache(size)
+		c.Add("a", []byte("1"))
+		if _, ok := c.Get("a"); ok {
+			t.Errorf("cache of size %d stored an entry", size)
+		}
+	}
+}
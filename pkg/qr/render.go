@@ -0,0 +1,250 @@
+// Package qr рисует QR-коды в PNG и SVG с настраиваемыми цветами, полями и
+// логотипом в центре. Кодирование символа выполняет go-qrcode.
+package qr
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"strconv"
+	"strings"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+type Format string
+
+const (
+	PNG Format = "png"
+	SVG Format = "svg"
+)
+
+// ContentType — MIME-тип изображения в формате f.
+func (f Format) ContentType() string {
+	if f == SVG {
+		return "image/svg+xml"
+	}
+	return "image/png"
+}
+
+// Level — уровень коррекции ошибок: L (~7%), M (~15%), Q (~25%), H (~30%).
+type Level string
+
+const (
+	LevelL Level = "L"
+	LevelM Level = "M"
+	LevelQ Level = "Q"
+	LevelH Level = "H"
+)
+
+var levels = map[Level]qrcode.RecoveryLevel{
+	LevelL: qrcode.Low,
+	LevelM: qrcode.Medium,
+	LevelQ: qrcode.High,
+	LevelH: qrcode.Highest,
+}
+
+const (
+	DefaultSize   = 256
+	DefaultMargin = 4
+	MaxSize       = 2048
+	MaxMargin     = 16
+
+	// logoScale — доля ширины символа под логотип. При уровне Q и выше код
+	// остаётся читаемым, поэтому с логотипом уровень поднимается до Q.
+	logoScale = 0.2
+)
+
+var ErrInvalidColor = errors.New("qr: color must be #rgb, #rrggbb or #rrggbbaa")
+
+type Options struct {
+	Format Format
+	// Size — ширина изображения в пикселях (для SVG — атрибуты width и height).
+	Size int
+	// Margin — ширина свободного поля вокруг кода в модулях; сканеры ждут
+	// не меньше DefaultMargin, но для печати на фоне его иногда убирают.
+	Margin     int
+	Level      Level
+	Foreground color.Color
+	Background color.Color
+	Logo       image.Image
+}
+
+func (o Options) withDefaults() Options {
+	if o.Format == "" {
+		o.Format = PNG
+	}
+	if o.Size <= 0 {
+		o.Size = DefaultSize
+	}
+	o.Size = min(o.Size, MaxSize)
+	o.Margin = min(max(o.Margin, 0), MaxMargin)
+	if _, ok := levels[o.Level]; !ok {
+		o.Level = LevelM
+	}
+	if o.Logo != nil && (o.Level == LevelL || o.Level == LevelM) {
+		o.Level = LevelQ
+	}
+	if o.Foreground == nil {
+		o.Foreground = color.Black
+	}
+	if o.Background == nil {
+		o.Background = color.White
+	}
+	return o
+}
+
+// Render кодирует content и рисует код в формате opts.Format.
+func Render(content string, opts Options) ([]byte, error) {
+	opts = opts.withDefaults()
+
+	code, err := qrcode.New(content, levels[opts.Level])
+	if err != nil {
+		return nil, fmt.Errorf("qr: encode: %w", err)
+	}
+	code.DisableBorder = true
+	bitmap := code.Bitmap()
+
+	if opts.Format == SVG {
+		return renderSVG(bitmap, opts)
+	}
+	return renderPNG(bitmap, opts)
+}
+
+func renderPNG(bitmap [][]bool, opts Options) ([]byte, error) {
+	modules := len(bitmap) + 2*opts.Margin
+	// модуль — целое число пикселей, иначе край модулей размывается;
+	// остаток уходит в поле
+	scale := max(opts.Size/modules, 1)
+	size := max(opts.Size, modules)
+	offset := (size-modules*scale)/2 + opts.Margin*scale
+
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(img, img.Bounds(), image.NewUniform(opts.Background), image.Point{}, draw.Src)
+	fg := image.NewUniform(opts.Foreground)
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if !dark {
+				continue
+			}
+			r := image.Rect(offset+x*scale, offset+y*scale, offset+(x+1)*scale, offset+(y+1)*scale)
+			draw.Draw(img, r, fg, image.Point{}, draw.Src)
+		}
+	}
+
+	if opts.Logo != nil {
+		symbol := len(bitmap) * scale
+		box := logoBox(size, symbol)
+		// подложка цвета фона отделяет логотип от модулей
+		draw.Draw(img, box, image.NewUniform(opts.Background), image.Point{}, draw.Src)
+		inner := box.Inset(max(box.Dx()/10, 1))
+		draw.Draw(img, inner, scaleImage(opts.Logo, inner.Dx(), inner.Dy()), image.Point{}, draw.Over)
+	}
+
+	var buf bytes.Buffer
+	enc := png.Encoder{CompressionLevel: png.BestCompression}
+	if err := enc.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func renderSVG(bitmap [][]bool, opts Options) ([]byte, error) {
+	modules := len(bitmap) + 2*opts.Margin
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		opts.Size, opts.Size, modules, modules)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d"%s/>`, modules, modules, svgFill(opts.Background))
+
+	// тёмные модули одной строки склеиваются в горизонтальные отрезки
+	fmt.Fprintf(&buf, `<path%s d="`, svgFill(opts.Foreground))
+	for y, row := range bitmap {
+		for x := 0; x < len(row); {
+			if !row[x] {
+				x++
+				continue
+			}
+			start := x
+			for x < len(row) && row[x] {
+				x++
+			}
+			fmt.Fprintf(&buf, "M%d %dh%dv1h-%dz", start+opts.Margin, y+opts.Margin, x-start, x-start)
+		}
+	}
+	buf.WriteString(`"/>`)
+
+	if opts.Logo != nil {
+		box := logoBox(modules, len(bitmap))
+		inner := box.Inset(max(box.Dx()/10, 1))
+		var logo bytes.Buffer
+		if err := png.Encode(&logo, opts.Logo); err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&buf, `<rect x="%d" y="%d" width="%d" height="%d"%s/>`, box.Min.X, box.Min.Y, box.Dx(), box.Dy(), svgFill(opts.Background))
+		fmt.Fprintf(&buf, `<image x="%d" y="%d" width="%d" height="%d" href="data:image/png;base64,%s"/>`,
+			inner.Min.X, inner.Min.Y, inner.Dx(), inner.Dy(), base64.StdEncoding.EncodeToString(logo.Bytes()))
+	}
+
+	buf.WriteString("</svg>\n")
+	return buf.Bytes(), nil
+}
+
+// logoBox — квадрат под логотип в центре изображения со стороной size.
+func logoBox(size, symbol int) image.Rectangle {
+	side := max(int(float64(symbol)*logoScale), 3)
+	origin := (size - side) / 2
+	return image.Rect(origin, origin, origin+side, origin+side)
+}
+
+// scaleImage масштабирует src до w×h методом ближайшего соседа с сохранением
+// пропорций; лишнее остаётся прозрачным.
+func scaleImage(src image.Image, w, h int) image.Image {
+	b := src.Bounds()
+	ratio := min(float64(w)/float64(b.Dx()), float64(h)/float64(b.Dy()))
+	sw, sh := max(int(float64(b.Dx())*ratio), 1), max(int(float64(b.Dy())*ratio), 1)
+	dx, dy := (w-sw)/2, (h-sh)/2
+
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := range sh {
+		for x := range sw {
+			sx := b.Min.X + int(float64(x)/ratio)
+			sy := b.Min.Y + int(float64(y)/ratio)
+			dst.Set(dx+x, dy+y, src.At(sx, sy))
+		}
+	}
+	return dst
+}
+
+func svgFill(c color.Color) string {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	attr := fmt.Sprintf(` fill="#%02x%02x%02x"`, n.R, n.G, n.B)
+	if n.A != 0xff {
+		attr += fmt.Sprintf(` fill-opacity="%.3g"`, float64(n.A)/0xff)
+	}
+	return attr
+}
+
+// ParseColor разбирает цвет в шестнадцатеричной записи; # в начале необязателен.
+func ParseColor(s string) (color.Color, error) {
+	s = strings.TrimPrefix(s, "#")
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+	if len(s) == 6 {
+		s += "ff"
+	}
+	if len(s) != 8 {
+		return nil, ErrInvalidColor
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return nil, ErrInvalidColor
+	}
+	return color.NRGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}, nil
+}
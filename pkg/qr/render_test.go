@@ -0,0 +1,263 @@
+package qr
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"strconv"
+	"strings"
+	"testing"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+const testContent = "https://sho.rt/abc123"
+
+// symbolSize — число модулей в символе без поля.
+func symbolSize(t *testing.T, level Level) int {
+	t.Helper()
+	code, err := qrcode.New(testContent, levels[level])
+	if err != nil {
+		t.Fatal(err)
+	}
+	code.DisableBorder = true
+	return len(code.Bitmap())
+}
+
+func decodePNG(t *testing.T, data []byte) image.Image {
+	t.Helper()
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
+
+func sameColor(a, b color.Color) bool {
+	return color.NRGBAModel.Convert(a) == color.NRGBAModel.Convert(b)
+}
+
+func TestRenderPNG(t *testing.T) {
+	symbol := symbolSize(t, LevelM)
+	red := color.NRGBA{R: 0xff, A: 0xff}
+	navy := color.NRGBA{B: 0x80, A: 0xff}
+
+	tests := []struct {
+		name     string
+		opts     Options
+		wantSize int
+		// pixels — ожидаемые цвета отдельных точек
+		pixels map[image.Point]color.Color
+	}{
+		{
+			name:     "default size",
+			opts:     Options{Margin: DefaultMargin},
+			wantSize: DefaultSize,
+			pixels:   map[image.Point]color.Color{{0, 0}: color.White},
+		},
+		{
+			// изображение не бывает меньше символа: модуль занимает один пиксель
+			name:     "no margin, tiny size",
+			opts:     Options{Size: 1},
+			wantSize: symbol,
+			pixels:   map[image.Point]color.Color{{0, 0}: color.Black, {symbol - 1, 0}: color.Black, {7, 7}: color.White},
+		},
+		{
+			name:     "margin without scaling",
+			opts:     Options{Size: 1, Margin: 2},
+			wantSize: symbol + 4,
+			pixels:   map[image.Point]color.Color{{1, 1}: color.White, {2, 2}: color.Black},
+		},
+		{
+			name:     "size is clamped",
+			opts:     Options{Size: MaxSize * 2, Margin: DefaultMargin},
+			wantSize: MaxSize,
+		},
+		{
+			name:     "colors",
+			opts:     Options{Size: 1, Foreground: navy, Background: red},
+			wantSize: symbol,
+			pixels:   map[image.Point]color.Color{{0, 0}: navy, {7, 7}: red},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := Render(testContent, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			img := decodePNG(t, data)
+			if b := img.Bounds(); b.Dx() != tt.wantSize || b.Dy() != tt.wantSize {
+				t.Fatalf("size = %v, want %d", b, tt.wantSize)
+			}
+			for p, want := range tt.pixels {
+				if got := img.At(p.X, p.Y); !sameColor(got, want) {
+					t.Errorf("pixel %v = %v, want %v", p, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderPNGCentersSymbol(t *testing.T) {
+	symbol := symbolSize(t, LevelM)
+	modules := symbol + 2*DefaultMargin
+	scale := DefaultSize / modules
+	offset := (DefaultSize-modules*scale)/2 + DefaultMargin*scale
+
+	img := decodePNG(t, mustRender(t, Options{Margin: DefaultMargin}))
+	// левый верхний модуль — угол поискового узора, он всегда тёмный
+	for _, p := range []image.Point{{offset, offset}, {offset + scale - 1, offset + scale - 1}} {
+		if !sameColor(img.At(p.X, p.Y), color.Black) {
+			t.Errorf("pixel %v is not dark", p)
+		}
+	}
+	if !sameColor(img.At(offset-1, offset-1), color.White) {
+		t.Errorf("quiet zone is not light at %d", offset-1)
+	}
+}
+
+func TestRenderWithLogo(t *testing.T) {
+	logo := image.NewNRGBA(image.Rect(0, 0, 10, 5))
+	green := color.NRGBA{G: 0xff, A: 0xff}
+	for y := range 5 {
+		for x := range 10 {
+			logo.Set(x, y, green)
+		}
+	}
+
+	img := decodePNG(t, mustRender(t, Options{Logo: logo, Level: LevelL}))
+	center := DefaultSize / 2
+	if got := img.At(center, center); !sameColor(got, green) {
+		t.Errorf("center pixel = %v, want the logo color", got)
+	}
+
+	svg := string(mustRender(t, Options{Format: SVG, Logo: logo}))
+	if !strings.Contains(svg, `<image `) || !strings.Contains(svg, `href="data:image/png;base64,`) {
+		t.Errorf("svg has no logo: %s", svg)
+	}
+}
+
+func TestRenderSVG(t *testing.T) {
+	symbol := symbolSize(t, LevelM)
+	modules := symbol + 2*DefaultMargin
+
+	tests := []struct {
+		name string
+		opts Options
+		want []string
+	}{
+		{
+			name: "defaults",
+			opts: Options{Format: SVG, Margin: DefaultMargin},
+			want: []string{
+				`width="256" height="256"`,
+				`viewBox="0 0 ` + itoa(modules) + ` ` + itoa(modules) + `"`,
+				`<rect width="` + itoa(modules) + `" height="` + itoa(modules) + `" fill="#ffffff"/>`,
+				`<path fill="#000000" d="M4 4h7v1h-7z`,
+			},
+		},
+		{
+			name: "colors and transparency",
+			opts: Options{Format: SVG, Size: 100, Foreground: color.NRGBA{R: 0x11, G: 0x22, B: 0x33, A: 0xff}, Background: color.NRGBA{A: 0}},
+			want: []string{`width="100"`, `fill="#000000" fill-opacity="0"`, `<path fill="#112233" d="M0 0h7v1h-7z`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svg := string(mustRender(t, tt.opts))
+			if !strings.HasPrefix(svg, "<svg ") || !strings.HasSuffix(svg, "</svg>\n") {
+				t.Fatalf("not an svg document: %s", svg)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(svg, want) {
+					t.Errorf("svg does not contain %q:\n%s", want, svg)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderTooLong(t *testing.T) {
+	if _, err := Render(strings.Repeat("x", 8000), Options{}); err == nil {
+		t.Error("Render() succeeded for content that does not fit into a QR code")
+	}
+}
+
+func TestWithDefaults(t *testing.T) {
+	logo := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	tests := []struct {
+		name string
+		in   Options
+		want Options
+	}{
+		{name: "zero", in: Options{}, want: Options{Format: PNG, Size: DefaultSize, Level: LevelM}},
+		{name: "negative margin", in: Options{Margin: -3, Size: -1}, want: Options{Format: PNG, Size: DefaultSize, Level: LevelM}},
+		{name: "limits", in: Options{Margin: 100, Size: 1e6}, want: Options{Format: PNG, Size: MaxSize, Margin: MaxMargin, Level: LevelM}},
+		{name: "unknown level", in: Options{Level: "X"}, want: Options{Format: PNG, Size: DefaultSize, Level: LevelM}},
+		{name: "explicit level", in: Options{Level: LevelH, Format: SVG}, want: Options{Format: SVG, Size: DefaultSize, Level: LevelH}},
+		{name: "logo raises L", in: Options{Level: LevelL, Logo: logo}, want: Options{Format: PNG, Size: DefaultSize, Level: LevelQ, Logo: logo}},
+		{name: "logo keeps H", in: Options{Level: LevelH, Logo: logo}, want: Options{Format: PNG, Size: DefaultSize, Level: LevelH, Logo: logo}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.in.withDefaults()
+			if got.Format != tt.want.Format || got.Size != tt.want.Size || got.Margin != tt.want.Margin ||
+				got.Level != tt.want.Level || got.Logo != tt.want.Logo {
+				t.Errorf("withDefaults() = %+v, want %+v", got, tt.want)
+			}
+			if got.Foreground == nil || got.Background == nil {
+				t.Error("colors are not set")
+			}
+		})
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    color.NRGBA
+		wantErr bool
+	}{
+		{in: "#000", want: color.NRGBA{A: 0xff}},
+		{in: "fff", want: color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}},
+		{in: "#1a2B3c", want: color.NRGBA{R: 0x1a, G: 0x2b, B: 0x3c, A: 0xff}},
+		{in: "#11223380", want: color.NRGBA{R: 0x11, G: 0x22, B: 0x33, A: 0x80}},
+		{in: "", wantErr: true},
+		{in: "#12", wantErr: true},
+		{in: "#12345", wantErr: true},
+		{in: "#ggg", wantErr: true},
+		{in: "#+12345", wantErr: true},
+		{in: "red", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseColor(tt.in)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidColor) {
+				t.Errorf("ParseColor(%q) = %v, %v; want ErrInvalidColor", tt.in, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseColor(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func mustRender(t *testing.T, opts Options) []byte {
+	t.Helper()
+	data, err := Render(testContent, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func itoa(n int) string {
+	return strconv.Itoa(n)
+}
@@ -0,0 +1,311 @@
+// Package textsearch — небольшой полнотекстовый поиск в памяти: разбиение
+// текста на слова, обратный индекс с поиском по префиксу и с опечатками,
+// подсветка совпадений. Словом считается последовательность букв и цифр.
+package textsearch
+
+import (
+	"html"
+	"maps"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Качество совпадения слова запроса со словом текста; умножается на вес поля.
+const (
+	exactMatch  = 1.0
+	prefixMatch = 0.6
+	// fuzzyMatch делится на число правок.
+	fuzzyMatch = 0.4
+)
+
+// Terms разбивает текст на слова в нижнем регистре.
+func Terms(text string) []string {
+	runes := []rune(text)
+	var terms []string
+	for _, w := range words(runes) {
+		terms = append(terms, strings.ToLower(string(runes[w.start:w.end])))
+	}
+	return terms
+}
+
+// Field — поле документа и его вес в ранжировании.
+type Field struct {
+	Text   string
+	Weight float64
+}
+
+type Hit struct {
+	ID    string
+	Score float64
+}
+
+// Index — обратный индекс документов. Не безопасен для параллельного
+// использования: синхронизирует владелец.
+type Index struct {
+	// postings — слово -> документ -> наибольший вес поля, где слово встретилось
+	postings map[string]map[string]float64
+	docs     map[string][]string
+	// vocab — все слова по возрастанию, для поиска по префиксу
+	vocab []string
+}
+
+func NewIndex() *Index {
+	return &Index{
+		postings: make(map[string]map[string]float64),
+		docs:     make(map[string][]string),
+	}
+}
+
+// Put индексирует документ, заменяя прежнюю версию с тем же id.
+func (ix *Index) Put(id string, fields ...Field) {
+	ix.Remove(id)
+
+	weights := make(map[string]float64)
+	for _, field := range fields {
+		for _, term := range Terms(field.Text) {
+			weights[term] = max(weights[term], field.Weight)
+		}
+	}
+	if len(weights) == 0 {
+		return
+	}
+	for term, weight := range weights {
+		docs, ok := ix.postings[term]
+		if !ok {
+			docs = make(map[string]float64)
+			ix.postings[term] = docs
+			i, _ := slices.BinarySearch(ix.vocab, term)
+			ix.vocab = slices.Insert(ix.vocab, i, term)
+		}
+		docs[id] = weight
+	}
+	ix.docs[id] = slices.Collect(maps.Keys(weights))
+}
+
+func (ix *Index) Remove(id string) {
+	for _, term := range ix.docs[id] {
+		delete(ix.postings[term], id)
+		if len(ix.postings[term]) == 0 {
+			delete(ix.postings, term)
+			if i, found := slices.BinarySearch(ix.vocab, term); found {
+				ix.vocab = slices.Delete(ix.vocab, i, i+1)
+			}
+		}
+	}
+	delete(ix.docs, id)
+}
+
+// Search находит документы, в которых есть каждое слово запроса — целиком, как
+// начало слова или с опечаткой. Результат отсортирован по убыванию оценки,
+// при равенстве — по id.
+func (ix *Index) Search(query string) []Hit {
+	terms := slices.Compact(slices.Sorted(slices.Values(Terms(query))))
+	if len(terms) == 0 {
+		return nil
+	}
+
+	var scores map[string]float64
+	for _, q := range terms {
+		best := make(map[string]float64)
+		for term, quality := range ix.matches(q) {
+			for id, weight := range ix.postings[term] {
+				best[id] = max(best[id], quality*weight)
+			}
+		}
+		if scores == nil {
+			scores = best
+		} else {
+			for id := range scores {
+				if score, ok := best[id]; ok {
+					scores[id] += score
+				} else {
+					delete(scores, id)
+				}
+			}
+		}
+		if len(scores) == 0 {
+			return nil
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, Hit{ID: id, Score: score})
+	}
+	slices.SortFunc(hits, func(a, b Hit) int {
+		if a.Score != b.Score {
+			if a.Score > b.Score {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return hits
+}
+
+// matches возвращает слова словаря, подходящие к слову запроса, с качеством совпадения.
+func (ix *Index) matches(q string) map[string]float64 {
+	result := make(map[string]float64)
+	i, _ := slices.BinarySearch(ix.vocab, q)
+	for _, term := range ix.vocab[i:] {
+		if !strings.HasPrefix(term, q) {
+			break
+		}
+		result[term] = Match(q, term)
+	}
+	if maxEdits(q) == 0 {
+		return result
+	}
+	for _, term := range ix.vocab {
+		if _, ok := result[term]; ok {
+			continue
+		}
+		if quality := Match(q, term); quality > 0 {
+			result[term] = quality
+		}
+	}
+	return result
+}
+
+// Match оценивает, насколько слово текста term подходит к слову запроса q;
+// 0 — не подходит. Оба слова должны быть в нижнем регистре.
+func Match(q, term string) float64 {
+	switch {
+	case term == q:
+		return exactMatch
+	case strings.HasPrefix(term, q):
+		return prefixMatch
+	}
+	limit := maxEdits(q)
+	if limit == 0 {
+		return 0
+	}
+	if d := distance([]rune(q), []rune(term), limit); d <= limit {
+		return fuzzyMatch / float64(d)
+	}
+	return 0
+}
+
+// maxEdits — сколько опечаток допускается в слове запроса: в коротких словах
+// опечатка слишком часто даёт другое существующее слово.
+func maxEdits(q string) int {
+	switch n := utf8.RuneCountInString(q); {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// distance — расстояние Левенштейна между a и b; если оно больше limit,
+// возвращается limit+1.
+func distance(a, b []rune, limit int) int {
+	if abs(len(a)-len(b)) > limit {
+		return limit + 1
+	}
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			rowMin = min(rowMin, curr[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev, curr = curr, prev
+	}
+	return min(prev[len(b)], limit+1)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// Highlight возвращает отрывок text длиной до width символов вокруг первого
+// совпадения с query: подходящие слова обёрнуты в <mark></mark>, остальной
+// текст экранирован для HTML. ok=false, если совпадений нет.
+func Highlight(text string, query []string, width int) (snippet string, ok bool) {
+	runes := []rune(text)
+	var marked []span
+	for _, w := range words(runes) {
+		term := strings.ToLower(string(runes[w.start:w.end]))
+		for _, q := range query {
+			if Match(q, term) > 0 {
+				marked = append(marked, w)
+				break
+			}
+		}
+	}
+	if len(marked) == 0 {
+		return "", false
+	}
+
+	from, to := 0, len(runes)
+	if width > 0 && len(runes) > width {
+		// немного контекста перед первым совпадением
+		from = max(0, min(marked[0].start-width/4, len(runes)-width))
+		to = from + width
+	}
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	pos := from
+	for _, w := range marked {
+		if w.start < from || w.end > to {
+			continue
+		}
+		b.WriteString(html.EscapeString(string(runes[pos:w.start])))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(string(runes[w.start:w.end])))
+		b.WriteString("</mark>")
+		pos = w.end
+	}
+	b.WriteString(html.EscapeString(string(runes[pos:to])))
+	if to < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String(), true
+}
+
+type span struct {
+	start, end int
+}
+
+func words(runes []rune) []span {
+	var spans []span
+	start := -1
+	for i, r := range runes {
+		wordRune := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case wordRune && start < 0:
+			start = i
+		case !wordRune && start >= 0:
+			spans = append(spans, span{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, span{start, len(runes)})
+	}
+	return spans
+}
@@ -0,0 +1,185 @@
+package textsearch
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestTerms(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "", want: ""},
+		{text: "Hello, World!", want: "hello world"},
+		{text: "example.com/docs?id=42", want: "example com docs id 42"},
+		{text: "Весенняя РАСПРОДАЖА-2024", want: "весенняя распродажа 2024"},
+		{text: "  --  ", want: ""},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(Terms(tt.text), " "); got != tt.want {
+			t.Errorf("Terms(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		q, term string
+		want    float64
+	}{
+		{q: "sale", term: "sale", want: exactMatch},
+		{q: "sal", term: "sale", want: prefixMatch},
+		// короткие слова без опечаток
+		{q: "sal", term: "sol", want: 0},
+		{q: "sael", term: "sale", want: 0},
+		{q: "salr", term: "sale", want: fuzzyMatch},
+		{q: "spring", term: "sprint", want: fuzzyMatch},
+		{q: "spring", term: "spirnt", want: 0},
+		{q: "campaigns", term: "campains", want: fuzzyMatch},
+		{q: "campaigns", term: "compaign", want: fuzzyMatch / 2},
+		{q: "campaigns", term: "company", want: 0},
+		{q: "весна", term: "вясна", want: fuzzyMatch},
+		{q: "sale", term: "sal", want: fuzzyMatch},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.q, tt.term); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.q, tt.term, got, tt.want)
+		}
+	}
+}
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		limit int
+		want  int
+	}{
+		{a: "", b: "", limit: 2, want: 0},
+		{a: "abc", b: "", limit: 5, want: 3},
+		{a: "kitten", b: "sitting", limit: 5, want: 3},
+		// больше limit — всегда limit+1
+		{a: "kitten", b: "sitting", limit: 2, want: 3},
+		{a: "kitten", b: "sitting", limit: 1, want: 2},
+		{a: "a", b: "abcdef", limit: 2, want: 3},
+	}
+	for _, tt := range tests {
+		if got := distance([]rune(tt.a), []rune(tt.b), tt.limit); got != tt.want {
+			t.Errorf("distance(%q, %q, %d) = %d, want %d", tt.a, tt.b, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestIndexSearch(t *testing.T) {
+	ix := NewIndex()
+	ix.Put("a", Field{Text: "spring sale", Weight: 1}, Field{Text: "shoes", Weight: 0.2})
+	ix.Put("b", Field{Text: "shoes", Weight: 1}, Field{Text: "spring", Weight: 0.2})
+	ix.Put("c", Field{Text: "autumn sale", Weight: 1})
+	ix.Put("d", Field{Text: "", Weight: 1})
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{query: "", want: ""},
+		{query: "sale", want: "a c"},
+		// каждое слово запроса должно найтись
+		{query: "spring sale", want: "a"},
+		{query: "shoes", want: "b a"},
+		{query: "spring", want: "a b"},
+		{query: "spr", want: "a b"},
+		{query: "sprint", want: "a b"},
+		{query: "SALE sale", want: "a c"},
+		{query: "winter", want: ""},
+		{query: "spring winter", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := ids(ix.Search(tt.query)); got != tt.want {
+				t.Errorf("Search(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIndexScores(t *testing.T) {
+	ix := NewIndex()
+	ix.Put("exact", Field{Text: "report", Weight: 1})
+	ix.Put("prefix", Field{Text: "reports", Weight: 1})
+	ix.Put("typo", Field{Text: "raport", Weight: 1})
+	ix.Put("weak", Field{Text: "report", Weight: 0.5})
+
+	hits := ix.Search("report")
+	if got := ids(hits); got != "exact prefix weak typo" {
+		t.Fatalf("Search() = %q", got)
+	}
+	want := []float64{exactMatch, prefixMatch, 0.5 * exactMatch, fuzzyMatch}
+	for i, hit := range hits {
+		if hit.Score != want[i] {
+			t.Errorf("%s score = %v, want %v", hit.ID, hit.Score, want[i])
+		}
+	}
+}
+
+func TestIndexPutRemove(t *testing.T) {
+	ix := NewIndex()
+	ix.Put("a", Field{Text: "old title", Weight: 1})
+	ix.Put("a", Field{Text: "new title", Weight: 1})
+	if got := ids(ix.Search("old")); got != "" {
+		t.Errorf("replaced document still found by an old word: %q", got)
+	}
+	if got := ids(ix.Search("new")); got != "a" {
+		t.Errorf("Search(new) = %q", got)
+	}
+
+	ix.Put("b", Field{Text: "title", Weight: 1})
+	ix.Remove("a")
+	ix.Remove("missing")
+	if got := ids(ix.Search("title")); got != "b" {
+		t.Errorf("Search(title) after remove = %q", got)
+	}
+	// словарь не держит слова удалённых документов
+	if got := strings.Join(ix.vocab, " "); got != "title" {
+		t.Errorf("vocab = %q", got)
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	long := strings.Repeat("word ", 40) + "needle " + strings.Repeat("tail ", 40)
+
+	tests := []struct {
+		name   string
+		text   string
+		query  []string
+		width  int
+		want   string
+		wantOK bool
+	}{
+		{name: "no match", text: "spring sale", query: []string{"winter"}},
+		{name: "words", text: "Spring sale, spring shoes", query: []string{"spring"}, want: "<mark>Spring</mark> sale, <mark>spring</mark> shoes", wantOK: true},
+		{name: "prefix and typo", text: "Spring sale", query: []string{"spr", "salr"}, want: "<mark>Spring</mark> <mark>sale</mark>", wantOK: true},
+		{name: "escapes html", text: `<b>"sale"</b> & more`, query: []string{"sale"}, want: "&lt;b&gt;&#34;<mark>sale</mark>&#34;&lt;/b&gt; &amp; more", wantOK: true},
+		{name: "snippet", text: long, query: []string{"needle"}, width: 40, want: "…word word <mark>needle</mark> tail tail tail tail tai…", wantOK: true},
+		{name: "match at the end", text: "a b c d e f needle", query: []string{"needle"}, width: 10, want: "…e f <mark>needle</mark>", wantOK: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Highlight(tt.text, tt.query, tt.width)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("Highlight() = %q, %v\nwant          %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func ids(hits []Hit) string {
+	var b strings.Builder
+	for i, hit := range hits {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		fmt.Fprint(&b, hit.ID)
+	}
+	return b.String()
+}
@@ -0,0 +1,88 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	const (
+		traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		spanID  = "00f067aa0ba902b7"
+	)
+	tests := []struct {
+		name    string
+		header  string
+		wantErr bool
+		sampled bool
+	}{
+		{name: "sampled", header: "00-" + traceID + "-" + spanID + "-01", sampled: true},
+		{name: "not sampled", header: "00-" + traceID + "-" + spanID + "-00"},
+		{name: "surrounding spaces", header: " 00-" + traceID + "-" + spanID + "-01 ", sampled: true},
+		{name: "future version with extra field", header: "cc-" + traceID + "-" + spanID + "-01-extra", sampled: true},
+		{name: "version 00 with extra field", header: "00-" + traceID + "-" + spanID + "-01-extra", wantErr: true},
+		{name: "version ff", header: "ff-" + traceID + "-" + spanID + "-01", wantErr: true},
+		{name: "uppercase hex", header: "00-4BF92F3577B34DA6A3CE929D0E0E4736-" + spanID + "-01", wantErr: true},
+		{name: "zero trace id", header: "00-00000000000000000000000000000000-" + spanID + "-01", wantErr: true},
+		{name: "zero span id", header: "00-" + traceID + "-0000000000000000-01", wantErr: true},
+		{name: "short span id", header: "00-" + traceID + "-00f067aa-01", wantErr: true},
+		{name: "not hex", header: "00-" + traceID + "-" + spanID + "-zz", wantErr: true},
+		{name: "too few fields", header: "00-" + traceID + "-" + spanID, wantErr: true},
+		{name: "empty", header: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, err := ParseTraceparent(tt.header)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseTraceparent(%q) = %+v, want error", tt.header, sc)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sc.TraceID.String() != traceID || sc.SpanID.String() != spanID || !sc.Remote {
+				t.Errorf("got %+v", sc)
+			}
+			if sc.IsSampled() != tt.sampled {
+				t.Errorf("sampled = %v, want %v", sc.IsSampled(), tt.sampled)
+			}
+		})
+	}
+}
+
+func TestTraceparentRoundTrip(t *testing.T) {
+	sc := SpanContext{TraceID: newTraceID(), SpanID: newSpanID(), Flags: flagSampled}
+	parsed, err := ParseTraceparent(sc.Traceparent())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.TraceID != sc.TraceID || parsed.SpanID != sc.SpanID || parsed.Flags != sc.Flags {
+		t.Errorf("round trip: %+v != %+v", parsed, sc)
+	}
+}
+
+func TestStartInheritsRemoteParent(t *testing.T) {
+	tracer := NewTracer(nil, &recordingExporter{})
+	remote, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := ContextWithRemoteSpanContext(context.Background(), remote)
+
+	ctx, span := tracer.Start(ctx, "server", SpanKindServer)
+	if span.SpanContext().TraceID != remote.TraceID {
+		t.Error("trace id is not inherited from the remote parent")
+	}
+	if span.IsRecording() {
+		t.Error("parent-based sampler ignored the unsampled remote parent")
+	}
+	if TraceIDFromContext(ctx) != remote.TraceID.String() {
+		t.Errorf("TraceIDFromContext = %q", TraceIDFromContext(ctx))
+	}
+	if TraceIDFromContext(context.Background()) != "" {
+		t.Error("empty context has a trace id")
+	}
+}
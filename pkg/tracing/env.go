@@ -0,0 +1,90 @@
+package tracing
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// NewFromEnv настраивает трассировщик по стандартным переменным OpenTelemetry:
+// OTEL_TRACES_EXPORTER (otlp, console, file, none), OTEL_EXPORTER_OTLP_ENDPOINT,
+// OTEL_EXPORTER_OTLP_HEADERS, OTEL_TRACES_SAMPLER, OTEL_TRACES_SAMPLER_ARG и OTEL_SERVICE_NAME.
+// Для экспортёра file путь берётся из OTEL_TRACES_FILE.
+func NewFromEnv() (*Tracer, error) {
+	serviceName := getenv("OTEL_SERVICE_NAME", "shortener")
+
+	var exporter Exporter
+	switch name := getenv("OTEL_TRACES_EXPORTER", "none"); name {
+	case "none":
+	case "console", "stdout":
+		exporter = NewWriterExporter(os.Stdout)
+	case "file":
+		path := os.Getenv("OTEL_TRACES_FILE")
+		if path == "" {
+			return nil, fmt.Errorf("OTEL_TRACES_FILE is required for the file exporter")
+		}
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		exporter = NewWriterExporter(f)
+	case "otlp":
+		endpoint := getenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4318")
+		exporter = NewOTLPExporter(endpoint, serviceName, parseHeaders(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS")))
+	default:
+		return nil, fmt.Errorf("unknown OTEL_TRACES_EXPORTER %q", name)
+	}
+
+	sampler, err := samplerFromEnv(os.Getenv("OTEL_TRACES_SAMPLER"), os.Getenv("OTEL_TRACES_SAMPLER_ARG"))
+	if err != nil {
+		return nil, err
+	}
+
+	return NewTracer(sampler, exporter), nil
+}
+
+func samplerFromEnv(name, arg string) (Sampler, error) {
+	ratio := 1.0
+	if arg != "" {
+		var err error
+		if ratio, err = strconv.ParseFloat(arg, 64); err != nil {
+			return nil, fmt.Errorf("invalid OTEL_TRACES_SAMPLER_ARG %q", arg)
+		}
+	}
+
+	switch name {
+	case "", "parentbased_always_on":
+		return ParentBased(AlwaysSample()), nil
+	case "always_on":
+		return AlwaysSample(), nil
+	case "always_off":
+		return NeverSample(), nil
+	case "parentbased_always_off":
+		return ParentBased(NeverSample()), nil
+	case "traceidratio":
+		return TraceIDRatioBased(ratio), nil
+	case "parentbased_traceidratio":
+		return ParentBased(TraceIDRatioBased(ratio)), nil
+	default:
+		return nil, fmt.Errorf("unknown OTEL_TRACES_SAMPLER %q", name)
+	}
+}
+
+func parseHeaders(s string) map[string]string {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(pair, "=")
+		if ok {
+			headers[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return headers
+}
+
+func getenv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,192 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+func (e *recordingExporter) Export(ctx context.Context, spans []SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func (e *recordingExporter) names() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	names := make([]string, len(e.spans))
+	for i, s := range e.spans {
+		names[i] = s.Name
+	}
+	return names
+}
+
+func TestShutdownFlushesQueuedSpans(t *testing.T) {
+	exporter := &recordingExporter{}
+	tracer := NewTracer(AlwaysSample(), exporter)
+
+	for _, name := range []string{"a", "b", "c"} {
+		_, span := tracer.Start(context.Background(), name, SpanKindInternal)
+		span.End()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := tracer.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(exporter.names(), ","); got != "a,b,c" {
+		t.Errorf("exported %q, want a,b,c", got)
+	}
+}
+
+func TestSpanEndedAfterShutdown(t *testing.T) {
+	tracer := NewTracer(AlwaysSample(), &recordingExporter{})
+	_, late := tracer.Start(context.Background(), "late", SpanKindServer)
+
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// раньше отправка в закрытую очередь паниковала
+	late.End()
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConcurrentEndAndShutdown(t *testing.T) {
+	tracer := NewTracer(AlwaysSample(), &recordingExporter{})
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 500 {
+				_, span := tracer.Start(context.Background(), "span", SpanKindInternal)
+				span.End()
+			}
+		}()
+	}
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+}
+
+func TestNoExporter(t *testing.T) {
+	tracer := NewTracer(nil, nil)
+	_, span := tracer.Start(context.Background(), "x", SpanKindInternal)
+	if span.IsRecording() {
+		t.Error("span without exporter is recording")
+	}
+	span.End()
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriterExporter(t *testing.T) {
+	var buf bytes.Buffer
+	tracer := NewTracer(AlwaysSample(), NewWriterExporter(&buf))
+
+	ctx, parent := tracer.Start(context.Background(), "parent", SpanKindServer)
+	_, child := tracer.Start(ctx, "child", SpanKindClient)
+	child.SetAttribute("db.operation", "save")
+	child.RecordError(io.ErrUnexpectedEOF)
+	child.End()
+	parent.End()
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines: %s", len(lines), buf.String())
+	}
+	var got jsonSpan
+	if err := json.Unmarshal([]byte(lines[0]), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "child" || got.ParentID != parent.SpanContext().SpanID.String() ||
+		got.TraceID != parent.SpanContext().TraceID.String() {
+		t.Errorf("child span = %+v", got)
+	}
+	if got.Status != StatusError || got.Message != io.ErrUnexpectedEOF.Error() {
+		t.Errorf("status = %d %q", got.Status, got.Message)
+	}
+	if got.Attributes["db.operation"] != "save" {
+		t.Errorf("attributes = %v", got.Attributes)
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var (
+		body    []byte
+		headers http.Header
+		path    string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, headers = r.URL.Path, r.Header
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	exporter := NewOTLPExporter(srv.URL+"/", "svc", map[string]string{"Authorization": "Bearer x"})
+	span := SpanData{
+		Name:        "op",
+		Kind:        SpanKindServer,
+		SpanContext: SpanContext{TraceID: TraceID{1}, SpanID: SpanID{2}},
+		Start:       time.Unix(1, 0),
+		End:         time.Unix(2, 0),
+		Attributes:  []Attribute{{"n", 3}, {"ok", true}, {"f", 0.5}, {"s", "v"}},
+	}
+	if err := exporter.Export(context.Background(), []SpanData{span}); err != nil {
+		t.Fatal(err)
+	}
+
+	if path != "/v1/traces" {
+		t.Errorf("path = %s", path)
+	}
+	if headers.Get("Authorization") != "Bearer x" || headers.Get("Content-Type") != "application/json" {
+		t.Errorf("headers = %v", headers)
+	}
+	for _, want := range []string{
+		`"service.name","value":{"stringValue":"svc"}`,
+		`"traceId":"01000000000000000000000000000000"`,
+		`"startTimeUnixNano":"1000000000"`,
+		`{"key":"n","value":{"intValue":"3"}}`,
+		`{"key":"ok","value":{"boolValue":true}}`,
+		`{"key":"f","value":{"doubleValue":0.5}}`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("body does not contain %s:\n%s", want, body)
+		}
+	}
+}
+
+func TestOTLPExporterError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	err := NewOTLPExporter(srv.URL, "svc", nil).Export(context.Background(), []SpanData{{Name: "x"}})
+	if err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("err = %v, want collector status", err)
+	}
+}
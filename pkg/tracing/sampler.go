@@ -0,0 +1,47 @@
+package tracing
+
+import "encoding/binary"
+
+type Sampler interface {
+	ShouldSample(parent SpanContext, traceID TraceID) bool
+}
+
+type samplerFunc func(parent SpanContext, traceID TraceID) bool
+
+func (f samplerFunc) ShouldSample(parent SpanContext, traceID TraceID) bool {
+	return f(parent, traceID)
+}
+
+func AlwaysSample() Sampler {
+	return samplerFunc(func(SpanContext, TraceID) bool { return true })
+}
+
+func NeverSample() Sampler {
+	return samplerFunc(func(SpanContext, TraceID) bool { return false })
+}
+
+// TraceIDRatioBased сэмплирует долю трасс детерминированно по trace id,
+// поэтому все сервисы с тем же ratio принимают одинаковое решение.
+func TraceIDRatioBased(ratio float64) Sampler {
+	if ratio >= 1 {
+		return AlwaysSample()
+	}
+	if ratio <= 0 {
+		return NeverSample()
+	}
+
+	bound := uint64(ratio * (1 << 63))
+	return samplerFunc(func(_ SpanContext, traceID TraceID) bool {
+		return binary.BigEndian.Uint64(traceID[8:16])>>1 < bound
+	})
+}
+
+// ParentBased следует решению родителя, а для корневых спанов использует root.
+func ParentBased(root Sampler) Sampler {
+	return samplerFunc(func(parent SpanContext, traceID TraceID) bool {
+		if parent.IsValid() {
+			return parent.IsSampled()
+		}
+		return root.ShouldSample(parent, traceID)
+	})
+}
@@ -0,0 +1,102 @@
+package tracing
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestTraceIDRatioBased(t *testing.T) {
+	traceID := func(v uint64) TraceID {
+		var id TraceID
+		binary.BigEndian.PutUint64(id[8:], v)
+		return id
+	}
+
+	tests := []struct {
+		name  string
+		ratio float64
+		id    TraceID
+		want  bool
+	}{
+		{"ratio 1", 1, traceID(^uint64(0)), true},
+		{"ratio above 1", 2, traceID(^uint64(0)), true},
+		{"ratio 0", 0, traceID(0), false},
+		{"negative ratio", -1, traceID(0), false},
+		{"lowest id at half", 0.5, traceID(0), true},
+		{"below half", 0.5, traceID(1<<63 - 2), true},
+		{"at half", 0.5, traceID(1 << 63), false},
+		{"highest id at half", 0.5, traceID(^uint64(0)), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TraceIDRatioBased(tt.ratio).ShouldSample(SpanContext{}, tt.id); got != tt.want {
+				t.Errorf("ShouldSample = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParentBased(t *testing.T) {
+	sampledParent := SpanContext{TraceID: TraceID{1}, SpanID: SpanID{1}, Flags: flagSampled}
+	unsampledParent := SpanContext{TraceID: TraceID{1}, SpanID: SpanID{1}}
+
+	tests := []struct {
+		name   string
+		root   Sampler
+		parent SpanContext
+		want   bool
+	}{
+		{"root uses root sampler on", AlwaysSample(), SpanContext{}, true},
+		{"root uses root sampler off", NeverSample(), SpanContext{}, false},
+		{"sampled parent wins over root", NeverSample(), sampledParent, true},
+		{"unsampled parent wins over root", AlwaysSample(), unsampledParent, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParentBased(tt.root).ShouldSample(tt.parent, TraceID{2}); got != tt.want {
+				t.Errorf("ShouldSample = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSamplerFromEnv(t *testing.T) {
+	tests := []struct {
+		name, arg string
+		wantErr   bool
+	}{
+		{name: ""},
+		{name: "always_on"},
+		{name: "always_off"},
+		{name: "parentbased_always_on"},
+		{name: "parentbased_always_off"},
+		{name: "traceidratio", arg: "0.25"},
+		{name: "parentbased_traceidratio", arg: "0.25"},
+		{name: "traceidratio", arg: "quarter", wantErr: true},
+		{name: "jaeger_remote", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+"/"+tt.arg, func(t *testing.T) {
+			_, err := samplerFromEnv(tt.name, tt.arg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	got := parseHeaders(" api-key = secret ,x-tenant=a=b,broken,")
+	want := map[string]string{"api-key": "secret", "x-tenant": "a=b"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %q, want %q", k, got[k], v)
+		}
+	}
+}
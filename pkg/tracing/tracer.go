@@ -0,0 +1,59 @@
+package tracing
+
+import (
+	"context"
+	"time"
+)
+
+type Tracer struct {
+	sampler   Sampler
+	processor *batchProcessor
+}
+
+// NewTracer создаёт трассировщик; exporter == nil означает, что спаны никуда не отправляются.
+func NewTracer(sampler Sampler, exporter Exporter) *Tracer {
+	if sampler == nil {
+		sampler = ParentBased(AlwaysSample())
+	}
+	if exporter == nil {
+		sampler = NeverSample()
+	}
+	return &Tracer{
+		sampler:   sampler,
+		processor: newBatchProcessor(exporter),
+	}
+}
+
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	parent := spanContextFromContext(ctx)
+
+	sc := SpanContext{SpanID: newSpanID()}
+	if parent.TraceID.IsValid() {
+		sc.TraceID = parent.TraceID
+		sc.TraceState = parent.TraceState
+	} else {
+		sc.TraceID = newTraceID()
+	}
+	if t.sampler.ShouldSample(parent, sc.TraceID) {
+		sc.Flags |= flagSampled
+	}
+
+	span := &Span{
+		tracer:    t,
+		recording: sc.IsSampled(),
+		data: SpanData{
+			Name:        name,
+			Kind:        kind,
+			SpanContext: sc,
+			Parent:      parent.SpanID,
+			Start:       time.Now(),
+		},
+	}
+
+	return ContextWithSpan(ctx, span), span
+}
+
+// Shutdown дожидается отправки накопленных спанов.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	return t.processor.shutdown(ctx)
+}
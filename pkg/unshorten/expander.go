@@ -0,0 +1,121 @@
+package unshorten
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTimeout = 3 * time.Second
+	userAgent      = "shorted-unshorten/1.0"
+	// сколько тела ответа дочитываем, чтобы соединение вернулось в пул
+	maxDrainedBody = 4 << 10
+)
+
+var ErrNoRedirect = errors.New("unshorten: short link did not redirect")
+
+type Config struct {
+	// Domains — домены сторонних сокращателей; поддомены тоже считаются известными.
+	Domains []string
+	Timeout time.Duration
+	// Client, если задан, используется вместо клиента по умолчанию; редиректы
+	// он следовать не должен, иначе Expand увидит сразу конечный адрес.
+	Client *http.Client
+}
+
+// Expander раскрывает ссылки известных сокращателей на один шаг: запрашивает
+// адрес и возвращает Location из ответа-редиректа.
+type Expander struct {
+	domains map[string]bool
+	client  *http.Client
+}
+
+func New(cfg Config) *Expander {
+	domains := make(map[string]bool, len(cfg.Domains))
+	for _, d := range cfg.Domains {
+		if d = strings.ToLower(strings.TrimSpace(d)); d != "" {
+			domains[d] = true
+		}
+	}
+
+	client := cfg.Client
+	if client == nil {
+		timeout := cfg.Timeout
+		if timeout <= 0 {
+			timeout = defaultTimeout
+		}
+		client = &http.Client{
+			Timeout: timeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+	}
+
+	return &Expander{domains: domains, client: client}
+}
+
+// Known сообщает, принадлежит ли хост одному из известных сокращателей.
+func (e *Expander) Known(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for host != "" {
+		if e.domains[host] {
+			return true
+		}
+		_, parent, ok := strings.Cut(host, ".")
+		if !ok {
+			return false
+		}
+		host = parent
+	}
+	return false
+}
+
+// Expand возвращает следующий адрес цепочки. ok=false означает, что адрес не
+// относится к известному сокращателю и раскрывать его не нужно.
+func (e *Expander) Expand(ctx context.Context, rawURL string) (next string, ok bool, err error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || !e.Known(u.Hostname()) {
+		return "", false, nil
+	}
+
+	// HEAD дешевле, но часть сокращателей отвечает на него 405
+	location, err := e.location(ctx, http.MethodHead, u)
+	if errors.Is(err, ErrNoRedirect) {
+		location, err = e.location(ctx, http.MethodGet, u)
+	}
+	if err != nil {
+		return "", true, err
+	}
+	return location, true, nil
+}
+
+func (e *Expander) location(ctx context.Context, method string, u *url.URL) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("unshorten: %s %s: %w", method, u.Host, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainedBody))
+
+	if resp.StatusCode < 300 || resp.StatusCode >= 400 {
+		return "", ErrNoRedirect
+	}
+	location, err := resp.Location()
+	if err != nil {
+		return "", ErrNoRedirect
+	}
+	return location.String(), nil
+}
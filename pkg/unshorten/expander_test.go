@@ -0,0 +1,140 @@
+package unshorten
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestKnown(t *testing.T) {
+	e := New(Config{Domains: []string{"bit.ly", " T.CO "}})
+
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{host: "bit.ly", want: true},
+		{host: "BIT.LY.", want: true},
+		{host: "j.bit.ly", want: true},
+		{host: "t.co", want: true},
+		{host: "notbit.ly"},
+		{host: "bit.ly.example"},
+		{host: "ly"},
+		{host: ""},
+	}
+	for _, tt := range tests {
+		if got := e.Known(tt.host); got != tt.want {
+			t.Errorf("Known(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestExpand(t *testing.T) {
+	var methods []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		switch r.URL.Path {
+		case "/redirect":
+			http.Redirect(w, r, "https://example.com/final", http.StatusMovedPermanently)
+		case "/relative":
+			http.Redirect(w, r, "/other", http.StatusFound)
+		case "/get-only":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			http.Redirect(w, r, "https://example.com/get", http.StatusFound)
+		case "/no-location":
+			w.WriteHeader(http.StatusFound)
+		default:
+			w.Write([]byte("page"))
+		}
+	}))
+	defer srv.Close()
+
+	host := mustHost(t, srv.URL)
+	e := New(Config{Domains: []string{host}, Client: noRedirects(srv.Client())})
+
+	tests := []struct {
+		name        string
+		url         string
+		want        string
+		wantOK      bool
+		wantErr     error
+		wantMethods []string
+	}{
+		{name: "redirect", url: srv.URL + "/redirect", want: "https://example.com/final", wantOK: true, wantMethods: []string{"HEAD"}},
+		{name: "relative location", url: srv.URL + "/relative", want: srv.URL + "/other", wantOK: true, wantMethods: []string{"HEAD"}},
+		{name: "falls back to GET", url: srv.URL + "/get-only", want: "https://example.com/get", wantOK: true, wantMethods: []string{"HEAD", "GET"}},
+		{name: "no redirect", url: srv.URL + "/page", wantOK: true, wantErr: ErrNoRedirect, wantMethods: []string{"HEAD", "GET"}},
+		{name: "redirect without location", url: srv.URL + "/no-location", wantOK: true, wantErr: ErrNoRedirect, wantMethods: []string{"HEAD", "GET"}},
+		{name: "unknown host", url: "https://example.com/redirect"},
+		{name: "other scheme", url: "ftp://" + host + "/redirect"},
+		{name: "not a url", url: "://"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			methods = nil
+			next, ok, err := e.Expand(context.Background(), tt.url)
+			if next != tt.want || ok != tt.wantOK || !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Expand(%q) = %q, %v, %v; want %q, %v, %v", tt.url, next, ok, err, tt.want, tt.wantOK, tt.wantErr)
+			}
+			if len(methods) != len(tt.wantMethods) {
+				t.Fatalf("methods = %v, want %v", methods, tt.wantMethods)
+			}
+			for i := range methods {
+				if methods[i] != tt.wantMethods[i] {
+					t.Errorf("methods = %v, want %v", methods, tt.wantMethods)
+				}
+			}
+		})
+	}
+}
+
+func TestExpandDoesNotFollowRedirects(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/first" {
+			http.Redirect(w, r, "/second", http.StatusFound)
+			return
+		}
+		http.Redirect(w, r, "https://example.com/", http.StatusFound)
+	}))
+	defer srv.Close()
+
+	// клиент по умолчанию не следует редиректам: Expand раскрывает ровно один шаг
+	e := New(Config{Domains: []string{mustHost(t, srv.URL)}})
+	next, ok, err := e.Expand(context.Background(), srv.URL+"/first")
+	if err != nil || !ok || next != srv.URL+"/second" {
+		t.Fatalf("Expand = %q, %v, %v", next, ok, err)
+	}
+}
+
+func TestExpandUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	addr := srv.URL
+	srv.Close()
+
+	e := New(Config{Domains: []string{mustHost(t, addr)}})
+	_, ok, err := e.Expand(context.Background(), addr+"/x")
+	if !ok || err == nil || errors.Is(err, ErrNoRedirect) {
+		t.Fatalf("Expand = %v, %v; want a connection error", ok, err)
+	}
+}
+
+func mustHost(t *testing.T, raw string) string {
+	t.Helper()
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Hostname()
+}
+
+func noRedirects(c *http.Client) *http.Client {
+	c.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	return c
+}
@@ -0,0 +1,271 @@
+package urlpolicy
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultReloadInterval = 10 * time.Second
+
+var (
+	ErrEntryNotFound = errors.New("urlpolicy: entry not found")
+	// ErrReadOnly — запись загружена из файла и меняется только правкой файла.
+	ErrReadOnly = errors.New("urlpolicy: entry is read-only")
+)
+
+type Config struct {
+	// Files — файлы со списками правил; перечитываются при изменении.
+	Files []string
+	// AdminFile хранит записи, добавленные через API; без него они живут до перезапуска.
+	AdminFile      string
+	ReloadInterval time.Duration
+}
+
+// Engine проверяет адреса по спискам правил. Правила из файлов перечитываются
+// на лету, записи администратора хранятся отдельно.
+type Engine struct {
+	cfg   Config
+	rules atomic.Pointer[ruleset]
+
+	mu     sync.Mutex
+	files  map[string]fileState
+	loaded []*Entry
+	admin  []*Entry
+}
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+func New(cfg Config) (*Engine, error) {
+	if cfg.ReloadInterval <= 0 {
+		cfg.ReloadInterval = defaultReloadInterval
+	}
+
+	e := &Engine{cfg: cfg, files: make(map[string]fileState)}
+	if err := e.loadAdmin(); err != nil {
+		return nil, err
+	}
+	if err := e.Reload(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Check возвращает вердикт для адреса; неразборчивый адрес считается разрешённым,
+// его отсекает проверка URL раньше.
+func (e *Engine) Check(rawURL string) Decision {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Decision{Verdict: Allow}
+	}
+	return e.rules.Load().check(u)
+}
+
+func (e *Engine) Entries() []Entry {
+	rs := e.rules.Load()
+	entries := make([]Entry, len(rs.entries))
+	for i, entry := range rs.entries {
+		entries[i] = *entry
+	}
+	return entries
+}
+
+// Add добавляет запись администратора и сразу применяет её.
+func (e *Engine) Add(pattern string, verdict Verdict, reason string) (Entry, error) {
+	entry, err := NewEntry(pattern, verdict, reason)
+	if err != nil {
+		return Entry{}, err
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return Entry{}, err
+	}
+	entry.ID = hex.EncodeToString(id)
+	entry.Source = SourceAdmin
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	admin := append(e.admin[:len(e.admin):len(e.admin)], entry)
+	if err := e.saveAdmin(admin); err != nil {
+		return Entry{}, err
+	}
+	e.admin = admin
+	e.publish()
+	return *entry, nil
+}
+
+func (e *Engine) Remove(id string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for i, entry := range e.admin {
+		if entry.ID != id {
+			continue
+		}
+		admin := append(e.admin[:i:i], e.admin[i+1:]...)
+		if err := e.saveAdmin(admin); err != nil {
+			return err
+		}
+		e.admin = admin
+		e.publish()
+		return nil
+	}
+	for _, entry := range e.loaded {
+		if entry.ID == id {
+			return ErrReadOnly
+		}
+	}
+	return ErrEntryNotFound
+}
+
+// Reload перечитывает файлы правил. При ошибке действуют прежние правила.
+func (e *Engine) Reload() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.reload()
+}
+
+func (e *Engine) reload() error {
+	var loaded []*Entry
+	files := make(map[string]fileState, len(e.cfg.Files))
+	for _, path := range e.cfg.Files {
+		entries, state, err := readList(path)
+		if err != nil {
+			return err
+		}
+		loaded = append(loaded, entries...)
+		files[path] = state
+	}
+
+	e.loaded = loaded
+	e.files = files
+	e.publish()
+	return nil
+}
+
+// Watch перечитывает файлы, у которых поменялись время изменения или размер,
+// пока не отменён ctx.
+func (e *Engine) Watch(ctx context.Context) {
+	if len(e.cfg.Files) == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(e.cfg.ReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if !e.changed() {
+			continue
+		}
+		if err := e.Reload(); err != nil {
+			log.Printf("urlpolicy: reload: %v", err)
+			continue
+		}
+		log.Printf("urlpolicy: reloaded %d rules", len(e.Entries()))
+	}
+}
+
+func (e *Engine) changed() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, path := range e.cfg.Files {
+		info, err := os.Stat(path)
+		if err != nil {
+			return true
+		}
+		if prev := e.files[path]; !info.ModTime().Equal(prev.modTime) || info.Size() != prev.size {
+			return true
+		}
+	}
+	return false
+}
+
+// publish собирает новый набор правил; вызывается под mu.
+func (e *Engine) publish() {
+	entries := make([]*Entry, 0, len(e.loaded)+len(e.admin))
+	entries = append(append(entries, e.loaded...), e.admin...)
+	e.rules.Store(newRuleset(entries))
+}
+
+func readList(path string) ([]*Entry, fileState, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fileState{}, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fileState{}, err
+	}
+	entries, err := parseList(f, filepath.Base(path))
+	if err != nil {
+		return nil, fileState{}, err
+	}
+	return entries, fileState{modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+func (e *Engine) loadAdmin() error {
+	if e.cfg.AdminFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(e.cfg.AdminFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var stored []Entry
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return fmt.Errorf("urlpolicy: %s: %w", e.cfg.AdminFile, err)
+	}
+	for _, s := range stored {
+		entry, err := NewEntry(s.Pattern, s.Verdict, s.Reason)
+		if err != nil {
+			return fmt.Errorf("urlpolicy: %s: %w", e.cfg.AdminFile, err)
+		}
+		entry.ID, entry.Source = s.ID, SourceAdmin
+		e.admin = append(e.admin, entry)
+	}
+	return nil
+}
+
+// saveAdmin пишет записи во временный файл и подменяет им старый, чтобы сбой
+// посередине записи не оставил файл обрезанным.
+func (e *Engine) saveAdmin(entries []*Entry) error {
+	if e.cfg.AdminFile == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := e.cfg.AdminFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, e.cfg.AdminFile)
+}
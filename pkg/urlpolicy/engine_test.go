@@ -0,0 +1,233 @@
+package urlpolicy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// hashPattern — правило по четырёхбайтовому префиксу хэша выражения хост+путь.
+func hashPattern(expr string) string {
+	sum := sha256.Sum256([]byte(expr))
+	return "sha256:" + hex.EncodeToString(sum[:4])
+}
+
+func writeList(t *testing.T, path, list string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(list), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rules.txt")
+	writeList(t, path, `evil.example
+warn *.shady.example
+allow safe.shady.example
+/^https://regex\.example/login/
+block `+hashPattern("hashed.example/bad/")+`
+block `+hashPattern("sub.hashed.example/exact?q=1")+`
+warn `+hashPattern("ip.example/")+`
+`)
+
+	engine, err := New(Config{Files: []string{path}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		url  string
+		want Verdict
+	}{
+		{url: "https://example.com/", want: Allow},
+		{url: "https://evil.example/path", want: Block},
+		{url: "https://EVIL.example./", want: Block},
+		{url: "https://sub.evil.example/", want: Allow},
+		{url: "https://shady.example/", want: Warn},
+		{url: "https://a.b.shady.example/", want: Warn},
+		{url: "https://safe.shady.example/", want: Allow},
+		{url: "https://regex.example/login/form", want: Block},
+		{url: "http://regex.example/login/form", want: Allow},
+		{url: "https://regex.example/", want: Allow},
+		{url: "https://hashed.example/bad/page?x=1", want: Block},
+		{url: "https://www.hashed.example/bad/deeper/page", want: Block},
+		{url: "https://hashed.example/good/", want: Allow},
+		{url: "https://sub.hashed.example/exact?q=1", want: Block},
+		{url: "https://sub.hashed.example/exact?q=2", want: Allow},
+		{url: "https://a.b.c.d.e.ip.example/x", want: Warn},
+		{url: "://not a url", want: Allow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			got := engine.Check(tt.url)
+			if got.Verdict != tt.want {
+				t.Errorf("Check(%q) = %s, want %s", tt.url, got.Verdict, tt.want)
+			}
+			if tt.want != Allow && got.Entry == nil {
+				t.Errorf("Check(%q) has no matched entry", tt.url)
+			}
+		})
+	}
+}
+
+func TestExpressions(t *testing.T) {
+	got := expressions("a.b.c.d.e.f.example", mustParse(t, "https://a.b.c.d.e.f.example/1/2/3/4/5.html?q=1"))
+	want := map[string]bool{
+		"a.b.c.d.e.f.example/1/2/3/4/5.html?q=1": true,
+		"a.b.c.d.e.f.example/1/2/3/4/5.html":     true,
+		"a.b.c.d.e.f.example/":                   true,
+		"a.b.c.d.e.f.example/1/2/3/":             true,
+		"c.d.e.f.example/":                       true,
+		"f.example/1/":                           true,
+	}
+	has := make(map[string]bool, len(got))
+	for _, expr := range got {
+		has[expr] = true
+	}
+	for expr := range want {
+		if !has[expr] {
+			t.Errorf("expressions do not include %q: %v", expr, got)
+		}
+	}
+	// не больше пяти хостов и шести путей, как в Safe Browsing
+	if len(got) > 5*6 || has["b.c.d.e.f.example/"] || has["a.b.c.d.e.f.example/1/2/3/4/"] {
+		t.Errorf("too many expressions: %v", got)
+	}
+
+	ip := expressions("192.0.2.1", mustParse(t, "http://192.0.2.1/"))
+	if len(ip) != 1 || ip[0] != "192.0.2.1/" {
+		t.Errorf("expressions for an IP = %v", ip)
+	}
+}
+
+func TestReloadKeepsRulesOnError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.txt")
+	writeList(t, path, "evil.example\n")
+	engine, err := New(Config{Files: []string{path}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeList(t, path, "evil.example\nhttps://broken/\n")
+	if err := engine.Reload(); !errors.Is(err, ErrInvalidEntry) {
+		t.Fatalf("Reload() = %v, want ErrInvalidEntry", err)
+	}
+	if got := engine.Check("https://evil.example/").Verdict; got != Block {
+		t.Errorf("after failed reload verdict = %s, want block", got)
+	}
+
+	writeList(t, path, "other.example\n")
+	if err := engine.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if engine.Check("https://evil.example/").Verdict != Allow || engine.Check("https://other.example/").Verdict != Block {
+		t.Errorf("reload did not replace rules: %+v", engine.Entries())
+	}
+}
+
+func TestWatchReloadsChangedFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.txt")
+	writeList(t, path, "evil.example\n")
+	engine, err := New(Config{Files: []string{path}, ReloadInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		engine.Watch(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	writeList(t, path, "evil.example\nwarn other.example\n")
+	deadline := time.Now().Add(2 * time.Second)
+	for engine.Check("https://other.example/").Verdict != Warn {
+		if time.Now().After(deadline) {
+			t.Fatal("changed file was not reloaded")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestAdminEntries(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rules.txt")
+	adminFile := filepath.Join(dir, "admin.json")
+	writeList(t, path, "evil.example\n")
+
+	engine, err := New(Config{Files: []string{path}, AdminFile: adminFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	added, err := engine.Add("*.spam.example", Warn, "spam")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added.ID == "" || added.Source != SourceAdmin {
+		t.Errorf("added entry = %+v", added)
+	}
+	if _, err := engine.Add("bad pattern/", Block, ""); !errors.Is(err, ErrInvalidEntry) {
+		t.Errorf("Add(invalid) = %v, want ErrInvalidEntry", err)
+	}
+	if got := engine.Check("https://x.spam.example/").Verdict; got != Warn {
+		t.Errorf("admin entry is not applied: %s", got)
+	}
+
+	if err := engine.Remove("rules.txt:1"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Remove(file entry) = %v, want ErrReadOnly", err)
+	}
+	if err := engine.Remove("missing"); !errors.Is(err, ErrEntryNotFound) {
+		t.Errorf("Remove(missing) = %v, want ErrEntryNotFound", err)
+	}
+
+	// записи администратора переживают перезапуск и перезагрузку файлов
+	restarted, err := New(Config{Files: []string{path}, AdminFile: adminFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := restarted.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := restarted.Check("https://x.spam.example/").Verdict; got != Warn {
+		t.Errorf("admin entry lost after restart: %s", got)
+	}
+
+	if err := restarted.Remove(added.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := restarted.Check("https://x.spam.example/").Verdict; got != Allow {
+		t.Errorf("removed entry still applies: %s", got)
+	}
+	if _, err := os.Stat(adminFile + ".tmp"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+	again, err := New(Config{AdminFile: adminFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again.Entries()) != 0 {
+		t.Errorf("removed entry was not saved: %+v", again.Entries())
+	}
+}
+
+func mustParse(t *testing.T, raw string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
@@ -0,0 +1,147 @@
+package urlpolicy
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+type Verdict string
+
+const (
+	// Allow — запись из allowlist; перекрывает любые блокировки.
+	Allow Verdict = "allow"
+	// Warn — переход только через страницу-предупреждение.
+	Warn Verdict = "warn"
+	// Block запрещает создавать ссылки на адрес и отключает существующие.
+	Block Verdict = "block"
+)
+
+type Kind string
+
+const (
+	KindHost   Kind = "host"
+	KindSuffix Kind = "suffix"
+	KindRegex  Kind = "regex"
+	KindHash   Kind = "hash"
+)
+
+// SourceAdmin — источник записей, добавленных через API.
+const SourceAdmin = "admin"
+
+var ErrInvalidEntry = errors.New("urlpolicy: invalid entry")
+
+// Entry — одно правило. Вид правила определяется шаблоном:
+//
+//	evil.example         точный хост
+//	*.evil.example       хост и все его поддомены
+//	/^https?://x\.y/a/   регулярное выражение по всему URL
+//	sha256:1a2b3c4d      префикс SHA-256 выражения хост+путь, как в Safe Browsing
+type Entry struct {
+	ID      string  `json:"id"`
+	Kind    Kind    `json:"kind"`
+	Pattern string  `json:"pattern"`
+	Verdict Verdict `json:"verdict"`
+	Reason  string  `json:"reason,omitempty"`
+	Source  string  `json:"source"`
+
+	value  string
+	regex  *regexp.Regexp
+	prefix []byte
+}
+
+// NewEntry разбирает шаблон и проверяет вердикт; пустой вердикт означает Block.
+func NewEntry(pattern string, verdict Verdict, reason string) (*Entry, error) {
+	if verdict == "" {
+		verdict = Block
+	}
+	if verdict != Allow && verdict != Warn && verdict != Block {
+		return nil, fmt.Errorf("%w: unknown verdict %q", ErrInvalidEntry, verdict)
+	}
+
+	e := &Entry{Pattern: strings.TrimSpace(pattern), Verdict: verdict, Reason: strings.TrimSpace(reason)}
+	p := e.Pattern
+	switch {
+	case p == "":
+		return nil, fmt.Errorf("%w: empty pattern", ErrInvalidEntry)
+
+	case strings.HasPrefix(p, "sha256:"):
+		prefix, err := hex.DecodeString(strings.TrimPrefix(p, "sha256:"))
+		if err != nil || len(prefix) < 4 || len(prefix) > 32 {
+			return nil, fmt.Errorf("%w: hash prefix must be 4 to 32 bytes of hex", ErrInvalidEntry)
+		}
+		e.Kind, e.prefix = KindHash, prefix
+
+	case len(p) > 2 && strings.HasPrefix(p, "/") && strings.HasSuffix(p, "/"):
+		re, err := regexp.Compile(p[1 : len(p)-1])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidEntry, err)
+		}
+		e.Kind, e.regex = KindRegex, re
+
+	case strings.HasPrefix(p, "*."):
+		host, err := asciiHost(p[2:])
+		if err != nil {
+			return nil, err
+		}
+		e.Kind, e.value = KindSuffix, host
+
+	default:
+		host, err := asciiHost(p)
+		if err != nil {
+			return nil, err
+		}
+		e.Kind, e.value = KindHost, host
+	}
+
+	return e, nil
+}
+
+func asciiHost(s string) (string, error) {
+	if strings.ContainsAny(s, "/:*@ ") {
+		return "", fmt.Errorf("%w: %q is not a host name", ErrInvalidEntry, s)
+	}
+	host, err := idna.Lookup.ToASCII(strings.TrimSuffix(s, "."))
+	if err != nil || host == "" {
+		return "", fmt.Errorf("%w: %q is not a host name", ErrInvalidEntry, s)
+	}
+	return strings.ToLower(host), nil
+}
+
+// parseList читает файл правил: по строке "[allow|warn|block] шаблон [причина]",
+// строки с # — комментарии.
+func parseList(r io.Reader, source string) ([]*Entry, error) {
+	var entries []*Entry
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		verdict := Block
+		if v := Verdict(strings.ToLower(fields[0])); v == Allow || v == Warn || v == Block {
+			verdict = v
+			fields = fields[1:]
+		}
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("%s:%d: %w: missing pattern", source, line, ErrInvalidEntry)
+		}
+
+		e, err := NewEntry(fields[0], verdict, strings.Join(fields[1:], " "))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", source, line, err)
+		}
+		e.ID = fmt.Sprintf("%s:%d", source, line)
+		e.Source = source
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
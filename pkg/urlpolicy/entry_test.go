@@ -0,0 +1,107 @@
+package urlpolicy
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNewEntry(t *testing.T) {
+	tests := []struct {
+		pattern   string
+		verdict   Verdict
+		wantKind  Kind
+		wantValue string
+		wantErr   bool
+	}{
+		{pattern: "Evil.Example.", wantKind: KindHost, wantValue: "evil.example"},
+		{pattern: "*.evil.example", wantKind: KindSuffix, wantValue: "evil.example"},
+		{pattern: "пример.рф", wantKind: KindHost, wantValue: "xn--e1afmkfd.xn--p1ai"},
+		{pattern: `/^https?://x\.example/login/`, wantKind: KindRegex},
+		{pattern: "sha256:0123abcd", wantKind: KindHash},
+		{pattern: "evil.example", verdict: Warn, wantKind: KindHost, wantValue: "evil.example"},
+		{pattern: ""},
+		{pattern: "   "},
+		{pattern: "evil.example", verdict: "maybe"},
+		{pattern: "https://evil.example/"},
+		{pattern: "evil.example:443"},
+		{pattern: "*.*.example"},
+		{pattern: "/([a-/"},
+		{pattern: "sha256:0123ab"},
+		{pattern: "sha256:xyz12345"},
+		{pattern: "sha256:" + strings.Repeat("ab", 33)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			e, err := NewEntry(tt.pattern, tt.verdict, " reason ")
+			if tt.wantKind == "" {
+				if !errors.Is(err, ErrInvalidEntry) {
+					t.Fatalf("NewEntry(%q) = %+v, %v; want ErrInvalidEntry", tt.pattern, e, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantVerdict := tt.verdict
+			if wantVerdict == "" {
+				wantVerdict = Block
+			}
+			if e.Kind != tt.wantKind || e.value != tt.wantValue || e.Verdict != wantVerdict || e.Reason != "reason" {
+				t.Errorf("NewEntry(%q) = %+v", tt.pattern, e)
+			}
+		})
+	}
+}
+
+func TestParseList(t *testing.T) {
+	list := `# список правил
+evil.example phishing site
+
+warn *.shady.example   suspicious hosting
+ALLOW good.evil.example
+block sha256:0123abcd
+`
+	entries, err := parseList(strings.NewReader(list), "rules.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		id, pattern string
+		verdict     Verdict
+		reason      string
+	}{
+		{"rules.txt:2", "evil.example", Block, "phishing site"},
+		{"rules.txt:4", "*.shady.example", Warn, "suspicious hosting"},
+		{"rules.txt:5", "good.evil.example", Allow, ""},
+		{"rules.txt:6", "sha256:0123abcd", Block, ""},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(want))
+	}
+	for i, w := range want {
+		e := entries[i]
+		if e.ID != w.id || e.Pattern != w.pattern || e.Verdict != w.verdict || e.Reason != w.reason || e.Source != "rules.txt" {
+			t.Errorf("entry %d = %+v, want %+v", i, e, w)
+		}
+	}
+}
+
+func TestParseListErrors(t *testing.T) {
+	tests := []struct {
+		name, list, wantLine string
+	}{
+		{name: "verdict without pattern", list: "ok.example\nwarn\n", wantLine: "rules.txt:2"},
+		{name: "bad pattern", list: "# comment\nhttps://evil.example/\n", wantLine: "rules.txt:2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseList(strings.NewReader(tt.list), "rules.txt")
+			if !errors.Is(err, ErrInvalidEntry) || !strings.HasPrefix(err.Error(), tt.wantLine+":") {
+				t.Errorf("error = %v, want ErrInvalidEntry at %s", err, tt.wantLine)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package urlpolicy
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"net/netip"
+	"net/url"
+	"strings"
+)
+
+const (
+	maxHostSuffixes = 4
+	maxPathPrefixes = 4
+	// сколько последних компонентов хоста учитываем, как в Safe Browsing
+	maxHostComponents = 5
+)
+
+// Decision — итог проверки адреса; Entry — сработавшее правило, если было.
+type Decision struct {
+	Verdict Verdict
+	Entry   *Entry
+}
+
+var severity = map[Verdict]int{Allow: 0, Warn: 1, Block: 2}
+
+// ruleset — скомпилированные правила; после сборки не меняется.
+type ruleset struct {
+	entries  []*Entry
+	hosts    map[string][]*Entry
+	suffixes map[string][]*Entry
+	regexps  []*Entry
+	// хэш-правила сгруппированы по первым четырём байтам префикса
+	hashes map[[4]byte][]*Entry
+}
+
+func newRuleset(entries []*Entry) *ruleset {
+	rs := &ruleset{
+		entries:  entries,
+		hosts:    make(map[string][]*Entry),
+		suffixes: make(map[string][]*Entry),
+		hashes:   make(map[[4]byte][]*Entry),
+	}
+	for _, e := range entries {
+		switch e.Kind {
+		case KindHost:
+			rs.hosts[e.value] = append(rs.hosts[e.value], e)
+		case KindSuffix:
+			rs.suffixes[e.value] = append(rs.suffixes[e.value], e)
+		case KindRegex:
+			rs.regexps = append(rs.regexps, e)
+		case KindHash:
+			key := [4]byte(e.prefix)
+			rs.hashes[key] = append(rs.hashes[key], e)
+		}
+	}
+	return rs
+}
+
+// check собирает все сработавшие правила: любое Allow перекрывает блокировки,
+// иначе побеждает самый строгий вердикт.
+func (rs *ruleset) check(u *url.URL) Decision {
+	var matched []*Entry
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+
+	matched = append(matched, rs.hosts[host]...)
+	for h := host; h != ""; {
+		matched = append(matched, rs.suffixes[h]...)
+		_, parent, ok := strings.Cut(h, ".")
+		if !ok {
+			break
+		}
+		h = parent
+	}
+
+	full := u.String()
+	for _, e := range rs.regexps {
+		if e.regex.MatchString(full) {
+			matched = append(matched, e)
+		}
+	}
+
+	if len(rs.hashes) > 0 {
+		for _, expr := range expressions(host, u) {
+			sum := sha256.Sum256([]byte(expr))
+			for _, e := range rs.hashes[[4]byte(sum[:4])] {
+				if bytes.HasPrefix(sum[:], e.prefix) {
+					matched = append(matched, e)
+				}
+			}
+		}
+	}
+
+	decision := Decision{Verdict: Allow}
+	for _, e := range matched {
+		if e.Verdict == Allow {
+			return Decision{Verdict: Allow, Entry: e}
+		}
+		if severity[e.Verdict] > severity[decision.Verdict] {
+			decision = Decision{Verdict: e.Verdict, Entry: e}
+		}
+	}
+	return decision
+}
+
+// expressions строит выражения хост+путь для поиска по хэшам: точный хост и до
+// четырёх его родительских доменов, точный путь с запросом и без, и до четырёх
+// префиксов пути, начиная с корня.
+func expressions(host string, u *url.URL) []string {
+	hosts := []string{host}
+	if _, err := netip.ParseAddr(strings.Trim(host, "[]")); err != nil {
+		parts := strings.Split(host, ".")
+		if len(parts) > maxHostComponents {
+			parts = parts[len(parts)-maxHostComponents:]
+		}
+		for i := 0; i+2 <= len(parts) && len(hosts) <= maxHostSuffixes; i++ {
+			if suffix := strings.Join(parts[i:], "."); suffix != host {
+				hosts = append(hosts, suffix)
+			}
+		}
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	var paths []string
+	if u.RawQuery != "" {
+		paths = append(paths, path+"?"+u.RawQuery)
+	}
+	paths = append(paths, path)
+	prefix := "/"
+	for _, segment := range strings.Split(strings.Trim(path, "/"), "/") {
+		if len(paths) >= maxPathPrefixes+2 || prefix == path {
+			break
+		}
+		paths = append(paths, prefix)
+		if segment == "" {
+			break
+		}
+		prefix += segment + "/"
+	}
+
+	exprs := make([]string, 0, len(hosts)*len(paths))
+	for _, h := range hosts {
+		for _, p := range paths {
+			exprs = append(exprs, h+p)
+		}
+	}
+	return exprs
+}
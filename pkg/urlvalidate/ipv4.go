@@ -0,0 +1,89 @@
+package urlvalidate
+
+import (
+	"errors"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// parseIPv4 разбирает хост так же, как браузеры (WHATWG URL): "127.1", "0x7f.0.0.1" и
+// "2130706433" — это 127.0.0.1. ok=false значит, что хост не похож на IPv4 и
+// разбирается как доменное имя.
+func parseIPv4(host string) (addr netip.Addr, ok bool, err error) {
+	parts := strings.Split(host, ".")
+	if len(parts) > 1 && parts[len(parts)-1] == "" {
+		parts = parts[:len(parts)-1]
+	}
+	// хост считается IPv4, только если последняя метка числовая; "08" тоже
+	// числовая, но недопустимая, и такой хост браузеры отклоняют
+	if !endsInNumber(parts[len(parts)-1]) {
+		return netip.Addr{}, false, nil
+	}
+	if len(parts) > 4 {
+		return netip.Addr{}, true, errors.New("url has an invalid IPv4 address")
+	}
+
+	values := make([]uint64, len(parts))
+	for i, p := range parts {
+		n, numeric := parseIPv4Part(p)
+		if !numeric {
+			return netip.Addr{}, true, errors.New("url has an invalid IPv4 address")
+		}
+		values[i] = n
+	}
+
+	// все части, кроме последней, — по байту; последняя заполняет оставшиеся байты
+	last := values[len(values)-1]
+	if last >= 1<<(8*(5-len(values))) {
+		return netip.Addr{}, true, errors.New("url has an invalid IPv4 address")
+	}
+	var ip uint64
+	for _, n := range values[:len(values)-1] {
+		if n > 255 {
+			return netip.Addr{}, true, errors.New("url has an invalid IPv4 address")
+		}
+		ip = ip<<8 | n
+	}
+	ip = ip<<(8*(5-len(values))) | last
+
+	return netip.AddrFrom4([4]byte{byte(ip >> 24), byte(ip >> 16), byte(ip >> 8), byte(ip)}), true, nil
+}
+
+// endsInNumber — проверка "ends in a number" из WHATWG URL: метка из одних
+// десятичных цифр или 0x с шестнадцатеричными.
+func endsInNumber(label string) bool {
+	if len(label) >= 2 && (label[:2] == "0x" || label[:2] == "0X") {
+		label = label[2:]
+		return strings.Trim(label, "0123456789abcdefABCDEF") == ""
+	}
+	return label != "" && strings.Trim(label, "0123456789") == ""
+}
+
+// parseIPv4Part понимает десятичную, восьмеричную (0…) и шестнадцатеричную (0x…) запись.
+func parseIPv4Part(s string) (uint64, bool) {
+	if s == "" {
+		return 0, false
+	}
+	base := 10
+	switch {
+	case len(s) >= 2 && (s[:2] == "0x" || s[:2] == "0X"):
+		s, base = s[2:], 16
+		if s == "" {
+			return 0, true
+		}
+	case len(s) > 1 && s[0] == '0':
+		s, base = s[1:], 8
+	}
+
+	n, err := strconv.ParseUint(s, base, 64)
+	if err != nil {
+		// переполнение — всё ещё число, но недопустимое
+		var numErr *strconv.NumError
+		if errors.As(err, &numErr) && errors.Is(numErr.Err, strconv.ErrRange) {
+			return 1 << 40, true
+		}
+		return 0, false
+	}
+	return n, true
+}
@@ -0,0 +1,49 @@
+package urlvalidate
+
+import "testing"
+
+func TestParseIPv4(t *testing.T) {
+	tests := []struct {
+		host    string
+		want    string
+		isIPv4  bool
+		wantErr bool
+	}{
+		{host: "1.2.3.4", want: "1.2.3.4", isIPv4: true},
+		{host: "1.2.3.4.", want: "1.2.3.4", isIPv4: true},
+		{host: "0x7f.0.0.1", want: "127.0.0.1", isIPv4: true},
+		{host: "0X7F.1", want: "127.0.0.1", isIPv4: true},
+		{host: "0177.0.0.01", want: "127.0.0.1", isIPv4: true},
+		{host: "2130706433", want: "127.0.0.1", isIPv4: true},
+		{host: "0x7f000001", want: "127.0.0.1", isIPv4: true},
+		{host: "127.65537", want: "127.1.0.1", isIPv4: true},
+		{host: "10.1.65535", want: "10.1.255.255", isIPv4: true},
+		{host: "0x", want: "0.0.0.0", isIPv4: true},
+		{host: "4294967295", want: "255.255.255.255", isIPv4: true},
+		{host: "4294967296", isIPv4: true, wantErr: true},
+		{host: "1.256", want: "1.0.1.0", isIPv4: true},
+		{host: "1.16777216", isIPv4: true, wantErr: true},
+		{host: "1.2.65536", isIPv4: true, wantErr: true},
+		{host: "256.0.0.1", isIPv4: true, wantErr: true},
+		{host: "1.2.3.4.5", isIPv4: true, wantErr: true},
+		{host: "1.2.3.09", isIPv4: true, wantErr: true},
+		{host: "example.1", isIPv4: true, wantErr: true},
+		{host: "1..1", isIPv4: true, wantErr: true},
+		{host: "example.com"},
+		{host: "1.2.3.example"},
+		{host: "1.2.3.0xg"},
+		{host: "localhost"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			addr, isIPv4, err := parseIPv4(tt.host)
+			if isIPv4 != tt.isIPv4 || (err != nil) != tt.wantErr {
+				t.Fatalf("parseIPv4(%q) = %v, %v, %v", tt.host, addr, isIPv4, err)
+			}
+			if tt.want != "" && addr.String() != tt.want {
+				t.Errorf("parseIPv4(%q) = %s, want %s", tt.host, addr, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,281 @@
+package urlvalidate
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/idna"
+)
+
+const (
+	DefaultMaxLength = 2048
+	maxHostLength    = 253
+	resolveTimeout   = 2 * time.Second
+)
+
+// Коды ошибок стабильны и уходят клиентам в поле code.
+const (
+	CodeInvalid             = "invalid_url"
+	CodeTooLong             = "url_too_long"
+	CodeSchemeNotAllowed    = "scheme_not_allowed"
+	CodeCredentials         = "credentials_not_allowed"
+	CodeInvalidHost         = "invalid_host"
+	CodePrivateAddress      = "private_address"
+	CodeUnresolvableAddress = "unresolvable_host"
+)
+
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+type Config struct {
+	// Schemes — схемы кроме http и https, например для deep link в приложения.
+	// Небезопасные схемы (см. UnsafeScheme) отбрасываются.
+	Schemes   []string
+	MaxLength int
+	// StripFragment убирает #фрагмент; по умолчанию он сохраняется.
+	StripFragment bool
+	// AllowPrivate пропускает loopback, частные и link-local адреса.
+	AllowPrivate bool
+	// Resolver, если задан, проверяет адреса, в которые резолвится хост http(s).
+	Resolver *net.Resolver
+}
+
+// Validator проверяет адрес назначения и приводит его к каноническому виду.
+type Validator struct {
+	cfg     Config
+	schemes map[string]bool
+	profile *idna.Profile
+}
+
+func New(cfg Config) *Validator {
+	if cfg.MaxLength <= 0 {
+		cfg.MaxLength = DefaultMaxLength
+	}
+
+	schemes := map[string]bool{"http": true, "https": true}
+	for _, s := range cfg.Schemes {
+		if s = strings.ToLower(strings.TrimSpace(s)); s != "" && !UnsafeScheme(s) {
+			schemes[s] = true
+		}
+	}
+
+	return &Validator{
+		cfg:     cfg,
+		schemes: schemes,
+		profile: idna.New(
+			idna.MapForLookup(),
+			idna.BidiRule(),
+			idna.Transitional(false),
+			idna.StrictDomainName(true),
+			idna.ValidateLabels(true),
+			idna.VerifyDNSLength(true),
+		),
+	}
+}
+
+// unsafeSchemes исполняют код или открывают локальные данные в контексте
+// страницы, которая на них ссылается.
+var unsafeSchemes = map[string]bool{
+	"javascript": true, "vbscript": true, "data": true, "file": true, "blob": true, "about": true,
+}
+
+// UnsafeScheme сообщает, что адрес со схемой scheme нельзя отдавать посетителю
+// как ссылку, какие бы схемы ни разрешала конфигурация.
+func UnsafeScheme(scheme string) bool {
+	return unsafeSchemes[strings.ToLower(scheme)]
+}
+
+// Normalize возвращает канонический адрес: схема и хост в нижнем регистре, хост в
+// punycode, без порта по умолчанию, с нормализованным percent-encoding и "/" вместо
+// пустого пути у http(s).
+func (v *Validator) Normalize(ctx context.Context, raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if len(raw) > v.cfg.MaxLength {
+		return "", tooLong(v.cfg.MaxLength)
+	}
+	if strings.ContainsAny(raw, " \t\r\n\\") {
+		return "", &Error{Code: CodeInvalid, Message: "url must not contain whitespace or backslashes"}
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" {
+		return "", &Error{Code: CodeInvalid, Message: "url is not a valid absolute URL"}
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	if !v.schemes[u.Scheme] {
+		return "", &Error{Code: CodeSchemeNotAllowed, Message: "url scheme " + u.Scheme + " is not allowed"}
+	}
+	if u.User != nil {
+		return "", &Error{Code: CodeCredentials, Message: "url must not contain credentials"}
+	}
+
+	web := u.Scheme == "http" || u.Scheme == "https"
+	if web && (u.Opaque != "" || u.Host == "") {
+		return "", &Error{Code: CodeInvalid, Message: "url must have a host"}
+	}
+
+	if u.Host != "" {
+		if err := v.normalizeHost(u); err != nil {
+			return "", err
+		}
+	}
+
+	if u.Opaque == "" {
+		path := normalizePercent(u.EscapedPath())
+		if web && path == "" {
+			path = "/"
+		}
+		u.Path, _ = url.PathUnescape(path)
+		u.RawPath = path
+	}
+	u.RawQuery = normalizePercent(u.RawQuery)
+	if v.cfg.StripFragment {
+		u.Fragment, u.RawFragment = "", ""
+	}
+
+	if web && !v.cfg.AllowPrivate {
+		if err := v.checkAddress(ctx, u.Hostname()); err != nil {
+			return "", err
+		}
+	}
+
+	normalized := u.String()
+	if len(normalized) > v.cfg.MaxLength {
+		return "", tooLong(v.cfg.MaxLength)
+	}
+	return normalized, nil
+}
+
+func (v *Validator) normalizeHost(u *url.URL) error {
+	host, port := u.Hostname(), u.Port()
+	if strings.HasSuffix(u.Host, ":") {
+		return invalidHost("url has an empty port")
+	}
+
+	switch {
+	case strings.HasPrefix(u.Host, "["):
+		addr, err := netip.ParseAddr(host)
+		if err != nil || !addr.Is6() || addr.Zone() != "" {
+			return invalidHost("url has an invalid IPv6 address")
+		}
+		host = "[" + addr.String() + "]"
+	default:
+		host = strings.TrimSuffix(host, ".")
+		addr, isIPv4, err := parseIPv4(host)
+		if err != nil {
+			return invalidHost(err.Error())
+		}
+		if isIPv4 {
+			host = addr.String()
+			break
+		}
+
+		ascii, err := v.profile.ToASCII(host)
+		if err != nil || ascii == "" || len(ascii) > maxHostLength {
+			return invalidHost("url has an invalid host name")
+		}
+		host = ascii
+	}
+
+	if port != "" {
+		n, err := strconv.Atoi(port)
+		if err != nil || n < 1 || n > 65535 {
+			return invalidHost("url has an invalid port")
+		}
+		port = strconv.Itoa(n)
+	}
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	if port != "" {
+		host += ":" + port
+	}
+	u.Host = host
+	return nil
+}
+
+// checkAddress отклоняет адреса внутри сети: литеральные IP, localhost и, если задан
+// Resolver, имена, которые в них резолвятся.
+func (v *Validator) checkAddress(ctx context.Context, host string) error {
+	if addr, err := netip.ParseAddr(strings.Trim(host, "[]")); err == nil {
+		return checkIP(addr)
+	}
+
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return privateAddress()
+	}
+	if v.cfg.Resolver == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, resolveTimeout)
+	defer cancel()
+	addrs, err := v.cfg.Resolver.LookupNetIP(ctx, "ip", host)
+	if err != nil || len(addrs) == 0 {
+		return &Error{Code: CodeUnresolvableAddress, Message: "url host does not resolve"}
+	}
+	for _, addr := range addrs {
+		if err := checkIP(addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkIP(addr netip.Addr) error {
+	if !IsPublic(addr) {
+		return privateAddress()
+	}
+	return nil
+}
+
+// IsPublic сообщает, что адрес не loopback, не частный, не link-local и не из
+// специальных диапазонов, то есть запрос на него не уйдёт во внутреннюю сеть.
+func IsPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return !(addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() || addr.IsUnspecified() || isSpecialPurpose(addr))
+}
+
+// специальные диапазоны, которых нет в методах netip: CGNAT, 0.0.0.0/8, benchmark, reserved
+var specialPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+func isSpecialPurpose(addr netip.Addr) bool {
+	for _, p := range specialPrefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func tooLong(limit int) *Error {
+	return &Error{Code: CodeTooLong, Message: "url must not be longer than " + strconv.Itoa(limit) + " characters"}
+}
+
+func invalidHost(message string) *Error {
+	return &Error{Code: CodeInvalidHost, Message: message}
+}
+
+func privateAddress() *Error {
+	return &Error{Code: CodePrivateAddress, Message: "url must not point to a private, loopback or link-local address"}
+}
@@ -0,0 +1,70 @@
+// Package useragent определяет платформу посетителя по заголовку User-Agent.
+// Разбор намеренно грубый: нужна только операционная система, чтобы выбрать
+// магазин приложений или веб-адрес.
+package useragent
+
+import "strings"
+
+type Platform string
+
+const (
+	IOS      Platform = "ios"
+	Android  Platform = "android"
+	Windows  Platform = "windows"
+	MacOS    Platform = "macos"
+	Linux    Platform = "linux"
+	ChromeOS Platform = "chromeos"
+	Unknown  Platform = ""
+)
+
+// Mobile — iOS и Android.
+func (p Platform) Mobile() bool {
+	return p == IOS || p == Android
+}
+
+// Desktop — настольные ОС. iPad с iPadOS 13+ по умолчанию представляется как
+// Macintosh и по заголовку от Mac не отличается.
+func (p Platform) Desktop() bool {
+	return p == Windows || p == MacOS || p == Linux || p == ChromeOS
+}
+
+type Info struct {
+	Platform Platform
+	// Bot — поисковые роботы и сервисы предпросмотра ссылок.
+	Bot bool
+}
+
+var botMarkers = []string{
+	"bot", "crawler", "spider", "slurp", "facebookexternalhit", "embedly", "preview",
+	"curl/", "wget/", "python-requests", "go-http-client",
+}
+
+// Parse разбирает User-Agent. Порядок проверок важен: Android-браузеры пишут
+// Linux, а iOS-браузеры — like Mac OS X.
+func Parse(ua string) Info {
+	s := strings.ToLower(ua)
+
+	info := Info{}
+	for _, marker := range botMarkers {
+		if strings.Contains(s, marker) {
+			info.Bot = true
+			break
+		}
+	}
+
+	switch {
+	case strings.Contains(s, "iphone"), strings.Contains(s, "ipad"), strings.Contains(s, "ipod"):
+		info.Platform = IOS
+	case strings.Contains(s, "android"):
+		info.Platform = Android
+	case strings.Contains(s, "cros "):
+		info.Platform = ChromeOS
+	case strings.Contains(s, "windows"):
+		info.Platform = Windows
+	case strings.Contains(s, "macintosh"), strings.Contains(s, "mac os x"):
+		info.Platform = MacOS
+	case strings.Contains(s, "linux"), strings.Contains(s, "x11"):
+		info.Platform = Linux
+	}
+	return info
+}
@@ -0,0 +1,54 @@
+package useragent
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		ua   string
+		want Info
+	}{
+		{name: "iphone safari", ua: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1", want: Info{Platform: IOS}},
+		{name: "ipad", ua: "Mozilla/5.0 (iPad; CPU OS 12_2 like Mac OS X) AppleWebKit/605.1.15", want: Info{Platform: IOS}},
+		{name: "android chrome", ua: "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Mobile Safari/537.36", want: Info{Platform: Android}},
+		{name: "chromebook", ua: "Mozilla/5.0 (X11; CrOS x86_64 14541.0.0) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Safari/537.36", want: Info{Platform: ChromeOS}},
+		{name: "windows edge", ua: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Safari/537.36 Edg/120.0", want: Info{Platform: Windows}},
+		{name: "mac safari", ua: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Safari/605.1.15", want: Info{Platform: MacOS}},
+		{name: "linux firefox", ua: "Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0", want: Info{Platform: Linux}},
+		{name: "googlebot smartphone", ua: "Mozilla/5.0 (Linux; Android 6.0.1; Nexus 5X Build/MMB29P) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Mobile Safari/537.36 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", want: Info{Platform: Android, Bot: true}},
+		{name: "facebook preview", ua: "facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)", want: Info{Bot: true}},
+		{name: "slack preview", ua: "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)", want: Info{Bot: true}},
+		{name: "curl", ua: "curl/8.4.0", want: Info{Bot: true}},
+		{name: "go client", ua: "Go-http-client/1.1", want: Info{Bot: true}},
+		{name: "empty", ua: "", want: Info{}},
+		{name: "unknown", ua: "SomethingElse/1.0", want: Info{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.ua); got != tt.want {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlatformGroups(t *testing.T) {
+	tests := []struct {
+		p               Platform
+		mobile, desktop bool
+	}{
+		{p: IOS, mobile: true},
+		{p: Android, mobile: true},
+		{p: Windows, desktop: true},
+		{p: MacOS, desktop: true},
+		{p: Linux, desktop: true},
+		{p: ChromeOS, desktop: true},
+		{p: Unknown},
+	}
+	for _, tt := range tests {
+		if tt.p.Mobile() != tt.mobile || tt.p.Desktop() != tt.desktop {
+			t.Errorf("%q: Mobile() = %v, Desktop() = %v", tt.p, tt.p.Mobile(), tt.p.Desktop())
+		}
+	}
+}